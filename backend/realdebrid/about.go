@@ -0,0 +1,45 @@
+package realdebrid
+
+import (
+	"context"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// aboutFree is the free space reported by the synthetic about_policy
+// while the account is premium
+const aboutFree = 1 << 50 // 1 PiB
+
+// realUsage returns the size of the torrents as the space used, the
+// only number Real-Debrid has
+func (f *Fs) realUsage(ctx context.Context) (*fs.Usage, error) {
+	if err := f.refreshTorrents(ctx); err != nil {
+		return nil, err
+	}
+	var used int64
+	for _, torrent := range f.scoped() {
+		used += torrent.Bytes
+	}
+	return &fs.Usage{Used: fs.NewUsageValue(used)}, nil
+}
+
+// syntheticUsage returns the space used with aboutFree free while the
+// account is premium and nothing free once it expired, so a mount
+// shows a sensible size and an expired account is obvious in df.
+func (f *Fs) syntheticUsage(ctx context.Context) (*fs.Usage, error) {
+	usage, err := f.realUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, err := f.client.User(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var free int64
+	if user.Premium > 0 {
+		free = aboutFree
+	}
+	usage.Free = fs.NewUsageValue(free)
+	usage.Total = fs.NewUsageValue(*usage.Used + free)
+	return usage, nil
+}
@@ -0,0 +1,99 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAboutTestFs makes an Fs with 3 GB of torrents on an account with
+// premium seconds left
+func newAboutTestFs(t *testing.T, policy string, premium int64) *Fs {
+	f := newTestFs(t)
+	f.opt.AboutPolicy = policy
+	torrents := []api.Item{
+		{ID: "1", Name: "Show.S01", Status: "downloaded", Bytes: 2e9},
+		{ID: "2", Name: "Movie.2020", Status: "downloaded", Bytes: 1e9},
+	}
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/torrents":
+			w.Header().Set("X-Total-Count", strconv.Itoa(len(torrents)))
+			writeJSON(t, w, torrents)
+		case "/user":
+			writeJSON(t, w, api.User{Username: "jelly", Premium: premium})
+		default:
+			t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	f.cache.startup_cached_api_fetch = true
+	f.cache.torrents = torrents
+	return f
+}
+
+func TestAboutSynthetic(t *testing.T) {
+	ctx := context.Background()
+
+	// Free while premium
+	usage, err := newAboutTestFs(t, "synthetic", 3600).About(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &fs.Usage{
+		Total: fs.NewUsageValue[int64](3e9 + aboutFree),
+		Used:  fs.NewUsageValue[int64](3e9),
+		Free:  fs.NewUsageValue[int64](aboutFree),
+	}, usage)
+
+	// and full once expired
+	usage, err = newAboutTestFs(t, "", 0).About(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &fs.Usage{
+		Total: fs.NewUsageValue[int64](3e9),
+		Used:  fs.NewUsageValue[int64](3e9),
+		Free:  fs.NewUsageValue[int64](0),
+	}, usage)
+
+	// API errors are returned
+	f := newAboutTestFs(t, "synthetic", 3600)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	f.client.pacer.SetRetries(1)
+	_, err = f.About(ctx)
+	checkOpError(t, err, "about", "", "", "")
+}
+
+func TestAboutReal(t *testing.T) {
+	usage, err := newAboutTestFs(t, "real", 3600).About(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &fs.Usage{Used: fs.NewUsageValue[int64](3e9)}, usage)
+}
+
+func TestAboutOff(t *testing.T) {
+	ctx := context.Background()
+	usage, err := newAboutTestFs(t, "off", 3600).About(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, usage)
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+	oldRootURL, oldDumpDir := rootURL, dumpDir
+	rootURL, dumpDir = srv.URL, t.TempDir()
+	t.Cleanup(func() {
+		rootURL, dumpDir = oldRootURL, oldDumpDir
+	})
+	f, err := NewFs(ctx, "about", "", configmap.Simple{"api_key": "about-test", "about_policy": "off"})
+	require.NoError(t, err)
+	assert.Nil(t, f.Features().About)
+
+	_, err = NewFs(ctx, "about", "", configmap.Simple{"api_key": "about-test", "about_policy": "fake"})
+	assert.ErrorContains(t, err, `unknown about_policy "fake"`)
+}
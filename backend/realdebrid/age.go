@@ -0,0 +1,40 @@
+package realdebrid
+
+import (
+	"context"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs/filter"
+)
+
+// addedWithin returns true if torrent was added between from and to,
+// either of which may be zero for no limit.
+//
+// The torrents without a known added time always are.
+func addedWithin(torrent *api.Item, from, to time.Time) bool {
+	added, err := time.Parse("2006-01-02T15:04:05.000Z", torrent.Ended)
+	if err != nil {
+		return true
+	}
+	return (from.IsZero() || !added.Before(from)) && (to.IsZero() || !added.After(to))
+}
+
+// ageFiltered returns the torrents added within the --max-age and
+// --min-age window of the filters of ctx, so listing with them doesn't
+// fetch the details of the others nor unrestrict their links.
+//
+// The torrents are returned as they are without those filters.
+func ageFiltered(ctx context.Context, torrents []api.Item) []api.Item {
+	fi := filter.GetConfig(ctx)
+	if fi.ModTimeFrom.IsZero() && fi.ModTimeTo.IsZero() {
+		return torrents
+	}
+	kept := make([]api.Item, 0, len(torrents))
+	for i := range torrents {
+		if addedWithin(&torrents[i], fi.ModTimeFrom, fi.ModTimeTo) {
+			kept = append(kept, torrents[i])
+		}
+	}
+	return kept
+}
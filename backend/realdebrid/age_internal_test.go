@@ -0,0 +1,104 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/filter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddedWithin(t *testing.T) {
+	now := time.Now()
+	added := func(ago time.Duration) *api.Item {
+		return &api.Item{Ended: now.Add(-ago).UTC().Format("2006-01-02T15:04:05.000Z")}
+	}
+	week := 7 * 24 * time.Hour
+	assert.True(t, addedWithin(added(time.Hour), now.Add(-week), time.Time{}))
+	assert.False(t, addedWithin(added(2*week), now.Add(-week), time.Time{}))
+	assert.True(t, addedWithin(added(2*week), time.Time{}, now.Add(-week)))
+	assert.False(t, addedWithin(added(time.Hour), time.Time{}, now.Add(-week)))
+	assert.True(t, addedWithin(&api.Item{}, now.Add(-week), now))
+}
+
+func TestListAgeFiltered(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	requests := map[string]int{}
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests[r.Method+" "+r.URL.Path]++
+		if path.Dir(r.URL.Path) != "/torrents/info" {
+			t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		id := path.Base(r.URL.Path)
+		writeJSON(t, w, api.Item{
+			ID:     id,
+			Status: "downloaded",
+			Links:  []string{"L" + id},
+			Files:  []api.File{{ID: 1, Path: "/" + id + ".mkv", Bytes: 100, Selected: 1}},
+		})
+	})
+	added := func(ago time.Duration) string {
+		return time.Now().Add(-ago).UTC().Format("2006-01-02T15:04:05.000Z")
+	}
+	f.cache.torrents = []api.Item{
+		{ID: "NEW", Name: "New.Movie.2021", Status: "downloaded", Links: []string{"LNEW"}, Ended: added(time.Hour)},
+		{ID: "OLD", Name: "Old.Movie.2019", Status: "downloaded", Links: []string{"LOLD"}, Ended: added(30 * 24 * time.Hour)},
+	}
+	f.cache.addLink(api.Item{ID: "DNEW", Name: "NEW.mkv", Link: "https://dl/NEW.mkv", OriginalLink: "LNEW", Size: 100})
+	f.cache.addLink(api.Item{ID: "DOLD", Name: "OLD.mkv", Link: "https://dl/OLD.mkv", OriginalLink: "LOLD", Size: 100})
+	filtered, fi := filter.AddConfig(ctx)
+	fi.ModTimeFrom = time.Now().Add(-7 * 24 * time.Hour)
+
+	// The torrents outside the window aren't listed
+	entries, err := f.List(filtered, "movies")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"movies/New.Movie.2021"}, entryNames(entries))
+
+	// nor expanded by ListR
+	var names []string
+	require.NoError(t, f.ListR(filtered, "movies", func(entries fs.DirEntries) error {
+		names = append(names, entryNames(entries)...)
+		return nil
+	}))
+	assert.Equal(t, []string{"movies/New.Movie.2021", "movies/New.Movie.2021/NEW.mkv"}, names)
+	assert.Equal(t, 1, requests["GET /torrents/info/NEW"])
+	assert.Equal(t, 0, requests["GET /torrents/info/OLD"])
+
+	// nor when flattened
+	f.opt.FlattenSingleFile = true
+	f.dirCache.ResetRoot()
+	entries, err = f.List(filtered, "movies")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"movies/NEW.mkv"}, entryNames(entries))
+	assert.Equal(t, 0, requests["GET /torrents/info/OLD"])
+
+	// or in files mode
+	f.opt.SharedFolder = "files"
+	f.dirCache.ResetRoot()
+	entries, err = f.List(filtered, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"NEW.mkv"}, entryNames(entries))
+	assert.Equal(t, 0, requests["GET /torrents/info/OLD"])
+
+	// but are still found by their path
+	f.opt.SharedFolder = "folders"
+	f.opt.FlattenSingleFile = false
+	f.dirCache.ResetRoot()
+	entries, err = f.List(filtered, "movies/Old.Movie.2019")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"movies/Old.Movie.2019/OLD.mkv"}, entryNames(entries))
+
+	// All are listed without age filters
+	entries, err = f.List(ctx, "movies")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"movies/New.Movie.2021", "movies/Old.Movie.2019"}, entryNames(entries))
+}
@@ -6,8 +6,11 @@ import "fmt"
 // Response is returned by all messages and embedded in the
 // structures below
 type Response struct {
-	Message string `json:"message,omitempty"`
-	Status  string `json:"status"`
+	Message      string `json:"message,omitempty"`
+	Status       string `json:"status"`
+	ErrorName    string `json:"error,omitempty"`         // name of the error, eg "hoster_limit_reached"
+	ErrorCode    int    `json:"error_code,omitempty"`    // Real-Debrid error code
+	ErrorDetails string `json:"error_details,omitempty"` // more about the error, eg how long to wait
 }
 
 // Error satisfies the error interface
@@ -30,39 +33,36 @@ const (
 )
 
 // Item refers to a file or folder
+//
+// It only has the fields the backend uses as the torrents and
+// downloads of big accounts are all kept in memory.
 type Item struct {
-	Breadcrumbs     []Breadcrumb ``
-	CreatedAt       int64        ``
-	ID              string       `json:"id,omitempty"`
-	ParentID        string       ``
-	Link            string       `json:"download,omitempty"`
-	OriginalLink    string       `json:"link,omitempty"`
-	Name            string       `json:"filename,omitempty"`
-	Size            int64        `json:"filesize,omitempty"`
-	Status          string       `json:"status,omitempty"`
-	StreamLink      string       ``
-	Type            string       `json:"type,omitempty"`
-	TranscodeStatus string       ``
-	IP              string       ``
-	MimeType        string       `json:"mimeType,omitempty"`
-	Ended           string       `json:"added,omitempty"`
-	Generated       string       `json:"generated,omitempty"`
-	Links           []string     `json:"links,omitempty"`
-	Files           []File       `json:"files,omitempty"`
-	TorrentHash     string       `json:"hash,omitempty"`
+	CreatedAt    int64    ``
+	ID           string   `json:"id,omitempty"`
+	ParentID     string   ``
+	Link         string   `json:"download,omitempty"`
+	OriginalLink string   `json:"link,omitempty"`
+	Name         string   `json:"filename,omitempty"`
+	Size         int64    `json:"filesize,omitempty"`
+	Bytes        int64    `json:"bytes,omitempty"` // size of the selected files of a torrent
+	Status       string   `json:"status,omitempty"`
+	Type         string   `json:"type,omitempty"`
+	MimeType     string   `json:"mimeType,omitempty"`
+	Ended        string   `json:"added,omitempty"`
+	Generated    string   `json:"generated,omitempty"`
+	Links        []string `json:"links,omitempty"`
+	Files        []File   `json:"files,omitempty"`
+	TorrentHash  string   `json:"hash,omitempty"`
+	Progress     float64  `json:"progress,omitempty"` // percentage downloaded of a torrent
+	Remote       bool     ``                          // download link made for other IPs, not to be streamed
 }
 
+// File is a file inside a torrent as returned by torrents/info
 type File struct {
-	ID       int64 `json:"id,omitempty"`
-	Selected int64 `json:"selected,omitempty"`
-}
-
-// Breadcrumb is part the breadcrumb trail for a file or folder.  It
-// is returned as part of folder/list if required
-type Breadcrumb struct {
-	ID       string `json:"id,omitempty"`
-	Name     string `json:"name,omitempty"`
-	ParentID string `json:"parent_id,omitempty"`
+	ID       int64  `json:"id,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Bytes    int64  `json:"bytes,omitempty"`
+	Selected int64  `json:"selected,omitempty"`
 }
 
 // FolderListResponse is the response to folder/list
@@ -95,3 +95,14 @@ type AccountInfoResponse struct {
 	PremiumUntil int64   `json:"premium_until,omitempty"`
 	SpaceUsed    float64 `json:"space_used,omitempty"`
 }
+
+// User is the response to /user
+type User struct {
+	ID         int64  `json:"id,omitempty"`
+	Username   string `json:"username,omitempty"`
+	Email      string `json:"email,omitempty"`
+	Points     int64  `json:"points,omitempty"`
+	Type       string `json:"type,omitempty"`       // "premium" or "free"
+	Premium    int64  `json:"premium,omitempty"`    // seconds of premium left
+	Expiration string `json:"expiration,omitempty"` // end of premium as RFC3339
+}
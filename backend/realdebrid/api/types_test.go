@@ -0,0 +1,25 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Sizes must decode without truncation on 32 bit platforms
+func TestItemLargeSizes(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want int64
+	}{
+		{in: `{"filename":"small.mkv","filesize":1048576}`, want: 1 << 20},
+		{in: `{"filename":"remux.mkv","filesize":5368709120}`, want: 5 << 30},
+		{in: `{"filename":"pack.mkv","filesize":3298534883328}`, want: 3 << 40},
+	} {
+		var item Item
+		require.NoError(t, json.Unmarshal([]byte(test.in), &item), test.in)
+		assert.Equal(t, test.want, item.Size, test.in)
+	}
+}
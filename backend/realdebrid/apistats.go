@@ -0,0 +1,133 @@
+package realdebrid
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// The endpoints the API calls of a client are counted by
+const (
+	endpointTorrents   = iota // GET /torrents
+	endpointInfo              // GET /torrents/info/ID
+	endpointUnrestrict        // POST /unrestrict/link
+	endpointDelete            // DELETE /torrents/delete/ID and /downloads/delete/ID
+	endpointDownloads         // GET /downloads
+	endpointOther             // the others, like /user or /torrents/addMagnet
+	numEndpoints
+)
+
+// endpointNames are the names of the endpoints in the api-stats report
+var endpointNames = [numEndpoints]string{"torrents", "torrentInfo", "unrestrict", "deletes", "downloads", "other"}
+
+// endpointOf returns the endpoint of the API call made with method to
+// path
+func endpointOf(method, path string) int {
+	switch {
+	case method == "DELETE":
+		return endpointDelete
+	case path == "/torrents":
+		return endpointTorrents
+	case strings.HasPrefix(path, "/torrents/info/"):
+		return endpointInfo
+	case path == "/unrestrict/link":
+		return endpointUnrestrict
+	case path == "/downloads":
+		return endpointDownloads
+	}
+	return endpointOther
+}
+
+// endpointCounts counts the calls made to an endpoint
+type endpointCounts struct {
+	calls           atomic.Int64 // requests made, retries included
+	tooManyRequests atomic.Int64 // responses with 429 Too Many Requests
+	errors          atomic.Int64 // requests which failed other than with 429
+}
+
+// callStats counts the API calls made by a client by endpoint, unlike
+// the stats of realdebrid/stats which are for all the remotes.
+//
+// They are updated on every call so are atomics.
+type callStats struct {
+	since     atomic.Int64 // when they were last reset, in Unix nanoseconds
+	endpoints [numEndpoints]endpointCounts
+}
+
+// add counts a try of the API call made with method to path which
+// returned resp and err
+func (s *callStats) add(method, path string, resp *http.Response, err error) {
+	counts := &s.endpoints[endpointOf(method, path)]
+	counts.calls.Add(1)
+	switch {
+	case resp != nil && resp.StatusCode == http.StatusTooManyRequests:
+		counts.tooManyRequests.Add(1)
+	case err != nil:
+		counts.errors.Add(1)
+	}
+}
+
+// endpointReport is what the api-stats command returns for an endpoint
+type endpointReport struct {
+	Calls           int64 `json:"calls"`
+	TooManyRequests int64 `json:"tooManyRequests"`
+	Errors          int64 `json:"errors"`
+}
+
+// apiStatsReport is the result of the api-stats command
+type apiStatsReport struct {
+	Since           time.Time                 `json:"since"`
+	Calls           int64                     `json:"calls"`
+	CallsPerMinute  float64                   `json:"callsPerMinute"` // on average since then
+	TooManyRequests int64                     `json:"tooManyRequests"`
+	Endpoints       map[string]endpointReport `json:"endpoints"`
+}
+
+// report returns the counts, resetting them if reset is set
+func (s *callStats) report(reset bool, now time.Time) *apiStatsReport {
+	load := func(counter *atomic.Int64) int64 {
+		if reset {
+			return counter.Swap(0)
+		}
+		return counter.Load()
+	}
+	since := s.since.Load()
+	if reset {
+		since = s.since.Swap(now.UnixNano())
+	}
+	report := &apiStatsReport{
+		Since:     time.Unix(0, since),
+		Endpoints: make(map[string]endpointReport, numEndpoints),
+	}
+	for i := range s.endpoints {
+		counts := &s.endpoints[i]
+		endpoint := endpointReport{
+			Calls:           load(&counts.calls),
+			TooManyRequests: load(&counts.tooManyRequests),
+			Errors:          load(&counts.errors),
+		}
+		report.Endpoints[endpointNames[i]] = endpoint
+		report.Calls += endpoint.Calls
+		report.TooManyRequests += endpoint.TooManyRequests
+	}
+	if minutes := now.Sub(report.Since).Minutes(); minutes > 0 {
+		report.CallsPerMinute = float64(int64(float64(report.Calls)/minutes*10)) / 10
+	}
+	return report
+}
+
+// apiStatsCommand returns the API calls made by the remote, resetting
+// the counts if -o reset is set
+func (f *Fs) apiStatsCommand(opt map[string]string) (out *apiStatsReport, err error) {
+	reset := false
+	if value, ok := opt["reset"]; ok {
+		reset, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reset value %q: %w", value, err)
+		}
+	}
+	return f.client.calls.report(reset, time.Now()), nil
+}
@@ -0,0 +1,87 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpointOf(t *testing.T) {
+	for _, test := range []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/torrents", endpointTorrents},
+		{"GET", "/torrents/info/ABC", endpointInfo},
+		{"POST", "/unrestrict/link", endpointUnrestrict},
+		{"DELETE", "/torrents/delete/ABC", endpointDelete},
+		{"DELETE", "/downloads/delete/ABC", endpointDelete},
+		{"GET", "/downloads", endpointDownloads},
+		{"GET", "/user", endpointOther},
+		{"POST", "/torrents/addMagnet", endpointOther},
+	} {
+		assert.Equal(t, endpointNames[test.want], endpointNames[endpointOf(test.method, test.path)], test.path)
+	}
+}
+
+func TestAPIStatsCommand(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	var limited atomic.Bool
+	limited.Store(true)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/torrents":
+			w.Header().Set("X-Total-Count", "0")
+			writeJSON(t, w, []api.Item{})
+		case "/unrestrict/link":
+			// rate limited once, then served by the retry
+			if limited.Swap(false) {
+				w.WriteHeader(http.StatusTooManyRequests)
+				writeJSON(t, w, api.Response{ErrorName: "too_many_requests", ErrorCode: 34})
+				return
+			}
+			writeJSON(t, w, api.Item{ID: "D1", Link: "https://dl/file.mkv"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			writeJSON(t, w, api.Response{ErrorName: "unknown_ressource", ErrorCode: 7})
+		}
+	})
+	_, _, err := f.client.ListTorrents(ctx, 1, 10)
+	require.NoError(t, err)
+	_, err = f.client.Unrestrict(ctx, "https://hoster.example/a")
+	require.NoError(t, err)
+	_, err = f.client.TorrentInfo(ctx, "GONE")
+	require.Error(t, err)
+
+	out, err := f.Command(ctx, "api-stats", nil, nil)
+	require.NoError(t, err)
+	report := out.(*apiStatsReport)
+	assert.Equal(t, int64(4), report.Calls)
+	assert.Equal(t, int64(1), report.TooManyRequests)
+	assert.Equal(t, endpointReport{Calls: 1}, report.Endpoints["torrents"])
+	assert.Equal(t, endpointReport{Calls: 2, TooManyRequests: 1}, report.Endpoints["unrestrict"])
+	assert.Equal(t, endpointReport{Calls: 1, Errors: 1}, report.Endpoints["torrentInfo"])
+	assert.Equal(t, endpointReport{}, report.Endpoints["deletes"])
+
+	// The counts are returned once more when reset
+	before := time.Now()
+	out, err = f.Command(ctx, "api-stats", nil, map[string]string{"reset": "true"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), out.(*apiStatsReport).Calls)
+	out, err = f.Command(ctx, "api-stats", nil, nil)
+	require.NoError(t, err)
+	report = out.(*apiStatsReport)
+	assert.Zero(t, report.Calls)
+	assert.Equal(t, endpointReport{}, report.Endpoints["unrestrict"])
+	assert.False(t, report.Since.Before(before), report.Since)
+
+	_, err = f.Command(ctx, "api-stats", nil, map[string]string{"reset": "maybe"})
+	assert.ErrorContains(t, err, `invalid reset value "maybe"`)
+}
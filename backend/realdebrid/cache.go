@@ -0,0 +1,1007 @@
+package realdebrid
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+)
+
+// dumpDir is where the listing caches are dumped to survive restarts,
+// in files prefixed with the name of the remote
+var dumpDir = "/var/lib/rclone"
+
+// sharedCache holds the lists of received content of an account.
+// Realdebrid content is provided in pages with 100 items per page.
+// To limit api calls all pages are stored here and are only updated on changes in the total length
+//
+// With share_cache the Fs instances of the same account share one of
+// these so they share the API calls made to refresh it.
+type sharedCache struct {
+	key      string // key in sharedCaches, "" if not shared
+	refs     int    // number of Fs using this - protected by sharedCachesMu
+	dumpName string // name of the remote which made it, prefixing its dump files
+	dir      string // directory of the dump files, dumpDir if ""
+
+	refreshMu sync.Mutex // held while the torrents are refreshed
+
+	// mu protects cached, torrents, torrentswf and broken_torrents
+	// which concurrent listings and reads change. It is only held
+	// while they are accessed, never during API calls. The torrents
+	// are only replaced with the refreshMu held too so may be read
+	// holding either.
+	mu sync.RWMutex
+
+	cached                   []api.Item
+	torrents                 []api.Item
+	torrentswf               []api.Item
+	broken_torrents          []string
+	categories               map[string]string // category of the torrents by hash from the classifier
+	generation               int64             // changed each time the torrents are replaced
+	changes                  []*torrentChanges // diffs of the latest generations, oldest first
+	startup_cached_api_fetch bool              // fetch the full /downloads API result already in this rclone session ?
+
+	// IDs of the links and torrents deleted by us so listings made
+	// before the API caught up don't bring them back
+	deletedLinks    map[string]struct{}
+	deletedTorrents map[string]struct{}
+
+	// torrents being deleted so concurrent removes of their files
+	// only delete them once
+	deletingTorrents map[string]*torrentDeletion
+
+	// files open for reading by torrent ID, the repair of those
+	// torrents waits for them to be closed unless they were found
+	// broken by a read
+	readersMu  sync.Mutex
+	readers    map[string]int
+	readBroken map[string]struct{}
+
+	pruning atomic.Bool   // set while the downloads are auto pruned
+	state   snapshotState // how fresh the torrents are
+
+	// repairs of the torrents by hash which haven't worked yet and
+	// those given up on
+	repairsMu     sync.Mutex
+	repairs       map[string]*repairAttempts
+	repairsFailed map[string]*repairAttempts
+
+	traffic traffic // bytes served by torrent
+
+	refreshFailures  int       // refreshes which failed in a row
+	circuitOpenUntil time.Time // refreshes are skipped until then after too many failures
+}
+
+// torrentDeletion is the delete of a torrent, which is finished when
+// done is closed
+type torrentDeletion struct {
+	done chan struct{}
+	err  error
+}
+
+// Shared caches keyed by account
+var (
+	sharedCachesMu sync.Mutex
+	sharedCaches   = map[string]*sharedCache{}
+)
+
+// getSharedCache returns the cache for the account with key, creating
+// it if necessary, and adds a reference to it. If share is false it
+// always returns a new cache.
+//
+// The dump files of a new cache are in dir, named after the remote
+// called name so remotes of different accounts don't load each other's.
+//
+// created is set if the cache is new so needs loading.
+func getSharedCache(key, name, dir string, share bool) (c *sharedCache, created bool) {
+	if !share {
+		c = &sharedCache{dumpName: name, dir: dir}
+		c.state.MarkFresh(0)
+		return c, true
+	}
+	sharedCachesMu.Lock()
+	defer sharedCachesMu.Unlock()
+	c, found := sharedCaches[key]
+	if !found {
+		c = &sharedCache{key: key, dumpName: name, dir: dir}
+		c.state.MarkFresh(0)
+		sharedCaches[key] = c
+	}
+	c.refs++
+	return c, !found
+}
+
+// release removes a reference to the cache, forgetting it once it is
+// no longer used. It returns true if that was the last reference.
+func (c *sharedCache) release() (last bool) {
+	if c.key == "" {
+		return true
+	}
+	sharedCachesMu.Lock()
+	defer sharedCachesMu.Unlock()
+	c.refs--
+	if c.refs <= 0 && sharedCaches[c.key] == c {
+		delete(sharedCaches, c.key)
+	}
+	return c.refs <= 0
+}
+
+func removeDuplicates(slice []api.Item) []api.Item {
+	// the links for other IPs are kept apart from those to stream
+	type linkKey struct {
+		originalLink string
+		remote       bool
+	}
+	seen := make(map[linkKey]bool)
+	result := []api.Item{}
+
+	for _, item := range slice {
+		key := linkKey{item.OriginalLink, item.Remote}
+		if _, found := seen[key]; !found {
+			seen[key] = true
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+// torrent duplicaiton can happen on rare cases (pages are shifted so sequential requests get page-edged torrents several times) but it's taken care of with x-total changed that refreshes torrents
+// but for torrents w details, it's better to do some cleaning in the bottom of the list
+/* done directly in torrents alignements
+func removeTorrentsDuplicates(slice []api.Item) []api.Item {
+	seen := make(map[string]bool)
+	result := []api.Item{}
+
+	for _, item := range slice {
+		if _, found := seen[item.ID]; !found {
+			seen[item.ID] = true
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+*/
+
+// torrentNotReady looks up the torrent with id in the cached torrent
+// list and returns notReady for it, or nil if it isn't known.
+func (f *Fs) torrentNotReady(id string) error {
+	if id == "" {
+		return nil
+	}
+	torrents := f.cache.torrentsList()
+	for i := range torrents {
+		if torrents[i].ID == id {
+			return notReady(&torrents[i])
+		}
+	}
+	return nil
+}
+
+// forget removes the deleted link linkID and torrent torrentID from
+// the cache, either of which may be "", and tombstones them so they
+// are dropped from later API listings.
+//
+// This is used instead of forcing a full refresh after each delete.
+func (c *sharedCache) forget(linkID, torrentID string) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	if linkID != "" {
+		if c.deletedLinks == nil {
+			c.deletedLinks = make(map[string]struct{})
+		}
+		c.deletedLinks[linkID] = struct{}{}
+		c.mu.Lock()
+		c.cached = dropItems(c.cached, c.deletedLinks)
+		c.mu.Unlock()
+	}
+	if torrentID != "" {
+		if c.deletedTorrents == nil {
+			c.deletedTorrents = make(map[string]struct{})
+		}
+		c.deletedTorrents[torrentID] = struct{}{}
+		c.setTorrents(dropItems(c.torrents, c.deletedTorrents))
+		c.mu.Lock()
+		c.torrentswf = dropItems(c.torrentswf, c.deletedTorrents)
+		c.mu.Unlock()
+	}
+}
+
+// removedTorrents returns the torrents of before which aren't in after
+func removedTorrents(before, after []api.Item) (removed []api.Item) {
+	kept := make(map[string]struct{}, len(after))
+	for _, torrent := range after {
+		kept[torrent.ID] = struct{}{}
+	}
+	for _, torrent := range before {
+		if _, found := kept[torrent.ID]; !found {
+			removed = append(removed, torrent)
+		}
+	}
+	return removed
+}
+
+// dropLinksOf removes the cached download links of the removed
+// torrents, except those which are links of the kept torrents too.
+func (c *sharedCache) dropLinksOf(removed, kept []api.Item) {
+	links := make(map[string]struct{})
+	for _, torrent := range removed {
+		for _, link := range torrent.Links {
+			links[link] = struct{}{}
+		}
+	}
+	for _, torrent := range kept {
+		for _, link := range torrent.Links {
+			delete(links, link)
+		}
+	}
+	if len(links) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached := make([]api.Item, 0, len(c.cached))
+	for _, item := range c.cached {
+		if _, found := links[item.OriginalLink]; !found {
+			cached = append(cached, item)
+		}
+	}
+	c.cached = cached
+}
+
+// setTorrents replaces the torrents starting a new generation.
+//
+// It must be called with the refreshMu held.
+func (c *sharedCache) setTorrents(torrents []api.Item) {
+	c.mu.Lock()
+	before := c.torrents
+	c.torrents = torrents
+	c.generation = stats.generation.Add(1)
+	c.mu.Unlock()
+	c.state.Recount(len(torrents))
+	c.recordChanges(before)
+}
+
+// currentGeneration returns the generation of the torrents
+func (c *sharedCache) currentGeneration() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.generation
+}
+
+// torrentsList returns the torrents, which are replaced rather than
+// modified so can be read without the lock
+func (c *sharedCache) torrentsList() []api.Item {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.torrents
+}
+
+// snapshot is a copy of the cache at one generation for the
+// operations which go through all of it while it may be refreshed
+type snapshot struct {
+	generation int64
+	torrents   []api.Item
+	torrentswf []api.Item
+	cached     []api.Item
+	categories map[string]string
+}
+
+// snapshot copies the cache
+func (c *sharedCache) snapshot() *snapshot {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return &snapshot{
+		generation: c.generation,
+		torrents:   slices.Clone(c.torrents),
+		torrentswf: slices.Clone(c.torrentswf),
+		cached:     slices.Clone(c.cached),
+		categories: c.categories, // replaced rather than modified
+	}
+}
+
+// dropItems returns items without those whose IDs are in ids
+//
+// It returns a new slice so doesn't modify snapshots of items.
+func dropItems(items []api.Item, ids map[string]struct{}) []api.Item {
+	result := make([]api.Item, 0, len(items))
+	for _, item := range items {
+		if _, found := ids[item.ID]; !found {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// loadDumps loads the listing caches dumped by a previous run.
+//
+// The torrents are only loaded if dumped less than maxAge ago, and are
+// then checked against the total count of the API on the first
+// refresh. The links are always loaded but the /downloads of the API
+// are only fetched again if they are older than maxAge.
+func (c *sharedCache) loadDumps(maxAge time.Duration) {
+	// other Fs may get the cache while it is loaded
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// create var/lib folder if necessary
+	pathdir := c.dumpDirectory()
+
+	// Create the directory, including any necessary parent directories
+	errdir := os.MkdirAll(pathdir, 0755)
+	if errdir != nil {
+		fmt.Println("Error creating the dir to store dumps", errdir)
+	}
+
+	fmt.Println("Data dump Directory created successfully!")
+
+	c.renameLegacyDumps()
+
+	// load torrentswf from file
+	filetwf, err := os.Open(c.dumpPath("torrentswf.gob"))
+	if err != nil {
+		fmt.Println("> torrentswf.gob dump does not exist yet (normal on very first start) or other error: ", err)
+	} else {
+		// Create a Gob decoder
+		decoder := gob.NewDecoder(filetwf)
+
+		// Create a map to hold the decoded data
+
+		// Decode the Gob data into the map
+		err = decoder.Decode(&c.torrentswf)
+		if err != nil {
+			fmt.Println("Error decoding torrentswf.gob file data:", err)
+		}
+
+		fmt.Println("> Torrent details dump successfully red from torrentswf.gob file.")
+	}
+	defer filetwf.Close()
+
+	// load cached from file
+	filecached, err := os.Open(c.dumpPath("cached.gob"))
+	if err != nil {
+		fmt.Println("> cached.gob dump does not exist yet (normal on very first start) or other error: ", err)
+	} else {
+		// Create a Gob decoder
+		decodercached := gob.NewDecoder(filecached)
+
+		// Create a map to hold the decoded data
+
+		// Decode the Gob data into the map
+		err = decodercached.Decode(&c.cached)
+		if err != nil {
+			fmt.Println("Error decoding cached.gob file data:", err)
+		}
+
+		fmt.Println("> Dl-links dump successfully red from cached.gob file.")
+		if err == nil && dumpedWithin(filecached, maxAge) {
+			c.startup_cached_api_fetch = true
+		}
+	}
+	defer filecached.Close()
+
+	// load the torrents if recent enough
+	filetorrents, err := os.Open(c.dumpPath("torrents.gob"))
+	if err == nil {
+		defer filetorrents.Close()
+		if dumpedWithin(filetorrents, maxAge) {
+			var torrents []api.Item
+			err = gob.NewDecoder(filetorrents).Decode(&torrents)
+			if err != nil {
+				fs.Errorf(nil, "realdebrid: failed to decode torrents.gob: %v", err)
+			} else {
+				c.torrents = torrents
+				c.generation = stats.generation.Add(1)
+				c.state.MarkDumped(len(torrents))
+				fs.Debugf(nil, "realdebrid: read %d torrents from torrents.gob", len(torrents))
+			}
+		}
+	}
+
+	// load the torrents whose repair was given up on
+	filefailed, err := os.Open(c.dumpPath("repairsfailed.gob"))
+	if err == nil {
+		defer filefailed.Close()
+		err = gob.NewDecoder(filefailed).Decode(&c.repairsFailed)
+		if err != nil {
+			fs.Errorf(nil, "realdebrid: failed to decode repairsfailed.gob: %v", err)
+		} else {
+			fs.Debugf(nil, "realdebrid: read %d given up repairs from repairsfailed.gob", len(c.repairsFailed))
+		}
+	}
+
+	// load the categories returned by the classifier
+	filecategories, err := os.Open(c.dumpPath("categories.gob"))
+	if err == nil {
+		defer filecategories.Close()
+		err = gob.NewDecoder(filecategories).Decode(&c.categories)
+		if err != nil {
+			fs.Errorf(nil, "realdebrid: failed to decode categories.gob: %v", err)
+		} else {
+			fs.Debugf(nil, "realdebrid: read %d categories from categories.gob", len(c.categories))
+		}
+	}
+
+	c.loadTraffic()
+}
+
+// dumpedWithin returns whether the dump file was written less than
+// maxAge ago
+func dumpedWithin(file *os.File, maxAge time.Duration) bool {
+	info, err := file.Stat()
+	return err == nil && time.Since(info.ModTime()) < maxAge
+}
+
+// ensureTorrentsListed refreshes the torrents unless the cache holds a
+// recent list of them and returns them.
+func (f *Fs) ensureTorrentsListed(ctx context.Context) (torrents []api.Item, err error) {
+	f.cache.refreshMu.Lock()
+	fresh := len(f.cache.torrents) != 0 && !f.cache.state.IsStale(time.Duration(f.opt.CacheRefreshInterval))
+	f.cache.refreshMu.Unlock()
+	if !fresh {
+		err = f.refreshTorrents(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	f.cache.refreshMu.Lock()
+	defer f.cache.refreshMu.Unlock()
+	return f.cache.torrents, nil
+}
+
+// ensureTorrentsListedOrStale returns the torrents as
+// ensureTorrentsListed does or, if they can't be refreshed and
+// serve_stale_on_error is set, those listed before so an outage of the
+// API doesn't empty the listings.
+func (f *Fs) ensureTorrentsListedOrStale(ctx context.Context) (torrents []api.Item, err error) {
+	torrents, err = f.ensureTorrentsListed(ctx)
+	if err == nil {
+		if f.servingStale.CompareAndSwap(true, false) {
+			fs.Logf(f, "Torrents refreshed again, no longer listing those listed before")
+		}
+		return torrents, nil
+	}
+	if !f.opt.ServeStaleOnError || ctx.Err() != nil {
+		return nil, err
+	}
+	f.cache.refreshMu.Lock()
+	torrents = f.cache.torrents
+	f.cache.refreshMu.Unlock()
+	if len(torrents) == 0 {
+		return nil, err
+	}
+	stats.staleListings.Add(1)
+	if f.servingStale.CompareAndSwap(false, true) {
+		fs.Logf(f, "Listing the %d torrents listed before as they can't be refreshed: %v", len(torrents), err)
+	} else {
+		fs.Debugf(f, "Listing the torrents listed before: %v", err)
+	}
+	return torrents, nil
+}
+
+// refreshTorrents updates the download links and torrents in the cache
+// from the API if they have changed or are stale.
+//
+// It holds the cache refresh lock so concurrent listings of Fs sharing
+// the cache only paginate the API once.
+func (f *Fs) refreshTorrents(ctx context.Context) (err error) {
+	f.cache.refreshMu.Lock()
+	var refreshed []api.Item // the torrents after a complete refresh
+	defer func() {
+		f.cache.refreshMu.Unlock()
+		if refreshed != nil && err == nil {
+			f.autoPruneDownloads(ctx, refreshed)
+		}
+	}()
+	if f.cache.circuitOpen() {
+		stats.refreshSkipped.Add(1)
+		fs.Debugf(f, "Skipping the refresh of the torrents until %v", f.cache.circuitOpenUntil)
+		if len(f.cache.torrents) == 0 {
+			return errCircuitOpen
+		}
+		return nil
+	}
+	budgetCtx, cancel := f.withRefreshBudget(ctx)
+	defer func() {
+		cancel()
+		err = f.refreshFinished(budgetCtx, err)
+	}()
+	fmt.Printf("--- LISTING RCLONE REMOTE ROOT --- \n")
+	//update global cached list
+	if f.opt.ScanDownloads && !f.cache.startup_cached_api_fetch {
+		fmt.Printf("--> | CHECK API DL-LINKS (only on rclone load).\n")
+		fmt.Printf("                ~ RDAPIRequest@ /downloads\n")
+		newcached, err := f.newDownloads(budgetCtx)
+		if err != nil {
+			// scanned again by the next refresh
+			return fmt.Errorf("failed to list the downloads: %w", err)
+		}
+		fmt.Println("    | - RD API : enriching known dl-links with externally created ones.") // fetch only on rclone restart to profit from any links there that we wouldn't already have in dump, will be deduplicated later
+		f.cache.startup_cached_api_fetch = true
+		f.cache.mu.Lock()
+		f.cache.cached = dropItems(append(newcached, f.cache.cached...), f.cache.deletedLinks) // so links fetched are put at top of the cached array
+		f.cache.mu.Unlock()
+		fmt.Printf("DONE| - Number of API retrieved dl-links: %d.\n", len(newcached))
+	}
+
+	//get torrents
+	var newtorrents []api.Item
+	var tprinted = false
+	fmt.Printf("--> | CHECKS API TORRENTS\n")
+	fmt.Printf("                ~ RDAPIRequest@ /torrents\n")
+	// the first page only reads the total
+	var totalcount int
+	_, totalcount, err = f.client.ListTorrents(budgetCtx, 0, 1)
+	if err == nil {
+		fmt.Printf("    | - RD API torrents x-total info:%d\n", totalcount)
+		if f.cache.state.UpToDate(totalcount, time.Duration(f.opt.CacheRefreshInterval)) {
+			if f.cache.state.fromDump {
+				fs.Debugf(f, "Torrents loaded from the dump are up to date")
+				f.cache.state.MarkFresh(totalcount)
+			}
+		} else {
+			fmt.Printf("    | - Last RD API torrents update more than 15min ago or RD API torrents count info different from local, Updating torrents...\n")
+			tprinted = true
+			if len(f.cache.torrents) > 0 {
+				newtorrents, err = f.incrementalTorrents(budgetCtx, totalcount)
+				if err == nil && newtorrents == nil {
+					fs.Debugf(f, "Torrents deleted or too many changes, listing all the torrents")
+				}
+			}
+			if err == nil && newtorrents == nil {
+				newtorrents, err = f.listTorrentPages(budgetCtx, totalcount)
+			}
+		}
+	}
+
+	if err != nil {
+		// keep the torrents listed before rather than a part of them
+		return err
+	}
+	if tprinted {
+		fmt.Printf("DONE| - Number of retrieved Torrents: %d.\n", len(newtorrents))
+		// forget the tombstones of torrents the API no longer lists
+		listed := make(map[string]struct{}, len(newtorrents))
+		for _, torrent := range newtorrents {
+			listed[torrent.ID] = struct{}{}
+		}
+		for id := range f.cache.deletedTorrents {
+			if _, found := listed[id]; !found {
+				delete(f.cache.deletedTorrents, id)
+			}
+		}
+		before := f.cache.torrents
+		f.cache.setTorrents(dropItems(newtorrents, f.cache.deletedTorrents))
+		// the torrents deleted on the website
+		if removed := removedTorrents(before, f.cache.torrents); len(removed) > 0 {
+			f.cache.dropLinksOf(removed, f.cache.torrents)
+			for _, torrent := range removed {
+				f.torrentRemoved(torrent.ID)
+			}
+		}
+		f.cache.state.MarkFresh(len(f.cache.torrents))
+		// ------------- CLEANING AND DUMPING IS HERE only on complete refresh -------------
+		f.classifyTorrents(ctx)
+		f.cache.clean()
+		f.cache.dump()
+		if f.opt.MinTorrentSize > 0 {
+			fs.Debugf(f, "%d torrents hidden as smaller than %v", len(f.smallTorrents(f.cache.torrents)), f.opt.MinTorrentSize)
+		}
+		refreshed = f.cache.torrents
+	}
+
+	f.repairTorrents(ctx)
+	return err
+}
+
+// clean aligns the torrent details to the downloaded torrents and
+// removes the duplicate links after a complete refresh
+func (c *sharedCache) clean() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// dont remove duplicates from torrents as count comparison will trigger a new refresh anyway ? todo verif
+
+	// remove from torrentswf where is not found in downloaded torrents
+	seen := make(map[string]bool)
+	idsInT := make(map[string]struct{})
+	for _, itemt := range c.torrents {
+		if itemt.Status == "downloaded" {
+			idsInT[itemt.ID] = struct{}{}
+		}
+	}
+	var filteredtswf []api.Item
+	for _, itemtwf := range c.torrentswf {
+		if _, exists := idsInT[itemtwf.ID]; exists {
+			if _, found := seen[itemtwf.ID]; !found {
+				seen[itemtwf.ID] = true
+				filteredtswf = append(filteredtswf, itemtwf)
+			}
+		}
+	}
+	c.torrentswf = filteredtswf
+
+	// for the moment,  from cached only remove deplicates
+	c.cached = removeDuplicates(c.cached)
+
+	// clean cached not corresponding to any torrentswf original link, only possible if for every ID found in torrents, torrentswf has it ! todo !!
+}
+
+// dumpDirectory returns the directory of the dump files
+func (c *sharedCache) dumpDirectory() string {
+	if c.dir == "" {
+		return dumpDir
+	}
+	return c.dir
+}
+
+// dumpPath returns the path of the dump file called file
+func (c *sharedCache) dumpPath(file string) string {
+	dir := c.dumpDirectory()
+	if c.dumpName == "" {
+		return path.Join(dir, file)
+	}
+	return path.Join(dir, safeFileName(c.dumpName)+"-"+file)
+}
+
+// safeFileName returns name with the characters which may not be used
+// in a file name replaced by _
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x80 && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '.') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// legacyDumps are the dump files which were written before they were
+// named after the remote
+var legacyDumps = []string{"torrentswf.gob", "cached.gob", "categories.gob"}
+
+// renameLegacyDumps renames the dump files written before they were
+// named after the remote to its names, unless it has dumps of its own,
+// so the first remote started after an upgrade doesn't lose them.
+func (c *sharedCache) renameLegacyDumps() {
+	if c.dumpName == "" {
+		return
+	}
+	for _, file := range legacyDumps {
+		newPath := c.dumpPath(file)
+		if _, err := os.Stat(newPath); !os.IsNotExist(err) {
+			continue
+		}
+		oldPath := path.Join(c.dumpDirectory(), file)
+		err := os.Rename(oldPath, newPath)
+		if err == nil {
+			fs.Infof(nil, "realdebrid: renamed %s to %s", oldPath, newPath)
+		} else if !os.IsNotExist(err) {
+			fs.Errorf(nil, "realdebrid: failed to rename %s: %v", oldPath, err)
+		}
+	}
+}
+
+// dump writes the torrents, their details and links to the dump
+// directory so they survive restarts
+func (c *sharedCache) dump() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	// dumping these torrentswf items (torrents with files (torrents with original links))
+	filetwf, err := os.Create(c.dumpPath("torrentswf.gob"))
+	if err != nil {
+		fmt.Println("Error creating torrentswf file:", err)
+	} else {
+		defer filetwf.Close()
+		err = gob.NewEncoder(filetwf).Encode(c.torrentswf)
+		if err != nil {
+			fmt.Println("Error encoding torrentswf data:", err)
+		} else {
+			fmt.Println("DUMPING| Torrent details in torrentswf.gob file.")
+		}
+	}
+
+	// dumping the torrents so the first refresh only checks their count
+	if len(c.torrents) > 0 {
+		filetorrents, err := os.Create(c.dumpPath("torrents.gob"))
+		if err != nil {
+			fs.Errorf(nil, "realdebrid: failed to create torrents.gob: %v", err)
+		} else {
+			defer filetorrents.Close()
+			err = gob.NewEncoder(filetorrents).Encode(c.torrents)
+			if err != nil {
+				fs.Errorf(nil, "realdebrid: failed to encode the torrents: %v", err)
+			} else {
+				fs.Debugf(nil, "realdebrid: dumped %d torrents to torrents.gob", len(c.torrents))
+			}
+		}
+	}
+
+	// dumping these cached items (links from download or unrestrict)
+	filecached, err := os.Create(c.dumpPath("cached.gob"))
+	if err != nil {
+		fmt.Println("Error creating cached.gob file:", err)
+	} else {
+		defer filecached.Close()
+		err = gob.NewEncoder(filecached).Encode(c.cached)
+		if err != nil {
+			fmt.Println("Error encoding cached links data:", err)
+		} else {
+			fmt.Println("DUMPING| dl-links dump in cached.gob file.")
+		}
+	}
+
+	// dumping the torrents whose repair was given up on
+	c.repairsMu.Lock()
+	if c.repairsFailed != nil {
+		filefailed, err := os.Create(c.dumpPath("repairsfailed.gob"))
+		if err != nil {
+			fs.Errorf(nil, "realdebrid: failed to create repairsfailed.gob: %v", err)
+		} else {
+			defer filefailed.Close()
+			err = gob.NewEncoder(filefailed).Encode(c.repairsFailed)
+			if err != nil {
+				fs.Errorf(nil, "realdebrid: failed to encode the given up repairs: %v", err)
+			}
+		}
+	}
+	c.repairsMu.Unlock()
+
+	// dumping the categories returned by the classifier
+	if c.categories != nil {
+		filecategories, err := os.Create(c.dumpPath("categories.gob"))
+		if err != nil {
+			fs.Errorf(nil, "realdebrid: failed to create categories.gob: %v", err)
+		} else {
+			defer filecategories.Close()
+			err = gob.NewEncoder(filecategories).Encode(c.categories)
+			if err != nil {
+				fs.Errorf(nil, "realdebrid: failed to encode the categories: %v", err)
+			}
+		}
+	}
+
+	c.dumpTraffic()
+
+	fmt.Printf("STATUS| - Number of accumulated dl-links (after deduplication ; todo:alignement): %d.\n", len(c.cached))
+	fmt.Printf("STATUS| - Number of managed Torrents (after refresh): %d.\n", len(c.torrents))
+	fmt.Printf("STATUS| - Number of managed Torrents details (after alignement to dled torrents and deduplication): %d.\n", len(c.torrentswf))
+}
+
+// dumpListed dumps the cache if it holds torrents listed from the API,
+// not to replace a dump with nothing or with torrents not checked yet.
+// The bytes served are always dumped.
+func (c *sharedCache) dumpListed() {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	if len(c.torrents) == 0 || c.state.fromDump {
+		c.dumpTraffic()
+		return
+	}
+	c.dump()
+}
+
+// torrentDetails returns the details of the downloaded torrent with id
+// if they are cached
+func (c *sharedCache) torrentDetails(id string) (torrent api.Item, found bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, torrentwf := range c.torrentswf {
+		if id == torrentwf.ID && torrentwf.Status == "downloaded" {
+			return torrentwf, true
+		}
+	}
+	return torrent, false
+}
+
+// openReader counts a file of the torrent with id opened for reading
+func (c *sharedCache) openReader(id string) {
+	c.readersMu.Lock()
+	defer c.readersMu.Unlock()
+	if c.readers == nil {
+		c.readers = make(map[string]int)
+	}
+	c.readers[id]++
+}
+
+// closeReader stops counting a file of the torrent with id opened with
+// openReader
+func (c *sharedCache) closeReader(id string) {
+	c.readersMu.Lock()
+	defer c.readersMu.Unlock()
+	c.readers[id]--
+	if c.readers[id] <= 0 {
+		delete(c.readers, id)
+	}
+}
+
+// brokenByRead notes the torrent with id was found broken while
+// opening one of its files so it is repaired even if some are open:
+// those are most likely the streams which failed.
+func (c *sharedCache) brokenByRead(id string) {
+	c.readersMu.Lock()
+	defer c.readersMu.Unlock()
+	if c.readBroken == nil {
+		c.readBroken = make(map[string]struct{})
+	}
+	c.readBroken[id] = struct{}{}
+}
+
+// deferRepair returns true if the repair of the torrent with id must
+// wait for its files to be closed
+func (c *sharedCache) deferRepair(id string) bool {
+	c.readersMu.Lock()
+	defer c.readersMu.Unlock()
+	if _, found := c.readBroken[id]; found {
+		return false
+	}
+	return c.readers[id] > 0
+}
+
+// repaired forgets the torrent with id was found broken by a read
+func (c *sharedCache) repaired(id string) {
+	c.readersMu.Lock()
+	defer c.readersMu.Unlock()
+	delete(c.readBroken, id)
+}
+
+// addTorrentDetails caches the details of torrent first so they are
+// found before older ones
+func (c *sharedCache) addTorrentDetails(torrent api.Item) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.torrentswf = append([]api.Item{torrent}, c.torrentswf...)
+}
+
+// replaceTorrentDetails replaces the cached details of the torrent
+// with the ID of torrent
+func (c *sharedCache) replaceTorrentDetails(torrent api.Item) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.torrentswf {
+		if c.torrentswf[i].ID == torrent.ID {
+			c.torrentswf[i] = torrent
+			break
+		}
+	}
+}
+
+// link returns the cached download link unrestricted from originalLink
+//
+// The links made for other IPs are never returned by it or links so
+// they aren't streamed, which would use the remote traffic quota.
+func (c *sharedCache) link(originalLink string) (item api.Item, found bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, cachedfile := range c.cached {
+		if cachedfile.OriginalLink == originalLink && !cachedfile.Remote {
+			return cachedfile, true
+		}
+	}
+	return item, false
+}
+
+// links returns the first cached download link of each original link
+func (c *sharedCache) links() map[string]api.Item {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	links := make(map[string]api.Item, len(c.cached))
+	for _, cachedfile := range c.cached {
+		if _, found := links[cachedfile.OriginalLink]; !found && !cachedfile.Remote {
+			links[cachedfile.OriginalLink] = cachedfile
+		}
+	}
+	return links
+}
+
+// linksFor returns the first cached download link of each of
+// originalLinks as links does
+func (c *sharedCache) linksFor(originalLinks []string) map[string]api.Item {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	links := make(map[string]api.Item, len(originalLinks))
+	for _, cachedfile := range c.cached {
+		if _, found := links[cachedfile.OriginalLink]; !found && !cachedfile.Remote && slices.Contains(originalLinks, cachedfile.OriginalLink) {
+			links[cachedfile.OriginalLink] = cachedfile
+		}
+	}
+	return links
+}
+
+// linksOf returns the cached download links of originalLinks
+func (c *sharedCache) linksOf(originalLinks []string) (items []api.Item) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, cachedfile := range c.cached {
+		if slices.Contains(originalLinks, cachedfile.OriginalLink) {
+			items = append(items, cachedfile)
+		}
+	}
+	return items
+}
+
+// linkByURL returns the cached download link whose URL is url
+func (c *sharedCache) linkByURL(url string) (item api.Item, found bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, cachedfile := range c.cached {
+		if cachedfile.Link == url {
+			return cachedfile, true
+		}
+	}
+	return item, false
+}
+
+// addLink caches the download link item first so it is found before
+// older ones
+func (c *sharedCache) addLink(item api.Item) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cached = append([]api.Item{item}, c.cached...)
+}
+
+// relink replaces the URL oldURL of the cached download links with
+// newURL
+func (c *sharedCache) relink(oldURL, newURL string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.cached {
+		if c.cached[i].Link == oldURL {
+			c.cached[i].Link = newURL
+		}
+	}
+}
+
+// unlink stops the cached download links of originalLinks being found
+// from them, once they have been deleted
+func (c *sharedCache) unlink(originalLinks []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.cached {
+		if slices.Contains(originalLinks, c.cached[i].OriginalLink) {
+			c.cached[i].OriginalLink = "this-is-not-a-link"
+		}
+	}
+}
+
+// markBroken adds the torrent with id to the broken torrents,
+// returning false if it was already
+func (c *sharedCache) markBroken(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if slices.Contains(c.broken_torrents, id) {
+		return false
+	}
+	c.broken_torrents = append(c.broken_torrents, id)
+	return true
+}
+
+// isBroken returns whether the torrent with id is broken
+func (c *sharedCache) isBroken(id string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Contains(c.broken_torrents, id)
+}
+
+// unmarkBroken removes the torrent with id from the broken torrents
+// once it has been repaired
+func (c *sharedCache) unmarkBroken(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.broken_torrents = slices.DeleteFunc(c.broken_torrents, func(brokenID string) bool {
+		return brokenID == id
+	})
+}
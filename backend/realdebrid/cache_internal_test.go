@@ -0,0 +1,211 @@
+package realdebrid
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrentListings(t *testing.T) {
+	ctx := context.Background()
+	const n = 20
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.URL.Path)
+	}))
+	t.Cleanup(cdn.Close)
+	var torrents []api.Item
+	details := map[string]api.Item{}
+	for i := range n {
+		id := fmt.Sprint("T", i)
+		torrent := api.Item{ID: id, Name: fmt.Sprintf("Movie.%d", 2000+i), Status: "downloaded", TorrentHash: "h" + id, Links: []string{id + "-a", id + "-b"}}
+		torrents = append(torrents, torrent)
+		torrent.Files = []api.File{
+			{ID: 1, Path: "/a.mkv", Bytes: 10, Selected: 1},
+			{ID: 2, Path: "/b.mkv", Bytes: 10, Selected: 1},
+		}
+		details["/torrents/info/"+id] = torrent
+	}
+	f := newTestFs(t)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/unrestrict/link" {
+			link := r.FormValue("link")
+			name := link[strings.Index(link, "-")+1:] + ".mkv"
+			writeJSON(t, w, api.Item{ID: "d" + link, Name: name, OriginalLink: link, Link: cdn.URL + "/" + link, Size: 10})
+			return
+		}
+		writeJSON(t, w, details[r.URL.Path])
+	})
+	f.cache.torrents = torrents
+
+	// List and read all the torrents at once, as a media server scan
+	// does, while links are verified and torrents found broken
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dir := fmt.Sprintf("movies/Movie.%d", 2000+i)
+			entries, err := f.List(ctx, dir)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{dir + "/a.mkv", dir + "/b.mkv"}, entryNames(entries))
+			o, err := f.NewObject(ctx, dir+"/a.mkv")
+			require.NoError(t, err)
+			in, err := o.Open(ctx)
+			require.NoError(t, err)
+			data, err := io.ReadAll(in)
+			assert.NoError(t, err)
+			assert.NoError(t, in.Close())
+			assert.Equal(t, fmt.Sprintf("/T%d-a", i), string(data))
+		}()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.verifyNext(ctx)
+			f.markBroken(fmt.Sprint("T", i))
+			_, _ = f.cache.torrentDetails(fmt.Sprint("T", i))
+			_ = f.export()
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, f.cache.cached, 2*n)
+	assert.Len(t, f.cache.broken_torrents, n)
+}
+
+func TestTorrentsDumpedAcrossRestarts(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	var (
+		mu       sync.Mutex
+		requests = map[string]int{}
+		torrents = []api.Item{
+			{ID: "1", Name: "Show.S01", Status: "downloaded"},
+			{ID: "2", Name: "Movie.2020", Status: "downloaded"},
+		}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var items []api.Item
+		switch r.URL.Path {
+		case "/torrents":
+			requests["/torrents?limit="+r.URL.Query().Get("limit")]++
+			items = torrents
+		case "/downloads":
+			requests["/downloads"]++
+		}
+		w.Header().Set("X-Total-Count", fmt.Sprint(len(items)))
+		writeJSON(t, w, items)
+	}))
+	t.Cleanup(srv.Close)
+	oldRootURL := rootURL
+	rootURL = srv.URL
+	t.Cleanup(func() {
+		rootURL = oldRootURL
+	})
+	m := configmap.Simple{"api_key": "dump-test", "download_mode": "torrents", "folder_mode": "torrents", "dump_dir": dir, "dump_max_age": "24h", "scan_downloads": "true"}
+	list := func() (names []string, seen map[string]int) {
+		f, err := NewFs(ctx, "dump", "", m)
+		require.NoError(t, err)
+		entries, err := f.List(ctx, "")
+		require.NoError(t, err)
+		require.NoError(t, f.(*Fs).Shutdown(ctx))
+		mu.Lock()
+		defer mu.Unlock()
+		seen, requests = requests, map[string]int{}
+		return entryNames(entries), seen
+	}
+
+	// The first start fetches everything
+	names, seen := list()
+	assert.Equal(t, []string{"Movie.2020", "Show.S01"}, names)
+	assert.Equal(t, map[string]int{"/downloads": 1, "/torrents?limit=1": 1, "/torrents?limit=2500": 1}, seen)
+	assert.FileExists(t, filepath.Join(dir, "dump-torrents.gob"))
+
+	// then a restart only checks the count
+	names, seen = list()
+	assert.Equal(t, []string{"Movie.2020", "Show.S01"}, names)
+	assert.Equal(t, map[string]int{"/torrents?limit=1": 1}, seen)
+
+	// and fetches them again if it changed
+	mu.Lock()
+	torrents = append([]api.Item{{ID: "3", Name: "Other.S02", Status: "downloaded"}}, torrents...)
+	mu.Unlock()
+	names, seen = list()
+	assert.Equal(t, []string{"Movie.2020", "Other.S02", "Show.S01"}, names)
+	assert.Equal(t, map[string]int{"/torrents?limit=1": 1, "/torrents?limit=100": 1}, seen)
+
+	// or if the dump is too old
+	old := time.Now().Add(-48 * time.Hour)
+	for _, file := range []string{"dump-torrents.gob", "dump-cached.gob"} {
+		require.NoError(t, os.Chtimes(filepath.Join(dir, file), old, old))
+	}
+	_, seen = list()
+	assert.Equal(t, map[string]int{"/downloads": 1, "/torrents?limit=1": 1, "/torrents?limit=2500": 1}, seen)
+}
+
+func TestScanDownloadsOff(t *testing.T) {
+	ctx := context.Background()
+	oldDumpDir := dumpDir
+	dumpDir = t.TempDir()
+	t.Cleanup(func() {
+		dumpDir = oldDumpDir
+	})
+	torrent := api.Item{ID: "T1", Name: "Movie.2020", Status: "downloaded", Links: []string{"L1"}}
+	requests := map[string]int{}
+	f := newTestFs(t)
+	f.opt.SharedFolder = "torrents"
+	f.opt.ScanDownloads = false
+	f.cache.state.checked = time.Time{}
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests[r.Method+" "+r.URL.Path]++
+		switch {
+		case r.Method == "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/torrents":
+			w.Header().Set("X-Total-Count", "1")
+			writeJSON(t, w, []api.Item{torrent})
+		case r.URL.Path == "/torrents/info/T1":
+			details := torrent
+			details.Files = []api.File{{ID: 1, Path: "/Movie.2020.mkv", Bytes: 10, Selected: 1}}
+			writeJSON(t, w, details)
+		case r.URL.Path == "/unrestrict/link":
+			writeJSON(t, w, api.Item{ID: "D1", Name: "Movie.2020.mkv", OriginalLink: "L1", Link: "https://dl/Movie.2020.mkv", Size: 10})
+		default:
+			t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+
+	// The torrents are refreshed without reading the downloads
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Movie.2020"}, entryNames(entries))
+	entries, err = f.List(ctx, "Movie.2020")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Movie.2020/Movie.2020.mkv"}, entryNames(entries))
+	assert.Zero(t, requests["GET /downloads"])
+	assert.Equal(t, 1, requests["POST /unrestrict/link"])
+	assert.Equal(t, []string{"D1"}, itemIDs(f.cache.cached))
+
+	// and the links unrestricted are removed with their torrent
+	o, err := f.NewObject(ctx, "Movie.2020/Movie.2020.mkv")
+	require.NoError(t, err)
+	require.NoError(t, o.Remove(ctx))
+	assert.Equal(t, 1, requests["DELETE /downloads/delete/D1"])
+	assert.Equal(t, 1, requests["DELETE /torrents/delete/T1"])
+	assert.Empty(t, f.cache.cached)
+	assert.Zero(t, requests["GET /downloads"])
+}
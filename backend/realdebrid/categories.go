@@ -0,0 +1,114 @@
+package realdebrid
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// category is a folder the torrents are grouped in in folders mode
+type category struct {
+	name string
+	re   *regexp.Regexp // nil for the fallback, which has the others
+}
+
+// categoryStart matches the start of a name=regex pair of the
+// categories option, telling it apart from a regex with a comma
+var categoryStart = regexp.MustCompile(`^\s*[\w.\- ]+=`)
+
+// parseCategories parses the name=regex pairs of the categories
+// option, separated by commas or newlines, in order.
+//
+// A part which doesn't start with a name is the rest of the regex
+// before it, like in "uhd=(?i)(2160p|4k),hd=(?i)1080p", so the regexes
+// can have commas.
+func parseCategories(value, fallback string) (categories []category, err error) {
+	var pairs []string
+	for _, line := range strings.Split(value, "\n") {
+		for _, part := range strings.Split(line, ",") {
+			if len(pairs) > 0 && !categoryStart.MatchString(part) && strings.TrimSpace(part) != "" {
+				pairs[len(pairs)-1] += "," + part
+				continue
+			}
+			pairs = append(pairs, part)
+		}
+	}
+	for _, pair := range pairs {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, expr, found := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("%q isn't name=regex", strings.TrimSpace(pair))
+		}
+		if strings.Contains(name, "/") {
+			return nil, fmt.Errorf("category %q: the name can't contain /", name)
+		}
+		if name == fallback || slices.ContainsFunc(categories, func(c category) bool { return c.name == name }) {
+			return nil, fmt.Errorf("category %q is defined twice", name)
+		}
+		re, err := regexp.Compile(strings.TrimSpace(expr))
+		if err != nil {
+			return nil, fmt.Errorf("category %q: %w", name, err)
+		}
+		categories = append(categories, category{name: name, re: re})
+	}
+	if len(categories) == 0 {
+		return nil, fmt.Errorf("no name=regex in %q", value)
+	}
+	return append(categories, category{name: fallback}), nil
+}
+
+// newCategories returns the category folders of opt in listing order,
+// the fallback last. opt must have been checked by validateOptions.
+//
+// They are defined by the categories option, or are shows, movies and
+// default sorted with regex_shows and regex_movies when it isn't set,
+// after anime if regex_anime is.
+func newCategories(opt *Options) []category {
+	if opt.Categories != "" {
+		categories, _ := parseCategories(opt.Categories, opt.CategoriesFallback)
+		return categories
+	}
+	var categories []category
+	if opt.RegexAnime != "" {
+		anime, _ := regexp.Compile(opt.RegexAnime)
+		categories = append(categories, category{name: "anime", re: anime})
+	}
+	shows, _ := regexp.Compile(opt.RegexShows)   //(?i)(S[0-9]{2}|SEASON|COMPLETE)
+	movies, _ := regexp.Compile(opt.RegexMovies) //`(?i)([0-9]{4} ?\.?)`
+	return append(categories, category{name: "shows", re: shows}, category{name: "movies", re: movies}, category{name: "default"})
+}
+
+// categories returns the category folders of f in listing order, the
+// fallback last.
+//
+// They are made from the options when the Fs is made or they are
+// reloaded rather than each time they are used.
+func (f *Fs) categories() []category {
+	return f.categoryList
+}
+
+// categoryNames returns the names of the category folders of f in
+// listing order
+func (f *Fs) categoryNames() []string {
+	categories := f.categories()
+	names := make([]string, len(categories))
+	for i, c := range categories {
+		names[i] = c.name
+	}
+	return names
+}
+
+// matchCategory returns the name of the first of categories whose
+// regex matches name, which is the fallback if none does
+func matchCategory(categories []category, name string) string {
+	for _, c := range categories {
+		if c.re == nil || c.re.MatchString(name) {
+			return c.name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,141 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCategories(t *testing.T) {
+	for _, test := range []struct {
+		value string
+		want  []string // name=regex, the fallback last
+		err   string
+	}{
+		{"kids=(?i)bluey,uhd=2160p", []string{"kids=(?i)bluey", "uhd=2160p", "other="}, ""},
+		{"kids = (?i)bluey\nuhd=2160p\n", []string{"kids=(?i)bluey", "uhd=2160p", "other="}, ""},
+		{"hd=[0-9]{3,4}p,uhd=2160p", []string{"hd=[0-9]{3,4}p", "uhd=2160p", "other="}, ""},
+		{"kids=(bluey|paw.patrol),\n", []string{"kids=(bluey|paw.patrol)", "other="}, ""},
+		{"", nil, "no name=regex"},
+		{"(?i)bluey", nil, `"(?i)bluey" isn't name=regex`},
+		{"kids=(bluey", nil, `category "kids": error parsing regexp`},
+		{"kids=bluey,kids=paw", nil, `category "kids" is defined twice`},
+		{"other=bluey", nil, `category "other" is defined twice`},
+		{"a/b=bluey", nil, `category "a/b": the name can't contain /`},
+	} {
+		categories, err := parseCategories(test.value, "other")
+		if test.err != "" {
+			assert.ErrorContains(t, err, test.err, test.value)
+			continue
+		}
+		require.NoError(t, err, test.value)
+		var got []string
+		for _, c := range categories {
+			expr := ""
+			if c.re != nil {
+				expr = c.re.String()
+			}
+			got = append(got, c.name+"="+expr)
+		}
+		assert.Equal(t, test.want, got, test.value)
+	}
+}
+
+func TestCategories(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.opt.Categories = "kids=(?i)(bluey|paw.patrol)\nuhd=(?i)2160p"
+	f.opt.CategoriesFallback = "other"
+	f.categoryList = newCategories(&f.opt)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	f.cache.torrents = []api.Item{
+		{ID: "1", Name: "Bluey.S01.2160p", Status: "downloaded"},
+		{ID: "2", Name: "Movie.2020.2160p", Status: "downloaded"},
+		{ID: "3", Name: "Show.S01", Status: "downloaded"},
+		{ID: "4", Name: "Paw.Patrol.S02", Status: "downloaded", TorrentHash: "h4"},
+	}
+	// the classifier is followed for the categories defined
+	f.cache.categories = map[string]string{"h4": "uhd"}
+
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kids", "uhd", "other"}, entryNames(entries))
+
+	// the first category matching wins
+	for dir, want := range map[string][]string{
+		"kids":  {"kids/Bluey.S01.2160p"},
+		"uhd":   {"uhd/Movie.2020.2160p", "uhd/Paw.Patrol.S02"},
+		"other": {"other/Show.S01"},
+	} {
+		entries, err = f.List(ctx, dir)
+		require.NoError(t, err, dir)
+		assert.ElementsMatch(t, want, entryNames(entries), dir)
+	}
+	assert.Equal(t, categoryID("uhd"), f.torrentsDir(&f.cache.torrents[3]))
+	assert.Equal(t, categoryID("other"), f.torrentsDir(&f.cache.torrents[2]))
+
+	// the built in categories are gone
+	_, err = f.List(ctx, "shows")
+	assert.ErrorIs(t, err, fs.ErrorDirNotFound)
+	assert.False(t, f.isCategory("shows"))
+
+	// and the ones the classifier doesn't know fall back to the regexes
+	f.cache.categories = map[string]string{"h4": "shows"}
+	entries, err = f.List(ctx, "kids")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"kids/Bluey.S01.2160p", "kids/Paw.Patrol.S02"}, entryNames(entries))
+}
+
+func TestAnimeCategory(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.m = configmap.Simple{}
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	f.cache.torrents = []api.Item{
+		{ID: "1", Name: "[SubsPlease] Show - 05 (1080p)", Status: "downloaded"},
+		{ID: "2", Name: "[Erai-raws] Other.S02 - 2021", Status: "downloaded"},
+		{ID: "3", Name: "Show.S01", Status: "downloaded"},
+	}
+
+	// There is no anime folder by default
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shows", "movies", "default"}, entryNames(entries))
+	entries, err = f.List(ctx, "shows")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"shows/[Erai-raws] Other.S02 - 2021", "shows/Show.S01"}, entryNames(entries))
+
+	// The anime are classified before the shows and movies
+	_, err = f.Command(ctx, "reload-options", nil, map[string]string{"regex_anime": `^\[(SubsPlease|Erai-raws)\]`})
+	require.NoError(t, err)
+	entries, err = f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"anime", "shows", "movies", "default"}, entryNames(entries))
+	entries, err = f.List(ctx, "anime")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"anime/[Erai-raws] Other.S02 - 2021", "anime/[SubsPlease] Show - 05 (1080p)"}, entryNames(entries))
+	entries, err = f.List(ctx, "shows")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shows/Show.S01"}, entryNames(entries))
+	entries, err = f.List(ctx, "default")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// and the folder goes with the option
+	_, err = f.Command(ctx, "reload-options", nil, map[string]string{"regex_anime": ""})
+	require.NoError(t, err)
+	_, err = f.List(ctx, "anime")
+	assert.ErrorIs(t, err, fs.ErrorDirNotFound)
+}
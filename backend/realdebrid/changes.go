@@ -0,0 +1,163 @@
+package realdebrid
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+)
+
+const (
+	changesKept       = 10  // diffs of the latest generations kept for the changes command
+	changesMaxEntries = 100 // torrents listed in each diff, the others are only counted
+)
+
+// torrentChange is a torrent which changed between two generations
+type torrentChange struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Change string `json:"change"`         // added, removed, status or links
+	From   string `json:"from,omitempty"` // status or number of links before
+	To     string `json:"to,omitempty"`   // and after
+}
+
+// torrentChanges is the diff of the torrents of a generation against
+// the previous one
+type torrentChanges struct {
+	Generation    int64           `json:"generation"`
+	Time          string          `json:"time"`
+	Added         int             `json:"added"`
+	Removed       int             `json:"removed"`
+	StatusChanged int             `json:"statusChanged"`
+	LinksChanged  int             `json:"linksChanged"`
+	Changes       []torrentChange `json:"changes"`
+	More          int             `json:"more"` // changes not listed past changesMaxEntries
+}
+
+// add adds change counting it in kind
+func (d *torrentChanges) add(kind *int, change torrentChange) {
+	*kind++
+	if len(d.Changes) >= changesMaxEntries {
+		d.More++
+		return
+	}
+	d.Changes = append(d.Changes, change)
+}
+
+// empty returns true if nothing changed
+func (d *torrentChanges) empty() bool {
+	return d.Added+d.Removed+d.StatusChanged+d.LinksChanged == 0
+}
+
+// String summarizes the counts of changes
+func (d *torrentChanges) String() string {
+	return fmt.Sprintf("%d added, %d removed, %d changed status, %d changed links", d.Added, d.Removed, d.StatusChanged, d.LinksChanged)
+}
+
+// diffTorrents returns the changes from the torrents before to after,
+// both newest first
+func diffTorrents(before, after []api.Item) *torrentChanges {
+	d := &torrentChanges{Changes: []torrentChange{}}
+	previous := make(map[string]*api.Item, len(before))
+	for i := range before {
+		previous[before[i].ID] = &before[i]
+	}
+	for i := range after {
+		torrent := &after[i]
+		old, found := previous[torrent.ID]
+		if !found {
+			d.add(&d.Added, torrentChange{ID: torrent.ID, Name: torrent.Name, Change: "added", To: torrent.Status})
+			continue
+		}
+		delete(previous, torrent.ID)
+		if old.Status != torrent.Status {
+			d.add(&d.StatusChanged, torrentChange{ID: torrent.ID, Name: torrent.Name, Change: "status", From: old.Status, To: torrent.Status})
+		}
+		if len(old.Links) != len(torrent.Links) {
+			d.add(&d.LinksChanged, torrentChange{ID: torrent.ID, Name: torrent.Name, Change: "links", From: strconv.Itoa(len(old.Links)), To: strconv.Itoa(len(torrent.Links))})
+		}
+	}
+	for i := range before {
+		if old, found := previous[before[i].ID]; found {
+			d.add(&d.Removed, torrentChange{ID: old.ID, Name: old.Name, Change: "removed", From: old.Status})
+		}
+	}
+	return d
+}
+
+// recordChanges keeps the diff of the torrents of the current
+// generation against those before and logs it.
+//
+// The first listing isn't recorded as every torrent would be added.
+//
+// It must be called with the refreshMu held.
+func (c *sharedCache) recordChanges(before []api.Item) {
+	if len(before) == 0 {
+		return
+	}
+	d := diffTorrents(before, c.torrents)
+	if d.empty() {
+		return
+	}
+	d.Generation = c.generation
+	d.Time = time.Now().UTC().Format(time.RFC3339)
+	fs.Infof(nil, "realdebrid: torrents changed in generation %d: %v", d.Generation, d)
+	for _, change := range d.Changes {
+		fs.Debugf(nil, "realdebrid: torrent %s %q %s %s -> %s", change.ID, change.Name, change.Change, change.From, change.To)
+	}
+	c.changes = append(c.changes, d)
+	if len(c.changes) > changesKept {
+		c.changes = c.changes[len(c.changes)-changesKept:]
+	}
+}
+
+// changesCommand returns the diffs of the latest generations in the
+// scope of f, newest first, for the changes command
+func (f *Fs) changesCommand() []*torrentChanges {
+	c := f.cache
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	changes := make([]*torrentChanges, 0, len(c.changes))
+	for i := len(c.changes) - 1; i >= 0; i-- {
+		changes = append(changes, f.scopedChanges(c.changes[i], c.categories))
+	}
+	return changes
+}
+
+// scopedChanges returns the changes of d to the torrents in the scope
+// of f, classified with categories.
+//
+// The torrents are classified by the names in the changes so those
+// past changesMaxEntries, which aren't kept, aren't counted.
+func (f *Fs) scopedChanges(d *torrentChanges, categories map[string]string) *torrentChanges {
+	if _, ok := f.scopeCategory(); !ok {
+		return d
+	}
+	torrents := make([]api.Item, len(d.Changes))
+	for i, change := range d.Changes {
+		torrents[i] = api.Item{ID: change.ID, Name: change.Name}
+	}
+	in := make(map[string]struct{})
+	for _, torrent := range f.scopedTorrents(torrents, categories) {
+		in[torrent.ID] = struct{}{}
+	}
+	scoped := &torrentChanges{Generation: d.Generation, Time: d.Time, Changes: []torrentChange{}}
+	for _, change := range d.Changes {
+		if _, found := in[change.ID]; !found {
+			continue
+		}
+		switch change.Change {
+		case "added":
+			scoped.add(&scoped.Added, change)
+		case "removed":
+			scoped.add(&scoped.Removed, change)
+		case "status":
+			scoped.add(&scoped.StatusChanged, change)
+		case "links":
+			scoped.add(&scoped.LinksChanged, change)
+		}
+	}
+	return scoped
+}
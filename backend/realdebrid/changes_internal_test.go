@@ -0,0 +1,75 @@
+package realdebrid
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTorrentChanges(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	set := func(torrents ...api.Item) {
+		f.cache.refreshMu.Lock()
+		f.cache.setTorrents(torrents)
+		f.cache.refreshMu.Unlock()
+	}
+	changes := func() []*torrentChanges {
+		out, err := f.Command(ctx, "changes", nil, nil)
+		require.NoError(t, err)
+		return out.([]*torrentChanges)
+	}
+
+	// The first listing isn't a change
+	set(
+		api.Item{ID: "1", Name: "Show.S01", Status: "downloaded", Links: []string{"a", "b"}},
+		api.Item{ID: "2", Name: "Movie.2020", Status: "downloaded", Links: []string{"c"}},
+		api.Item{ID: "3", Name: "Old.2019", Status: "downloaded", Links: []string{"d"}},
+	)
+	assert.Empty(t, changes())
+
+	// nor one with the same torrents
+	set(f.cache.torrents...)
+	assert.Empty(t, changes())
+
+	set(
+		api.Item{ID: "4", Name: "New.2024", Status: "downloading"},
+		api.Item{ID: "1", Name: "Show.S01", Status: "downloaded", Links: []string{"a", "b", "e"}},
+		api.Item{ID: "2", Name: "Movie.2020", Status: "dead", Links: []string{"c"}},
+	)
+	got := changes()
+	require.Len(t, got, 1)
+	assert.Equal(t, f.cache.generation, got[0].Generation)
+	assert.NotEmpty(t, got[0].Time)
+	assert.Equal(t, []torrentChange{
+		{ID: "4", Name: "New.2024", Change: "added", To: "downloading"},
+		{ID: "1", Name: "Show.S01", Change: "links", From: "2", To: "3"},
+		{ID: "2", Name: "Movie.2020", Change: "status", From: "downloaded", To: "dead"},
+		{ID: "3", Name: "Old.2019", Change: "removed", From: "downloaded"},
+	}, got[0].Changes)
+	assert.Equal(t, 1, got[0].Added)
+	assert.Equal(t, 1, got[0].Removed)
+	assert.Equal(t, 1, got[0].StatusChanged)
+	assert.Equal(t, 1, got[0].LinksChanged)
+	assert.Zero(t, got[0].More)
+
+	// Big changes are capped and only the latest generations kept
+	for i := range changesKept {
+		var torrents []api.Item
+		for j := range changesMaxEntries + 5 {
+			torrents = append(torrents, api.Item{ID: fmt.Sprint(i, "-", j), Status: "downloaded"})
+		}
+		set(torrents...)
+	}
+	got = changes()
+	require.Len(t, got, changesKept)
+	assert.Equal(t, f.cache.generation, got[0].Generation)
+	assert.Len(t, got[0].Changes, changesMaxEntries)
+	assert.Equal(t, 2*(changesMaxEntries+5)-changesMaxEntries, got[0].More)
+	assert.Equal(t, changesMaxEntries+5, got[0].Added)
+	assert.Equal(t, changesMaxEntries+5, got[0].Removed)
+}
@@ -0,0 +1,146 @@
+package realdebrid
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/operations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCheckTestFs makes an Fs in files mode serving contents by file
+// name, returning it and the requests made to the API and the CDN
+func newCheckTestFs(t *testing.T, contents map[string]string) (f *Fs, requests func() map[string]int) {
+	var (
+		mu   sync.Mutex
+		seen = map[string]int{}
+	)
+	count := func(r *http.Request) {
+		mu.Lock()
+		seen[r.Method+" "+r.URL.Path]++
+		mu.Unlock()
+	}
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count(r)
+		http.ServeContent(w, r, path.Base(r.URL.Path), time.Time{}, bytes.NewReader([]byte(contents[path.Base(r.URL.Path)])))
+	}))
+	t.Cleanup(cdn.Close)
+	movie := api.Item{ID: "1", Name: "Movie.2020", Status: "downloaded", Links: []string{"l1", "l2"}, Files: []api.File{
+		{ID: 1, Path: "/Movie.2020/Movie.2020.mkv", Bytes: int64(len(contents["Movie.2020.mkv"])), Selected: 1},
+		{ID: 2, Path: "/Movie.2020/Movie.2020.srt", Bytes: int64(len(contents["Movie.2020.srt"])), Selected: 1},
+	}}
+	show := api.Item{ID: "2", Name: "Show.S01", Status: "downloaded", Links: []string{"l3"}, Files: []api.File{
+		{ID: 1, Path: "/Show.S01/Show.S01E01.mkv", Bytes: int64(len(contents["Show.S01E01.mkv"])), Selected: 1},
+	}}
+	names := map[string]string{"l1": "Movie.2020.mkv", "l2": "Movie.2020.srt", "l3": "Show.S01E01.mkv"}
+	f = newTestFs(t)
+	f.opt.SharedFolder = "files"
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		count(r)
+		switch r.URL.Path {
+		case "/torrents/info/1":
+			writeJSON(t, w, movie)
+		case "/torrents/info/2":
+			writeJSON(t, w, show)
+		case "/unrestrict/link":
+			link := r.FormValue("link")
+			name := names[link]
+			writeJSON(t, w, api.Item{ID: "d-" + link, Name: name, OriginalLink: link, Link: cdn.URL + "/d/" + name, Size: int64(len(contents[name]))})
+		default:
+			t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	f.cache.torrents = []api.Item{movie, show}
+	return f, func() map[string]int {
+		mu.Lock()
+		defer mu.Unlock()
+		requests := seen
+		seen = map[string]int{}
+		return requests
+	}
+}
+
+func TestCheckAgainstLocal(t *testing.T) {
+	ctx := context.Background()
+	contents := map[string]string{
+		"Movie.2020.mkv":  "movie content",
+		"Movie.2020.srt":  "subtitles",
+		"Show.S01E01.mkv": "episode one",
+	}
+	f, requests := newCheckTestFs(t, contents)
+	dir := t.TempDir()
+	for name, content := range contents {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	dst, err := local.NewFs(ctx, "local", dir, configmap.Simple{})
+	require.NoError(t, err)
+	check := func(checkFn func(context.Context, *operations.CheckOpt) error) (matched, differ string, err error) {
+		var match, differs bytes.Buffer
+		err = checkFn(ctx, &operations.CheckOpt{Fdst: dst, Fsrc: f, Match: &match, Differ: &differs})
+		return match.String(), differs.String(), err
+	}
+
+	// The sizes of the file info match without reading anything
+	matched, _, err := check(operations.Check)
+	require.NoError(t, err)
+	assert.Equal(t, 3, bytes.Count([]byte(matched), []byte("\n")), matched)
+	assert.Equal(t, map[string]int{"GET /torrents/info/1": 1, "GET /torrents/info/2": 1}, requests())
+
+	// Downloading reads each file once, unrestricting it the first time
+	matched, _, err = check(operations.CheckDownload)
+	require.NoError(t, err)
+	assert.Equal(t, 3, bytes.Count([]byte(matched), []byte("\n")), matched)
+	assert.Equal(t, map[string]int{
+		"POST /unrestrict/link":  3,
+		"GET /d/Movie.2020.mkv":  1,
+		"GET /d/Movie.2020.srt":  1,
+		"GET /d/Show.S01E01.mkv": 1,
+	}, requests())
+	_, _, err = check(operations.CheckDownload)
+	require.NoError(t, err)
+	assert.Zero(t, requests()["POST /unrestrict/link"])
+
+	// A local copy which differs is found
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Movie.2020.srt"), []byte("SUBTITLES"), 0o600))
+	_, differ, err := check(operations.CheckDownload)
+	assert.Error(t, err)
+	assert.Equal(t, "Movie.2020.srt\n", differ)
+}
+
+func TestOpenUsesLinkUnrestrictedByOtherObject(t *testing.T) {
+	ctx := context.Background()
+	f, requests := newCheckTestFs(t, map[string]string{"Movie.2020.mkv": "movie content"})
+
+	// two objects listed before either is read
+	var objects []fs.Object
+	for range 2 {
+		o, err := f.NewObject(ctx, "Movie.2020.mkv")
+		require.NoError(t, err)
+		objects = append(objects, o)
+	}
+	requests()
+	for _, o := range objects {
+		in, err := o.Open(ctx)
+		require.NoError(t, err)
+		data, err := io.ReadAll(in)
+		require.NoError(t, err)
+		require.NoError(t, in.Close())
+		assert.Equal(t, "movie content", string(data))
+	}
+	assert.Equal(t, map[string]int{"POST /unrestrict/link": 1, "GET /d/Movie.2020.mkv": 2}, requests())
+}
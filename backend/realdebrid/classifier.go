@@ -0,0 +1,139 @@
+package realdebrid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+// classifyTorrent is a torrent sent to the classifier
+type classifyTorrent struct {
+	Hash  string   `json:"hash"`
+	Name  string   `json:"name"`
+	Files []string `json:"files,omitempty"` // paths of the selected files if known
+}
+
+// classifyRequest is sent to classify_command on stdin or POSTed to
+// classify_url
+type classifyRequest struct {
+	Torrents []classifyTorrent `json:"torrents"`
+}
+
+// classifyResponse is the reply of the classifier
+type classifyResponse struct {
+	Categories map[string]string `json:"categories"` // category name by hash
+}
+
+// classifyTorrents sends the torrents which haven't been classified
+// yet to classify_command or classify_url in batches of
+// classify_batch_size, caching the categories returned by hash.
+//
+// All the batches must be classified within classify_timeout. The
+// torrents left when it runs out or the classifier fails are classified
+// with the regexes until they are sent again on the next refresh.
+//
+// It must be called with the refreshMu held.
+func (f *Fs) classifyTorrents(ctx context.Context) {
+	if len(f.opt.ClassifyCommand) == 0 && f.opt.ClassifyURL == "" {
+		return
+	}
+	c := f.cache
+	var pending []classifyTorrent
+	for _, torrent := range c.torrents {
+		if torrent.TorrentHash == "" {
+			continue
+		}
+		if _, found := c.categories[torrent.TorrentHash]; found {
+			continue
+		}
+		t := classifyTorrent{Hash: torrent.TorrentHash, Name: torrent.Name}
+		if details, found := c.torrentDetails(torrent.ID); found {
+			for _, file := range details.Files {
+				if file.Selected == 1 {
+					t.Files = append(t.Files, file.Path)
+				}
+			}
+		}
+		pending = append(pending, t)
+	}
+	if len(pending) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(f.opt.ClassifyTimeout))
+	defer cancel()
+	// replaced rather than updated as listings read it without the lock
+	categories := maps.Clone(c.categories)
+	if categories == nil {
+		categories = make(map[string]string, len(pending))
+	}
+	names := f.categoryNames()
+	batchSize := max(f.opt.ClassifyBatchSize, 1)
+	classified := 0
+	for start := 0; start < len(pending); start += batchSize {
+		batch := pending[start:min(start+batchSize, len(pending))]
+		result, err := f.runClassifier(ctx, batch)
+		if err != nil {
+			fs.Errorf(f, "Classifying %d torrents with the regexes: %v", len(pending)-start, err)
+			break
+		}
+		for _, t := range batch {
+			category := result[t.Hash]
+			if !slices.Contains(names, category) {
+				fs.Debugf(f, "Classifying %q with the regexes: unknown category %q", t.Name, category)
+				continue
+			}
+			categories[t.Hash] = category
+			classified++
+		}
+	}
+	fs.Debugf(f, "Classifier classified %d of %d torrents", classified, len(pending))
+	c.categories = categories
+}
+
+// runClassifier returns the categories of batch by hash from the
+// classifier
+func (f *Fs) runClassifier(ctx context.Context, batch []classifyTorrent) (categories map[string]string, err error) {
+	in := classifyRequest{Torrents: batch}
+	var out classifyResponse
+	if f.opt.ClassifyURL != "" {
+		opts := rest.Opts{
+			Method:  "POST",
+			RootURL: f.opt.ClassifyURL,
+		}
+		_, err = f.classifier.CallJSON(ctx, &opts, &in, &out)
+		if err != nil {
+			return nil, fmt.Errorf("classifier %s: %w", f.opt.ClassifyURL, err)
+		}
+		return out.Categories, nil
+	}
+	cmdLine := f.opt.ClassifyCommand
+	inBytes, err := json.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("classifier: failed to marshal input: %w", err)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, cmdLine[0], cmdLine[1:]...)
+	cmd.Stdin = bytes.NewReader(inBytes)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// don't wait for children holding the pipes once killed
+	cmd.WaitDelay = time.Second
+	err = cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("classifier: failed on %v: %q: %w", cmdLine, strings.TrimSpace(stderr.String()), err)
+	}
+	err = json.Unmarshal(stdout.Bytes(), &out)
+	if err != nil {
+		return nil, fmt.Errorf("classifier: failed to read output: %q: %w", stdout.String(), err)
+	}
+	return out.Categories, nil
+}
@@ -0,0 +1,160 @@
+package realdebrid
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/rest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newClassifierTestFs makes an Fs with torrents which the regexes
+// classify as a show, a movie and default
+func newClassifierTestFs(t *testing.T) *Fs {
+	f := newTestFs(t)
+	f.opt.ClassifyTimeout = fs.Duration(time.Minute)
+	f.opt.ClassifyBatchSize = 2
+	f.cache.torrents = []api.Item{
+		{ID: "1", Name: "Show.S01", TorrentHash: "h1", Status: "downloaded"},
+		{ID: "2", Name: "Movie.2020", TorrentHash: "h2", Status: "downloaded"},
+		{ID: "3", Name: "Concert", TorrentHash: "h3", Status: "downloaded"},
+	}
+	f.cache.torrentswf = []api.Item{
+		{ID: "2", Name: "Movie.2020", TorrentHash: "h2", Status: "downloaded", Files: []api.File{
+			{Path: "/Movie.2020.mkv", Selected: 1},
+			{Path: "/Sample.mkv"},
+		}},
+	}
+	return f
+}
+
+// newClassifierServer makes a classifier which answers with categories
+// returning the batches received
+func newClassifierServer(t *testing.T, categories map[string]string) (f *Fs, batches func() [][]classifyTorrent) {
+	var (
+		mu       sync.Mutex
+		received [][]classifyTorrent
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in classifyRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+		mu.Lock()
+		received = append(received, in.Torrents)
+		mu.Unlock()
+		writeJSON(t, w, classifyResponse{Categories: categories})
+	}))
+	t.Cleanup(srv.Close)
+	f = newClassifierTestFs(t)
+	f.opt.ClassifyURL = srv.URL
+	f.classifier = rest.NewClient(srv.Client())
+	return f, func() [][]classifyTorrent {
+		mu.Lock()
+		defer mu.Unlock()
+		batches := received
+		received = nil
+		return batches
+	}
+}
+
+func TestClassifierURL(t *testing.T) {
+	ctx := context.Background()
+	f, batches := newClassifierServer(t, map[string]string{
+		"h1": "movies",
+		"h2": "shows",
+		"h3": "concerts",
+	})
+	f.classifyTorrents(ctx)
+	assert.Equal(t, [][]classifyTorrent{
+		{{Hash: "h1", Name: "Show.S01"}, {Hash: "h2", Name: "Movie.2020", Files: []string{"/Movie.2020.mkv"}}},
+		{{Hash: "h3", Name: "Concert"}},
+	}, batches())
+	assert.Equal(t, map[string]string{"h1": "movies", "h2": "shows"}, f.cache.categories)
+
+	// The categories override the regexes, unknown ones fall back
+	for dir, want := range map[string][]string{
+		"shows":   {"shows/Movie.2020"},
+		"movies":  {"movies/Show.S01"},
+		"default": {"default/Concert"},
+	} {
+		entries, err := f.List(ctx, dir)
+		require.NoError(t, err)
+		assert.Equal(t, want, entryNames(entries), dir)
+	}
+
+	// Only the torrents without a category are sent again
+	f.classifyTorrents(ctx)
+	assert.Equal(t, [][]classifyTorrent{{{Hash: "h3", Name: "Concert"}}}, batches())
+}
+
+func TestClassifierFailing(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			// read the request so the disconnect is noticed
+			_, _ = io.Copy(io.Discard, r.Body)
+			select {
+			case <-r.Context().Done():
+			case <-time.After(10 * time.Second):
+			}
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+	for _, url := range []string{srv.URL + "/error", srv.URL + "/slow"} {
+		f := newClassifierTestFs(t)
+		f.opt.ClassifyURL = url
+		f.opt.ClassifyTimeout = fs.Duration(100 * time.Millisecond)
+		f.classifier = rest.NewClient(srv.Client())
+		start := time.Now()
+		f.classifyTorrents(ctx)
+		assert.Less(t, time.Since(start), 5*time.Second, url)
+		assert.Empty(t, f.cache.categories, url)
+
+		// so the regexes are used
+		entries, err := f.List(ctx, "shows")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"shows/Show.S01"}, entryNames(entries), url)
+	}
+}
+
+func TestClassifierCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	ctx := context.Background()
+	f := newClassifierTestFs(t)
+	f.opt.ClassifyBatchSize = 10
+	f.opt.ClassifyCommand = fs.SpaceSepList{"sh", "-c", `grep -q Concert && echo '{"categories": {"h3": "movies"}}'`}
+	f.classifyTorrents(ctx)
+	assert.Equal(t, map[string]string{"h3": "movies"}, f.cache.categories)
+
+	// Failures leave the torrents to the regexes
+	f = newClassifierTestFs(t)
+	f.opt.ClassifyCommand = fs.SpaceSepList{"sh", "-c", "echo broken >&2; exit 1"}
+	f.classifyTorrents(ctx)
+	assert.Empty(t, f.cache.categories)
+}
+
+func TestClassifierCategoriesDumped(t *testing.T) {
+	oldDumpDir := dumpDir
+	dumpDir = t.TempDir()
+	t.Cleanup(func() {
+		dumpDir = oldDumpDir
+	})
+	c := &sharedCache{categories: map[string]string{"h1": "movies"}}
+	c.dump()
+	loaded := &sharedCache{}
+	loaded.loadDumps(0)
+	assert.Equal(t, c.categories, loaded.categories)
+}
@@ -0,0 +1,117 @@
+package realdebrid
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"golang.org/x/text/unicode/norm"
+)
+
+// notReady returns a *fs.ContentNotReadyError wrapped with the
+// torrent name if the torrent hasn't finished downloading yet, so its
+// links can't be unrestricted, or nil otherwise.
+func notReady(torrent *api.Item) error {
+	switch torrent.Status {
+	case "magnet_conversion", "waiting_files_selection", "queued", "downloading", "compressing", "uploading":
+		return fmt.Errorf("torrent %q: %w", torrent.Name, &fs.ContentNotReadyError{Progress: torrent.Progress})
+	}
+	return nil
+}
+
+// isCategory returns true if dirID is one of the folders which group
+// the torrents in folders mode
+func (f *Fs) isCategory(dirID string) bool {
+	_, ok := f.categoryName(dirID)
+	return ok
+}
+
+// classify returns the torrents of f in the category or year folder
+// dirID
+func (f *Fs) classify(torrents []api.Item, dirID string, classified map[string]string) []api.Item {
+	classified = f.withOverrides(classified)
+	if f.isYear(dirID) {
+		return f.inYear(classify(torrents, categoryID("movies"), f.categories(), classified), dirID)
+	}
+	return classify(torrents, dirID, f.categories(), classified)
+}
+
+// categoryOf returns the name of the category of f torrent is in
+func (f *Fs) categoryOf(torrent *api.Item, classified map[string]string) string {
+	return categoryOf(torrent, f.categories(), f.withOverrides(classified))
+}
+
+// categoryOf returns the name of the category torrent is in.
+//
+// It is the category of its hash in classified, if it is one of
+// categories. Otherwise it is the first of categories whose regex
+// matches its name, or the fallback if none does.
+func categoryOf(torrent *api.Item, categories []category, classified map[string]string) string {
+	name, found := classified[torrent.TorrentHash]
+	if found && torrent.TorrentHash != "" && slices.ContainsFunc(categories, func(c category) bool { return c.name == name }) {
+		return name
+	}
+	return matchCategory(categories, torrent.Name)
+}
+
+// classify returns the torrents in the category folder dirID
+func classify(torrents []api.Item, dirID string, categories []category, classified map[string]string) []api.Item {
+	_, name, _ := parseSynID(dirID)
+	var artificialType []api.Item
+	for _, torrent := range torrents {
+		if categoryOf(&torrent, categories, classified) == name {
+			artificialType = append(artificialType, torrent)
+		}
+	}
+	return uniqueTorrentNames(artificialType)
+}
+
+// addArtificialRootFolders appends the category folders of f to result
+func (f *Fs) addArtificialRootFolders(result []api.Item) []api.Item {
+	for _, name := range f.categoryNames() {
+		result = append(result, api.Item{ID: categoryID(name), Name: name, Generated: "2006-01-02T15:04:05.000Z"})
+	}
+	return result
+}
+
+// datedCategories dates the category folders by when the newest of
+// their cached torrents was added, so they sort and sync like the
+// torrent folders. Those without any keep the placeholder time.
+//
+// The torrents aren't listed again for it, which would make listing
+// the root fetch them.
+func (f *Fs) datedCategories(folders []api.Item) []api.Item {
+	f.cache.refreshMu.Lock()
+	torrents, categories := f.cache.torrents, f.cache.categories
+	f.cache.refreshMu.Unlock()
+	for i := range folders {
+		if !f.isCategory(folders[i].ID) {
+			continue
+		}
+		for _, torrent := range f.classify(torrents, folders[i].ID, categories) {
+			if torrent.Ended > folders[i].Ended {
+				folders[i].Ended = torrent.Ended
+			}
+		}
+	}
+	return folders
+}
+
+// uniqueTorrentNames renames torrents with the same name, which
+// happens when a torrent is added twice, e.g. by the reselect command,
+// so they are all reachable. The oldest one keeps its name and the
+// others have their ID appended.
+func uniqueTorrentNames(items []api.Item) []api.Item {
+	seen := make(map[string]struct{}, len(items))
+	// the API lists the newest torrents first
+	for i := len(items) - 1; i >= 0; i-- {
+		key := foldName(norm.NFC.String(items[i].Name))
+		if _, found := seen[key]; found {
+			items[i].Name += " (" + items[i].ID + ")"
+			continue
+		}
+		seen[key] = struct{}{}
+	}
+	return items
+}
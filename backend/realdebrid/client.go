@@ -0,0 +1,603 @@
+package realdebrid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/rclone/rclone/lib/rest"
+)
+
+// errLinkUnavailable is returned by Unrestrict if the hoster can't
+// serve the link any more, which means its torrent needs repairing.
+var errLinkUnavailable = errors.New("link unavailable")
+
+// errServerError is wrapped by the errors of the lists Real-Debrid
+// failed to serve with a 5xx status, which it does when they take too
+// long to make.
+var errServerError = errors.New("server error")
+
+// client is a thin typed layer over the Real-Debrid REST API.
+//
+// All the API calls of the backend go through it so it is the one
+// place where they are paced, retried, have their errors mapped and
+// the api key redacted from them.
+type client struct {
+	srv      *rest.Client  // the connection to the server
+	pacer    *fs.Pacer     // pacer for API calls
+	apiKey   string        // api key if not using oauth
+	simulate bool          // log the calls which change the account instead of making them
+	timeout  time.Duration // time each try of an API call may take, 0 for no limit
+	calls    callStats     // the API calls made, for the api-stats command
+}
+
+// newClient makes a client calling rootURL with httpClient
+func newClient(httpClient *http.Client, pacer *fs.Pacer, apiKey string) *client {
+	c := &client{
+		srv:    rest.NewClient(httpClient).SetRoot(rootURL),
+		pacer:  pacer,
+		apiKey: apiKey,
+	}
+	c.srv.SetErrorHandler(errorHandler)
+	c.calls.since.Store(time.Now().UnixNano())
+	return c
+}
+
+// params returns a url.Values with the api key in
+func (c *client) params() url.Values {
+	params := url.Values{}
+	if c.apiKey != "" {
+		params.Add("auth_token", c.apiKey)
+	}
+	return params
+}
+
+// call makes the API call described by opts, decoding the JSON
+// response into response unless it is nil, retrying as necessary.
+func (c *client) call(ctx context.Context, opts *rest.Opts, response any) (resp *http.Response, err error) {
+	if opts.Parameters == nil {
+		opts.Parameters = c.params()
+	}
+	if response == nil {
+		opts.NoResponse = true
+	}
+	// Unrestricting is needed to read files so is always done
+	if c.simulate && opts.Method != "GET" && opts.Path != "/unrestrict/link" {
+		stats.simulated.Add(1)
+		fs.Logf(nil, "realdebrid: simulate: skipping %s %s %s", opts.Method, opts.Path, opts.MultipartParams.Encode())
+		return nil, nil
+	}
+	err = c.pacer.Call(func() (bool, error) {
+		// retries count against the budget of a refresh too
+		if err := takeRequest(ctx); err != nil {
+			return false, err
+		}
+		callCtx, cancel := ctx, context.CancelFunc(func() {})
+		if c.timeout > 0 {
+			callCtx, cancel = context.WithTimeout(ctx, c.timeout)
+		}
+		defer cancel()
+		// CallJSON as Call doesn't send the MultipartParams
+		resp, err = c.srv.CallJSON(callCtx, opts, nil, response)
+		c.calls.add(opts.Method, opts.Path, resp, err)
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("no answer within api_timeout %v: %w", c.timeout, err)
+	}
+	return resp, c.redact(err)
+}
+
+// redact removes the api key from the URL of a failed request in err
+func (c *client) redact(err error) error {
+	var urlErr *url.Error
+	if c.apiKey != "" && errors.As(err, &urlErr) {
+		urlErr.URL = strings.ReplaceAll(urlErr.URL, c.apiKey, "REDACTED")
+	}
+	return err
+}
+
+// list reads a page of limit items of the list at path returning the
+// total number of items in the list. If page is 0 the page isn't set.
+func (c *client) list(ctx context.Context, path string, page, limit int) (items []api.Item, total int, err error) {
+	params := c.params()
+	params.Set("limit", strconv.Itoa(limit))
+	if page > 0 {
+		params.Set("page", strconv.Itoa(page))
+	}
+	return c.listWith(ctx, path, params)
+}
+
+// listWith reads the part of the list at path selected by params,
+// returning the total number of items in the list.
+//
+// If Real-Debrid fails with a server error the error wraps
+// errServerError.
+func (c *client) listWith(ctx context.Context, path string, params url.Values) (items []api.Item, total int, err error) {
+	return c.appendList(ctx, path, params, nil)
+}
+
+// pageDecoder decodes a page of a list item by item straight onto the
+// end of items, rather than into a slice of its own which would then
+// be appended, so a page is only held once however big it is.
+type pageDecoder struct {
+	items   []api.Item
+	start   int               // len(items) before the page
+	size    int               // number of items expected, so items only grows once
+	strings map[string]string // the values shared by the items of the page
+}
+
+// UnmarshalJSON decodes the page in data, dropping the items of an
+// earlier try of the call
+func (p *pageDecoder) UnmarshalJSON(data []byte) error {
+	p.items = slices.Grow(p.items[:p.start], p.size)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		// null as an empty list
+		return nil
+	}
+	if tok != json.Delim('[') {
+		return fmt.Errorf("json: cannot unmarshal %v into a list of items", tok)
+	}
+	for dec.More() {
+		p.items = append(p.items, api.Item{})
+		item := &p.items[len(p.items)-1]
+		if err := dec.Decode(item); err != nil {
+			return err
+		}
+		// the few statuses and mime types aren't allocated again for
+		// each item
+		item.Status = p.intern(item.Status)
+		item.MimeType = p.intern(item.MimeType)
+	}
+	_, err = dec.Token()
+	return err
+}
+
+// intern returns the copy of s already used by the page
+func (p *pageDecoder) intern(s string) string {
+	if shared, ok := p.strings[s]; ok {
+		return shared
+	}
+	if p.strings == nil {
+		p.strings = make(map[string]string)
+	}
+	p.strings[s] = s
+	return s
+}
+
+// appendList reads the part of the list at path selected by params as
+// listWith does, appending its items to items as they are decoded.
+func (c *client) appendList(ctx context.Context, path string, params url.Values, items []api.Item) (_ []api.Item, total int, err error) {
+	opts := rest.Opts{
+		Method:     "GET",
+		Path:       path,
+		Parameters: params,
+	}
+	size, _ := strconv.Atoi(params.Get("limit"))
+	page := &pageDecoder{items: items, start: len(items), size: size}
+	resp, err := c.call(ctx, &opts, page)
+	items = page.items
+	if err != nil {
+		if resp != nil && resp.StatusCode >= http.StatusInternalServerError {
+			err = fmt.Errorf("%w: %w", errServerError, err)
+		}
+		return items[:page.start], 0, fmt.Errorf("list %s: %w", path, err)
+	}
+	// an error page may be served without it when the API is down
+	header := resp.Header.Get("X-Total-Count")
+	if header == "" {
+		return items[:page.start], 0, fmt.Errorf("list %s: missing X-Total-Count", path)
+	}
+	total, err = strconv.Atoi(header)
+	if err != nil {
+		return items[:page.start], 0, fmt.Errorf("list %s: bad X-Total-Count: %w", path, err)
+	}
+	return items, total, nil
+}
+
+// ListTorrents reads a page of the torrents, newest first
+func (c *client) ListTorrents(ctx context.Context, page, limit int) (items []api.Item, total int, err error) {
+	return c.list(ctx, "/torrents", page, limit)
+}
+
+// ListTorrentsAt reads limit torrents from offset, newest first
+func (c *client) ListTorrentsAt(ctx context.Context, offset, limit int) (items []api.Item, total int, err error) {
+	return c.appendTorrentsAt(ctx, nil, offset, limit)
+}
+
+// AppendTorrents reads up to limit torrents following on from those
+// in torrents, newest first, and appends them to torrents
+func (c *client) AppendTorrents(ctx context.Context, torrents []api.Item, limit int) (_ []api.Item, total int, err error) {
+	return c.appendTorrentsAt(ctx, torrents, len(torrents), limit)
+}
+
+// appendTorrentsAt appends limit torrents from offset to torrents
+func (c *client) appendTorrentsAt(ctx context.Context, torrents []api.Item, offset, limit int) (_ []api.Item, total int, err error) {
+	params := c.params()
+	params.Set("offset", strconv.Itoa(offset))
+	params.Set("limit", strconv.Itoa(limit))
+	return c.appendList(ctx, "/torrents", params, torrents)
+}
+
+// ListDownloads reads a page of the unrestricted links, newest first
+func (c *client) ListDownloads(ctx context.Context, page, limit int) (items []api.Item, total int, err error) {
+	return c.list(ctx, "/downloads", page, limit)
+}
+
+// ListAllDownloads reads the pages of limit unrestricted links, up to
+// maxPages of them if it is > 0.
+//
+// Links added or deleted while paging shift the pages so a link may
+// be read twice or missed. The links are deduplicated by ID and if
+// fewer than the total are read the pages are read once more. On
+// error the links read so far are returned with it.
+func (c *client) ListAllDownloads(ctx context.Context, limit, maxPages int) (items []api.Item, err error) {
+	for try := 1; ; try++ {
+		var total int
+		items = items[:0]
+		seen := make(map[string]struct{})
+		for page := 1; maxPages <= 0 || page <= maxPages; page++ {
+			params := c.params()
+			params.Set("limit", strconv.Itoa(limit))
+			params.Set("page", strconv.Itoa(page))
+			start := len(items)
+			items, total, err = c.appendList(ctx, "/downloads", params, items)
+			if err != nil {
+				return items, err
+			}
+			read := len(items) - start
+			// the page is deduplicated where it was decoded
+			kept := items[:start]
+			for _, item := range items[start:] {
+				if _, found := seen[item.ID]; found {
+					continue
+				}
+				seen[item.ID] = struct{}{}
+				kept = append(kept, item)
+			}
+			items = kept
+			if read < limit || page*limit >= total {
+				break
+			}
+		}
+		if maxPages > 0 {
+			total = min(total, maxPages*limit)
+		}
+		if len(items) >= total || try >= 2 {
+			return items, nil
+		}
+		fs.Debugf(nil, "realdebrid: read %d of %d download links, reading them again", len(items), total)
+	}
+}
+
+// TorrentInfo reads the details of the torrent with id
+func (c *client) TorrentInfo(ctx context.Context, id string) (info *api.Item, err error) {
+	opts := rest.Opts{
+		Method: "GET",
+		Path:   "/torrents/info/" + id,
+	}
+	_, err = c.call(ctx, &opts, &info)
+	if err != nil {
+		return nil, fmt.Errorf("torrent info %q: %w", id, err)
+	}
+	if info == nil || info.ID == "" {
+		return nil, fmt.Errorf("torrent info %q: no torrent returned", id)
+	}
+	return info, nil
+}
+
+// User returns the account of the API key
+func (c *client) User(ctx context.Context) (user *api.User, err error) {
+	opts := rest.Opts{
+		Method: "GET",
+		Path:   "/user",
+	}
+	_, err = c.call(ctx, &opts, &user)
+	if err != nil {
+		return nil, fmt.Errorf("user: %w", err)
+	}
+	return user, nil
+}
+
+// Unrestrict makes a download link for link, returning
+// errLinkUnavailable if the hoster can't serve it and a
+// *hosterWaitError if it asks to wait before trying again.
+func (c *client) Unrestrict(ctx context.Context, link string) (item *api.Item, err error) {
+	return c.unrestrict(ctx, link, false)
+}
+
+// UnrestrictRemote is like Unrestrict but makes a download link which
+// works from any IP, its downloads using the remote traffic quota of
+// the account.
+func (c *client) UnrestrictRemote(ctx context.Context, link string) (item *api.Item, err error) {
+	return c.unrestrict(ctx, link, true)
+}
+
+func (c *client) unrestrict(ctx context.Context, link string, remote bool) (item *api.Item, err error) {
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/unrestrict/link",
+		MultipartParams: url.Values{
+			"link": {link},
+		},
+	}
+	if remote {
+		opts.MultipartParams.Set("remote", "1")
+	}
+	resp, err := c.call(ctx, &opts, &item)
+	if err != nil {
+		// a hoster asking to wait isn't a dead link
+		if wait := hosterWait(resp, err); wait > 0 {
+			return nil, fmt.Errorf("unrestrict %q: %w", link, &hosterWaitError{wait: wait, err: err})
+		}
+		if resp != nil && resp.StatusCode == http.StatusServiceUnavailable {
+			return nil, fmt.Errorf("unrestrict %q: %w: %w", link, errLinkUnavailable, err)
+		}
+		return nil, fmt.Errorf("unrestrict %q: %w", link, err)
+	}
+	if item == nil || item.Link == "" {
+		return nil, fmt.Errorf("unrestrict %q: no download link returned", link)
+	}
+	return item, nil
+}
+
+// AddMagnet adds the torrent with hash returning the new torrent ID
+func (c *client) AddMagnet(ctx context.Context, hash string) (id string, err error) {
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/torrents/addMagnet",
+		MultipartParams: url.Values{
+			"magnet": {"magnet:?xt=urn:btih:" + hash},
+		},
+	}
+	var result api.Item
+	_, err = c.call(ctx, &opts, &result)
+	if err != nil {
+		return "", fmt.Errorf("add magnet %q: %w", hash, err)
+	}
+	if result.ID == "" {
+		return "", fmt.Errorf("add magnet %q: no torrent ID returned", hash)
+	}
+	return result.ID, nil
+}
+
+// SelectFiles selects the files with fileIDs in the torrent with id
+func (c *client) SelectFiles(ctx context.Context, id string, fileIDs []int64) (err error) {
+	ids := make([]string, len(fileIDs))
+	for i, fileID := range fileIDs {
+		ids[i] = strconv.FormatInt(fileID, 10)
+	}
+	opts := rest.Opts{
+		Method: "POST",
+		Path:   "/torrents/selectFiles/" + id,
+		MultipartParams: url.Values{
+			"files": {strings.Join(ids, ",")},
+		},
+	}
+	_, err = c.call(ctx, &opts, nil)
+	if err != nil {
+		return fmt.Errorf("select files of %q: %w", id, err)
+	}
+	return nil
+}
+
+// DeleteTorrent deletes the torrent with id
+func (c *client) DeleteTorrent(ctx context.Context, id string) (err error) {
+	opts := rest.Opts{
+		Method: "DELETE",
+		Path:   "/torrents/delete/" + id,
+	}
+	_, err = c.call(ctx, &opts, nil)
+	if err != nil {
+		return fmt.Errorf("delete torrent %q: %w", id, err)
+	}
+	return nil
+}
+
+// DeleteDownload deletes the unrestricted link with id
+func (c *client) DeleteDownload(ctx context.Context, id string) (err error) {
+	opts := rest.Opts{
+		Method: "DELETE",
+		Path:   "/downloads/delete/" + id,
+	}
+	_, err = c.call(ctx, &opts, nil)
+	if err != nil {
+		return fmt.Errorf("delete download %q: %w", id, err)
+	}
+	return nil
+}
+
+// Download opens link for reading with options. It is called once
+// since on failure the caller decides whether to get a new link.
+//
+// It isn't limited by the timeout of the API calls as the body is
+// read long after.
+//
+// Some CDN nodes gzip small text files like subtitles when they may,
+// so compression is refused for the bytes read to match the size.
+func (c *client) Download(ctx context.Context, link string, options []fs.OpenOption) (resp *http.Response, err error) {
+	opts := rest.Opts{
+		Method:  "GET",
+		RootURL: link,
+		Options: options,
+		ExtraHeaders: map[string]string{
+			"Accept-Encoding": "identity",
+		},
+	}
+	resp, err = c.srv.Call(ctx, &opts)
+	return resp, c.redact(err)
+}
+
+// retryErrorCodes is a slice of error codes that we will retry
+var retryErrorCodes = []int{
+	429, // Too Many Requests.
+	500, // Internal Server Error
+	502, // Bad Gateway
+	504, // Gateway Timeout
+	509, // Bandwidth Limit Exceeded
+}
+
+// shouldRetry returns a boolean as to whether this resp and err
+// deserve to be retried.  It returns the err as a convenience
+func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if fserrors.ContextError(ctx, &err) {
+		return false, err
+	}
+	// retrying a hoster asking to wait only fails again
+	if hosterWait(resp, err) > 0 {
+		return false, err
+	}
+	// wait for the time given by a rate limited response if any
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		if retryAfter, parseErr := strconv.Atoi(resp.Header.Get("Retry-After")); parseErr == nil && retryAfter > 0 {
+			return true, pacer.RetryAfterError(err, time.Duration(retryAfter)*time.Second)
+		}
+	}
+	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
+}
+
+// errorHandler parses a non 2xx error response into an error
+func errorHandler(resp *http.Response) error {
+	body, err := rest.ReadBody(resp)
+	if err != nil {
+		body = nil
+	}
+	var e = api.Response{
+		Message: string(body),
+		Status:  fmt.Sprintf("%s (%d)", resp.Status, resp.StatusCode),
+	}
+	if body != nil {
+		_ = json.Unmarshal(body, &e)
+	}
+	return &e
+}
+
+// apiStats counts the requests made to Real-Debrid for realdebrid/stats.
+//
+// They are updated on every request so are atomics.
+type apiStats struct {
+	apiCalls        atomic.Int64 // requests to the API
+	tooManyRequests atomic.Int64 // API responses with 429 Too Many Requests
+	apiErrors       atomic.Int64 // API requests which failed other than with 429
+	unrestricts     atomic.Int64 // requests to /unrestrict/link
+	downloads       atomic.Int64 // requests for file content
+	downloadErrors  atomic.Int64 // requests for file content which failed
+	relinks         atomic.Int64 // expired download links replaced on open
+	repairs         atomic.Int64 // dead or broken torrents added again
+	repairsDeferred atomic.Int64 // repairs left for later as files of the torrent were open
+	repairsFailed   atomic.Int64 // torrents given up on after repair_max_attempts
+	verifiedLinks   atomic.Int64 // download links checked in the background
+	deadLinks       atomic.Int64 // checked download links which failed
+	brokenLinks     atomic.Int64 // dead links which couldn't be unrestricted again
+	verifyPaused    atomic.Int64 // checks skipped while files were streamed
+	simulated       atomic.Int64 // API calls skipped by the simulate option
+	generation      atomic.Int64 // generation of the latest torrents listed
+	refreshErrors   atomic.Int64 // refreshes of the torrents which failed or ran out of budget
+	circuitOpened   atomic.Int64 // times refreshes were stopped after too many failures
+	refreshSkipped  atomic.Int64 // refreshes skipped while they were stopped
+	autoRefreshes   atomic.Int64 // refreshes made in the background by background_refresh
+	missHits        atomic.Int64 // lookups of missing files answered without listing
+	streamLimitHits atomic.Int64 // files not opened as max_concurrent_streams were open
+	staleListings   atomic.Int64 // listings of the torrents listed before as they couldn't be refreshed
+}
+
+var stats apiStats
+
+// params returns the counters for realdebrid/stats
+func (s *apiStats) params() rc.Params {
+	return rc.Params{
+		"apiCalls":        s.apiCalls.Load(),
+		"tooManyRequests": s.tooManyRequests.Load(),
+		"apiErrors":       s.apiErrors.Load(),
+		"unrestricts":     s.unrestricts.Load(),
+		"downloads":       s.downloads.Load(),
+		"downloadErrors":  s.downloadErrors.Load(),
+		"relinks":         s.relinks.Load(),
+		"repairs":         s.repairs.Load(),
+		"repairsDeferred": s.repairsDeferred.Load(),
+		"repairsFailed":   s.repairsFailed.Load(),
+		"verifiedLinks":   s.verifiedLinks.Load(),
+		"deadLinks":       s.deadLinks.Load(),
+		"brokenLinks":     s.brokenLinks.Load(),
+		"verifyPaused":    s.verifyPaused.Load(),
+		"simulated":       s.simulated.Load(),
+		"generation":      s.generation.Load(),
+		"refreshErrors":   s.refreshErrors.Load(),
+		"circuitOpened":   s.circuitOpened.Load(),
+		"refreshSkipped":  s.refreshSkipped.Load(),
+		"autoRefreshes":   s.autoRefreshes.Load(),
+		"missHits":        s.missHits.Load(),
+		"streamLimitHits": s.streamLimitHits.Load(),
+		"staleListings":   s.staleListings.Load(),
+	}
+}
+
+// apiHost is the host the API calls are made to
+var apiHost = func() string {
+	u, _ := url.Parse(rootURL)
+	return u.Host
+}()
+
+// statsTransport counts the requests made through it in stats
+type statsTransport struct {
+	wrapped http.RoundTripper
+}
+
+// newStatsClient returns a copy of client which counts its requests
+func newStatsClient(client *http.Client) *http.Client {
+	c := *client
+	transport := c.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	c.Transport = &statsTransport{wrapped: transport}
+	return &c
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *statsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	isAPI := req.URL.Host == apiHost
+	if isAPI {
+		stats.apiCalls.Add(1)
+		if strings.HasSuffix(req.URL.Path, "/unrestrict/link") {
+			stats.unrestricts.Add(1)
+		}
+	} else {
+		stats.downloads.Add(1)
+	}
+	resp, err := t.wrapped.RoundTrip(req)
+	failed := err != nil || resp.StatusCode >= 400
+	switch {
+	case !failed:
+	case isAPI && err == nil && resp.StatusCode == http.StatusTooManyRequests:
+		stats.tooManyRequests.Add(1)
+	case isAPI:
+		stats.apiErrors.Add(1)
+	default:
+		stats.downloadErrors.Add(1)
+	}
+	return resp, err
+}
+
+func rcStats(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	return stats.params(), nil
+}
@@ -0,0 +1,501 @@
+package realdebrid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAPIKey = "test-api-key"
+
+// newTestClient makes a client calling a mock server serving handler
+func newTestClient(t *testing.T, handler http.HandlerFunc) *client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, testAPIKey, r.URL.Query().Get("auth_token"))
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	oldRootURL := rootURL
+	rootURL = srv.URL
+	t.Cleanup(func() {
+		rootURL = oldRootURL
+	})
+	p := fs.NewPacer(context.Background(), pacer.NewDefault(pacer.MinSleep(time.Millisecond), pacer.MaxSleep(time.Millisecond)))
+	return newClient(srv.Client(), p, testAPIKey)
+}
+
+// writeJSON writes v as the JSON response
+func writeJSON(t *testing.T, w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	require.NoError(t, json.NewEncoder(w).Encode(v))
+}
+
+func TestClientList(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		query := r.URL.Query()
+		w.Header().Set("X-Total-Count", "3")
+		id := r.URL.Path + "?page=" + query.Get("page") + "&limit=" + query.Get("limit")
+		if query.Has("offset") {
+			id += "&offset=" + query.Get("offset")
+		}
+		writeJSON(t, w, []api.Item{{ID: id}})
+	})
+
+	items, total, err := c.ListTorrents(ctx, 2, 2500)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, []api.Item{{ID: "/torrents?page=2&limit=2500"}}, items)
+
+	// page 0 isn't set
+	items, _, err = c.ListDownloads(ctx, 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []api.Item{{ID: "/downloads?page=&limit=1"}}, items)
+
+	// or read from an offset
+	items, _, err = c.ListTorrentsAt(ctx, 100, 50)
+	require.NoError(t, err)
+	assert.Equal(t, []api.Item{{ID: "/torrents?page=&limit=50&offset=100"}}, items)
+}
+
+func TestListWithoutTotalCount(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"missing", `[]`, "missing X-Total-Count"},
+		{"error page", `<html>Bad Gateway</html>`, "invalid character"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			f := newTestFs(t)
+			f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(test.content))
+			})
+			f.client.pacer.SetRetries(1)
+			f.cache.startup_cached_api_fetch = true
+			f.cache.state.Invalidate()
+
+			// the listing fails rather than the mount
+			_, err := f.List(ctx, "movies")
+			assert.ErrorContains(t, err, test.want)
+
+			f.opt.RootFolderID = "downloads"
+			f.dirCache.ResetRoot()
+			_, err = f.List(ctx, "")
+			assert.ErrorContains(t, err, test.want)
+		})
+	}
+}
+
+func TestClientListAllDownloads(t *testing.T) {
+	ctx := context.Background()
+	var (
+		downloads []api.Item
+		pages     []string
+	)
+	for i := range 5 {
+		downloads = append([]api.Item{{ID: strconv.Itoa(i)}}, downloads...)
+	}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		pages = append(pages, strconv.Itoa(page))
+		// A link is added before the second page is first read
+		// shifting the pages
+		if len(pages) == 2 {
+			downloads = append([]api.Item{{ID: "new"}}, downloads...)
+		}
+		start := min((page-1)*limit, len(downloads))
+		end := min(start+limit, len(downloads))
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(downloads)))
+		writeJSON(t, w, downloads[start:end])
+	})
+
+	items, err := c.ListAllDownloads(ctx, 2, 0)
+	require.NoError(t, err)
+	var ids []string
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	assert.Equal(t, []string{"new", "4", "3", "2", "1", "0"}, ids)
+	// The first pass reads 3 twice and misses "new" so is read again
+	assert.Equal(t, []string{"1", "2", "3", "1", "2", "3"}, pages)
+
+	// maxPages limits what is read and expected
+	pages = nil
+	items, err = c.ListAllDownloads(ctx, 2, 1)
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+	assert.Equal(t, []string{"1"}, pages)
+}
+
+func TestPageDecoder(t *testing.T) {
+	ctx := context.Background()
+	var calls atomic.Int64
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Total-Count", "3")
+		if calls.Add(1) == 1 {
+			// cut short so the page is read again
+			w.Header().Set("Content-Length", "1000")
+			_, _ = w.Write([]byte(`[{"id":"B","status":"downloaded"},`))
+			return
+		}
+		writeJSON(t, w, []api.Item{{ID: "B", Status: "downloaded"}, {ID: "C", Status: "downloaded"}})
+	})
+
+	// The page is appended after the items given, once however many
+	// times it is read
+	items, total, err := c.AppendTorrents(ctx, []api.Item{{ID: "A"}}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, []string{"A", "B", "C"}, itemIDs(items))
+	assert.Equal(t, int64(2), calls.Load())
+	assert.Equal(t, unsafe.StringData(items[1].Status), unsafe.StringData(items[2].Status), "the statuses are shared")
+
+	// null is an empty page and anything else but a list an error
+	page := &pageDecoder{items: items, start: 1}
+	require.NoError(t, json.Unmarshal([]byte(`null`), page))
+	assert.Equal(t, []string{"A"}, itemIDs(page.items))
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"error":"bad_token"}`), page), "cannot unmarshal")
+}
+
+// BenchmarkListPage compares decoding a page of torrents into a slice
+// of its own appended to the torrents read so far with decoding it
+// straight onto their end
+func BenchmarkListPage(b *testing.B) {
+	torrents := make([]api.Item, listPageSize)
+	for i := range torrents {
+		id := strconv.Itoa(i)
+		torrents[i] = api.Item{
+			ID:          "ID" + id,
+			Name:        "Some.Show.S01E" + id + ".1080p.WEB.H264",
+			TorrentHash: "0123456789abcdef0123456789abcdef" + id,
+			Bytes:       int64(i) << 20,
+			Status:      "downloaded",
+			Ended:       "2024-03-12T03:12:00.000Z",
+			Links:       []string{"https://real-debrid.com/d/" + id},
+		}
+	}
+	data, err := json.Marshal(torrents)
+	require.NoError(b, err)
+	const pages = 4
+	b.Run("slice", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var all []api.Item
+			for range pages {
+				var page []api.Item
+				if err := json.Unmarshal(data, &page); err != nil {
+					b.Fatal(err)
+				}
+				all = append(all, page...)
+			}
+		}
+	})
+	b.Run("stream", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var all []api.Item
+			for range pages {
+				page := &pageDecoder{items: all, start: len(all), size: listPageSize}
+				if err := json.Unmarshal(data, page); err != nil {
+					b.Fatal(err)
+				}
+				all = page.items
+			}
+		}
+	})
+}
+
+func TestClientTorrents(t *testing.T) {
+	ctx := context.Background()
+	var calls []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		call := r.Method + " " + r.URL.Path
+		if r.Method == "POST" {
+			require.NoError(t, r.ParseMultipartForm(1<<20))
+			call += " " + r.FormValue("magnet") + r.FormValue("files")
+		}
+		calls = append(calls, call)
+		switch r.URL.Path {
+		case "/torrents/info/ID":
+			writeJSON(t, w, api.Item{ID: "ID", Files: []api.File{{ID: 1, Path: "/a.mkv", Selected: 1}}})
+		case "/torrents/addMagnet":
+			w.WriteHeader(http.StatusCreated)
+			writeJSON(t, w, api.Item{ID: "NEWID"})
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+
+	info, err := c.TorrentInfo(ctx, "ID")
+	require.NoError(t, err)
+	assert.Equal(t, "ID", info.ID)
+	assert.Equal(t, "/a.mkv", info.Files[0].Path)
+
+	id, err := c.AddMagnet(ctx, "HASH")
+	require.NoError(t, err)
+	assert.Equal(t, "NEWID", id)
+
+	require.NoError(t, c.SelectFiles(ctx, "NEWID", []int64{1, 3}))
+	require.NoError(t, c.DeleteTorrent(ctx, "ID"))
+	require.NoError(t, c.DeleteDownload(ctx, "LINKID"))
+
+	assert.Equal(t, []string{
+		"GET /torrents/info/ID",
+		"POST /torrents/addMagnet magnet:?xt=urn:btih:HASH",
+		"POST /torrents/selectFiles/NEWID 1,3",
+		"DELETE /torrents/delete/ID",
+		"DELETE /downloads/delete/LINKID",
+	}, calls)
+}
+
+func TestClientUnrestrict(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	status := http.StatusOK
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/unrestrict/link", r.URL.Path)
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		calls++
+		// rate limited on the first call
+		if calls == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			writeJSON(t, w, map[string]any{"error": "hoster_unavailable", "error_code": 19})
+			return
+		}
+		writeJSON(t, w, api.Item{ID: "LINKID", OriginalLink: r.FormValue("link"), Link: "https://download.example.com/file.mkv"})
+	})
+
+	item, err := c.Unrestrict(ctx, "https://real-debrid.com/d/ABC")
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, "https://real-debrid.com/d/ABC", item.OriginalLink)
+	assert.Equal(t, "https://download.example.com/file.mkv", item.Link)
+
+	// 503 means the link needs repairing and isn't retried
+	calls, status = 1, http.StatusServiceUnavailable
+	_, err = c.Unrestrict(ctx, "https://real-debrid.com/d/ABC")
+	assert.True(t, errors.Is(err, errLinkUnavailable), err)
+	assert.Equal(t, 2, calls)
+
+	// other errors aren't errLinkUnavailable
+	calls, status = 1, http.StatusForbidden
+	_, err = c.Unrestrict(ctx, "https://real-debrid.com/d/ABC")
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, errLinkUnavailable), err)
+}
+
+func TestRetriesFollowLowLevelRetries(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.LowLevelRetries = 2
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	t.Cleanup(srv.Close)
+	oldRootURL, oldDumpDir := rootURL, dumpDir
+	rootURL, dumpDir = srv.URL, t.TempDir()
+	t.Cleanup(func() {
+		rootURL, dumpDir = oldRootURL, oldDumpDir
+	})
+	f, err := NewFs(ctx, "retries", "", configmap.Simple{"api_key": "retries-test"})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.(*Fs).Shutdown(ctx))
+	})
+
+	// Every API call is retried by the pacer of the Fs, only sleeping
+	// less here
+	client := f.(*Fs).client
+	client.pacer.SetCalculator(pacer.NewDefault(pacer.MinSleep(time.Millisecond), pacer.MaxSleep(time.Millisecond)))
+	for _, call := range []func() error{
+		func() error { _, err := client.TorrentInfo(ctx, "T1"); return err },
+		func() error { _, err := client.Unrestrict(ctx, "l1"); return err },
+		func() error { _, err := client.AddMagnet(ctx, "H"); return err },
+		func() error { return client.DeleteTorrent(ctx, "T1") },
+	} {
+		calls.Store(0)
+		assert.Error(t, call())
+		assert.Equal(t, int64(2), calls.Load())
+	}
+}
+
+func TestAPIRetries(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.LowLevelRetries = 2
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(srv.Close)
+	oldRootURL, oldDumpDir := rootURL, dumpDir
+	rootURL, dumpDir = srv.URL, t.TempDir()
+	t.Cleanup(func() {
+		rootURL, dumpDir = oldRootURL, oldDumpDir
+	})
+	f, err := NewFs(ctx, "retries", "", configmap.Simple{
+		"api_key":         "retries-test",
+		"api_retries":     "4",
+		"api_retry_delay": "1ms",
+		"pacer_min_sleep": "1ms",
+		"pacer_max_sleep": "1ms",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.(*Fs).Shutdown(ctx))
+	})
+
+	// api_retries is used rather than --low-level-retries
+	_, err = f.(*Fs).client.TorrentInfo(ctx, "T1")
+	assert.Error(t, err)
+	assert.Equal(t, int64(4), calls.Load())
+}
+
+func TestAPITimeout(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.Timeout = fs.Duration(time.Hour)
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/d/file" {
+			// slower than api_timeout but answers
+			time.Sleep(300 * time.Millisecond)
+			_, _ = w.Write([]byte("content"))
+			return
+		}
+		// the API never answers
+		calls.Add(1)
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+	oldRootURL, oldDumpDir := rootURL, dumpDir
+	rootURL, dumpDir = srv.URL, t.TempDir()
+	t.Cleanup(func() {
+		rootURL, dumpDir = oldRootURL, oldDumpDir
+	})
+	f, err := NewFs(ctx, "timeout", "", configmap.Simple{
+		"api_key":         "timeout-test",
+		"api_timeout":     "100ms",
+		"api_retries":     "2",
+		"pacer_min_sleep": "1ms",
+		"pacer_max_sleep": "1ms",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, f.(*Fs).Shutdown(ctx))
+	})
+	client := f.(*Fs).client
+
+	// Each try of an API call fails after api_timeout, not --timeout
+	start := time.Now()
+	_, _, err = client.ListTorrents(ctx, 1, 100)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "api_timeout")
+	assert.Equal(t, int64(2), calls.Load())
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// but downloads aren't limited by it
+	resp, err := client.Download(ctx, srv.URL+"/d/file", nil)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, "content", string(body))
+}
+
+func TestClientRedact(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {})
+	// make the requests fail in the transport
+	c.srv.SetRoot("http://127.0.0.1:1")
+	_, err := c.TorrentInfo(ctx, "ID")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), testAPIKey)
+	assert.Contains(t, err.Error(), "auth_token=REDACTED")
+}
+
+// roundTripperFunc makes a func into an http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+func TestStatsTransport(t *testing.T) {
+	oldStats := stats.params()
+	status := http.StatusOK
+	client := newStatsClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: status, Body: http.NoBody, Request: req}, nil
+	})})
+	get := func(url string) {
+		resp, err := client.Get(url)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	}
+	get(rootURL + "/torrents")
+	get(rootURL + "/unrestrict/link")
+	status = http.StatusTooManyRequests
+	get(rootURL + "/torrents")
+	status = http.StatusServiceUnavailable
+	get(rootURL + "/torrents")
+	get("https://download.example.com/file.mkv")
+	status = http.StatusPartialContent
+	get("https://download.example.com/file.mkv")
+
+	delta := map[string]int64{}
+	for k, v := range stats.params() {
+		delta[k] = v.(int64) - oldStats[k].(int64)
+	}
+	assert.Equal(t, map[string]int64{
+		"apiCalls":        4,
+		"tooManyRequests": 1,
+		"apiErrors":       1,
+		"unrestricts":     1,
+		"downloads":       2,
+		"downloadErrors":  1,
+		"relinks":         0,
+		"repairs":         0,
+		"repairsDeferred": 0,
+		"repairsFailed":   0,
+		"verifiedLinks":   0,
+		"deadLinks":       0,
+		"brokenLinks":     0,
+		"verifyPaused":    0,
+		"simulated":       0,
+		"generation":      0,
+		"refreshErrors":   0,
+		"circuitOpened":   0,
+		"refreshSkipped":  0,
+		"autoRefreshes":   0,
+		"missHits":        0,
+		"streamLimitHits": 0,
+		"staleListings":   0,
+	}, delta)
+}
@@ -0,0 +1,194 @@
+package realdebrid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+)
+
+// errHosterCooldown is wrapped by the errors of the unrestricts of a
+// hoster which asked to wait before unrestricting its links again
+var errHosterCooldown = errors.New("hoster cooldown")
+
+// hosterErrorCodes are the Real-Debrid error codes of a hoster which
+// is limited rather than the link being dead
+var hosterErrorCodes = []int{
+	17, // hoster in maintenance
+	18, // hoster limit reached
+	19, // hoster temporarily unavailable
+}
+
+// waitPattern finds the wait period in the error message of a limited
+// hoster, like "you must wait 90 seconds"
+var waitPattern = regexp.MustCompile(`(?i)wait\D{0,20}?(\d+)\s*(second|sec|minute|min|hour|h|m|s)s?\b`)
+
+// hosterWait returns how long Real-Debrid asked to wait before
+// unrestricting the links of the hoster which failed with resp and
+// err, or 0 if it isn't a hoster cooldown.
+//
+// The period is read from the error details then the message, then
+// from the Retry-After header.
+func hosterWait(resp *http.Response, err error) time.Duration {
+	var apiErr *api.Response
+	if !errors.As(err, &apiErr) {
+		return 0
+	}
+	for _, text := range []string{apiErr.ErrorDetails, apiErr.Message} {
+		if wait := parseWait(text); wait > 0 {
+			return wait
+		}
+	}
+	if !isHosterError(apiErr) || resp == nil {
+		return 0
+	}
+	if retryAfter, parseErr := strconv.Atoi(resp.Header.Get("Retry-After")); parseErr == nil && retryAfter > 0 {
+		return time.Duration(retryAfter) * time.Second
+	}
+	return 0
+}
+
+// isHosterError returns true if apiErr is about the hoster of the link
+func isHosterError(apiErr *api.Response) bool {
+	for _, code := range hosterErrorCodes {
+		if apiErr.ErrorCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// parseWait returns the wait period in text or 0 if there isn't one
+func parseWait(text string) time.Duration {
+	match := waitPattern.FindStringSubmatch(text)
+	if match == nil {
+		return 0
+	}
+	n, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0
+	}
+	unit := time.Second
+	switch strings.ToLower(match[2]) {
+	case "minute", "min", "m":
+		unit = time.Minute
+	case "hour", "h":
+		unit = time.Hour
+	}
+	return time.Duration(n) * unit
+}
+
+// linkHost returns the hoster of the original link
+func linkHost(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link
+	}
+	return strings.ToLower(strings.TrimPrefix(u.Hostname(), "www."))
+}
+
+// cooldowns remembers until when each hoster asked not to unrestrict
+// its links
+type cooldowns struct {
+	mu    sync.Mutex
+	until map[string]time.Time // end of the cooldown by host
+	now   func() time.Time     // the clock, replaced in tests
+}
+
+// newCooldowns makes an empty cooldowns
+func newCooldowns() *cooldowns {
+	return &cooldowns{
+		until: make(map[string]time.Time),
+		now:   time.Now,
+	}
+}
+
+// get returns the end of the cooldown of host if it is cooling down
+func (c *cooldowns) get(host string) (until time.Time, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	until, ok = c.until[host]
+	if ok && !c.now().Before(until) {
+		delete(c.until, host)
+		return time.Time{}, false
+	}
+	return until, ok
+}
+
+// set starts a cooldown of host for wait, returning its end
+func (c *cooldowns) set(host string, wait time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	until := c.now().Add(wait)
+	if until.After(c.until[host]) {
+		c.until[host] = until
+	}
+	return c.until[host]
+}
+
+// cooldownError returns the error of an unrestrict of link skipped as
+// its hoster is cooling down until until
+func cooldownError(link, host string, until time.Time) error {
+	return fmt.Errorf("unrestrict %q: %w of %s: cooldown until %s", link, errHosterCooldown, host, until.Local().Format("15:04"))
+}
+
+// unrestrictLink unrestricts link, for other IPs if remote is set,
+// unless its hoster is cooling down.
+//
+// If it is, the call fails at once, or waits for the end of the
+// cooldown if queue is set as it is for the background verifier. A
+// hoster asking to wait starts its cooldown.
+func (f *Fs) unrestrictLink(ctx context.Context, link string, remote, queue bool) (*api.Item, error) {
+	host := linkHost(link)
+	if until, ok := f.cooldowns.get(host); ok {
+		if !queue {
+			return nil, cooldownError(link, host, until)
+		}
+		fs.Debugf(f, "Waiting for the cooldown of %s until %s", host, until.Local().Format("15:04:05"))
+		timer := time.NewTimer(until.Sub(f.cooldowns.now()))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+	unrestrict := f.client.Unrestrict
+	if remote {
+		unrestrict = f.client.UnrestrictRemote
+	}
+	item, err := unrestrict(ctx, link)
+	var wait *hosterWaitError
+	if errors.As(err, &wait) {
+		until := f.cooldowns.set(host, wait.wait)
+		fs.Logf(f, "Hoster %s asked to wait %v: not unrestricting its links until %s", host, wait.wait, until.Local().Format("15:04:05"))
+		return nil, cooldownError(link, host, until)
+	}
+	return item, err
+}
+
+// hosterWaitError is the error of an unrestrict which failed as the
+// hoster asked to wait for wait
+type hosterWaitError struct {
+	wait time.Duration
+	err  error
+}
+
+// Error satisfies the error interface
+func (e *hosterWaitError) Error() string {
+	return fmt.Sprintf("%v: wait %v", e.err, e.wait)
+}
+
+// Unwrap returns the API error and errHosterCooldown
+func (e *hosterWaitError) Unwrap() []error {
+	return []error{e.err, errHosterCooldown}
+}
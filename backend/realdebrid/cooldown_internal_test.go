@@ -0,0 +1,97 @@
+package realdebrid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHosterWait(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		err  error
+		hdr  string
+		want time.Duration
+	}{
+		{"details", &api.Response{ErrorCode: 18, ErrorDetails: "You must wait 90 seconds"}, "", 90 * time.Second},
+		{"message", &api.Response{Message: "please wait 5 minutes before the next download"}, "", 5 * time.Minute},
+		{"short unit", &api.Response{ErrorCode: 18, ErrorDetails: "wait: 2h"}, "", 2 * time.Hour},
+		{"retry after", &api.Response{ErrorCode: 19}, "30", 30 * time.Second},
+		{"retry after not hoster", &api.Response{ErrorCode: 34}, "30", 0},
+		{"no period", &api.Response{ErrorCode: 19, ErrorDetails: "hoster_unavailable"}, "", 0},
+		{"not api", errors.New("wait 10 seconds"), "", 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if test.hdr != "" {
+				resp.Header.Set("Retry-After", test.hdr)
+			}
+			assert.Equal(t, test.want, hosterWait(resp, test.err))
+		})
+	}
+	assert.Equal(t, "rapidgator.net", linkHost("https://www.RapidGator.net/file/abc"))
+}
+
+func TestUnrestrictCooldown(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	now := time.Unix(1700000000, 0)
+	f.cooldowns.now = func() time.Time { return now }
+	var calls atomic.Int64
+	var limited atomic.Bool
+	limited.Store(true)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.FormValue("link") == "https://other.example/ok" || !limited.Load() {
+			writeJSON(t, w, api.Item{ID: "D1", OriginalLink: r.FormValue("link"), Link: "https://dl/file.mkv"})
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		writeJSON(t, w, api.Response{ErrorName: "hoster_limit_reached", ErrorCode: 18, ErrorDetails: "you must wait 60 seconds"})
+	})
+
+	// A hoster asking to wait fails without being retried nor the link
+	// being taken as dead
+	_, err := f.unrestrictLink(ctx, "https://hoster.example/a", false, false)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errHosterCooldown), err)
+	assert.False(t, errors.Is(err, errLinkUnavailable), err)
+	assert.Contains(t, err.Error(), "cooldown until "+now.Add(time.Minute).Local().Format("15:04"))
+	assert.Equal(t, int64(1), calls.Load())
+
+	// Its other links then fail fast until the cooldown ends
+	_, err = f.unrestrictLink(ctx, "https://hoster.example/b", false, false)
+	assert.True(t, errors.Is(err, errHosterCooldown), err)
+	_, err = f.downloadLink(ctx, "https://hoster.example/c")
+	assert.True(t, errors.Is(err, errHosterCooldown), err)
+	assert.Equal(t, int64(1), calls.Load())
+
+	// but not the links of other hosters
+	item, err := f.unrestrictLink(ctx, "https://other.example/ok", false, false)
+	require.NoError(t, err)
+	assert.Equal(t, "https://dl/file.mkv", item.Link)
+	assert.Equal(t, int64(2), calls.Load())
+
+	// The background verifier waits for the end of the cooldown
+	now = now.Add(time.Minute - 50*time.Millisecond)
+	limited.Store(false)
+	start := time.Now()
+	item, err = f.unrestrictLink(ctx, "https://hoster.example/b", false, true)
+	require.NoError(t, err)
+	assert.Equal(t, "https://dl/file.mkv", item.Link)
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+	// or for ctx to be cancelled
+	f.cooldowns.set("hoster.example", time.Hour)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = f.unrestrictLink(cancelled, "https://hoster.example/b", false, true)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,157 @@
+package realdebrid
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+)
+
+// doctorPageSize is the number of torrents and downloads read by the
+// doctor command
+const doctorPageSize = 50
+
+// doctorStep is a step of the doctor command
+type doctorStep struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Skipped bool   `json:"skipped,omitempty"` // not run as a step before it failed
+	Took    string `json:"took,omitempty"`
+	Detail  string `json:"detail,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// doctorReport is the result of the doctor command
+type doctorReport struct {
+	OK    bool         `json:"ok"`
+	Took  string       `json:"took"`
+	Steps []doctorStep `json:"steps"`
+}
+
+// doctor checks the remote works from end to end, making the API calls
+// a listing and a read make one at a time: the account, a page of
+// torrents and of downloads, the links of a downloaded torrent picked
+// at random, the download link of one of them and its first byte from
+// the CDN.
+//
+// The steps after one which failed are skipped. The calls go through
+// the pacer and the download link made, if one had to be, is deleted
+// at the end, so it is safe to run on the account in use.
+func (f *Fs) doctor(ctx context.Context) *doctorReport {
+	start := time.Now()
+	report := &doctorReport{OK: true}
+	step := func(name string, fn func() (detail string, err error)) {
+		if !report.OK {
+			report.Steps = append(report.Steps, doctorStep{Name: name, Skipped: true})
+			return
+		}
+		stepStart := time.Now()
+		detail, err := fn()
+		s := doctorStep{
+			Name:   name,
+			OK:     err == nil,
+			Took:   time.Since(stepStart).Round(time.Millisecond).String(),
+			Detail: detail,
+		}
+		if err != nil {
+			s.Error = err.Error()
+			report.OK = false
+			fs.Debugf(f, "doctor: %s failed: %v", name, err)
+		}
+		report.Steps = append(report.Steps, s)
+	}
+
+	var (
+		torrents  []api.Item
+		downloads []api.Item
+		torrent   *api.Item
+		link      api.Item
+		created   bool // whether link was made by the doctor
+	)
+	step("user", func() (string, error) {
+		user, err := f.client.User(ctx)
+		if err != nil {
+			return "", err
+		}
+		left := fs.Duration(time.Duration(user.Premium) * time.Second)
+		return fmt.Sprintf("%s (%s, premium left %v)", user.Username, user.Type, left), nil
+	})
+	step("torrents", func() (detail string, err error) {
+		var total int
+		torrents, total, err = f.client.ListTorrents(ctx, 1, doctorPageSize)
+		return fmt.Sprintf("%d of %d torrents listed", len(torrents), total), err
+	})
+	step("downloads", func() (detail string, err error) {
+		var total int
+		downloads, total, err = f.client.ListDownloads(ctx, 1, doctorPageSize)
+		return fmt.Sprintf("%d of %d downloads listed", len(downloads), total), err
+	})
+	step("torrentInfo", func() (string, error) {
+		var downloaded []api.Item
+		for _, item := range torrents {
+			if item.Status == "downloaded" {
+				downloaded = append(downloaded, item)
+			}
+		}
+		if len(downloaded) == 0 {
+			return "", fmt.Errorf("none of the %d torrents listed is downloaded", len(torrents))
+		}
+		var err error
+		torrent, err = f.client.TorrentInfo(ctx, downloaded[rand.IntN(len(downloaded))].ID)
+		if err != nil {
+			return "", err
+		}
+		if len(torrent.Links) == 0 {
+			return "", fmt.Errorf("torrent %q (%s) has no links", torrent.Name, torrent.ID)
+		}
+		return fmt.Sprintf("%q (%s) has %d links", torrent.Name, torrent.ID, len(torrent.Links)), nil
+	})
+	step("unrestrict", func() (string, error) {
+		original := torrent.Links[0]
+		// reuse a download link made before rather than make another
+		cached, found := f.cache.link(original)
+		if found && cached.Link != "" && !cached.Remote {
+			link = cached
+			return fmt.Sprintf("%q unrestricted before as %s", link.Name, link.ID), nil
+		}
+		for _, item := range downloads {
+			if item.OriginalLink == original && item.Link != "" && !item.Remote {
+				link = item
+				return fmt.Sprintf("%q unrestricted before as %s", link.Name, link.ID), nil
+			}
+		}
+		item, err := f.client.Unrestrict(ctx, original)
+		if err != nil {
+			return "", err
+		}
+		link = *item
+		// the API may return a download of the account made before
+		created = link.ID != cached.ID && !slices.ContainsFunc(downloads, func(download api.Item) bool {
+			return download.ID == link.ID
+		})
+		return fmt.Sprintf("%q unrestricted as %s", link.Name, link.ID), nil
+	})
+	step("cdn", func() (string, error) {
+		if err := f.checkLink(ctx, link.Link); err != nil {
+			return "", err
+		}
+		return "first byte read", nil
+	})
+	if created {
+		// whether the check of the CDN worked or not
+		cleanStart := time.Now()
+		s := doctorStep{Name: "cleanup", OK: true, Detail: fmt.Sprintf("download %s deleted", link.ID)}
+		if err := f.client.DeleteDownload(ctx, link.ID); err != nil {
+			s.OK, s.Detail, s.Error = false, "", err.Error()
+			report.OK = false
+		}
+		s.Took = time.Since(cleanStart).Round(time.Millisecond).String()
+		report.Steps = append(report.Steps, s)
+	}
+	report.Took = time.Since(start).Round(time.Millisecond).String()
+	return report
+}
@@ -0,0 +1,129 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoctor(t *testing.T) {
+	ctx := context.Background()
+	var cdnFails atomic.Bool
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "bytes=0-0", r.Header.Get("Range"))
+		if cdnFails.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Range", "bytes 0-0/100")
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write([]byte{0})
+	}))
+	t.Cleanup(cdn.Close)
+
+	var (
+		downloads   []api.Item
+		unrestricts atomic.Int32
+		deleted     []string
+	)
+	f := newTestFs(t)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/user":
+			writeJSON(t, w, api.User{Username: "someone", Type: "premium", Premium: 3600})
+		case r.URL.Path == "/torrents":
+			w.Header().Set("X-Total-Count", "2")
+			writeJSON(t, w, []api.Item{
+				{ID: "T1", Name: "Movie.2020", Status: "downloaded"},
+				{ID: "T2", Name: "Show.S01", Status: "downloading"},
+			})
+		case r.URL.Path == "/downloads":
+			w.Header().Set("X-Total-Count", "1")
+			writeJSON(t, w, downloads)
+		case r.URL.Path == "/torrents/info/T1":
+			writeJSON(t, w, api.Item{ID: "T1", Name: "Movie.2020", Status: "downloaded", Links: []string{"https://hoster/L1"}})
+		case r.URL.Path == "/unrestrict/link":
+			unrestricts.Add(1)
+			writeJSON(t, w, api.Item{ID: "D1", Name: "movie.mkv", OriginalLink: "https://hoster/L1", Link: cdn.URL + "/d/movie.mkv"})
+		case r.Method == "DELETE":
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	f.client.pacer.SetRetries(1)
+	doctor := func() *doctorReport {
+		out, err := f.Command(ctx, "doctor", nil, nil)
+		require.NoError(t, err)
+		return out.(*doctorReport)
+	}
+	stepNames := func(report *doctorReport) (names []string) {
+		for _, s := range report.Steps {
+			names = append(names, s.Name)
+		}
+		return names
+	}
+
+	// The download link made is deleted at the end
+	report := doctor()
+	assert.True(t, report.OK, report.Steps)
+	assert.Equal(t, []string{"user", "torrents", "downloads", "torrentInfo", "unrestrict", "cdn", "cleanup"}, stepNames(report))
+	for _, s := range report.Steps {
+		assert.True(t, s.OK, s.Name)
+		assert.NotEmpty(t, s.Took, s.Name)
+	}
+	assert.Equal(t, "2 of 2 torrents listed", report.Steps[1].Detail)
+	assert.Equal(t, int32(1), unrestricts.Load())
+	assert.Equal(t, []string{"/downloads/delete/D1"}, deleted)
+
+	// but not one of the account, which is read rather than made again
+	downloads = []api.Item{{ID: "D0", Name: "movie.mkv", OriginalLink: "https://hoster/L1", Link: cdn.URL + "/d/movie.mkv"}}
+	report = doctor()
+	assert.True(t, report.OK, report.Steps)
+	assert.Equal(t, []string{"user", "torrents", "downloads", "torrentInfo", "unrestrict", "cdn"}, stepNames(report))
+	assert.Contains(t, report.Steps[4].Detail, "unrestricted before as D0")
+	assert.Equal(t, int32(1), unrestricts.Load())
+	assert.Len(t, deleted, 1)
+
+	// A failed check of the CDN still cleans up
+	downloads = nil
+	cdnFails.Store(true)
+	report = doctor()
+	assert.False(t, report.OK)
+	assert.Equal(t, []string{"user", "torrents", "downloads", "torrentInfo", "unrestrict", "cdn", "cleanup"}, stepNames(report))
+	assert.False(t, report.Steps[5].OK)
+	assert.NotEmpty(t, report.Steps[5].Error)
+	assert.True(t, report.Steps[6].OK)
+	assert.Equal(t, []string{"/downloads/delete/D1", "/downloads/delete/D1"}, deleted)
+}
+
+func TestDoctorSkipsAfterFailure(t *testing.T) {
+	f := newTestFs(t)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/user" {
+			w.WriteHeader(http.StatusUnauthorized)
+			writeJSON(t, w, api.Response{ErrorName: "bad_token", ErrorCode: 8})
+			return
+		}
+		t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	f.client.pacer.SetRetries(1)
+	report := f.doctor(context.Background())
+	assert.False(t, report.OK)
+	require.Len(t, report.Steps, 6)
+	assert.False(t, report.Steps[0].OK)
+	assert.Contains(t, report.Steps[0].Error, "bad_token")
+	for _, s := range report.Steps[1:] {
+		assert.True(t, s.Skipped, s.Name)
+		assert.False(t, s.OK, s.Name)
+	}
+}
@@ -0,0 +1,86 @@
+package realdebrid
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// errReadOnly is returned by the operations which would add files or
+// directories as Real-Debrid only serves the files of its torrents
+var errReadOnly = errors.New("can't upload files or make directories on Real-Debrid")
+
+// errPurgeCategory is returned by purging a category folder, which
+// would delete all its torrents, unless purge_categories is set
+var errPurgeCategory = errors.New("can't purge a category folder unless purge_categories is set")
+
+// errExcluded is returned by deleting a torrent hidden by
+// regex_exclude, which the backend leaves alone
+var errExcluded = errors.New("torrent is hidden by regex_exclude")
+
+// opError is an error returned by the backend with the context needed
+// to tell where it came from when several remotes are in use, e.g.
+//
+//	realdebrid{rd-main}: open shows/X/e01.mkv (torrent ABC123 "X.S01"): 503 ...
+type opError struct {
+	Name        string // name of the remote
+	Op          string // the operation which failed, e.g. "open"
+	Remote      string // path of the object or directory, may be ""
+	TorrentID   string // ID of the torrent it belongs to, may be ""
+	TorrentName string // name of the torrent if known
+	Err         error  // the underlying error
+}
+
+func (e *opError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "realdebrid{%s}: %s", e.Name, e.Op)
+	if e.Remote != "" {
+		b.WriteString(" " + e.Remote)
+	}
+	if e.TorrentID != "" {
+		b.WriteString(" (torrent " + e.TorrentID)
+		if e.TorrentName != "" {
+			fmt.Fprintf(&b, " %q", e.TorrentName)
+		}
+		b.WriteString(")")
+	}
+	b.WriteString(": " + e.Err.Error())
+	return b.String()
+}
+
+func (e *opError) Unwrap() error {
+	return e.Err
+}
+
+// wrapErr wraps err with the remote name, the operation op, the path
+// remote and the torrent with torrentID, either of which may be "".
+//
+// nil, errors which are already wrapped and the sentinel errors rclone
+// compares directly are returned as is.
+func (f *Fs) wrapErr(op, remote, torrentID string, err error) error {
+	if err == nil || err == fs.ErrorDirNotFound || err == fs.ErrorObjectNotFound || err == fs.ErrorIsFile {
+		return err
+	}
+	var opErr *opError
+	if errors.As(err, &opErr) {
+		return err
+	}
+	opErr = &opError{
+		Name:      f.name,
+		Op:        op,
+		Remote:    remote,
+		TorrentID: torrentID,
+		Err:       err,
+	}
+	if torrentID != "" && f.cache != nil {
+		for _, torrent := range f.cache.torrentsList() {
+			if torrent.ID == torrentID {
+				opErr.TorrentName = torrent.Name
+				break
+			}
+		}
+	}
+	return opErr
+}
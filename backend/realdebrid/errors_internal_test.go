@@ -0,0 +1,142 @@
+package realdebrid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpError(t *testing.T) {
+	cause := errors.New("503 Service Unavailable")
+	err := error(&opError{Name: "rd-main", Op: "open", Remote: "shows/X/e01.mkv", TorrentID: "ABC123", TorrentName: "X.S01", Err: cause})
+	assert.Equal(t, `realdebrid{rd-main}: open shows/X/e01.mkv (torrent ABC123 "X.S01"): 503 Service Unavailable`, err.Error())
+	assert.ErrorIs(t, err, cause)
+
+	err = &opError{Name: "rd-main", Op: "list", Err: cause}
+	assert.Equal(t, `realdebrid{rd-main}: list: 503 Service Unavailable`, err.Error())
+
+	// sentinels are returned as is and errors are only wrapped once
+	f := newTestFs(t)
+	for _, sentinel := range []error{nil, fs.ErrorDirNotFound, fs.ErrorObjectNotFound, fs.ErrorIsFile} {
+		assert.Equal(t, sentinel, f.wrapErr("open", "file.mkv", "", sentinel))
+	}
+	err = f.wrapErr("open", "file.mkv", "", cause)
+	assert.Equal(t, err, f.wrapErr("remove", "other.mkv", "", err))
+}
+
+// checkOpError checks err is an *opError with the fields given and the
+// message showing them
+func checkOpError(t *testing.T, err error, op, remote, torrentID, torrentName string) {
+	t.Helper()
+	var opErr *opError
+	require.ErrorAs(t, err, &opErr)
+	assert.Equal(t, "test", opErr.Name)
+	assert.Equal(t, op, opErr.Op)
+	assert.Equal(t, remote, opErr.Remote)
+	assert.Equal(t, torrentID, opErr.TorrentID)
+	assert.Equal(t, torrentName, opErr.TorrentName)
+	assert.Contains(t, err.Error(), "realdebrid{test}: "+op)
+	assert.Contains(t, err.Error(), remote)
+	assert.Contains(t, err.Error(), torrentID)
+	assert.Contains(t, err.Error(), torrentName)
+}
+
+func TestErrorContext(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.opt.SharedFolder = "torrents"
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/unrestrict/link":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			writeJSON(t, w, api.Response{Message: "hoster_unavailable"})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(t, w, api.Response{Message: "bad_request"})
+		}
+	})
+	torrent := api.Item{ID: "ABC123", Name: "Movie.2020", Status: "downloaded", TorrentHash: "abc", Links: []string{"l1"}}
+	f.cache.torrents = []api.Item{torrent}
+	f.cache.torrentswf = []api.Item{torrent}
+	f.cache.cached = []api.Item{{ID: "d1", Name: "movie.mkv", OriginalLink: "l1", Link: "https://example.com/1", Size: 100}}
+
+	o, err := f.NewObject(ctx, "Movie.2020/movie.mkv")
+	require.NoError(t, err)
+	err = o.Remove(ctx)
+	checkOpError(t, err, "remove", "Movie.2020/movie.mkv", "ABC123", "Movie.2020")
+	var apiErr *api.Response
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "bad_request", apiErr.Message)
+
+	// Opening a file listed from the torrent info which can't be
+	// unrestricted marks its torrent broken
+	o, err = f.newObjectWithInfo(ctx, "Movie.2020/extra.mkv", &api.Item{Name: "extra.mkv", Type: api.ItemTypeFile, OriginalLink: "l2", ParentID: "ABC123"})
+	require.NoError(t, err)
+	_, err = o.Open(ctx)
+	checkOpError(t, err, "open", "Movie.2020/extra.mkv", "ABC123", "Movie.2020")
+	assert.ErrorIs(t, err, errLinkUnavailable)
+	assert.Equal(t, []string{"ABC123"}, f.cache.broken_torrents)
+
+	err = f.Purge(ctx, "Movie.2020")
+	checkOpError(t, err, "purge", "Movie.2020", "ABC123", "Movie.2020")
+
+	_, err = f.Command(ctx, "reselect", []string{"DEF456"}, map[string]string{"files": "."})
+	checkOpError(t, err, "reselect", "", "DEF456", "")
+	_, err = f.reselect(ctx, "DEF456", regexp.MustCompile("."), false)
+	var opErr *opError
+	assert.False(t, errors.As(err, &opErr), "only wrapped once at the top")
+
+	// Missing objects are still reported with the sentinel error
+	_, err = f.NewObject(ctx, "Movie.2020/missing.mkv")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+
+	// Listing fails when the torrents can't be refreshed
+	f.cache.torrents = nil
+	f.dirCache.Flush()
+	_, err = f.List(ctx, "")
+	checkOpError(t, err, "list", "", "", "")
+	assert.ErrorAs(t, err, &apiErr)
+}
+
+func TestListTorrentErrors(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.opt.SharedFolder = "torrents"
+	infoFails := true
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/torrents/info/ABC123" && infoFails:
+			// garbage which decodes to nothing
+			_, _ = w.Write([]byte("null"))
+		case r.URL.Path == "/torrents/info/ABC123":
+			writeJSON(t, w, api.Item{ID: "ABC123", Status: "downloaded", Links: []string{"l1", "l2"}})
+		case r.URL.Path == "/unrestrict/link" && r.FormValue("link") == "l1":
+			writeJSON(t, w, api.Item{ID: "d1", Name: "movie.mkv", OriginalLink: "l1", Link: "https://example.com/1"})
+		default:
+			writeJSON(t, w, api.Item{})
+		}
+	})
+	f.cache.torrents = []api.Item{{ID: "ABC123", Name: "Movie.2020", Status: "downloaded", Links: []string{"l1", "l2"}}}
+
+	// Listing a torrent fails if its details can't be read
+	_, err := f.List(ctx, "Movie.2020")
+	checkOpError(t, err, "list", "Movie.2020", "ABC123", "Movie.2020")
+	assert.ErrorContains(t, err, "no torrent returned")
+	_, cached := f.cache.torrentDetails("ABC123")
+	assert.False(t, cached)
+
+	// but a bad link only leaves its file out
+	infoFails = false
+	entries, err := f.List(ctx, "Movie.2020")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Movie.2020/movie.mkv"}, entryNames(entries))
+	_, found := f.cache.link("l2")
+	assert.False(t, found)
+}
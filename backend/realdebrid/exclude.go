@@ -0,0 +1,45 @@
+package realdebrid
+
+import (
+	"regexp"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+)
+
+// excludeRegexp returns the regex of regex_exclude, or nil if it isn't
+// set
+func (f *Fs) excludeRegexp() *regexp.Regexp {
+	if f.opt.RegexExclude == "" {
+		return nil
+	}
+	// checked by validateOptions
+	re, _ := regexp.Compile(f.opt.RegexExclude)
+	return re
+}
+
+// excludedBy returns true if torrent is hidden by exclude, the regex
+// returned by excludeRegexp
+func excludedBy(exclude *regexp.Regexp, torrent *api.Item) bool {
+	return exclude != nil && exclude.MatchString(torrent.Name)
+}
+
+// excludedID returns true if the cached torrent with id is hidden by
+// regex_exclude.
+//
+// They aren't listed so can't be found from their path, but they are
+// checked before reading or deleting one from its ID too.
+func (f *Fs) excludedID(id string) bool {
+	exclude := f.excludeRegexp()
+	if exclude == nil {
+		return false
+	}
+	f.cache.refreshMu.Lock()
+	torrents := f.cache.torrents
+	f.cache.refreshMu.Unlock()
+	for i := range torrents {
+		if torrents[i].ID == id {
+			return excludedBy(exclude, &torrents[i])
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegexExclude(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.opt.RegexExclude = `(?i)\bseed`
+	f.opt.PurgeCategories = true
+	var deleted []string
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	f.cache.torrents = []api.Item{
+		{ID: "S1", Name: "Seed.Only.2020", Status: "downloaded", Links: []string{"l1"}},
+		{ID: "M1", Name: "Movie.2021", Status: "downloaded"},
+		{ID: "S2", Name: "Archive.S01.seed", Status: "dead"},
+	}
+
+	entries, err := f.List(ctx, "movies")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"movies/Movie.2021"}, entryNames(entries))
+	entries, err = f.List(ctx, "shows")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// Their folders aren't read from their IDs either
+	_, _, err = f.listAll(ctx, "S1", false, false, func(*api.Item) bool { return false })
+	assert.ErrorIs(t, err, fs.ErrorDirNotFound)
+
+	// nor deleted
+	assert.ErrorIs(t, f.remove(ctx, "D1", "S1"), errExcluded)
+	f.dirCache.Put("movies/Seed.Only.2020", "S1")
+	assert.ErrorIs(t, f.Purge(ctx, "movies/Seed.Only.2020"), errExcluded)
+	assert.Empty(t, deleted)
+
+	// even when purging the category they would be in
+	require.NoError(t, f.Purge(ctx, "movies"))
+	assert.Equal(t, []string{"/torrents/delete/M1"}, deleted)
+
+	// nor repaired
+	f.cache.refreshMu.Lock()
+	f.repairTorrents(ctx)
+	f.cache.refreshMu.Unlock()
+	assert.Equal(t, []string{"/torrents/delete/M1"}, deleted)
+
+	// They aren't listed in the other modes either, the others being
+	// deleted
+	assert.Len(t, f.cache.torrents, 2)
+	f.opt.SharedFolder = "torrents"
+	f.dirCache.ResetRoot()
+	entries, err = f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,67 @@
+package realdebrid
+
+// exportFile is a file of an exported torrent
+type exportFile struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	Link string `json:"link"`
+}
+
+// exportTorrent is an exported torrent
+type exportTorrent struct {
+	ID     string       `json:"id"`
+	Name   string       `json:"name"`
+	Hash   string       `json:"hash"`
+	Status string       `json:"status"`
+	Files  []exportFile `json:"files"`
+}
+
+// exportReport is the result of the export command
+type exportReport struct {
+	Generation int64           `json:"generation"`
+	Torrents   []exportTorrent `json:"torrents"`
+}
+
+// export returns the torrents in the scope of f and the files listed
+// for them from a snapshot of the cache so they are all from the same
+// generation.
+func (f *Fs) export() *exportReport {
+	snap := f.cache.snapshot()
+	links := make(map[string]exportFile, len(snap.cached))
+	for _, item := range snap.cached {
+		if _, found := links[item.OriginalLink]; !found {
+			links[item.OriginalLink] = exportFile{Name: item.Name, Size: item.Size, Link: item.Link}
+		}
+	}
+	details := make(map[string][]string, len(snap.torrentswf))
+	for _, torrent := range snap.torrentswf {
+		if _, found := details[torrent.ID]; !found {
+			details[torrent.ID] = torrent.Links
+		}
+	}
+	torrents := f.scopedTorrents(snap.torrents, snap.categories)
+	report := &exportReport{
+		Generation: snap.generation,
+		Torrents:   make([]exportTorrent, 0, len(torrents)),
+	}
+	for _, torrent := range torrents {
+		t := exportTorrent{
+			ID:     torrent.ID,
+			Name:   torrent.Name,
+			Hash:   torrent.TorrentHash,
+			Status: torrent.Status,
+			Files:  []exportFile{},
+		}
+		torrentLinks, found := details[torrent.ID]
+		if !found {
+			torrentLinks = torrent.Links
+		}
+		for _, link := range torrentLinks {
+			if file, found := links[link]; found {
+				t.Files = append(t.Files, file)
+			}
+		}
+		report.Torrents = append(report.Torrents, t)
+	}
+	return report
+}
@@ -0,0 +1,94 @@
+package realdebrid
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// refreshGeneration replaces the torrents and their links with n
+// torrents prefixed with name as a refresh would, returning the
+// generation of the new torrents
+func refreshGeneration(c *sharedCache, name string, n int) int64 {
+	var torrents, cached []api.Item
+	for i := range n {
+		link := fmt.Sprintf("%s-link-%d", name, i)
+		torrents = append(torrents, api.Item{ID: fmt.Sprintf("%s-%d", name, i), Name: name, Status: "downloaded", Links: []string{link}})
+		cached = append(cached, api.Item{ID: link, Name: name + ".mkv", OriginalLink: link, Link: "https://example.com/" + link})
+	}
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	c.setTorrents(torrents)
+	c.cached = cached
+	return c.generation
+}
+
+func TestExport(t *testing.T) {
+	f := newTestFs(t)
+	before := stats.generation.Load()
+	generation := refreshGeneration(f.cache, "first", 2)
+	assert.Greater(t, generation, before)
+	assert.Equal(t, generation, stats.generation.Load())
+
+	out, err := f.Command(context.Background(), "export", nil, nil)
+	require.NoError(t, err)
+	report := out.(*exportReport)
+	assert.Equal(t, generation, report.Generation)
+	require.Len(t, report.Torrents, 2)
+	assert.Equal(t, exportTorrent{
+		ID:     "first-1",
+		Name:   "first",
+		Status: "downloaded",
+		Files:  []exportFile{{Name: "first.mkv", Link: "https://example.com/first-link-1"}},
+	}, report.Torrents[1])
+
+	// Deletes start a new generation
+	f.cache.forget("", "first-0")
+	assert.Greater(t, f.export().Generation, generation)
+	assert.Len(t, f.export().Torrents, 1)
+}
+
+func TestExportDuringRefresh(t *testing.T) {
+	f := newTestFs(t)
+	var (
+		mu          sync.Mutex
+		generations = map[int64]string{}
+		wg          sync.WaitGroup
+	)
+	refresh := func(name string) {
+		// held so exports can't look the generation up before it is known
+		mu.Lock()
+		defer mu.Unlock()
+		generations[refreshGeneration(f.cache, name, 50)] = name
+	}
+	refresh("gen0")
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= 200; i++ {
+			refresh(fmt.Sprintf("gen%d", i))
+		}
+	}()
+	for range 200 {
+		report := f.export()
+		require.Len(t, report.Torrents, 50)
+		// all the torrents and files are from the generation reported
+		mu.Lock()
+		name, found := generations[report.Generation]
+		mu.Unlock()
+		require.True(t, found, report.Generation)
+		for _, torrent := range report.Torrents {
+			assert.Equal(t, name, torrent.Name)
+			require.Len(t, torrent.Files, 1)
+			assert.True(t, strings.HasPrefix(torrent.Files[0].Link, "https://example.com/"+name+"-link-"), torrent.Files[0].Link)
+		}
+	}
+	wg.Wait()
+}
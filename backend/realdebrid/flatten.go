@@ -0,0 +1,80 @@
+package realdebrid
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"golang.org/x/text/unicode/norm"
+)
+
+// flattened returns true if the single file torrents of the directory
+// dirID are listed as their file
+func (f *Fs) flattened(dirID string) bool {
+	return f.opt.FlattenSingleFile && f.opt.SharedFolder == "folders" && (f.isCategory(dirID) && !f.byYear(dirID) || f.isYear(dirID))
+}
+
+// singleFile returns true if torrent is downloaded with a single file
+//
+// This only needs the torrents list so the torrents folders are found
+// without fetching their details.
+func singleFile(torrent *api.Item) bool {
+	return torrent.Status == "downloaded" && len(torrent.Links) == 1
+}
+
+// flattenSingleFiles replaces the single file torrents of the
+// category listing torrents by their file.
+//
+// With directoriesOnly they are only dropped so finding a torrent
+// folder doesn't fetch the details of the others.
+func (f *Fs) flattenSingleFiles(ctx context.Context, torrents []api.Item, directoriesOnly bool) []api.Item {
+	var links map[string]api.Item
+	result := make([]api.Item, 0, len(torrents))
+	for _, torrent := range torrents {
+		if !singleFile(&torrent) {
+			result = append(result, torrent)
+			continue
+		}
+		if directoriesOnly {
+			continue
+		}
+		if links == nil {
+			links = f.cache.links()
+		}
+		// not listed if it can't be named, as in files mode
+		if files := f.torrentFiles(ctx, torrent, links); len(files) == 1 {
+			result = append(result, files[0])
+		}
+	}
+	return uniqueFileNames(result)
+}
+
+// uniqueFileNames renames the files of flattened torrents whose name is
+// already used in items so they are all reachable.
+//
+// The torrent folders keep their names as do the oldest files, the
+// others have the ID of their torrent added before their extension.
+func uniqueFileNames(items []api.Item) []api.Item {
+	seen := make(map[string]struct{}, len(items))
+	for i := range items {
+		if items[i].ParentID == "" {
+			seen[foldName(norm.NFC.String(items[i].Name))] = struct{}{}
+		}
+	}
+	// the API lists the newest torrents first
+	for i := len(items) - 1; i >= 0; i-- {
+		item := &items[i]
+		if item.ParentID == "" {
+			continue
+		}
+		key := foldName(norm.NFC.String(item.Name))
+		if _, found := seen[key]; found {
+			ext := path.Ext(item.Name)
+			item.Name = strings.TrimSuffix(item.Name, ext) + " (" + item.ParentID + ")" + ext
+			key = foldName(norm.NFC.String(item.Name))
+		}
+		seen[key] = struct{}{}
+	}
+	return items
+}
@@ -0,0 +1,90 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"testing"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlattenSingleFile(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	requests := map[string]int{}
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests[r.Method+" "+r.URL.Path]++
+		switch {
+		case r.Method == "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		case path.Dir(r.URL.Path) == "/torrents/info":
+			id := path.Base(r.URL.Path)
+			writeJSON(t, w, api.Item{
+				ID:     id,
+				Status: "downloaded",
+				Links:  []string{"I" + id},
+				Files:  []api.File{{ID: 1, Path: "/Movie.2020.1080p.mkv", Bytes: 100, Selected: 1}},
+			})
+		default:
+			t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	// newest first, the single files of 1 and 3 have the same name
+	f.cache.torrents = []api.Item{
+		{ID: "1", Name: "Movie.2020", Status: "downloaded", Links: []string{"I1"}},
+		{ID: "2", Name: "Collection.2019", Status: "downloaded", Links: []string{"L2", "L3"}},
+		{ID: "3", Name: "Movie.2020.REPACK", Status: "downloaded", Links: []string{"I3"}},
+		{ID: "4", Name: "Adding.2022", Status: "magnet_conversion"},
+	}
+	f.cache.addTorrentDetails(f.cache.torrents[1])
+	f.cache.addLink(api.Item{ID: "D2", Name: "a.mkv", Link: "https://dl/a.mkv", OriginalLink: "L2", Size: 1})
+	f.cache.addLink(api.Item{ID: "D3", Name: "b.mkv", Link: "https://dl/b.mkv", OriginalLink: "L3", Size: 2})
+	list := func(dir string) []string {
+		entries, err := f.List(ctx, dir)
+		require.NoError(t, err)
+		return entryNames(entries)
+	}
+
+	// Every torrent is a folder by default
+	assert.Equal(t, []string{"movies/Adding.2022", "movies/Collection.2019", "movies/Movie.2020", "movies/Movie.2020.REPACK"}, list("movies"))
+
+	// Single file torrents are listed as their file, the newest one
+	// renamed, when flattened
+	f.opt.FlattenSingleFile = true
+	f.dirCache.ResetRoot()
+	assert.Equal(t, []string{"movies/Adding.2022", "movies/Collection.2019", "movies/Movie.2020.1080p (1).mkv", "movies/Movie.2020.1080p.mkv"}, list("movies"))
+	assert.Equal(t, 1, requests["GET /torrents/info/1"])
+	assert.Equal(t, 1, requests["GET /torrents/info/3"])
+
+	// Both shapes are found
+	f.dirCache.ResetRoot()
+	o, err := f.NewObject(ctx, "movies/Movie.2020.1080p (1).mkv")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), o.Size())
+	assert.Equal(t, "1", o.(*Object).ParentID)
+	o, err = f.NewObject(ctx, "movies/Movie.2020.1080p.mkv")
+	require.NoError(t, err)
+	assert.Equal(t, "3", o.(*Object).ParentID)
+	o, err = f.NewObject(ctx, "movies/Collection.2019/a.mkv")
+	require.NoError(t, err)
+	assert.Equal(t, "2", o.(*Object).ParentID)
+	assert.Equal(t, []string{"movies/Collection.2019/a.mkv", "movies/Collection.2019/b.mkv"}, list("movies/Collection.2019"))
+
+	// but not the folders of flattened torrents
+	_, err = f.NewObject(ctx, "movies/Movie.2020/Movie.2020.1080p.mkv")
+	assert.ErrorIs(t, err, fs.ErrorObjectNotFound)
+	_, err = f.List(ctx, "movies/Movie.2020")
+	assert.ErrorIs(t, err, fs.ErrorDirNotFound)
+
+	// Removing the file deletes its torrent
+	o, err = f.NewObject(ctx, "movies/Movie.2020.1080p (1).mkv")
+	require.NoError(t, err)
+	require.NoError(t, o.Remove(ctx))
+	assert.Equal(t, 1, requests["DELETE /torrents/delete/1"])
+	assert.Equal(t, []string{"movies/Adding.2022", "movies/Collection.2019", "movies/Movie.2020.1080p.mkv"}, list("movies"))
+}
@@ -0,0 +1,1559 @@
+package realdebrid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+	"github.com/rclone/rclone/fs/fserrors"
+	"github.com/rclone/rclone/fs/fshttp"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/lib/dircache"
+	"github.com/rclone/rclone/lib/oauthutil"
+	"github.com/rclone/rclone/lib/readers"
+	"github.com/rclone/rclone/lib/rest"
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Fs represents a remote cloud storage system
+type Fs struct {
+	name         string             // name of this remote
+	root         string             // the path we are working on
+	opt          Options            // parsed options
+	categoryList []category         // the category folders, made from opt
+	features     *fs.Features       // optional features
+	client       *client            // the Real-Debrid API
+	dirCache     *dircache.DirCache // Map of directory path to directory id
+	tokenRenewer *oauthutil.Renew   // renew the token on expiry
+	cache        *sharedCache       // listing caches, maybe shared with other Fs
+	releaseOnce  sync.Once          // release the cache only once
+	infos        *infoCache         // recently read torrent details
+	infoFetches  singleflight.Group // torrent details being fetched by ID
+	misses       *missCache         // files recently looked up and not found
+	cooldowns    *cooldowns         // hosters asking to wait before unrestricting their links
+	unrestricts  singleflight.Group // links being unrestricted by original link
+	streams      atomic.Int64       // number of files open for reading
+	servingStale atomic.Bool        // set while the torrents are listed stale, see serve_stale_on_error
+	ctx          context.Context    // cancelled by Shutdown to stop the background work
+	stop         context.CancelFunc // cancels ctx
+	background   sync.WaitGroup     // goroutines started by goBackground
+	verifyCursor int                // index in cache.cached of the next link to verify, under cache.mu
+	classifier   *rest.Client       // client for classify_url if set
+
+	categoryOverrides *categoryOverrides // categories set with set-category, nil if not loaded
+
+	reloadMu  sync.Mutex       // held while the options are reloaded
+	m         configmap.Mapper // the config the options are reloaded from
+	overrides configmap.Simple // options set by reload-options, under reloadMu
+
+	statusMu   sync.Mutex // held while the status file content is made
+	statusData []byte     // content of the status file, nil if not made yet
+	statusTime time.Time  // when statusData was made
+
+	flushes flushQueue // directories changed in the background
+
+	// the newest torrents found by the last poll of ChangeNotify
+	mu                sync.Mutex
+	torrentStatuses   map[string]string // status by ID
+	torrentTotal      int               // number of torrents of the account
+	torrentStatusBase bool              // set once polled
+}
+
+// Object describes a file
+type Object struct {
+	fs          *Fs       // what this object is part of
+	remote      string    // The remote path
+	hasMetaData bool      // metadata is present and correct
+	size        int64     // size of the object
+	modTime     time.Time // modification time of the object
+	id          string    // ID of the object
+	ParentID    string    // ID of parent directory
+	mimeType    string    // Mime type of object
+	url         string    // URL to download file
+	TorrentHash string    // Torrent Hash
+	OriginalUrl string    // Original link
+}
+
+// ------------------------------------------------------------
+
+// Name of the remote (as passed into NewFs)
+func (f *Fs) Name() string {
+	return f.name
+}
+
+// Root of the remote (as passed into NewFs)
+func (f *Fs) Root() string {
+	return f.root
+}
+
+// String converts this Fs to a string
+func (f *Fs) String() string {
+	return fmt.Sprintf("realdebrid root '%s'", f.root)
+}
+
+// Features returns the optional features of this Fs
+func (f *Fs) Features() *fs.Features {
+	return f.features
+}
+
+// parsePath parses a realdebrid 'url'
+func parsePath(path string) (root string) {
+	root = strings.Trim(path, "/")
+	return
+}
+
+// readMetaDataForPath reads the metadata from the path
+func (f *Fs) readMetaDataForPath(ctx context.Context, path string, directoriesOnly bool, filesOnly bool) (info *api.Item, err error) {
+	// defer fs.Trace(f, "path=%q", path)("info=%+v, err=%v", &info, &err)
+	//fmt.Printf("stating '%s'", path)
+	leaf, directoryID, err := f.dirCache.FindPath(ctx, path, false)
+	if err != nil {
+		if err == fs.ErrorDirNotFound {
+			return nil, fs.ErrorObjectNotFound
+		}
+		return nil, err
+	}
+
+	key := foldName(f.normalize(leaf))
+	// media servers keep looking for the same missing files
+	cacheMiss := filesOnly && !directoriesOnly
+	generation := f.cache.currentGeneration()
+	if cacheMiss && f.misses.has(directoryID, key, generation) {
+		stats.missHits.Add(1)
+		return nil, fs.ErrorObjectNotFound
+	}
+
+	if !directoriesOnly {
+		if info, found := f.findCachedFile(ctx, directoryID, leaf); found {
+			return info, nil
+		}
+	}
+	//fmt.Printf("...with listAll\n")
+	_, found, err := f.listAll(ctx, directoryID, directoriesOnly, filesOnly, func(item *api.Item) bool {
+		if foldName(item.Name) == key {
+			info = item
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		if cacheMiss {
+			f.misses.add(directoryID, key, generation)
+		}
+		return nil, fs.ErrorObjectNotFound
+	}
+	return info, nil
+}
+
+// normalize returns name composed to NFC if unicode_normalization is
+// set so names with decomposed accents match those typed by clients
+// which compose them.
+func (f *Fs) normalize(name string) string {
+	if !f.opt.UnicodeNormalization {
+		return name
+	}
+	return norm.NFC.String(name)
+}
+
+// foldName returns name case folded so names differing only in case
+// compare equal, including those where the case mapping changes the
+// length such as ß and SS.
+//
+// All the case insensitive comparisons of names use it so a name is
+// found by every lookup or by none of them. The folding doesn't depend
+// on the locale so the Turkish dotless ı and dotted İ don't match I and
+// i.
+func foldName(name string) string {
+	return cases.Fold().String(name)
+}
+
+// ChangeNotify polls the newest torrents at the rclone poll interval,
+// notifying the folders listing the torrents added, removed or which
+// finished downloading.
+func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	f.goBackground(ctx, func(ctx context.Context) {
+		f.changeNotify(ctx, notifyFunc, pollIntervalChan)
+	})
+}
+
+func (f *Fs) changeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
+	f.flushes.setNotify(notifyFunc)
+	var ticker *time.Ticker
+	var tickerC <-chan time.Time
+	for {
+		select {
+		case pollInterval, ok := <-pollIntervalChan:
+			if !ok {
+				if ticker != nil {
+					ticker.Stop()
+				}
+				return
+			}
+			if ticker != nil {
+				ticker.Stop()
+				ticker, tickerC = nil, nil
+			}
+			if pollInterval > 0 {
+				ticker = time.NewTicker(pollInterval)
+				tickerC = ticker.C
+				fs.Infof(f, "RealDebrid torrent polling enabled: interval=%v", pollInterval)
+			} else {
+				fs.Infof(f, "RealDebrid torrent polling disabled")
+			}
+		case <-tickerC:
+			f.pollTorrents(ctx)
+		case <-ctx.Done():
+			if ticker != nil {
+				ticker.Stop()
+			}
+			return
+		}
+	}
+}
+
+// NewFs constructs an Fs from the path, container:path
+func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
+	// Parse config into Options struct
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateOptions(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	root = parsePath(root)
+
+	var httpClient *http.Client
+	var ts *oauthutil.TokenSource
+	if opt.APIKey == "" {
+		httpClient, ts, err = oauthutil.NewClient(ctx, name, m, oauthConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure realdebrid: %w", err)
+		}
+	} else {
+		httpClient = fshttp.NewClient(ctx)
+	}
+	httpClient = newStatsClient(httpClient)
+
+	f := &Fs{
+		name:      name,
+		root:      root,
+		opt:       *opt,
+		m:         m,
+		client:    newClient(httpClient, fs.NewPacer(ctx, newCalculator(time.Duration(opt.PacerMinSleep), time.Duration(opt.PacerMaxSleep), time.Duration(opt.APIRetryDelay))), opt.APIKey),
+		infos:     newInfoCache(opt.InfoCacheSize, time.Duration(opt.InfoCacheTTL)),
+		misses:    newMissCache(time.Duration(opt.CacheRefreshInterval)),
+		cooldowns: newCooldowns(),
+
+		torrentStatuses: make(map[string]string),
+	}
+	f.categoryList = newCategories(opt)
+	f.ctx, f.stop = context.WithCancel(context.Background())
+	f.client.simulate = opt.Simulate
+	f.client.timeout = time.Duration(opt.APITimeout)
+	if opt.APIRetries > 0 {
+		f.client.pacer.SetRetries(opt.APIRetries)
+	}
+	if opt.ClassifyURL != "" {
+		f.classifier = rest.NewClient(fshttp.NewClient(ctx))
+	}
+	if opt.Simulate {
+		fs.Logf(f, "Simulating: changes to the account are logged and skipped")
+	}
+	f.features = (&fs.Features{
+		CaseInsensitive:         true,
+		CanHaveEmptyDirectories: true,
+		ReadMimeType:            true,
+		Immutable:               true,
+	}).Fill(ctx, f)
+	if opt.AboutPolicy == "off" {
+		f.features.About = nil
+	}
+	if !opt.ProgressiveListing {
+		f.features.ListP = nil
+	}
+
+	// Renew the token in the background
+	if ts != nil {
+		f.tokenRenewer = oauthutil.NewRenew(f.String(), ts, func() error {
+			_, err := f.About(ctx)
+			return err
+		})
+	}
+
+	// Get rootID
+	f.dirCache = dircache.New(root, rootID, f)
+
+	// Share the listing caches with the other Fs of the same account
+	cacheKey := opt.APIKey
+	if cacheKey == "" {
+		cacheKey = "oauth:" + name
+	}
+	var created bool
+	f.cache, created = getSharedCache(cacheKey, name, opt.DumpDir, opt.ShareCache)
+	if created {
+		f.cache.loadDumps(time.Duration(opt.DumpMaxAge))
+	}
+	f.categoryOverrides = getCategoryOverrides(overridesPath(name))
+	f.startVerifier()
+	f.startBackgroundRefresh()
+
+	// A root below the top level is found in the torrents so make sure
+	// they are listed, failing now rather than giving an empty Fs if
+	// they can't be
+	if root != "" && opt.RootFolderID == "torrents" {
+		_, err = f.ensureTorrentsListedOrStale(ctx)
+		if err != nil {
+			_ = f.Shutdown(ctx)
+			return nil, err
+		}
+	}
+
+	// Find the current root
+	err = f.dirCache.FindRoot(ctx, false)
+	if err != nil {
+		// Assume it is a file and look for it in the parent as
+		// NewObject does, so it is found whatever the case or
+		// composition of its name
+		newRoot, remote := dircache.SplitPath(root)
+		f.dirCache = dircache.New(newRoot, rootID, f)
+		f.root = newRoot
+		restore := func() {
+			f.dirCache = dircache.New(root, rootID, f)
+			f.root = root
+		}
+		err = f.dirCache.FindRoot(ctx, false)
+		if err != nil {
+			// No root so return old f
+			restore()
+			return f, nil
+		}
+		_, err := f.NewObject(ctx, remote)
+		if err != nil {
+			restore()
+			if err == fs.ErrorObjectNotFound {
+				// File doesn't exist so return old f
+				return f, nil
+			}
+			_ = f.Shutdown(ctx)
+			return nil, err
+		}
+		// return an error with an fs which points to the parent
+		return f, fs.ErrorIsFile
+	}
+	if opt.PrewarmDirCache && opt.RootFolderID == "torrents" {
+		// the folders are still found one by one if this fails
+		if err := f.warmDirCache(ctx); err != nil {
+			fs.Errorf(f, "Failed to pre-warm the directory cache: %v", err)
+		}
+	}
+	return f, nil
+}
+
+// Shutdown the backend, stopping its background work and releasing
+// its reference to the shared cache, dumping it if it was the last one
+// so the links unrestricted since the last refresh aren't lost.
+func (f *Fs) Shutdown(ctx context.Context) error {
+	f.stop()
+	f.background.Wait()
+	f.flushes.stop()
+	f.tokenRenewer.Shutdown()
+	f.releaseOnce.Do(func() {
+		if f.cache.release() {
+			f.cache.dumpListed()
+		}
+	})
+	return nil
+}
+
+// goBackground runs fn in a goroutine with a context cancelled when
+// ctx is or by Shutdown, which waits for fn to return. Nothing is run
+// once the Fs is shut down.
+func (f *Fs) goBackground(ctx context.Context, fn func(ctx context.Context)) {
+	if f.ctx.Err() != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	stopAfter := context.AfterFunc(f.ctx, cancel)
+	f.background.Add(1)
+	go func() {
+		defer f.background.Done()
+		defer stopAfter()
+		defer cancel()
+		fn(ctx)
+	}()
+}
+
+// Return an Object from a path
+//
+// If it can't be found it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) newObjectWithInfo(ctx context.Context, remote string, info *api.Item) (fs.Object, error) {
+	o := &Object{
+		fs:     f,
+		remote: remote,
+	}
+	var err error
+	if info != nil {
+		// Set info
+		err = o.setMetaData(info)
+	} else {
+		err = o.readMetaData(ctx) // reads info and meta, returning an error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// NewObject finds the Object at remote.  If it can't be found
+// it returns the error fs.ErrorObjectNotFound.
+func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
+	if f.isStatusFile(remote) {
+		return f.newStatusObject(ctx), nil
+	}
+	return f.newObjectWithInfo(ctx, remote, nil)
+}
+
+// FindLeaf finds a directory of name leaf in the folder with ID pathID
+func (f *Fs) FindLeaf(ctx context.Context, pathID string, leaf string) (pathIDOut string, found bool, err error) {
+	// Find the leaf in pathID
+	fmt.Printf("Finding directory named: '%s' in dir named: '%s'\n", leaf, pathID)
+	// The root ID never changes so isn't aliased, which the dircache
+	// doesn't lock for lookups made concurrently
+	key := foldName(f.normalize(leaf))
+	_, found, err = f.listAll(ctx, pathID, true, false, func(item *api.Item) bool {
+		if foldName(item.Name) == key {
+			pathIDOut = item.ID
+			return true
+		}
+		return false
+	})
+	return pathIDOut, found, err
+}
+
+// CreateDir makes a directory with pathID as parent and name leaf
+func (f *Fs) CreateDir(ctx context.Context, pathID, leaf string) (newID string, err error) {
+	return "", errReadOnly
+}
+
+// list the objects into the function supplied
+//
+// If directories is set it only sends directories
+// User function to process a File item from listAll
+//
+// Should return true to finish processing
+type listAllFn func(*api.Item) bool
+
+// unrestrict makes a download link for link adding it to the top of
+// the cached links
+//
+// Concurrent calls for the same link share one API call and its
+// result, including its error.
+func (f *Fs) unrestrict(ctx context.Context, link string) (api.Item, error) {
+	return f.shareUnrestrict(ctx, link, false)
+}
+
+// downloadLink returns the cached download link of link, unrestricting
+// it if there isn't one.
+//
+// The cache is looked up again in the shared call so a file opened
+// while another open of it finishes unrestricting it isn't unrestricted
+// twice.
+func (f *Fs) downloadLink(ctx context.Context, link string) (api.Item, error) {
+	if item, found := f.cache.link(link); found && item.Link != "" {
+		return item, nil
+	}
+	return f.shareUnrestrict(ctx, link, true)
+}
+
+// shareUnrestrict unrestricts link in a call shared with the concurrent
+// calls for it, first looking it up in the cached links if cached is set
+func (f *Fs) shareUnrestrict(ctx context.Context, link string, cached bool) (api.Item, error) {
+	item, err, _ := f.unrestricts.Do(link, func() (any, error) {
+		if cached {
+			if item, found := f.cache.link(link); found && item.Link != "" {
+				return item, nil
+			}
+		}
+		item, err := f.unrestrictLink(ctx, link, false, false)
+		if err != nil {
+			return api.Item{}, err
+		}
+		f.cache.addLink(*item)
+		return *item, nil
+	})
+	return item.(api.Item), err
+}
+
+// markBroken tracks the torrent with id as broken so it is repaired on
+// the next refresh
+func (f *Fs) markBroken(id string) {
+	if !f.cache.markBroken(id) {
+		fs.Debugf(f, "Torrent %s is broken and already tracked", id)
+		return
+	}
+	fs.Logf(f, "Torrent %s is broken, it will be repaired on the next refresh", id)
+}
+
+// flatFiles returns the files of the downloaded torrents for the root
+// of files mode.
+func (f *Fs) flatFiles(ctx context.Context, torrents []api.Item) (files []api.Item) {
+	links := f.cache.links()
+	for _, torrent := range torrents {
+		if torrent.Status != "downloaded" {
+			continue
+		}
+		files = append(files, f.torrentFiles(ctx, torrent, links)...)
+	}
+	return files
+}
+
+// torrentFiles returns the files of the downloaded torrent with the
+// links cached in links.
+//
+// The names and sizes come from the torrent file info so no links are
+// unrestricted, that is left to Open. The file info is only fetched
+// if the torrent details aren't cached.
+func (f *Fs) torrentFiles(ctx context.Context, torrent api.Item, links map[string]api.Item) (files []api.Item) {
+	details, cached := f.cache.torrentDetails(torrent.ID)
+	if !cached {
+		info, err := f.torrentInfo(ctx, torrent.ID, false)
+		if err != nil {
+			// one bad torrent doesn't stop the others being listed
+			fs.Errorf(f, "Not listing torrent %q: %v", torrent.Name, err)
+			return nil
+		}
+		details = *info
+		f.cache.addTorrentDetails(details)
+	}
+	selected := selectedFiles(&details)
+	added := details.Ended
+	if added == "" {
+		added = torrent.Ended
+	}
+	for i, link := range details.Links {
+		item, found := links[link]
+		if !found {
+			if selected == nil {
+				fs.Debugf(f, "Not listing %q: can't name it without unrestricting it", link)
+				continue
+			}
+			item = api.Item{
+				Name:         path.Base(selected[i].Path),
+				OriginalLink: link,
+			}
+		}
+		if !f.shownFile(item.Name) {
+			// hidden by video_only
+			continue
+		}
+		// as when listing the torrent folder
+		if selected != nil && selected[i].Bytes > 0 {
+			item.Size = selected[i].Bytes
+		}
+		item.ParentID = details.ID
+		item.TorrentHash = details.TorrentHash
+		item.Ended = added
+		files = append(files, item)
+	}
+	return files
+}
+
+// selectedFiles returns the files of torrent selected for download,
+// which are in the order of its links, or nil if they can't be matched
+// to the links as they were packed into an archive.
+func selectedFiles(torrent *api.Item) (selected []api.File) {
+	for _, file := range torrent.Files {
+		if file.Selected == 1 {
+			selected = append(selected, file)
+		}
+	}
+	if len(selected) != len(torrent.Links) {
+		return nil
+	}
+	return selected
+}
+
+// Lists the directory required calling the user function on each item found
+//
+// If the user fn ever returns true then it early exits with found = true
+//
+// It returns a newDirID which is what the system returned as the directory ID
+func (f *Fs) listAll(ctx context.Context, dirID string, directoriesOnly bool, filesOnly bool, fn listAllFn) (newDirID string, found bool, err error) {
+	var result []api.Item
+	// emit passes item to fn if it is of the type listed, returning
+	// true once fn has found what it looks for
+	emit := func(item *api.Item) bool {
+		if found {
+			return true
+		}
+		f.prepareItem(item, dirID)
+		if item.Type == api.ItemTypeFolder {
+			if filesOnly {
+				return false
+			}
+		} else if item.Type == api.ItemTypeFile {
+			if directoriesOnly {
+				return false
+			}
+		} else {
+			fs.Debugf(f, "Ignoring %q - unknown type %q", item.Name, item.Type)
+			return false
+		}
+		found = fn(item)
+		return found
+	}
+	if f.opt.RootFolderID == "torrents" {
+		// Torrent folders only hold files and the folders above them
+		// only hold folders, so don't expand links or fetch torrent
+		// info when looking for the other kind, e.g. for the missing
+		// directories of a deep path.
+		if directoriesOnly && !f.torrentFolders(dirID) && !f.opt.NestedFolders || filesOnly && f.torrentFolders(dirID) && !f.flattened(dirID) {
+			return newDirID, false, nil
+		}
+		// the torrents outside the age filters are left out of the
+		// listings, but not of the lookups of their paths
+		listing := !directoriesOnly && !filesOnly
+		if dirID == rootID {
+			switch f.opt.SharedFolder {
+			case "folders":
+				result = f.datedCategories(f.addArtificialRootFolders(result))
+				goto processResults
+			case "torrents":
+				var torrents []api.Item
+				torrents, err = f.listedTorrents(ctx)
+				if err != nil {
+					return newDirID, found, err
+				}
+				// copy as uniqueTorrentNames renames in place
+				result = uniqueTorrentNames(append([]api.Item(nil), torrents...))
+				if listing {
+					result = ageFiltered(ctx, result)
+				}
+				goto processResults
+			case "files":
+				var torrents []api.Item
+				torrents, err = f.listedTorrents(ctx)
+				if err != nil {
+					return newDirID, found, err
+				}
+				if listing {
+					torrents = ageFiltered(ctx, torrents)
+				}
+				result = f.flatFiles(ctx, torrents)
+				goto processResults
+			}
+			err = f.refreshTorrents(ctx)
+		} else if kind, _, ok := parseSynID(dirID); ok && kind != synFolder {
+			if !f.isCategory(dirID) && !f.isYear(dirID) || f.opt.SharedFolder != "folders" {
+				return newDirID, found, fs.ErrorDirNotFound
+			}
+			var torrents []api.Item
+			torrents, err = f.listedTorrents(ctx)
+			if err != nil {
+				return newDirID, found, err
+			}
+			f.cache.refreshMu.Lock()
+			categories := f.cache.categories
+			f.cache.refreshMu.Unlock()
+			result = f.classify(torrents, dirID, categories)
+			if listing {
+				result = ageFiltered(ctx, result)
+			}
+			if f.byYear(dirID) {
+				result = f.yearFolders(result)
+			} else if f.flattened(dirID) {
+				result = f.flattenSingleFiles(ctx, result, directoriesOnly)
+			}
+		} else if f.opt.SharedFolder != "folders" || dirID != rootID {
+			//fmt.Printf("Listing the contents of a torrent folder")
+			// or of a folder in it with nested_folders
+			torrentID, sub := dirID, ""
+			if ok {
+				torrentID, sub, ok = f.subfolder(dirID)
+				if !ok {
+					return newDirID, found, fs.ErrorDirNotFound
+				}
+			}
+			if f.excludedID(torrentID) {
+				// so its links are never unrestricted
+				return newDirID, found, fs.ErrorDirNotFound
+			}
+			torrent, cached := f.cache.torrentDetails(torrentID)
+			if !cached {
+				// it means it does not exist yet or not yet downloaded
+				fmt.Printf("                ~ RDAPIRequest@ /torrent/info\n")
+				var info *api.Item
+				info, err = f.torrentInfo(ctx, torrentID, false)
+				if err != nil {
+					// an empty folder would look like the torrent lost its files
+					return newDirID, found, err
+				}
+				torrent = *info
+				// put at the top, duplicates will be removed later
+				f.cache.addTorrentDetails(torrent)
+			}
+
+			/* put as comments but must be removed
+			   			for i, torrent := range torrents {
+							var broken = false
+							if f.opt.SharedFolder == "folders" {
+								if dirID != torrent.ID {
+									continue
+								}
+							}
+			*/
+			var broken = false
+			// with progressive_listing the files are passed on as they
+			// are unrestricted rather than once they all are
+			add := func(item api.Item) {
+				if f.opt.ProgressiveListing {
+					emit(&item)
+				} else {
+					result = append(result, item)
+				}
+			}
+			// the sizes of the torrent file info don't change when the
+			// links are unrestricted again so are used when known
+			selected := selectedFiles(&torrent)
+			// the folders in the torrent folder are known without
+			// unrestricting its links
+			dirs := f.fileDirs(&torrent)
+			shown := f.shownDirs(selected, dirs)
+			if sub != "" && !slices.ContainsFunc(shown, func(dir string) bool { return dir == sub || strings.HasPrefix(dir, sub+"/") }) {
+				return newDirID, found, fs.ErrorDirNotFound
+			}
+			for _, folder := range childFolders(&torrent, shown, sub) {
+				add(folder)
+			}
+			if directoriesOnly {
+				goto processResults
+			}
+			for i, link := range torrent.Links {
+				if dirs != nil && dirs[i] != sub {
+					// in one of the folders
+					continue
+				}
+				if selected != nil && !f.shownFile(selected[i].Path) {
+					// hidden by video_only before it is unrestricted
+					continue
+				}
+				ItemFile, _ := f.cache.link(link)
+				if ItemFile.Link == "" {
+					if err := notReady(&torrent); err != nil {
+						// don't cache a failed unrestrict - the link will be there once downloaded
+						fs.Debugf(f, "Not listing %q: %v", link, err)
+						continue
+					}
+					fmt.Printf("                ~ RDAPIRequest@ /unrestrict/link for: '%s'\n", torrent.Name)
+					var err error
+					ItemFile, err = f.unrestrict(ctx, link)
+					if errors.Is(err, errLinkUnavailable) {
+						broken = true
+						break
+					} else if err != nil {
+						fs.Errorf(f, "Not listing %q of torrent %q: %v", link, torrent.Name, err)
+						continue
+					}
+				}
+				if selected == nil && !f.shownFile(ItemFile.Name) {
+					// the files of an archive are only named once unrestricted
+					continue
+				}
+				if selected != nil && selected[i].Bytes > 0 {
+					ItemFile.Size = selected[i].Bytes
+				}
+				if dirs != nil {
+					ItemFile.Name = path.Base(selected[i].Path)
+				}
+				ItemFile.ParentID = torrent.ID
+				ItemFile.TorrentHash = torrent.TorrentHash
+				ItemFile.Ended = torrent.Ended
+				add(ItemFile)
+			}
+			if broken && f.cache.deferRepair(torrent.ID) {
+				// repaired on a later refresh once its files are closed
+				stats.repairsDeferred.Add(1)
+				f.markBroken(torrent.ID)
+			} else if broken && !f.cache.repairFailed(&torrent) {
+				torrent = f.redownloadTorrent(ctx, torrent)
+				// and put it back in torretswf array
+				f.cache.replaceTorrentDetails(torrent)
+
+				// its folders are named after the old ID so are found
+				// again by the next lookups
+				dirs := f.fileDirs(&torrent)
+				selected := selectedFiles(&torrent)
+				for i, link := range torrent.Links {
+					if dirs != nil && dirs[i] != sub {
+						continue
+					}
+					if selected != nil && !f.shownFile(selected[i].Path) {
+						continue
+					}
+					fmt.Printf("                ~ RDAPIRequest@ /unrestrict/link - after fixing broken torrent: '%s'\n", torrent.Name)
+					ItemFile, err := f.unrestrict(ctx, link)
+					if err != nil {
+						fs.Errorf(f, "Not listing %q of torrent %q: %v", link, torrent.Name, err)
+						continue
+					}
+					if selected == nil && !f.shownFile(ItemFile.Name) {
+						continue
+					}
+					if dirs != nil {
+						ItemFile.Name = path.Base(selected[i].Path)
+					}
+					ItemFile.ParentID = torrent.ID
+					ItemFile.TorrentHash = torrent.TorrentHash
+					ItemFile.Ended = torrent.Ended
+					add(ItemFile)
+				}
+			}
+			/*if f.opt.SharedFolder == "folders" { not needed anymore as torrent is not taken from a tested range anmore
+				break
+			}*/
+			//fmt.Printf("...torrent listing done.\n")
+		}
+	} else {
+		result, err = f.client.ListAllDownloads(ctx, 5000, 0)
+	}
+processResults:
+	if err != nil {
+		return newDirID, found, err
+	}
+	// the category folders are always in the same order
+	if !(dirID == rootID && f.opt.RootFolderID == "torrents" && f.opt.SharedFolder == "folders") {
+		f.sortItems(result)
+	}
+	for i := range result {
+		if emit(&result[i]) {
+			break
+		}
+	}
+	return
+}
+
+// prepareItem sets the creation time, type and name of item listed in
+// the directory dirID
+//
+// The torrent folders and their files are dated by when the torrent
+// was added, the downloads by when they were unrestricted.
+func (f *Fs) prepareItem(item *api.Item, dirID string) {
+	layout := "2006-01-02T15:04:05.000Z"
+	date := item.Ended
+	if date == "" {
+		date = item.Generated
+	}
+	if date != "" {
+		if t, err := time.Parse(layout, date); err == nil {
+			item.CreatedAt = t.Unix()
+		} else {
+			fs.Errorf(f, "Bad date of %q: %v", item.Name, err)
+		}
+	}
+	// the files of flattened torrents are listed with the torrent
+	// folders but have their torrent as parent
+	if item.ParentID == "" && (f.torrentFolders(dirID) || isSubfolderID(item.ID)) {
+		item.Type = "folder"
+	} else {
+		item.Type = "file"
+	}
+	item.Name = f.normalize(f.opt.Enc.ToStandardName(item.Name))
+}
+
+// torrentFolders returns true if the directory dirID contains the
+// torrent folders rather than the files of a torrent.
+func (f *Fs) torrentFolders(dirID string) bool {
+	switch f.opt.SharedFolder {
+	case "folders":
+		return dirID == rootID || f.isCategory(dirID) || f.isYear(dirID)
+	case "torrents":
+		return dirID == rootID
+	}
+	return false
+}
+
+// List the objects and directories in dir into entries.  The
+// entries can be returned in any order but should be for a
+// complete directory.
+//
+// dir should be "" to list the root, and should not have
+// trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
+	//fmt.Println("Listing Items ... ")
+	directoryID, err := f.listDir(ctx, dir, func(entry fs.DirEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// listAll sorts the entries as set by list_sort, except those
+	// passed on as they are ready by progressive_listing, the files
+	// of a torrent which are sorted by name. Sorting by name also
+	// sorts the status file in with them.
+	byName := f.opt.ListSort == "name" || f.opt.ProgressiveListing && f.listedTorrent(directoryID) != ""
+	if byName && !(directoryID == rootID && f.opt.RootFolderID == "torrents" && f.opt.SharedFolder == "folders") {
+		sort.Sort(entries)
+	}
+	//fmt.Println("Done Listing Items.")
+	return entries, nil
+}
+
+// ListP lists the objects and directories in dir into callback as they
+// are ready, the files of a torrent folder as each of them is
+// unrestricted. It is only used with progressive_listing.
+//
+// The entries are in no particular order.
+func (f *Fs) ListP(ctx context.Context, dir string, callback fs.ListRCallback) error {
+	_, err := f.listDir(ctx, dir, func(entry fs.DirEntry) error {
+		return callback(fs.DirEntries{entry})
+	})
+	return err
+}
+
+// listDir passes the objects and directories in dir to fn as they are
+// listed, returning the ID of dir. The listing stops at the first
+// error returned by fn.
+func (f *Fs) listDir(ctx context.Context, dir string, fn func(fs.DirEntry) error) (directoryID string, err error) {
+	directoryID, err = f.dirCache.FindDir(ctx, dir, false)
+	if err != nil {
+		return "", err
+	}
+	var iErr error
+	_, _, err = f.listAll(ctx, directoryID, false, false, func(info *api.Item) bool {
+		remote := path.Join(dir, info.Name)
+		if info.Type == api.ItemTypeFolder {
+			// cache the directory ID for later lookups
+			f.dirCache.Put(remote, info.ID)
+			iErr = fn(fs.NewDir(remote, time.Unix(info.CreatedAt, 0)).SetID(info.ID))
+		} else if info.Type == api.ItemTypeFile {
+			var o fs.Object
+			o, iErr = f.newObjectWithInfo(ctx, remote, info)
+			if iErr == nil {
+				iErr = fn(o)
+			}
+		}
+		return iErr != nil
+	})
+	if err != nil {
+		return "", f.wrapErr("list", dir, f.listedTorrent(directoryID), err)
+	}
+	if iErr != nil {
+		return "", iErr
+	}
+	if dir == "" && f.isStatusFile(statusFileName) {
+		if err := fn(f.newStatusObject(ctx)); err != nil {
+			return "", err
+		}
+	}
+	return directoryID, nil
+}
+
+// listedTorrent returns dirID if it is the ID of a torrent folder, the
+// ID of the torrent if it is a folder in one, or "" otherwise
+func (f *Fs) listedTorrent(dirID string) string {
+	if f.opt.RootFolderID != "torrents" || dirID == rootID || f.torrentFolders(dirID) {
+		return ""
+	}
+	if torrentID, _, ok := f.subfolder(dirID); ok {
+		return torrentID
+	}
+	return dirID
+}
+
+// Put the object
+//
+// # Copy the reader in to the new object which is returned
+//
+// The new object may have been created if an error is returned
+func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return nil, f.wrapErr("put", src.Remote(), "", errReadOnly)
+}
+
+// PutUnchecked the object into the container
+//
+// # This will produce an error if the object already exists
+//
+// # Copy the reader in to the new object which is returned
+//
+// The new object may have been created if an error is returned
+func (f *Fs) PutUnchecked(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
+	return nil, f.wrapErr("put", src.Remote(), "", errReadOnly)
+}
+
+// Mkdir creates the container if it doesn't exist
+//
+// Only existing directories can be "made" as new ones can't be stored.
+func (f *Fs) Mkdir(ctx context.Context, dir string) error {
+	_, err := f.dirCache.FindDir(ctx, dir, false)
+	if err == fs.ErrorDirNotFound {
+		err = errReadOnly
+	}
+	return f.wrapErr("mkdir", dir, "", err)
+}
+
+// purgeCheck removes the root directory, if check is set then it
+// refuses to do so if it has anything in
+func (f *Fs) purgeCheck(ctx context.Context, dir string, check bool) error {
+	//fmt.Printf("Purging torrent: '%s'\n", rootID)
+	root := path.Join(f.root, dir)
+	if root == "" {
+		return errors.New("can't purge root directory")
+	}
+	dc := f.dirCache
+	rootID, err := dc.FindDir(ctx, dir, false)
+	if err != nil {
+		return err
+	}
+	if f.isCategory(rootID) || f.isYear(rootID) {
+		if check || !f.opt.PurgeCategories {
+			return f.wrapErr("purge", dir, "", errPurgeCategory)
+		}
+		return f.purgeCategory(ctx, dir, rootID)
+	}
+	if f.excludedID(rootID) {
+		return f.wrapErr("purge", dir, rootID, errExcluded)
+	}
+	err = f.deleteTorrent(ctx, rootID)
+	if err != nil {
+		return f.wrapErr("purge", dir, rootID, err)
+	}
+	f.dirCache.FlushDir(dir)
+	return nil
+}
+
+// Rmdir deletes the root folder
+//
+// Returns an error if it isn't empty
+func (f *Fs) Rmdir(ctx context.Context, dir string) error {
+	//fmt.Printf("Rmdir: '%s'\n", dir)
+	return f.purgeCheck(ctx, dir, true)
+}
+
+// Precision return the precision of this Fs
+//
+// The modification times are made up when links are generated so
+// they aren't reported as supported. This keeps them out of the
+// fingerprints of objects which then only change if the size does.
+func (f *Fs) Precision() time.Duration {
+	return fs.ModTimeNotSupported
+}
+
+// Purge deletes all the files in the directory
+//
+// Optional interface: Only implement this if you have a way of
+// deleting all the files quicker than just running Remove() on the
+// result of List()
+func (f *Fs) Purge(ctx context.Context, dir string) error {
+	//fmt.Printf("Purge: '%s'\n", dir)
+	return f.purgeCheck(ctx, dir, false)
+}
+
+// Move src to this remote using server-side move operations.
+//
+// # This is stored with the remote path given
+//
+// # It returns the destination Object and a possible error
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantMove
+//
+// Real-Debrid names the files after the torrent contents so they can't
+// be moved or renamed.
+func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
+	fs.Debugf(src, "Can't move - files can't be renamed on Real-Debrid")
+	return nil, fs.ErrorCantMove
+}
+
+// Copy src to this remote using server-side copy operations.
+//
+// This is stored with the remote path given.
+//
+// It returns the destination Object and a possible error.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantCopy
+//
+// In downloads mode the original link of src is unrestricted again,
+// making a new download. It keeps the name Real-Debrid gives the link
+// so the destination name only lasts as long as the returned Object.
+func (f *Fs) Copy(ctx context.Context, src fs.Object, remote string) (dst fs.Object, err error) {
+	srcObj, ok := src.(*Object)
+	if !ok || f.opt.RootFolderID == "torrents" || srcObj.OriginalUrl == "" {
+		fs.Debugf(src, "Can't copy - only downloads can be copied")
+		return nil, fs.ErrorCantCopy
+	}
+	if dir := path.Dir(remote); dir != "." {
+		fs.Debugf(src, "Can't copy - downloads can't be put in directories")
+		return nil, fs.ErrorCantCopy
+	}
+	if f.opt.Simulate {
+		stats.simulated.Add(1)
+		fs.Logf(src, "simulate: would unrestrict %q again to copy it to %q", srcObj.OriginalUrl, remote)
+		return &Object{
+			fs:          f,
+			remote:      remote,
+			hasMetaData: true,
+			size:        srcObj.size,
+			modTime:     srcObj.modTime,
+			mimeType:    srcObj.mimeType,
+			url:         srcObj.url,
+			OriginalUrl: srcObj.OriginalUrl,
+		}, nil
+	}
+	item, err := f.unrestrictLink(ctx, srcObj.OriginalUrl, false, false)
+	if err != nil {
+		return nil, f.wrapErr("copy", remote, "", err)
+	}
+	item.Type = api.ItemTypeFile
+	item.CreatedAt = time.Now().Unix()
+	return f.newObjectWithInfo(ctx, remote, item)
+}
+
+// DirMove moves src, srcRemote to this remote at dstRemote
+// using server-side move operations.
+//
+// The directories are made from the torrents so can't be moved either.
+//
+// Will only be called if src.Fs().Name() == f.Name()
+//
+// If it isn't possible then return fs.ErrorCantDirMove
+//
+// If destination exists then return fs.ErrorDirExists
+func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
+	fs.Debugf(f, "Can't move directory %q - directories can't be renamed on Real-Debrid", srcRemote)
+	return fs.ErrorCantDirMove
+}
+
+// PublicLink adds a "readable by anyone with link" permission on the given file or folder.
+//
+// With --expire the link works from other IPs, see publicLink.
+func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (string, error) {
+	return f.publicLink(ctx, remote, expire != fs.DurationOff)
+}
+
+// About gets quota information according to about_policy
+func (f *Fs) About(ctx context.Context) (usage *fs.Usage, err error) {
+	switch f.opt.AboutPolicy {
+	case "off":
+		return nil, nil
+	case "real":
+		usage, err = f.realUsage(ctx)
+	default:
+		usage, err = f.syntheticUsage(ctx)
+	}
+	return usage, f.wrapErr("about", "", "", err)
+}
+
+// DirCacheFlush resets the directory cache - used in testing as an
+// optional interface
+func (f *Fs) DirCacheFlush() {
+	f.dirCache.ResetRoot()
+}
+
+// Hashes returns the supported hash sets.
+//
+// Real-Debrid doesn't give file hashes. The torrent hash isn't offered
+// as one since it identifies the torrent not the file content, and a
+// new hash type would be computed by other backends too.
+func (f *Fs) Hashes() hash.Set {
+	return hash.Set(hash.None)
+}
+
+// ------------------------------------------------------------
+
+// Fs returns the parent Fs
+func (o *Object) Fs() fs.Info {
+	return o.fs
+}
+
+// Return a string version
+func (o *Object) String() string {
+	if o == nil {
+		return "<nil>"
+	}
+	return o.remote
+}
+
+// Remote returns the remote path
+func (o *Object) Remote() string {
+	return o.remote
+}
+
+// Hash returns the SHA-1 of an object returning a lowercase hex string
+func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+// Size returns the size of an object in bytes
+func (o *Object) Size() int64 {
+	err := o.readMetaData(context.TODO())
+	if err != nil {
+		fs.Logf(o, "Failed to read metadata: %v", err)
+		return 0
+	}
+	return o.size
+}
+
+// setMetaData sets the metadata from info
+func (o *Object) setMetaData(info *api.Item) (err error) {
+	if info.Type != "file" {
+		return fmt.Errorf("%q is %q: %w", o.remote, info.Type, fs.ErrorNotAFile)
+	}
+	o.hasMetaData = true
+	o.size = info.Size
+	o.modTime = time.Unix(info.CreatedAt, 0)
+	o.id = info.ID
+	o.mimeType = info.MimeType
+	o.url = info.Link
+	o.OriginalUrl = info.OriginalLink
+	o.ParentID = info.ParentID
+	o.TorrentHash = info.TorrentHash
+	return nil
+}
+
+// readMetaData gets the metadata if it hasn't already been fetched
+//
+// it also sets the info
+func (o *Object) readMetaData(ctx context.Context) (err error) {
+	if o.hasMetaData {
+		return nil
+	}
+	info, err := o.fs.readMetaDataForPath(ctx, o.remote, false, true)
+	if err != nil {
+		return err
+	}
+	return o.setMetaData(info)
+}
+
+// ModTime returns the modification time of the object
+//
+// It attempts to read the objects mtime and if that isn't present the
+// LastModified returned in the http headers
+func (o *Object) ModTime(ctx context.Context) time.Time {
+	err := o.readMetaData(ctx)
+	if err != nil {
+		fs.Logf(o, "Failed to read metadata: %v", err)
+		return time.Now()
+	}
+	return o.modTime
+}
+
+// SetModTime sets the modification time of the local fs object
+func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
+	return fs.ErrorCantSetModTime
+}
+
+// Storable returns a boolean showing whether this object storable
+func (o *Object) Storable() bool {
+	return true
+}
+
+// Open an object for read
+func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
+	//fmt.Printf("-- Open dl-link : %s --\n", o.url)
+	defer func() {
+		err = o.fs.wrapErr("open", o.remote, o.ParentID, err)
+	}()
+	fs.FixRangeOption(options, o.size)
+	var rangeStart, rangeEnd int64 = 0, -1
+	for _, option := range options {
+		if x, ok := option.(*fs.RangeOption); ok {
+			rangeStart, rangeEnd = x.Start, x.End
+		}
+	}
+	if rangeStart > 0 && rangeStart >= o.size {
+		// the range would be sent as bytes=size-(size-1) which
+		// some servers ignore, sending the whole file
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	if err := o.fs.takeStream(); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			o.fs.giveStream()
+		}
+	}()
+	if o.url == "" {
+		if err := o.fs.torrentNotReady(o.ParentID); err != nil {
+			return nil, err
+		}
+		if o.OriginalUrl == "" {
+			return nil, errors.New("can't download - no URL")
+		}
+		// files listed from the torrent file info are unrestricted
+		// when they are first opened, through this object or another
+		// one for the same file so each read doesn't unrestrict it
+		item, err := o.fs.downloadLink(ctx, o.OriginalUrl)
+		if errors.Is(err, errLinkUnavailable) {
+			o.fs.markBroken(o.ParentID)
+			o.fs.cache.brokenByRead(o.ParentID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		o.url, o.id = item.Link, item.ID
+	}
+	var resp *http.Response
+	err = o.fs.client.pacer.Call(func() (bool, error) {
+		var err_code = 0
+		resp, err = o.fs.client.Download(ctx, o.url, options)
+		if resp != nil {
+			err_code = resp.StatusCode
+		}
+		//fmt.Printf("-- Open HTTP code is : %d --\n", err_code)
+		if err_code == http.StatusRequestedRangeNotSatisfiable {
+			// the link is fine but the file is smaller than we think
+			return false, fs.ErrorRangeNotSatisfiable
+		}
+		if !fserrors.ShouldRetryHTTP(resp, retryErrorCodes) && err_code != 200 && err_code != 206 {
+			if notReadyErr := o.fs.torrentNotReady(o.ParentID); notReadyErr != nil {
+				// unrestricting would fail and mark the torrent broken
+				return false, notReadyErr
+			}
+			// it means it is a link to unrestrict again
+			fs.Debugf(o, "Download link %q is down, unrestricting the original link again", o.url)
+			// then go through cachedfile to find Originallink (o.OriginalUrl)
+			var broken = false
+			var relinked = false
+			if cachedfile, found := o.fs.cache.linkByURL(o.url); found {
+				// found the one badguy (could be several potentially but we take the first one found)
+				// we don't delete it from cached, it will be replaced in place
+				if err := o.fs.client.DeleteDownload(ctx, cachedfile.ID); err != nil {
+					fs.Debugf(o, "Open: %v", err)
+				}
+
+				// unrestrict to have new link
+				fs.Debugf(o, "Unrestricting original link %q", cachedfile.OriginalLink)
+				tempFile, err := o.fs.unrestrictLink(ctx, cachedfile.OriginalLink, false, false)
+				if errors.Is(err, errHosterCooldown) {
+					// retrying before the hoster's cooldown ends only fails again
+					return false, err
+				} else if err != nil {
+					fs.Debugf(o, "Open: %v", err)
+					broken = true
+				} else if tempFile.Link != "" {
+					// replace in o. and cachedfile (so no need to invalidate the torrents)
+					stats.relinks.Add(1)
+					o.fs.cache.relink(o.url, tempFile.Link) // so no need to add it at top of cached array
+					o.url = tempFile.Link                   // will right away retry with the new link
+					relinked = true
+				}
+			}
+
+			if !relinked {
+				// the torrent may have been repaired giving it new links
+				if err := o.reresolve(ctx); err == nil {
+					broken, relinked = false, true
+				} else {
+					fs.Debugf(o, "Open: %v", err)
+				}
+			}
+
+			if broken {
+				fs.Logf(o, "Live unrestriction failed for stalled link %q", o.url)
+				o.fs.markBroken(o.ParentID)
+				o.fs.cache.brokenByRead(o.ParentID)
+			}
+
+			if relinked {
+				return true, err // if unrestrict not broken and link not empty, it will retry with new URL value
+				// issue here if unrestricting is ok but dl-link provided fails over and over again TODO fix
+			}
+
+		}
+
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		/* this is already happened in above code
+		if err_code == 503 {
+			for _, TorrentID := range broken_torrents {
+				if o.ParentID == TorrentID {
+					return nil, err
+				}
+			}
+			fmt.Println("Error opening file: '" + o.url + "'.")
+			fmt.Println("This link seems to be broken. Torrent will be re-downloaded on next refresh.")
+			broken_torrents = append(broken_torrents, o.ParentID)
+		}
+		*/
+		return nil, err
+	}
+	in = resp.Body
+	if resp.StatusCode == http.StatusOK && (rangeStart > 0 || rangeEnd >= 0) {
+		// the server ignored the range so skip to its start
+		fs.Debugf(o, "Range %d-%d ignored by the server, skipping to it", rangeStart, rangeEnd)
+		if _, err = io.CopyN(io.Discard, in, rangeStart); err != nil {
+			_ = in.Close()
+			return nil, err
+		}
+		if rangeEnd >= 0 {
+			in = readers.NewLimitedReadCloser(in, rangeEnd-rangeStart+1)
+		}
+	}
+	return newStreamReader(o.fs, o.ParentID, in), nil
+}
+
+// Update the object with the contents of the io.Reader, modTime and size
+//
+// # If existing is set then it updates the object rather than creating a new one
+//
+// The new object may have been created if an error is returned
+func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
+	return o.fs.wrapErr("update", o.remote, o.ParentID, errReadOnly)
+}
+
+// Remove an object by ID
+//
+// id is the ID of the download link, followed by the ID of its
+// torrent in torrents mode which is deleted along with it.
+func (f *Fs) remove(ctx context.Context, id ...string) (err error) {
+	if len(id) > 1 && f.excludedID(id[1]) {
+		return errExcluded
+	}
+	// the link may not have been unrestricted yet
+	if id[0] != "" {
+		err = f.client.DeleteDownload(ctx, id[0])
+		if err != nil {
+			return err
+		}
+		if !f.opt.Simulate {
+			f.cache.forget(id[0], "")
+		}
+	}
+	if f.opt.RootFolderID == "torrents" && len(id) > 1 && id[1] != "" {
+		// the torrent goes with the first of its files removed
+		return f.deleteTorrent(ctx, id[1])
+	}
+	return nil
+}
+
+// deleteTorrent deletes the torrent with id and forgets it from the
+// cache.
+//
+// It is safe to call concurrently: only the first call for a torrent
+// deletes it, the others wait for its result, and calls after it has
+// been deleted do nothing.
+func (f *Fs) deleteTorrent(ctx context.Context, id string) error {
+	c := f.cache
+	c.refreshMu.Lock()
+	if _, deleted := c.deletedTorrents[id]; deleted {
+		c.refreshMu.Unlock()
+		return nil
+	}
+	d, deleting := c.deletingTorrents[id]
+	if !deleting {
+		d = &torrentDeletion{done: make(chan struct{})}
+		if c.deletingTorrents == nil {
+			c.deletingTorrents = make(map[string]*torrentDeletion)
+		}
+		c.deletingTorrents[id] = d
+	}
+	c.refreshMu.Unlock()
+	if deleting {
+		select {
+		case <-d.done:
+			return d.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	d.err = f.client.DeleteTorrent(ctx, id)
+	if d.err == nil && !f.opt.Simulate {
+		c.forget("", id)
+		f.infos.remove(id)
+		f.torrentChanged(id)
+	}
+	c.refreshMu.Lock()
+	delete(c.deletingTorrents, id)
+	c.refreshMu.Unlock()
+	close(d.done)
+	return d.err
+}
+
+// Remove an object
+func (o *Object) Remove(ctx context.Context) (err error) {
+	//fmt.Printf("Removing: '%s'\n", o.remote)
+	defer func() {
+		err = o.fs.wrapErr("remove", o.remote, o.ParentID, err)
+	}()
+	err = o.readMetaData(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read metadata: %w", err)
+	}
+	if o.ParentID == "" {
+		return o.fs.remove(ctx, o.id)
+	}
+	err = o.fs.remove(ctx, o.id, o.ParentID)
+	if err == nil && o.fs.opt.RootFolderID == "torrents" {
+		// the torrent folder has gone too, which may be above the
+		// folder of the file with nested_folders
+		if dir, ok := o.fs.dirCache.GetInv(o.ParentID); ok && dir != "" {
+			o.fs.dirCache.FlushDir(dir)
+		} else if dir := path.Dir(o.remote); dir != "." {
+			o.fs.dirCache.FlushDir(dir)
+		}
+	}
+	return err
+}
+
+// MimeType of an Object if known, "" otherwise
+func (o *Object) MimeType(ctx context.Context) string {
+	return o.mimeType
+}
+
+// ID returns the ID of the Object if known, or "" if not
+func (o *Object) ID() string {
+	return o.id
+}
+
+// Check the interfaces are satisfied
+var (
+	_ fs.Fs              = (*Fs)(nil)
+	_ fs.Commander       = (*Fs)(nil)
+	_ fs.Purger          = (*Fs)(nil)
+	_ fs.Copier          = (*Fs)(nil)
+	_ fs.Mover           = (*Fs)(nil)
+	_ fs.DirMover        = (*Fs)(nil)
+	_ fs.DirCacheFlusher = (*Fs)(nil)
+	_ fs.Shutdowner      = (*Fs)(nil)
+	_ fs.Abouter         = (*Fs)(nil)
+	_ fs.PublicLinker    = (*Fs)(nil)
+	_ fs.ListRer         = (*Fs)(nil)
+	_ fs.ListPer         = (*Fs)(nil)
+	_ fs.Object          = (*Object)(nil)
+	_ fs.MimeTyper       = (*Object)(nil)
+	_ fs.IDer            = (*Object)(nil)
+	_ fs.Object          = (*statusObject)(nil)
+)
@@ -0,0 +1,106 @@
+package realdebrid
+
+import (
+	"context"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+)
+
+const (
+	incrementalPageSize = 100 // items read per page by an incremental refresh
+	incrementalMaxPages = 5   // pages read before listing everything instead
+)
+
+// incrementalTorrents reads the torrents newest first until one is
+// found already in the cache with the same status and no torrent in
+// progress older than it, returning those read merged with the cached
+// torrents from that one on.
+//
+// It returns nil if the torrents changed too much or if the merged
+// torrents aren't total, as some were deleted, so they must all be
+// listed again.
+//
+// It must be called with the refreshMu held.
+func (f *Fs) incrementalTorrents(ctx context.Context, total int) (torrents []api.Item, err error) {
+	cached := f.cache.torrentsList()
+	index := make(map[string]int, len(cached))
+	pending := -1 // index of the oldest cached torrent in progress
+	for i := range cached {
+		index[cached[i].ID] = i
+		if notReady(&cached[i]) != nil {
+			pending = i
+		}
+	}
+	var read []api.Item
+	for page := 1; page <= incrementalMaxPages; page++ {
+		if page > 1 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Second):
+			}
+		}
+		items, _, err := f.client.ListTorrents(ctx, page, incrementalPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if i, found := index[item.ID]; found && i > pending && cached[i].Status == item.Status {
+				return mergeTorrents(read, cached[i:], total), nil
+			}
+			read = append(read, item)
+		}
+		if len(items) < incrementalPageSize {
+			return mergeTorrents(read, nil, total), nil
+		}
+	}
+	return nil, nil
+}
+
+// mergeTorrents returns the torrents read followed by the older ones
+// cached which weren't read again, or nil if there aren't total of them
+func mergeTorrents(read, cached []api.Item, total int) []api.Item {
+	seen := make(map[string]struct{}, len(read))
+	for _, torrent := range read {
+		seen[torrent.ID] = struct{}{}
+	}
+	merged := append([]api.Item(nil), read...)
+	merged = append(merged, dropItems(cached, seen)...)
+	if len(merged) != total {
+		return nil
+	}
+	return merged
+}
+
+// newDownloads reads the unrestricted links newest first until one
+// already in the cache is found, returning those read before it. If
+// the cache has no links they are all read.
+func (f *Fs) newDownloads(ctx context.Context) (items []api.Item, err error) {
+	known := make(map[string]struct{})
+	f.cache.mu.RLock()
+	for _, link := range f.cache.cached {
+		known[link.ID] = struct{}{}
+	}
+	f.cache.mu.RUnlock()
+	if len(known) == 0 {
+		// hardcoded limit of 100 000 dl links, change that at your own risk
+		return f.client.ListAllDownloads(ctx, 5000, 20)
+	}
+	for page := 1; page <= incrementalMaxPages; page++ {
+		partial, _, err := f.client.ListDownloads(ctx, page, incrementalPageSize)
+		if err != nil {
+			return items, err
+		}
+		for _, item := range partial {
+			if _, found := known[item.ID]; found {
+				return items, nil
+			}
+			items = append(items, item)
+		}
+		if len(partial) < incrementalPageSize {
+			return items, nil
+		}
+	}
+	return f.client.ListAllDownloads(ctx, 5000, 20)
+}
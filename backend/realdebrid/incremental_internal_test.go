@@ -0,0 +1,122 @@
+package realdebrid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncrementalRefresh(t *testing.T) {
+	ctx := context.Background()
+	var (
+		mu       sync.Mutex
+		requests = map[string]int{}
+		torrents []api.Item
+	)
+	for i := 250; i > 0; i-- {
+		torrents = append(torrents, api.Item{ID: fmt.Sprint("T", i), Name: fmt.Sprintf("Movie.%d", 1700+i), Status: "downloaded"})
+	}
+	f := newTestFs(t)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		requests[fmt.Sprintf("%s?limit=%d", r.URL.Path, limit)]++
+		page = max(page, 1)
+		items := []api.Item{}
+		if r.URL.Path == "/torrents" {
+			start := (page - 1) * limit
+			if offset, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil {
+				start = offset
+			}
+			start = min(start, len(torrents))
+			items = torrents[start:min(start+limit, len(torrents))]
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(torrents)))
+		writeJSON(t, w, items)
+	})
+	f.cache.startup_cached_api_fetch = true
+	refresh := func(change func()) map[string]int {
+		mu.Lock()
+		change()
+		requests = map[string]int{}
+		mu.Unlock()
+		f.cache.state.Invalidate()
+		require.NoError(t, f.refreshTorrents(ctx))
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, itemIDs(torrents), itemIDs(f.cache.torrents))
+		return requests
+	}
+
+	// The first refresh lists everything
+	seen := refresh(func() {})
+	assert.Equal(t, map[string]int{"/torrents?limit=1": 1, "/torrents?limit=2500": 1}, seen)
+
+	// Added torrents are read from the first page
+	seen = refresh(func() {
+		torrents = append([]api.Item{{ID: "N1", Name: "New.2024", Status: "downloading"}}, torrents...)
+	})
+	assert.Equal(t, map[string]int{"/torrents?limit=1": 1, "/torrents?limit=100": 1}, seen)
+
+	// Torrents in progress are read again until they are downloaded
+	seen = refresh(func() {
+		torrents = append([]api.Item{{ID: "N2", Name: "Newer.2024", Status: "downloaded"}}, torrents...)
+	})
+	assert.Equal(t, map[string]int{"/torrents?limit=1": 1, "/torrents?limit=100": 1}, seen)
+	seen = refresh(func() {
+		torrents[1].Status = "downloaded"
+	})
+	assert.Equal(t, map[string]int{"/torrents?limit=1": 1, "/torrents?limit=100": 1}, seen)
+	assert.Equal(t, "downloaded", f.cache.torrents[1].Status)
+
+	// Deleted torrents make the total differ so everything is listed
+	seen = refresh(func() {
+		torrents = append(torrents[:100:100], torrents[101:]...)
+	})
+	assert.Equal(t, map[string]int{"/torrents?limit=1": 1, "/torrents?limit=100": 1, "/torrents?limit=2500": 1}, seen)
+
+	// as are too many changes
+	seen = refresh(func() {
+		var added []api.Item
+		for i := range incrementalMaxPages * incrementalPageSize {
+			added = append(added, api.Item{ID: fmt.Sprint("A", i), Name: fmt.Sprintf("Added.%d", i), Status: "downloaded"})
+		}
+		torrents = append(added, torrents...)
+	})
+	assert.Equal(t, map[string]int{"/torrents?limit=1": 1, "/torrents?limit=100": incrementalMaxPages, "/torrents?limit=2500": 1}, seen)
+}
+
+func TestIncrementalDownloads(t *testing.T) {
+	ctx := context.Background()
+	var pages []string
+	downloads := []api.Item{{ID: "d3"}, {ID: "d2"}, {ID: "d1"}}
+	f := newTestFs(t)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		pages = append(pages, r.URL.Query().Get("limit"))
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(downloads)))
+		writeJSON(t, w, downloads)
+	})
+
+	// All the links are read when none are cached
+	items, err := f.newDownloads(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d3", "d2", "d1"}, itemIDs(items))
+	assert.Equal(t, []string{"5000"}, pages)
+
+	// otherwise only those before the newest cached
+	pages = nil
+	f.cache.cached = []api.Item{{ID: "d2"}, {ID: "d1"}}
+	items, err = f.newDownloads(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d3"}, itemIDs(items))
+	assert.Equal(t, []string{"100"}, pages)
+}
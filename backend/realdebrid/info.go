@@ -0,0 +1,127 @@
+package realdebrid
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+)
+
+// infoCache is a least recently used cache of /torrents/info
+// responses holding at most size of them for at most ttl.
+type infoCache struct {
+	mu      sync.Mutex
+	size    int                      // max number of entries, <= 0 disables the cache
+	ttl     time.Duration            // how long entries are valid for
+	entries map[string]*list.Element // entries by torrent ID
+	lru     *list.List               // of *infoEntry, most recently used first
+	now     func() time.Time         // the clock, replaced in tests
+}
+
+// infoEntry is a cached /torrents/info response
+type infoEntry struct {
+	id      string
+	info    api.Item
+	fetched time.Time
+}
+
+// newInfoCache makes an infoCache with size entries valid for ttl
+func newInfoCache(size int, ttl time.Duration) *infoCache {
+	return &infoCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		now:     time.Now,
+	}
+}
+
+// get returns the info of torrent id if it is cached and current
+func (c *infoCache) get(id string) (info api.Item, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[id]
+	if !ok {
+		return info, false
+	}
+	entry := elem.Value.(*infoEntry)
+	if c.now().Sub(entry.fetched) >= c.ttl {
+		c.lru.Remove(elem)
+		delete(c.entries, id)
+		return info, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry.info, true
+}
+
+// put caches the info of torrent id evicting the least recently used
+// entries if the cache is full
+func (c *infoCache) put(id string, info api.Item) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[id]; ok {
+		entry := elem.Value.(*infoEntry)
+		entry.info, entry.fetched = info, c.now()
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[id] = c.lru.PushFront(&infoEntry{id: id, info: info, fetched: c.now()})
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*infoEntry).id)
+	}
+}
+
+// remove forgets the info of the torrents with ids, which must be
+// called when they are changed or deleted
+func (c *infoCache) remove(ids ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range ids {
+		if elem, ok := c.entries[id]; ok {
+			c.lru.Remove(elem)
+			delete(c.entries, id)
+		}
+	}
+}
+
+// torrentInfo returns the details of the torrent with id from the
+// cache, fetching them from the API if they aren't cached or force is
+// set.
+//
+// All the reads of /torrents/info should use this. Concurrent fetches
+// for the same ID share one API call and its result, including its
+// error, the cache being looked up again in it as it may just have
+// been filled.
+func (f *Fs) torrentInfo(ctx context.Context, id string, force bool) (*api.Item, error) {
+	if !force {
+		if info, ok := f.infos.get(id); ok {
+			return &info, nil
+		}
+	}
+	info, err, _ := f.infoFetches.Do(id, func() (any, error) {
+		if !force {
+			if info, ok := f.infos.get(id); ok {
+				return info, nil
+			}
+		}
+		info, err := f.client.TorrentInfo(ctx, id)
+		if err != nil {
+			return api.Item{}, err
+		}
+		f.infos.put(id, *info)
+		return *info, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// a copy so callers can't change the details of the others
+	details := info.(api.Item)
+	return &details, nil
+}
@@ -0,0 +1,119 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfoCacheLRU(t *testing.T) {
+	c := newInfoCache(2, time.Minute)
+	c.put("1", api.Item{ID: "1"})
+	c.put("2", api.Item{ID: "2"})
+	_, ok := c.get("1")
+	assert.True(t, ok)
+
+	// 2 is the least recently used so is evicted
+	c.put("3", api.Item{ID: "3"})
+	_, ok = c.get("2")
+	assert.False(t, ok)
+	for _, id := range []string{"1", "3"} {
+		info, ok := c.get(id)
+		assert.True(t, ok, id)
+		assert.Equal(t, id, info.ID)
+	}
+
+	// size 0 disables the cache
+	c = newInfoCache(0, time.Minute)
+	c.put("1", api.Item{ID: "1"})
+	_, ok = c.get("1")
+	assert.False(t, ok)
+}
+
+func TestTorrentInfo(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	now := time.Now()
+	f.infos.now = func() time.Time { return now }
+	fetches := map[string]int{}
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		id := path.Base(r.URL.Path)
+		if r.Method == "DELETE" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		fetches[id]++
+		writeJSON(t, w, api.Item{ID: id, Status: "downloaded", Progress: float64(fetches[id])})
+	})
+	progress := func(id string, force bool) float64 {
+		info, err := f.torrentInfo(ctx, id, force)
+		require.NoError(t, err)
+		assert.Equal(t, id, info.ID)
+		return info.Progress
+	}
+
+	// Cached after the first read
+	assert.Equal(t, 1.0, progress("A", false))
+	assert.Equal(t, 1.0, progress("A", false))
+	assert.Equal(t, 1, fetches["A"])
+
+	// Forced refresh fetches again and updates the cache
+	assert.Equal(t, 2.0, progress("A", true))
+	assert.Equal(t, 2.0, progress("A", false))
+	assert.Equal(t, 2, fetches["A"])
+
+	// Expires after the TTL
+	now = now.Add(10*time.Minute - time.Second)
+	assert.Equal(t, 2.0, progress("A", false))
+	now = now.Add(time.Second)
+	assert.Equal(t, 3.0, progress("A", false))
+	assert.Equal(t, 3, fetches["A"])
+
+	// Deleting the torrent invalidates it
+	require.NoError(t, f.deleteTorrent(ctx, "A"))
+	_, ok := f.infos.get("A")
+	assert.False(t, ok)
+
+	// and so does removing one of its files
+	assert.Equal(t, 1.0, progress("B", false))
+	o, err := f.newObjectWithInfo(ctx, "B/file.mkv", &api.Item{ID: "link1", ParentID: "B", Type: api.ItemTypeFile})
+	require.NoError(t, err)
+	require.NoError(t, o.Remove(ctx))
+	_, ok = f.infos.get("B")
+	assert.False(t, ok)
+}
+
+func TestTorrentInfoShared(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	var fetches atomic.Int32
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		// long enough for the other reads to wait for it
+		time.Sleep(50 * time.Millisecond)
+		writeJSON(t, w, api.Item{ID: path.Base(r.URL.Path), Status: "downloaded"})
+	})
+
+	// Concurrent reads of the details of a torrent fetch them once
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			info, err := f.torrentInfo(ctx, "A", false)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "A", info.ID)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), fetches.Load())
+}
@@ -0,0 +1,93 @@
+package realdebrid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// remoteLinkWarning is given with the links made for other IPs
+const remoteLinkWarning = "the link works from any IP and its downloads use the remote traffic quota of the account"
+
+// linkResult is the output of the link command
+type linkResult struct {
+	Link    string `json:"link"`
+	Remote  bool   `json:"remote"`
+	Warning string `json:"warning,omitempty"`
+}
+
+// publicLink returns a download link for the file at remote.
+//
+// Download links only work from the IP they were made from unless
+// anyIP is set, when the original link is unrestricted again with
+// remote=1. That link is cached marked remote so it is never streamed
+// from, which would use the remote traffic quota.
+func (f *Fs) publicLink(ctx context.Context, remote string, anyIP bool) (string, error) {
+	_, err := f.dirCache.FindDir(ctx, remote, false)
+	if err == nil {
+		return "", fs.ErrorCantShareDirectories
+	}
+	obj, err := f.NewObject(ctx, remote)
+	if err != nil {
+		return "", err
+	}
+	o, ok := obj.(*Object)
+	if !ok {
+		return "", fmt.Errorf("%q: %w", remote, fs.ErrorNotAFile)
+	}
+	if !anyIP && o.url != "" {
+		return o.url, nil
+	}
+	if o.OriginalUrl == "" {
+		return "", errors.New("can't make a link - no original link")
+	}
+	if !anyIP {
+		item, err := f.downloadLink(ctx, o.OriginalUrl)
+		if err != nil {
+			return "", f.wrapErr("link", remote, o.ParentID, err)
+		}
+		return item.Link, nil
+	}
+	if f.opt.Simulate {
+		stats.simulated.Add(1)
+		fs.Logf(o, "simulate: would unrestrict %q again for other IPs", o.OriginalUrl)
+		return o.url, nil
+	}
+	item, err := f.unrestrictLink(ctx, o.OriginalUrl, true, false)
+	if err != nil {
+		return "", f.wrapErr("link", remote, o.ParentID, err)
+	}
+	item.Remote = true
+	if item.Generated == "" {
+		item.Generated = time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	}
+	f.cache.addLink(*item)
+	fs.Logf(o, "Made a link for other IPs: %s", remoteLinkWarning)
+	return item.Link, nil
+}
+
+// linkCommand returns a download link for the file at remote, for
+// other IPs if the remote option is set, for the link command
+func (f *Fs) linkCommand(ctx context.Context, remote string, opt map[string]string) (*linkResult, error) {
+	anyIP := false
+	if value, ok := opt["remote"]; ok {
+		var err error
+		anyIP, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid remote value %q: %w", value, err)
+		}
+	}
+	link, err := f.publicLink(ctx, remote, anyIP)
+	if err != nil {
+		return nil, err
+	}
+	result := &linkResult{Link: link, Remote: anyIP}
+	if anyIP {
+		result.Warning = remoteLinkWarning
+	}
+	return result, nil
+}
@@ -0,0 +1,77 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicLinkRemote(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	unrestricts := 0
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/unrestrict/link" {
+			t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+			return
+		}
+		unrestricts++
+		assert.Equal(t, "1", r.FormValue("remote"))
+		writeJSON(t, w, api.Item{ID: "D2", Name: "movie.mkv", OriginalLink: r.FormValue("link"), Link: "https://dl/remote"})
+	})
+	f.cache.torrents = []api.Item{{ID: "1", Name: "Movie.2020", Status: "downloaded", Links: []string{"L1"}}}
+	f.cache.addTorrentDetails(f.cache.torrents[0])
+	f.cache.addLink(api.Item{ID: "D1", Name: "movie.mkv", OriginalLink: "L1", Link: "https://dl/local"})
+	const remote = "movies/Movie.2020/movie.mkv"
+
+	// The link made from this IP is given by default
+	link, err := f.PublicLink(ctx, remote, fs.DurationOff, false)
+	require.NoError(t, err)
+	assert.Equal(t, "https://dl/local", link)
+	assert.Zero(t, unrestricts)
+
+	// and one for other IPs with --expire
+	link, err = f.PublicLink(ctx, remote, fs.Duration(time.Hour), false)
+	require.NoError(t, err)
+	assert.Equal(t, "https://dl/remote", link)
+	assert.Equal(t, 1, unrestricts)
+
+	// which is cached marked remote
+	require.Len(t, f.cache.cached, 2)
+	assert.True(t, f.cache.cached[0].Remote)
+	assert.NotEmpty(t, f.cache.cached[0].Generated)
+
+	// but never streamed, even after the duplicates are removed
+	f.cache.clean()
+	require.Len(t, f.cache.cached, 2)
+	item, found := f.cache.link("L1")
+	assert.True(t, found)
+	assert.Equal(t, "https://dl/local", item.Link)
+	assert.Equal(t, "https://dl/local", f.cache.links()["L1"].Link)
+	f.dirCache.ResetRoot()
+	o, err := f.NewObject(ctx, remote)
+	require.NoError(t, err)
+	assert.Equal(t, "https://dl/local", o.(*Object).url)
+
+	// The link command gives the warning
+	out, err := f.Command(ctx, "link", []string{remote}, map[string]string{"remote": "true"})
+	require.NoError(t, err)
+	assert.Equal(t, &linkResult{Link: "https://dl/remote", Remote: true, Warning: remoteLinkWarning}, out)
+	assert.Equal(t, 2, unrestricts)
+
+	out, err = f.Command(ctx, "link", []string{remote}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &linkResult{Link: "https://dl/local"}, out)
+	assert.Equal(t, 2, unrestricts)
+
+	_, err = f.Command(ctx, "link", []string{remote}, map[string]string{"remote": "maybe"})
+	assert.ErrorContains(t, err, `invalid remote value "maybe"`)
+	_, err = f.PublicLink(ctx, "movies/Movie.2020", fs.Duration(time.Hour), false)
+	assert.ErrorIs(t, err, fs.ErrorCantShareDirectories)
+}
@@ -0,0 +1,105 @@
+package realdebrid
+
+import (
+	"context"
+	"path"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/list"
+)
+
+// ListR lists the objects and directories of the Fs starting
+// from dir recursively into out.
+//
+// dir should be "" to start from the root, and should not
+// have trailing slashes.
+//
+// This should return ErrDirNotFound if the directory isn't
+// found.
+//
+// It should call callback for each tranche of entries read.
+// These need not be returned in any particular order.  If
+// callback returns an error then the listing will stop
+// immediately.
+//
+// The folders above the torrents are listed from the cached torrents
+// as by List. The files of the torrents are named from their file info
+// like in files mode, so only the files of torrents whose file info
+// can't be matched to their links, as they were packed into an
+// archive, are unrestricted.
+func (f *Fs) ListR(ctx context.Context, dir string, callback fs.ListRCallback) (err error) {
+	list := list.NewHelper(callback)
+	err = f.listR(ctx, dir, list, f.cache.links())
+	if err != nil {
+		return err
+	}
+	return list.Flush()
+}
+
+// listR lists dir recursively into list, naming the files of the
+// torrents with the cached links
+func (f *Fs) listR(ctx context.Context, dir string, list *list.Helper, links map[string]api.Item) error {
+	dirID, err := f.dirCache.FindDir(ctx, dir, false)
+	if err != nil {
+		return err
+	}
+	// the files of nested_folders are named from their paths by List
+	if id := f.listedTorrent(dirID); id != "" && !f.opt.NestedFolders {
+		return f.listTorrentR(ctx, dir, id, list, links)
+	}
+	entries, err := f.List(ctx, dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := list.Add(entry); err != nil {
+			return err
+		}
+		if d, ok := entry.(fs.Directory); ok {
+			if err := f.listR(ctx, d.Remote(), list, links); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// listTorrentR lists the files of the torrent with id in the folder
+// dir into list without unrestricting them if they can be named
+// otherwise.
+func (f *Fs) listTorrentR(ctx context.Context, dir, id string, list *list.Helper, links map[string]api.Item) error {
+	files := f.torrentFiles(ctx, api.Item{ID: id, Name: path.Base(dir)}, links)
+	details, cached := f.cache.torrentDetails(id)
+	if !cached {
+		// not listed, as logged by torrentFiles
+		return nil
+	}
+	// the links left out can't be named, unless they are the files
+	// hidden by video_only
+	if selectedFiles(&details) == nil && len(files) < len(details.Links) || notReady(&details) != nil {
+		// listed as by List which knows which links to unrestrict
+		entries, err := f.List(ctx, dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := list.Add(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for i := range files {
+		item := &files[i]
+		f.prepareItem(item, id)
+		o, err := f.newObjectWithInfo(ctx, path.Join(dir, item.Name), item)
+		if err != nil {
+			return err
+		}
+		if err := list.Add(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"sort"
+	"testing"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListR(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	files := map[string][]api.File{
+		"1": {{ID: 1, Path: "/Show.S01E01.mkv", Bytes: 10, Selected: 1}, {ID: 2, Path: "/Show.S01E02.mkv", Bytes: 20, Selected: 1}, {ID: 3, Path: "/sample.txt"}},
+		"2": {{ID: 1, Path: "/Movie.2020.mkv", Bytes: 30, Selected: 1}},
+		// packed into a single archive so can't be matched to the link
+		"3": {{ID: 1, Path: "/a.mkv", Bytes: 1, Selected: 1}, {ID: 2, Path: "/b.mkv", Bytes: 2, Selected: 1}},
+	}
+	f.cache.torrents = []api.Item{
+		{ID: "1", Name: "Show.S01", Status: "downloaded", Links: []string{"s1", "s2"}},
+		{ID: "2", Name: "Movie.2020", Status: "downloaded", Links: []string{"m1"}},
+		{ID: "3", Name: "Archive.2021", Status: "downloaded", Links: []string{"a1"}},
+	}
+	names := map[string]string{"s1": "Show.S01E01.mkv", "s2": "Show.S01E02.mkv", "m1": "Movie.2020.mkv", "a1": "Archive.2021.rar"}
+	requests := map[string]int{}
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests[r.Method+" "+r.URL.Path]++
+		switch {
+		case path.Dir(r.URL.Path) == "/torrents/info":
+			for _, torrent := range f.cache.torrents {
+				if torrent.ID == path.Base(r.URL.Path) {
+					torrent.Files = files[torrent.ID]
+					writeJSON(t, w, torrent)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/unrestrict/link":
+			link := r.FormValue("link")
+			writeJSON(t, w, api.Item{ID: "d-" + link, Name: names[link], OriginalLink: link, Link: "https://dl/" + link, Size: 3})
+		default:
+			t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	listR := func(dir string) map[string]int64 {
+		entries := map[string]int64{}
+		require.NoError(t, f.ListR(ctx, dir, func(tranche fs.DirEntries) error {
+			for _, entry := range tranche {
+				entries[entry.Remote()] = entry.Size()
+			}
+			return nil
+		}))
+		return entries
+	}
+	require.NotNil(t, f.Features().ListR)
+
+	// Everything is listed fetching each torrent info once and only
+	// unrestricting the archive which can't be named otherwise
+	assert.Equal(t, map[string]int64{
+		"shows":                                -1,
+		"shows/Show.S01":                       -1,
+		"shows/Show.S01/Show.S01E01.mkv":       10,
+		"shows/Show.S01/Show.S01E02.mkv":       20,
+		"movies":                               -1,
+		"movies/Movie.2020":                    -1,
+		"movies/Movie.2020/Movie.2020.mkv":     30,
+		"movies/Archive.2021":                  -1,
+		"movies/Archive.2021/Archive.2021.rar": 3,
+		"default":                              -1,
+	}, listR(""))
+	assert.Equal(t, map[string]int{
+		"GET /torrents/info/1":  1,
+		"GET /torrents/info/2":  1,
+		"GET /torrents/info/3":  1,
+		"POST /unrestrict/link": 1,
+	}, requests)
+
+	// The same as List gives, which unrestricts every file
+	var walked []string
+	var walk func(dir string)
+	walk = func(dir string) {
+		entries, err := f.List(ctx, dir)
+		require.NoError(t, err)
+		for _, entry := range entries {
+			walked = append(walked, entry.Remote())
+			if _, ok := entry.(fs.Directory); ok {
+				walk(entry.Remote())
+			}
+		}
+	}
+	walk("")
+	var listed []string
+	for remote := range listR("") {
+		listed = append(listed, remote)
+	}
+	sort.Strings(walked)
+	sort.Strings(listed)
+	assert.Equal(t, walked, listed)
+
+	// from any directory
+	assert.Equal(t, map[string]int64{
+		"shows/Show.S01/Show.S01E01.mkv": 10,
+		"shows/Show.S01/Show.S01E02.mkv": 20,
+	}, listR("shows/Show.S01"))
+	err := f.ListR(ctx, "shows/Missing", func(fs.DirEntries) error { return nil })
+	assert.ErrorIs(t, err, fs.ErrorDirNotFound)
+}
@@ -0,0 +1,45 @@
+package realdebrid
+
+import (
+	"sort"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+)
+
+// sortItems sorts the items listed in a directory as set by list_sort.
+//
+// Items added at the same time, like the files of a torrent, are
+// sorted by name so listings are the same from one refresh to the next
+// whatever order the API returned them in.
+func (f *Fs) sortItems(items []api.Item) {
+	type sortedItem struct {
+		added string // as formatted by the API, which sorts as the times do
+		name  string // as listed
+		item  api.Item
+	}
+	sorted := make([]sortedItem, len(items))
+	for i, item := range items {
+		// the files of a torrent all have its added time, the
+		// downloads only the time they were unrestricted
+		added := item.Ended
+		if added == "" {
+			added = item.Generated
+		}
+		sorted[i] = sortedItem{added: added, name: f.normalize(f.opt.Enc.ToStandardName(item.Name)), item: item}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := &sorted[i], &sorted[j]
+		if a.added != b.added {
+			switch f.opt.ListSort {
+			case "added_asc":
+				return a.added < b.added
+			case "", "added_desc":
+				return a.added > b.added
+			}
+		}
+		return a.name < b.name
+	})
+	for i := range sorted {
+		items[i] = sorted[i].item
+	}
+}
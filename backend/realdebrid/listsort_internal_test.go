@@ -0,0 +1,49 @@
+package realdebrid
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListSort(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.opt.SharedFolder = "torrents"
+	f.cache.torrents = []api.Item{
+		{ID: "1", Name: "B.Movie.2020", Status: "downloading", Ended: "2024-03-01T10:00:00.000Z"},
+		{ID: "2", Name: "C.Movie.2020", Status: "downloading", Ended: "2024-01-01T10:00:00.000Z"},
+		{ID: "3", Name: "A.Movie.2020", Status: "downloading", Ended: "2024-02-01T10:00:00.000Z"},
+		{ID: "4", Name: "D.Movie.2020", Status: "downloading", Ended: "2024-02-01T10:00:00.000Z"},
+	}
+	list := func() []string {
+		f.dirCache.ResetRoot()
+		entries, err := f.List(ctx, "")
+		require.NoError(t, err)
+		return entryNames(entries)
+	}
+
+	// Newest first by default, by name when added at the same time
+	newest := []string{"B.Movie.2020", "A.Movie.2020", "D.Movie.2020", "C.Movie.2020"}
+	assert.Equal(t, newest, list())
+
+	// whatever order the API returns them in
+	slices.Reverse(f.cache.torrents)
+	assert.Equal(t, newest, list())
+
+	f.opt.ListSort = "added_asc"
+	assert.Equal(t, []string{"C.Movie.2020", "A.Movie.2020", "D.Movie.2020", "B.Movie.2020"}, list())
+
+	f.opt.ListSort = "name"
+	assert.Equal(t, []string{"A.Movie.2020", "B.Movie.2020", "C.Movie.2020", "D.Movie.2020"}, list())
+
+	// The category folders keep their order
+	f.opt.SharedFolder = "folders"
+	assert.Equal(t, []string{"shows", "movies", "default"}, list())
+	f.opt.ListSort = "added_desc"
+	assert.Equal(t, []string{"shows", "movies", "default"}, list())
+}
@@ -0,0 +1,46 @@
+package realdebrid
+
+import (
+	"context"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+)
+
+// findCachedFile returns the file leaf of the torrent folder dirID
+// named from the cached torrent details and links as ListR names them.
+//
+// Listing the torrent folder unrestricts the links of all its files
+// which aren't cached, so this makes finding one file cost at most the
+// fetch of the torrent details. found is false if the torrent folder
+// has to be listed to find it, because dirID isn't a torrent folder or
+// the file isn't named that way, or the files are in the folders of
+// nested_folders which this doesn't know.
+func (f *Fs) findCachedFile(ctx context.Context, dirID, leaf string) (info *api.Item, found bool) {
+	id := f.listedTorrent(dirID)
+	if id == "" || f.opt.NestedFolders {
+		return nil, false
+	}
+	details, cached := f.cache.torrentDetails(id)
+	if !cached {
+		torrent, err := f.torrentInfo(ctx, id, false)
+		if err != nil {
+			// reported by the listing
+			return nil, false
+		}
+		f.cache.addTorrentDetails(*torrent)
+		if notReady(torrent) != nil {
+			return nil, false
+		}
+		details = *torrent
+	}
+	key := foldName(f.normalize(leaf))
+	files := f.torrentFiles(ctx, details, f.cache.linksFor(details.Links))
+	for i := range files {
+		item := &files[i]
+		f.prepareItem(item, id)
+		if foldName(item.Name) == key {
+			return item, true
+		}
+	}
+	return nil, false
+}
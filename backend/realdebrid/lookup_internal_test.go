@@ -0,0 +1,78 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewObjectFromCache(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	var mu sync.Mutex
+	requests := map[string]int{}
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.Method+" "+r.URL.Path]++
+		mu.Unlock()
+		switch {
+		case path.Dir(r.URL.Path) == "/torrents/info":
+			writeJSON(t, w, api.Item{
+				ID:     path.Base(r.URL.Path),
+				Status: "downloaded",
+				Links:  []string{"L1", "L2", "L3"},
+				Files: []api.File{
+					{ID: 1, Path: "/Movie.2020/a.mkv", Bytes: 100, Selected: 1},
+					{ID: 2, Path: "/Movie.2020/sample.mkv", Bytes: 5, Selected: 0},
+					{ID: 3, Path: "/Movie.2020/b.mkv", Bytes: 200, Selected: 1},
+					{ID: 4, Path: "/Movie.2020/c.srt", Bytes: 300, Selected: 1},
+				},
+			})
+		case r.URL.Path == "/unrestrict/link":
+			link := r.FormValue("link")
+			names := map[string]string{"L1": "a.mkv", "L2": "b.mkv", "L3": "c.en.srt"}
+			writeJSON(t, w, api.Item{ID: "D" + link, Name: names[link], OriginalLink: link, Link: "https://dl/" + link, Size: 1})
+		default:
+			t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	f.cache.torrents = []api.Item{
+		{ID: "1", Name: "Movie.2020", Status: "downloaded", Links: []string{"L1", "L2", "L3"}},
+	}
+	f.cache.addLink(api.Item{ID: "DL1", Name: "a.mkv", Link: "https://dl/L1", OriginalLink: "L1", Size: 1})
+
+	// A file is found with the torrent details only
+	o, err := f.NewObject(ctx, "movies/Movie.2020/B.mkv")
+	require.NoError(t, err)
+	assert.Equal(t, "movies/Movie.2020/B.mkv", o.Remote())
+	assert.Equal(t, int64(200), o.Size())
+	assert.Equal(t, "1", o.(*Object).ParentID)
+	assert.Equal(t, "L2", o.(*Object).OriginalUrl)
+	assert.Equal(t, map[string]int{"GET /torrents/info/1": 1}, requests)
+
+	// and then without any call, using the cached link if there is one
+	o, err = f.NewObject(ctx, "movies/Movie.2020/a.mkv")
+	require.NoError(t, err)
+	assert.Equal(t, "https://dl/L1", o.(*Object).url)
+	assert.Equal(t, int64(100), o.Size())
+	assert.Equal(t, map[string]int{"GET /torrents/info/1": 1}, requests)
+
+	// Files not named the same by the file info are found by listing
+	o, err = f.NewObject(ctx, "movies/Movie.2020/c.en.srt")
+	require.NoError(t, err)
+	assert.Equal(t, "L3", o.(*Object).OriginalUrl)
+	assert.Equal(t, 2, requests["POST /unrestrict/link"])
+
+	_, err = f.NewObject(ctx, "movies/Movie.2020/d.mkv")
+	assert.ErrorIs(t, err, fs.ErrorObjectNotFound)
+	_, err = f.NewObject(ctx, "movies/Movie.2021/a.mkv")
+	assert.ErrorIs(t, err, fs.ErrorObjectNotFound)
+}
@@ -0,0 +1,43 @@
+package realdebrid
+
+import (
+	"context"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+)
+
+// tooSmall returns true if torrent is hidden by min_torrent_size.
+//
+// The size of a torrent still being added isn't known so it isn't.
+func (f *Fs) tooSmall(torrent *api.Item) bool {
+	return f.opt.MinTorrentSize > 0 && torrent.Bytes < int64(f.opt.MinTorrentSize) && notReady(torrent) == nil
+}
+
+// listedTorrents returns the torrents to list, refreshing them as
+// ensureTorrentsListedOrStale does, without those hidden by
+// min_torrent_size or regex_exclude.
+func (f *Fs) listedTorrents(ctx context.Context) ([]api.Item, error) {
+	torrents, err := f.ensureTorrentsListedOrStale(ctx)
+	exclude := f.excludeRegexp()
+	if err != nil || f.opt.MinTorrentSize <= 0 && exclude == nil {
+		return torrents, err
+	}
+	listed := make([]api.Item, 0, len(torrents))
+	for i := range torrents {
+		if !f.tooSmall(&torrents[i]) && !excludedBy(exclude, &torrents[i]) {
+			listed = append(listed, torrents[i])
+		}
+	}
+	return listed, nil
+}
+
+// smallTorrents returns the names of the torrents hidden by
+// min_torrent_size
+func (f *Fs) smallTorrents(torrents []api.Item) (names []string) {
+	for i := range torrents {
+		if f.tooSmall(&torrents[i]) {
+			names = append(names, torrents[i].Name)
+		}
+	}
+	return names
+}
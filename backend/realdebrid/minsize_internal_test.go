@@ -0,0 +1,51 @@
+package realdebrid
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinTorrentSize(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" {
+			t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+		}
+		writeJSON(t, w, api.User{Username: "jelly"})
+	})
+	f.cache.torrents = []api.Item{
+		{ID: "1", Name: "Movie.2020", Status: "downloaded", Bytes: 2 * int64(fs.Gibi)},
+		{ID: "2", Name: "Spam.2021", Status: "downloaded", Bytes: 300 * int64(fs.Kibi)},
+		{ID: "3", Name: "Adding.2022", Status: "magnet_conversion"},
+	}
+	list := func() []string {
+		entries, err := f.List(ctx, "movies")
+		require.NoError(t, err)
+		return entryNames(entries)
+	}
+
+	// All the torrents are shown by default
+	assert.Equal(t, []string{"movies/Adding.2022", "movies/Movie.2020", "movies/Spam.2021"}, list())
+
+	// Small torrents are hidden but not those still being added
+	f.opt.MinTorrentSize = fs.Mebi
+	f.dirCache.ResetRoot()
+	assert.Equal(t, []string{"movies/Adding.2022", "movies/Movie.2020"}, list())
+	_, err := f.NewObject(ctx, "movies/Spam.2021/spam.mkv")
+	assert.ErrorIs(t, err, fs.ErrorObjectNotFound)
+
+	// and are shown in the status file
+	data, _ := f.status(ctx)
+	var report statusReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, []string{"Spam.2021"}, report.Hidden)
+	assert.Equal(t, 3, report.Torrents)
+}
@@ -0,0 +1,94 @@
+package realdebrid
+
+import (
+	"sync"
+	"time"
+)
+
+// missCacheSize is the number of misses kept before they are all
+// forgotten, as media servers look for the same few names
+const missCacheSize = 10000
+
+// missKey is a file looked up in a directory by its folded name
+type missKey struct {
+	dirID string
+	leaf  string
+}
+
+// missEntry is when a file was found missing and in which generation
+// of the torrents
+type missEntry struct {
+	at         time.Time
+	generation int64
+}
+
+// missCache remembers the files which weren't found, like the sidecar
+// files media servers keep looking for, so looking them up again
+// doesn't list their directory.
+//
+// A miss is valid for ttl and while the torrents aren't refreshed.
+type missCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration // how long misses are valid for, <= 0 disables the cache
+	entries map[missKey]missEntry
+	now     func() time.Time // the clock, replaced in tests
+}
+
+// newMissCache makes a missCache with misses valid for ttl
+func newMissCache(ttl time.Duration) *missCache {
+	return &missCache{
+		ttl:     ttl,
+		entries: make(map[missKey]missEntry),
+		now:     time.Now,
+	}
+}
+
+// has returns true if leaf was missing from the directory dirID in
+// generation of the torrents within the ttl
+func (c *missCache) has(dirID, leaf string, generation int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl <= 0 {
+		return false
+	}
+	key := missKey{dirID: dirID, leaf: leaf}
+	entry, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+	if entry.generation != generation || c.now().Sub(entry.at) >= c.ttl {
+		delete(c.entries, key)
+		return false
+	}
+	return true
+}
+
+// add remembers leaf was missing from the directory dirID in
+// generation of the torrents
+func (c *missCache) add(dirID, leaf string, generation int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl <= 0 {
+		return
+	}
+	if len(c.entries) >= missCacheSize {
+		clear(c.entries)
+	}
+	c.entries[missKey{dirID: dirID, leaf: leaf}] = missEntry{at: c.now(), generation: generation}
+}
+
+// setTTL sets how long the misses are valid for, forgetting them
+func (c *missCache) setTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+	clear(c.entries)
+}
+
+// clear forgets all the misses, which must be called when the files of
+// a torrent change other than by a refresh
+func (c *missCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
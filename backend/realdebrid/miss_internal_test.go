@@ -0,0 +1,88 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMissCache(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	c := newMissCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	assert.False(t, c.has("T1", "movie.nfo", 1))
+	c.add("T1", "movie.nfo", 1)
+	assert.True(t, c.has("T1", "movie.nfo", 1))
+	assert.False(t, c.has("T2", "movie.nfo", 1))
+	assert.False(t, c.has("T1", "poster.jpg", 1))
+
+	// Misses are forgotten when the torrents are refreshed
+	assert.False(t, c.has("T1", "movie.nfo", 2))
+	assert.False(t, c.has("T1", "movie.nfo", 1))
+
+	// after the ttl
+	c.add("T1", "movie.nfo", 2)
+	now = now.Add(time.Minute)
+	assert.False(t, c.has("T1", "movie.nfo", 2))
+
+	// or when cleared
+	c.add("T1", "movie.nfo", 2)
+	c.clear()
+	assert.False(t, c.has("T1", "movie.nfo", 2))
+
+	// Not cached without a ttl
+	c = newMissCache(0)
+	c.add("T1", "movie.nfo", 1)
+	assert.False(t, c.has("T1", "movie.nfo", 1))
+}
+
+func TestNewObjectMisses(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	torrent := api.Item{
+		ID: "1", Name: "Movie.2020", Status: "downloaded", Links: []string{"L1"},
+		Files: []api.File{{ID: 1, Path: "/Movie.2020.mkv", Bytes: 100, Selected: 1}},
+	}
+	f.cache.torrents = []api.Item{torrent}
+	f.cache.addTorrentDetails(torrent)
+	f.cache.addLink(api.Item{ID: "D1", Name: "Movie.2020.mkv", Link: "https://dl/L1", OriginalLink: "L1", Size: 100})
+	missing := func(remote string) (hits int64) {
+		before := stats.missHits.Load()
+		_, err := f.NewObject(ctx, remote)
+		assert.ErrorIs(t, err, fs.ErrorObjectNotFound)
+		return stats.missHits.Load() - before
+	}
+
+	// The second lookup of a missing file is answered from memory
+	assert.Equal(t, int64(0), missing("movies/Movie.2020/Movie.2020.nfo"))
+	assert.Equal(t, int64(1), missing("movies/Movie.2020/movie.2020.NFO"))
+	assert.Equal(t, int64(0), missing("movies/Movie.2020/poster.jpg"))
+
+	// until the files of the torrent change
+	torrent.Links = append(torrent.Links, "L2")
+	torrent.Files = append(torrent.Files, api.File{ID: 2, Path: "/Movie.2020.nfo", Bytes: 1, Selected: 1})
+	f.cache.replaceTorrentDetails(torrent)
+	f.cache.addLink(api.Item{ID: "D2", Name: "Movie.2020.nfo", Link: "https://dl/L2", OriginalLink: "L2", Size: 1})
+	f.torrentChanged(torrent.ID)
+	o, err := f.NewObject(ctx, "movies/Movie.2020/Movie.2020.nfo")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), o.Size())
+
+	// or the torrents are refreshed
+	assert.Equal(t, int64(0), missing("movies/Movie.2020/poster.jpg"))
+	assert.Equal(t, int64(1), missing("movies/Movie.2020/poster.jpg"))
+	f.cache.setTorrents([]api.Item{torrent})
+	assert.Equal(t, int64(0), missing("movies/Movie.2020/poster.jpg"))
+	assert.Equal(t, int64(1), missing("movies/Movie.2020/poster.jpg"))
+}
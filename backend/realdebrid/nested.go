@@ -0,0 +1,101 @@
+package realdebrid
+
+import (
+	"path"
+	"strings"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+)
+
+// subfolderID returns the ID of the folder dir, relative to the folder
+// of the torrent with torrentID, listed with nested_folders
+func subfolderID(torrentID, dir string) string {
+	return synID(synFolder, torrentID+"/"+dir)
+}
+
+// isSubfolderID returns true if id is the ID of a folder in a torrent
+// folder
+func isSubfolderID(id string) bool {
+	kind, _, ok := parseSynID(id)
+	return ok && kind == synFolder
+}
+
+// subfolder returns the ID of the torrent and the path relative to its
+// folder of the folder dirID, or ok false if it isn't a folder in a
+// torrent folder of f
+func (f *Fs) subfolder(dirID string) (torrentID, dir string, ok bool) {
+	kind, key, ok := parseSynID(dirID)
+	if !ok || kind != synFolder || !f.opt.NestedFolders {
+		return "", "", false
+	}
+	torrentID, dir, ok = strings.Cut(key, "/")
+	if !ok || torrentID == "" || dir == "" {
+		return "", "", false
+	}
+	return torrentID, dir, true
+}
+
+// fileDirs returns the folder of each link of torrent relative to the
+// torrent folder from the paths of its selected files, or nil if its
+// files are listed at the top of the torrent folder, as nested_folders
+// isn't set or the files can't be matched to the links.
+//
+// The folder named after the torrent some put all the files in is
+// left out as the torrent folder stands for it.
+func (f *Fs) fileDirs(torrent *api.Item) []string {
+	if !f.opt.NestedFolders {
+		return nil
+	}
+	selected := selectedFiles(torrent)
+	if selected == nil {
+		return nil
+	}
+	dirs := make([]string, len(selected))
+	top := ""
+	for i, file := range selected {
+		dir := path.Dir(path.Clean("/" + file.Path))
+		dirs[i] = strings.TrimPrefix(dir, "/")
+		first, _, _ := strings.Cut(dirs[i], "/")
+		if i == 0 {
+			top = first
+		} else if first != top {
+			top = ""
+		}
+	}
+	if top != "" && strings.EqualFold(top, torrent.Name) {
+		for i, dir := range dirs {
+			dirs[i] = strings.TrimPrefix(strings.TrimPrefix(dir, top), "/")
+		}
+	}
+	return dirs
+}
+
+// childFolders returns the folders in the folder dir of torrent whose
+// links are in the folders dirs
+func childFolders(torrent *api.Item, dirs []string, dir string) (folders []api.Item) {
+	seen := map[string]struct{}{}
+	for _, fileDir := range dirs {
+		rest := fileDir
+		if dir != "" {
+			var found bool
+			rest, found = strings.CutPrefix(fileDir, dir+"/")
+			if !found {
+				continue
+			}
+		}
+		if rest == "" || rest == "." {
+			continue
+		}
+		name, _, _ := strings.Cut(rest, "/")
+		if _, found := seen[name]; found {
+			continue
+		}
+		seen[name] = struct{}{}
+		folders = append(folders, api.Item{
+			ID:    subfolderID(torrent.ID, path.Join(dir, name)),
+			Name:  name,
+			Ended: torrent.Ended,
+		})
+	}
+	return folders
+}
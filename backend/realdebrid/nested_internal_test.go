@@ -0,0 +1,112 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNestedFolders(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.opt.NestedFolders = true
+	var mu sync.Mutex
+	unrestricted := map[string]int{}
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/torrents/info/1":
+			writeJSON(t, w, api.Item{
+				ID:     "1",
+				Name:   "Show.S01",
+				Status: "downloaded",
+				Links:  []string{"L1", "L2", "L3", "L4"},
+				Files: []api.File{
+					{ID: 1, Path: "/Show.S01/Season 01/E01.mkv", Bytes: 100, Selected: 1},
+					{ID: 2, Path: "/Show.S01/Season 01/sample.mkv", Bytes: 5, Selected: 0},
+					{ID: 3, Path: "/Show.S01/Season 01/E02.mkv", Bytes: 200, Selected: 1},
+					{ID: 4, Path: "/Show.S01/Subs/en/E01.srt", Bytes: 10, Selected: 1},
+					{ID: 5, Path: "/Show.S01/Show.S01.nfo", Bytes: 1, Selected: 1},
+				},
+			})
+		case r.URL.Path == "/unrestrict/link":
+			link := r.FormValue("link")
+			mu.Lock()
+			unrestricted[link]++
+			mu.Unlock()
+			writeJSON(t, w, api.Item{ID: "D" + link, Name: link + ".bin", OriginalLink: link, Link: "https://dl/" + link, Size: 1})
+		default:
+			t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	f.cache.torrents = []api.Item{
+		{ID: "1", Name: "Show.S01", Status: "downloaded", Links: []string{"L1", "L2", "L3", "L4"}},
+	}
+	list := func(dir string) []string {
+		entries, err := f.List(ctx, dir)
+		require.NoError(t, err, dir)
+		return entryNames(entries)
+	}
+
+	// The torrent folder has the folders of its files, the one named
+	// after the torrent left out
+	assert.ElementsMatch(t, []string{"shows/Show.S01/Season 01", "shows/Show.S01/Subs", "shows/Show.S01/Show.S01.nfo"}, list("shows/Show.S01"))
+	assert.Equal(t, map[string]int{"L4": 1}, unrestricted)
+	entries, err := f.List(ctx, "shows/Show.S01")
+	require.NoError(t, err)
+	for _, entry := range entries {
+		_, isDir := entry.(fs.Directory)
+		assert.Equal(t, path.Ext(entry.Remote()) == "", isDir, entry.Remote())
+	}
+
+	// whose IDs are made of the torrent ID and their path
+	id, err := f.dirCache.FindDir(ctx, "shows/Show.S01/Subs/en", false)
+	require.NoError(t, err)
+	assert.Equal(t, "syn:folder:1/Subs/en", id)
+	assert.Equal(t, "1", f.listedTorrent(id))
+
+	// A file is found through them, only unrestricting the links of
+	// its folder
+	o, err := f.NewObject(ctx, "shows/Show.S01/Subs/en/E01.srt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), o.Size())
+	assert.Equal(t, "1", o.(*Object).ParentID)
+	assert.Equal(t, "L3", o.(*Object).OriginalUrl)
+	assert.Equal(t, map[string]int{"L3": 1, "L4": 1}, unrestricted)
+	assert.Equal(t, []string{"shows/Show.S01/Season 01/E01.mkv", "shows/Show.S01/Season 01/E02.mkv"}, list("shows/Show.S01/Season 01"))
+
+	_, err = f.List(ctx, "shows/Show.S01/Extras")
+	assert.ErrorIs(t, err, fs.ErrorDirNotFound)
+	_, _, err = f.listAll(ctx, subfolderID("1", "Extras"), false, false, func(*api.Item) bool { return false })
+	assert.ErrorIs(t, err, fs.ErrorDirNotFound)
+
+	// ListR goes through the folders too
+	var remotes []string
+	require.NoError(t, f.ListR(ctx, "shows/Show.S01", func(entries fs.DirEntries) error {
+		remotes = append(remotes, entryNames(entries)...)
+		return nil
+	}))
+	assert.ElementsMatch(t, []string{
+		"shows/Show.S01/Season 01",
+		"shows/Show.S01/Season 01/E01.mkv",
+		"shows/Show.S01/Season 01/E02.mkv",
+		"shows/Show.S01/Subs",
+		"shows/Show.S01/Subs/en",
+		"shows/Show.S01/Subs/en/E01.srt",
+		"shows/Show.S01/Show.S01.nfo",
+	}, remotes)
+
+	// Without the option the folders aren't there
+	f.opt.NestedFolders = false
+	f.dirCache.ResetRoot()
+	assert.ElementsMatch(t, []string{"shows/Show.S01/L1.bin", "shows/Show.S01/L2.bin", "shows/Show.S01/L3.bin", "shows/Show.S01/L4.bin"}, list("shows/Show.S01"))
+	_, _, err = f.listAll(ctx, subfolderID("1", "Subs"), false, false, func(*api.Item) bool { return false })
+	assert.ErrorIs(t, err, fs.ErrorDirNotFound)
+}
@@ -0,0 +1,228 @@
+package realdebrid
+
+import (
+	"context"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+)
+
+// pollSize is the number of the newest torrents listed by each poll of
+// ChangeNotify
+const pollSize = 100
+
+// flushWindow is how long the directories changed by background
+// activity are queued before being flushed, so a burst of changes to
+// the same directories flushes and notifies each of them once.
+var flushWindow = time.Second
+
+// flushQueue holds the directories changed by repairs, tombstones and
+// polling until they are flushed from the directory cache and notified
+// to the VFS.
+type flushQueue struct {
+	mu      sync.Mutex
+	pending map[string]struct{}        // directories to flush, relative to the root
+	timer   *time.Timer                // flushes pending, nil if nothing is queued
+	notify  func(string, fs.EntryType) // set by ChangeNotify
+	stopped bool                       // set by Shutdown, nothing is queued after
+}
+
+// setNotify sets the function the changed directories are notified to
+func (q *flushQueue) setNotify(notifyFunc func(string, fs.EntryType)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.notify = notifyFunc
+}
+
+// stop drops the queued directories and stops queuing more
+func (q *flushQueue) stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.timer != nil {
+		q.timer.Stop()
+	}
+	q.pending, q.timer, q.stopped = nil, nil, true
+}
+
+// queueChanged queues the directories dirs to be flushed and notified
+// at the end of the current flush window, starting one if needed.
+func (f *Fs) queueChanged(dirs ...string) {
+	q := &f.flushes
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.stopped {
+		return
+	}
+	if q.pending == nil {
+		q.pending = make(map[string]struct{})
+	}
+	for _, dir := range dirs {
+		q.pending[dir] = struct{}{}
+	}
+	if q.timer == nil {
+		q.timer = time.AfterFunc(flushWindow, f.flushChanged)
+	}
+}
+
+// flushChanged flushes the queued directories from the directory cache
+// and notifies them.
+func (f *Fs) flushChanged() {
+	q := &f.flushes
+	q.mu.Lock()
+	dirs := make([]string, 0, len(q.pending))
+	for dir := range q.pending {
+		dirs = append(dirs, dir)
+	}
+	q.pending, q.timer = nil, nil
+	notify := q.notify
+	q.mu.Unlock()
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		// the root is listed again anyway, flushing it would forget
+		// every directory found so far
+		if dir != "" {
+			f.dirCache.FlushDir(dir)
+		}
+		if notify != nil {
+			notify(dir, fs.EntryDirectory)
+		}
+	}
+}
+
+// torrentChanged queues the folder of the torrent with id after it
+// was repaired or deleted, or the root if its files are listed there.
+//
+// A torrent folder not in the directory cache hasn't been listed so
+// there is nothing to flush, unless the torrent is flattened to a file
+// of a category folder which is flushed instead.
+func (f *Fs) torrentChanged(id string) {
+	// its files may be listed in the folders above it too
+	f.misses.clear()
+	if f.opt.RootFolderID != "torrents" || f.opt.SharedFolder == "files" {
+		f.queueChanged("")
+		return
+	}
+	if dir, ok := f.dirCache.GetInv(id); ok {
+		f.queueChanged(dir)
+		return
+	}
+	if f.opt.FlattenSingleFile && f.opt.SharedFolder == "folders" {
+		for _, category := range f.torrentsFolders() {
+			if dir, ok := f.dirCache.GetInv(category.ID); ok {
+				f.queueChanged(dir)
+			}
+		}
+	}
+}
+
+// pollTorrents lists the newest torrents to find those added or which
+// finished downloading since the last poll, and their total to find
+// if any were removed. The folders listing them are queued and the
+// cached torrents invalidated so they are listed again.
+//
+// Removed torrents can't be told apart from those pushed off the
+// page, nor more added torrents than fit on it found, so then all
+// the folders listing torrents are queued.
+//
+// The first poll only notes the torrents to compare the next one with.
+func (f *Fs) pollTorrents(ctx context.Context) {
+	items, total, err := f.client.ListTorrents(ctx, 1, pollSize)
+	if err != nil {
+		fs.Debugf(f, "Polling the torrents failed: %v", err)
+		return
+	}
+	current := make(map[string]string, len(items))
+	for _, item := range items {
+		current[item.ID] = item.Status
+	}
+	f.mu.Lock()
+	previous, previousTotal, polled := f.torrentStatuses, f.torrentTotal, f.torrentStatusBase
+	f.torrentStatuses, f.torrentTotal, f.torrentStatusBase = current, total, true
+	f.mu.Unlock()
+	if !polled {
+		return
+	}
+	var added, downloaded []api.Item
+	polledBefore := false // past the newest torrent polled before
+	for _, item := range items {
+		status, found := previous[item.ID]
+		polledBefore = polledBefore || found
+		if !polledBefore {
+			added = append(added, item)
+		} else if found && item.Status == "downloaded" && status != "downloaded" {
+			downloaded = append(downloaded, item)
+		}
+	}
+	removed := previousTotal + len(added) - total
+	if len(added) == 0 && len(downloaded) == 0 && removed == 0 {
+		return
+	}
+	fs.Infof(f, "Polling found %d torrents added, %d downloaded and %d torrents now instead of %d", len(added), len(downloaded), total, previousTotal)
+	f.cache.state.Invalidate()
+	if removed != 0 {
+		f.torrentsDirsChanged()
+	}
+	for _, torrent := range append(added, downloaded...) {
+		f.dirChanged(f.torrentsDir(&torrent))
+	}
+	for _, torrent := range downloaded {
+		// its files can be listed now
+		f.torrentChanged(torrent.ID)
+	}
+}
+
+// torrentsDir returns the ID of the directory listing torrent
+func (f *Fs) torrentsDir(torrent *api.Item) string {
+	if f.opt.RootFolderID != "torrents" || f.opt.SharedFolder != "folders" {
+		return rootID
+	}
+	f.cache.refreshMu.Lock()
+	categories := f.cache.categories
+	f.cache.refreshMu.Unlock()
+	name := f.categoryOf(torrent, categories)
+	if re := f.moviesRegexp(); re != nil && name == "movies" {
+		return yearID(yearOf(re, torrent.Name))
+	}
+	return categoryID(name)
+}
+
+// torrentsDirsChanged queues all the directories listing torrents
+func (f *Fs) torrentsDirsChanged() {
+	if f.opt.RootFolderID != "torrents" || f.opt.SharedFolder != "folders" {
+		f.dirChanged(rootID)
+		return
+	}
+	for _, category := range f.torrentsFolders() {
+		f.dirChanged(category.ID)
+	}
+}
+
+// dirChanged queues the directory with ID id if it is in the directory
+// cache, otherwise it hasn't been listed so can't have changed.
+func (f *Fs) dirChanged(id string) {
+	if dir, ok := f.dirCache.GetInv(id); ok {
+		f.queueChanged(dir)
+	}
+}
+
+// torrentRemoved flushes the folder of the torrent with id which was
+// deleted outside rclone at once, so it isn't found from the directory
+// cache any more, and queues the folder it was listed in.
+func (f *Fs) torrentRemoved(id string) {
+	dir, ok := f.dirCache.GetInv(id)
+	if !ok || f.opt.RootFolderID != "torrents" || f.opt.SharedFolder == "files" {
+		// not listed, or listed as files
+		f.torrentChanged(id)
+		return
+	}
+	f.dirCache.FlushDir(dir)
+	parent := path.Dir(dir)
+	if parent == "." {
+		parent = ""
+	}
+	f.queueChanged(parent)
+}
@@ -0,0 +1,186 @@
+package realdebrid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newNotifyTestFs makes an Fs whose flush window is short, returning
+// it and a function returning the directories notified so far
+func newNotifyTestFs(t *testing.T) (f *Fs, notified func() []string) {
+	oldFlushWindow := flushWindow
+	flushWindow = 50 * time.Millisecond
+	t.Cleanup(func() {
+		flushWindow = oldFlushWindow
+	})
+	var (
+		mu   sync.Mutex
+		dirs []string
+	)
+	f = newTestFs(t)
+	f.flushes.setNotify(func(dir string, entryType fs.EntryType) {
+		assert.Equal(t, fs.EntryDirectory, entryType)
+		mu.Lock()
+		dirs = append(dirs, dir)
+		mu.Unlock()
+	})
+	return f, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), dirs...)
+	}
+}
+
+func TestFlushesCoalesced(t *testing.T) {
+	f, notified := newNotifyTestFs(t)
+	dirs := []string{"default/Concert", "movies/Movie.2020", "shows/Show.S01"}
+	for i, dir := range dirs {
+		f.dirCache.Put(dir, fmt.Sprint("T", i))
+	}
+
+	// A burst of changes to 3 directories notifies each once
+	for i := range 100 {
+		f.torrentChanged(fmt.Sprint("T", i%len(dirs)))
+	}
+	assert.Empty(t, notified(), "notified before the end of the window")
+	require.Eventually(t, func() bool {
+		return len(notified()) == len(dirs)
+	}, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(2 * flushWindow)
+	assert.Equal(t, dirs, notified())
+	for i := range dirs {
+		_, found := f.dirCache.GetInv(fmt.Sprint("T", i))
+		assert.False(t, found, "not flushed")
+	}
+
+	// Changes after the window are notified again
+	f.dirCache.Put(dirs[0], "T0")
+	f.torrentChanged("T0")
+	f.torrentChanged("T0")
+	require.Eventually(t, func() bool {
+		return len(notified()) == len(dirs)+1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, dirs[0], notified()[len(dirs)])
+
+	// Torrents which weren't listed aren't
+	f.torrentChanged("unlisted")
+	time.Sleep(2 * flushWindow)
+	assert.Len(t, notified(), len(dirs)+1)
+}
+
+func TestFlushesCoalescedFilesMode(t *testing.T) {
+	ctx := context.Background()
+	f, notified := newNotifyTestFs(t)
+	f.opt.SharedFolder = "files"
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// Deleting many torrents at once notifies the root once per window
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, f.deleteTorrent(ctx, fmt.Sprint("T", i)))
+		}()
+	}
+	wg.Wait()
+	windows := int(time.Since(start)/flushWindow) + 1
+	require.Eventually(t, func() bool {
+		return len(notified()) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(2 * flushWindow)
+	assert.LessOrEqual(t, len(notified()), windows)
+	for _, dir := range notified() {
+		assert.Equal(t, "", dir)
+	}
+}
+
+func TestPollTorrents(t *testing.T) {
+	ctx := context.Background()
+	f, notified := newNotifyTestFs(t)
+	var (
+		mu       sync.Mutex
+		torrents = []api.Item{
+			{ID: "T2", Name: "Movie.2020", Status: "downloading"},
+			{ID: "T1", Name: "Show.S01", Status: "downloaded"},
+		}
+		older = 5 // torrents past the polled page
+	)
+	set := func(fn func()) {
+		mu.Lock()
+		defer mu.Unlock()
+		fn()
+	}
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(torrents)+older))
+		writeJSON(t, w, torrents)
+	})
+	f.cache.torrents = torrents
+	poll := func() []string {
+		// the flushed categories are found again as by the VFS
+		_, err := f.List(context.Background(), "")
+		require.NoError(t, err)
+		f.cache.state.invalid.Store(false)
+		before := len(notified())
+		f.pollTorrents(ctx)
+		time.Sleep(3 * flushWindow)
+		changed := notified()[before:]
+		slices.Sort(changed)
+		return changed
+	}
+
+	// The first poll finds nothing
+	assert.Empty(t, poll())
+	assert.False(t, f.cache.state.invalid.Load())
+	assert.Empty(t, poll())
+
+	// A torrent added notifies its category
+	set(func() {
+		torrents = append([]api.Item{{ID: "T3", Name: "Other.S02", Status: "magnet_conversion"}}, torrents...)
+	})
+	assert.Equal(t, []string{"shows"}, poll())
+	assert.True(t, f.cache.state.invalid.Load())
+
+	// as does one finishing downloading, with its folder
+	f.dirCache.Put("movies/Movie.2020", "T2")
+	set(func() {
+		torrents[1].Status = "downloaded"
+	})
+	assert.Equal(t, []string{"movies", "movies/Movie.2020"}, poll())
+
+	// A torrent removed can't be placed so notifies all the categories
+	set(func() {
+		older--
+	})
+	assert.Equal(t, []string{"default", "movies", "shows"}, poll())
+
+	// Nothing else is notified
+	set(func() {
+		torrents[0].Status = "downloading"
+	})
+	assert.Empty(t, poll())
+	assert.False(t, f.cache.state.invalid.Load())
+
+	// The torrents listed in the root notify it
+	f.opt.SharedFolder = "torrents"
+	set(func() {
+		torrents = append([]api.Item{{ID: "T4", Name: "New.2024", Status: "downloaded"}}, torrents...)
+	})
+	assert.Equal(t, []string{""}, poll())
+}
@@ -0,0 +1,87 @@
+package realdebrid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+)
+
+// The values of the options with a fixed choice, "" for the default
+var (
+	downloadModes = []string{"", "torrents", "downloads"}
+	folderModes   = []string{"", "folders", "torrents", "files"}
+	aboutPolicies = []string{"", "synthetic", "real", "off"}
+	listSorts     = []string{"", "name", "added_asc", "added_desc"}
+)
+
+// validateOptions returns an error if opt has a value the backend
+// can't work with.
+//
+// It is checked by NewFs and when the remote is configured, so a
+// remote created through the rc with a mistyped mode or regex is
+// rejected then rather than listing nothing when used.
+func validateOptions(opt *Options) error {
+	for _, check := range []struct {
+		name   string
+		value  string
+		values []string
+	}{
+		{"download_mode", opt.RootFolderID, downloadModes},
+		{"folder_mode", opt.SharedFolder, folderModes},
+		{"about_policy", opt.AboutPolicy, aboutPolicies},
+		{"list_sort", opt.ListSort, listSorts},
+	} {
+		if !slices.Contains(check.values, check.value) {
+			return fmt.Errorf("realdebrid: unknown %s %q", check.name, check.value)
+		}
+	}
+	for _, check := range []struct {
+		name  string
+		value string
+	}{
+		{"regex_shows", opt.RegexShows},
+		{"regex_movies", opt.RegexMovies},
+		{"regex_anime", opt.RegexAnime},
+		{"regex_exclude", opt.RegexExclude},
+	} {
+		if _, err := regexp.Compile(check.value); err != nil {
+			return fmt.Errorf("realdebrid: invalid %s: %w", check.name, err)
+		}
+	}
+	if opt.Categories != "" {
+		if opt.CategoriesFallback == "" || strings.Contains(opt.CategoriesFallback, "/") {
+			return fmt.Errorf("realdebrid: invalid categories_fallback %q", opt.CategoriesFallback)
+		}
+		if _, err := parseCategories(opt.Categories, opt.CategoriesFallback); err != nil {
+			return fmt.Errorf("realdebrid: invalid categories: %w", err)
+		}
+	}
+	if opt.VideoOnly && len(opt.VideoExtensions) == 0 {
+		return errors.New("realdebrid: video_only needs video_extensions")
+	}
+	if opt.MaxConcurrentStreams < 0 {
+		return fmt.Errorf("realdebrid: max_concurrent_streams %d can't be negative", opt.MaxConcurrentStreams)
+	}
+	if opt.ListPageSize < 0 || opt.ListPageSize > maxListPageSize {
+		return fmt.Errorf("realdebrid: list_page_size %d must be between 1 and %d", opt.ListPageSize, maxListPageSize)
+	}
+	return nil
+}
+
+// configure checks the options of the remote once they are set by
+// rclone config or the config/create and config/update rc calls
+func configure(ctx context.Context, name string, m configmap.Mapper, in fs.ConfigIn) (*fs.ConfigOut, error) {
+	opt := new(Options)
+	err := configstruct.Set(m, opt)
+	if err != nil {
+		return nil, err
+	}
+	return nil, validateOptions(opt)
+}
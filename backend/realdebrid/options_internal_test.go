@@ -0,0 +1,97 @@
+package realdebrid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+	"github.com/rclone/rclone/fs/config/configfile"
+	"github.com/rclone/rclone/fs/rc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateOptions(t *testing.T) {
+	for _, test := range []struct {
+		opt  Options
+		want string
+	}{
+		{Options{}, ""},
+		{Options{RootFolderID: "torrents", SharedFolder: "files", AboutPolicy: "real", RegexShows: `(?i)S\d+`}, ""},
+		{Options{RootFolderID: "torrent"}, `unknown download_mode "torrent"`},
+		{Options{SharedFolder: "flat"}, `unknown folder_mode "flat"`},
+		{Options{AboutPolicy: "fake"}, `unknown about_policy "fake"`},
+		{Options{ListSort: "added_asc"}, ""},
+		{Options{ListSort: "size"}, `unknown list_sort "size"`},
+		{Options{RegexShows: `(S[0-9]{2}`}, "invalid regex_shows"},
+		{Options{RegexMovies: `[0-9`}, "invalid regex_movies"},
+		{Options{RegexAnime: `^\[(SubsPlease`}, "invalid regex_anime"},
+		{Options{Categories: "kids=(?i)bluey,uhd=2160p", CategoriesFallback: "other"}, ""},
+		{Options{Categories: "kids=(?i)(bluey", CategoriesFallback: "other"}, `invalid categories: category "kids"`},
+		{Options{Categories: "kids=(?i)bluey", CategoriesFallback: ""}, `invalid categories_fallback ""`},
+		{Options{VideoOnly: true, VideoExtensions: defaultVideoExtensions}, ""},
+		{Options{VideoOnly: true}, "video_only needs video_extensions"},
+		{Options{ListPageSize: 5000}, ""},
+		{Options{ListPageSize: 5001}, "list_page_size 5001 must be"},
+		{Options{ListPageSize: -1}, "list_page_size -1 must be"},
+	} {
+		err := validateOptions(&test.opt)
+		if test.want == "" {
+			assert.NoError(t, err, test.opt)
+		} else {
+			assert.ErrorContains(t, err, test.want, test.opt)
+		}
+	}
+}
+
+func TestConfigCreate(t *testing.T) {
+	ctx := context.Background()
+	oldConfigPath := config.GetConfigPath()
+	t.Cleanup(func() {
+		require.NoError(t, config.SetConfigPath(oldConfigPath))
+	})
+	require.NoError(t, config.SetConfigPath(filepath.Join(t.TempDir(), "rclone.conf")))
+	configfile.Install()
+	create := rc.Calls.Get("config/create")
+	require.NotNil(t, create)
+	call := func(parameters rc.Params) error {
+		_, err := create.Fn(ctx, rc.Params{
+			"name":       "rdtest",
+			"type":       "realdebrid",
+			"parameters": parameters,
+			"opt":        rc.Params{"nonInteractive": true},
+		})
+		return err
+	}
+
+	// Invalid values are rejected
+	assert.ErrorContains(t, call(rc.Params{"api_key": "key", "folder_mode": "flat"}), `unknown folder_mode "flat"`)
+	assert.ErrorContains(t, call(rc.Params{"api_key": "key", "regex_shows": "(S[0-9]{2}"}), "invalid regex_shows")
+	assert.ErrorContains(t, call(rc.Params{"api_key": "key", "download_mode": "all"}), `unknown download_mode "all"`)
+
+	// and valid ones accepted
+	require.NoError(t, call(rc.Params{"api_key": "key", "folder_mode": "files"}))
+	assert.Equal(t, "files", config.GetValue("rdtest", "folder_mode"))
+}
+
+func TestCommandHelp(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		writeJSON(t, w, api.Response{Message: "permission_denied"})
+	})
+
+	// Every command documented is run
+	for _, command := range commandHelp {
+		_, err := f.Command(ctx, command.Name, nil, nil)
+		assert.False(t, errors.Is(err, fs.ErrorCommandNotFound), command.Name)
+	}
+	_, err := f.Command(ctx, "undocumented", nil, nil)
+	assert.ErrorIs(t, err, fs.ErrorCommandNotFound)
+}
@@ -0,0 +1,175 @@
+package realdebrid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config"
+)
+
+// categoryOverrides are the categories of torrents set with the
+// set-category command by hash, which come before those of the
+// classifier and the regexes.
+//
+// They are saved as JSON as soon as they change. Being keyed by hash
+// they are kept when a torrent is repaired, which gives it a new ID.
+type categoryOverrides struct {
+	mu     sync.Mutex
+	path   string            // of the file they are saved in
+	byHash map[string]string // replaced rather than modified as listings read it
+}
+
+var (
+	overridesMu sync.Mutex
+	overrides   = map[string]*categoryOverrides{} // by path so the remotes using one share it
+)
+
+// overridesPath returns the path of the file the category overrides of
+// the remote called name are saved in, under the rclone cache dir
+func overridesPath(name string) string {
+	return filepath.Join(config.GetCacheDir(), "realdebrid", safeFileName(name)+"-categories.json")
+}
+
+// getCategoryOverrides returns the category overrides saved in path,
+// loading them if they aren't already
+func getCategoryOverrides(path string) *categoryOverrides {
+	overridesMu.Lock()
+	defer overridesMu.Unlock()
+	o, found := overrides[path]
+	if found {
+		return o
+	}
+	o = &categoryOverrides{path: path}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &o.byHash)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		fs.Errorf(nil, "realdebrid: failed to load the category overrides: %v", err)
+	}
+	overrides[path] = o
+	return o
+}
+
+// get returns the categories set by hash, which mustn't be modified
+func (o *categoryOverrides) get() map[string]string {
+	if o == nil {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.byHash
+}
+
+// set sets the category of the torrent with hash, clearing it if
+// category is "", and saves them
+func (o *categoryOverrides) set(hash, category string) error {
+	if o == nil {
+		return errors.New("category overrides aren't available")
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	byHash := maps.Clone(o.byHash)
+	if byHash == nil {
+		byHash = make(map[string]string, 1)
+	}
+	if category == "" {
+		delete(byHash, hash)
+	} else {
+		byHash[hash] = category
+	}
+	data, err := json.MarshalIndent(byHash, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(o.path), 0700); err != nil {
+		return fmt.Errorf("failed to save the category overrides: %w", err)
+	}
+	// written aside then renamed so a crash can't leave half a file
+	tmp := o.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to save the category overrides: %w", err)
+	}
+	if err := os.Rename(tmp, o.path); err != nil {
+		return fmt.Errorf("failed to save the category overrides: %w", err)
+	}
+	o.byHash = byHash
+	return nil
+}
+
+// withOverrides returns the categories of the classifier classified
+// with the overrides of f on top
+func (f *Fs) withOverrides(classified map[string]string) map[string]string {
+	set := f.categoryOverrides.get()
+	if len(set) == 0 {
+		return classified
+	}
+	merged := make(map[string]string, len(classified)+len(set))
+	maps.Copy(merged, classified)
+	maps.Copy(merged, set)
+	return merged
+}
+
+// categoryReport is the result of the category commands
+type categoryReport struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Hash     string `json:"hash"`
+	Category string `json:"category"`           // the folder it is listed in
+	Override string `json:"override,omitempty"` // set with set-category
+}
+
+// categoryCommand runs the set-category, get-category and
+// clear-category commands on the torrent with id. category is the one
+// to set, "" otherwise.
+func (f *Fs) categoryCommand(ctx context.Context, name, id, category string) (*categoryReport, error) {
+	if f.opt.RootFolderID != "torrents" || f.opt.SharedFolder != "folders" {
+		return nil, errors.New("the categories are only used with download_mode torrents and folder_mode folders")
+	}
+	if name == "set-category" && !slices.Contains(f.categoryNames(), category) {
+		return nil, fmt.Errorf("unknown category %q, need one of %s", category, strings.Join(f.categoryNames(), ", "))
+	}
+	if err := f.checkScope(ctx, id); err != nil {
+		return nil, err
+	}
+	torrent, err := f.torrentInfo(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if torrent.TorrentHash == "" {
+		return nil, fmt.Errorf("torrent %q has no hash", id)
+	}
+	switch name {
+	case "set-category", "clear-category":
+		if err := f.categoryOverrides.set(torrent.TorrentHash, category); err != nil {
+			return nil, err
+		}
+		// its folder may have changed
+		f.relist()
+	}
+	return f.categoryOfTorrent(torrent), nil
+}
+
+// categoryOfTorrent returns the category of torrent and its override
+func (f *Fs) categoryOfTorrent(torrent *api.Item) *categoryReport {
+	f.cache.refreshMu.Lock()
+	classified := f.cache.categories
+	f.cache.refreshMu.Unlock()
+	return &categoryReport{
+		ID:       torrent.ID,
+		Name:     torrent.Name,
+		Hash:     torrent.TorrentHash,
+		Category: f.categoryOf(torrent, classified),
+		Override: f.categoryOverrides.get()[torrent.TorrentHash],
+	}
+}
@@ -0,0 +1,86 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategoryOverrides(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "realdebrid", "test-categories.json")
+	forget := func() {
+		overridesMu.Lock()
+		delete(overrides, path)
+		overridesMu.Unlock()
+	}
+	t.Cleanup(forget)
+	f := newTestFs(t)
+	f.categoryOverrides = getCategoryOverrides(path)
+	torrents := map[string]api.Item{
+		"P1": {ID: "P1", Name: "Movie.Pack.S01", TorrentHash: "h1", Status: "downloaded"},
+		"P2": {ID: "P2", Name: "Movie.Pack.S01", TorrentHash: "h1", Status: "downloaded"},
+	}
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if torrent, ok := torrents[r.URL.Path[len("/torrents/info/"):]]; ok {
+			writeJSON(t, w, torrent)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(t, w, api.Response{ErrorName: "unknown_ressource", ErrorCode: 7})
+	})
+	f.cache.torrents = []api.Item{torrents["P1"], {ID: "M1", Name: "Movie.2021", Status: "downloaded"}}
+	command := func(name string, arg ...string) (*categoryReport, error) {
+		out, err := f.Command(ctx, name, arg, nil)
+		if err != nil {
+			return nil, err
+		}
+		return out.(*categoryReport), nil
+	}
+	list := func(dir string) []string {
+		entries, err := f.List(ctx, dir)
+		require.NoError(t, err)
+		return entryNames(entries)
+	}
+	assert.Equal(t, []string{"shows/Movie.Pack.S01"}, list("shows"))
+
+	// The category set comes before the regexes
+	report, err := command("set-category", "P1", "movies")
+	require.NoError(t, err)
+	assert.Equal(t, &categoryReport{ID: "P1", Name: "Movie.Pack.S01", Hash: "h1", Category: "movies", Override: "movies"}, report)
+	assert.Empty(t, list("shows"))
+	assert.ElementsMatch(t, []string{"movies/Movie.Pack.S01", "movies/Movie.2021"}, list("movies"))
+	assert.Equal(t, categoryID("movies"), f.torrentsDir(&f.cache.torrents[0]))
+
+	// and is kept across restarts and redownloads, being saved by hash
+	forget()
+	f.categoryOverrides = getCategoryOverrides(path)
+	f.cache.torrents[0] = torrents["P2"]
+	report, err = command("get-category", "P2")
+	require.NoError(t, err)
+	assert.Equal(t, "movies", report.Category)
+	assert.Equal(t, "movies", report.Override)
+	assert.ElementsMatch(t, []string{"movies/Movie.Pack.S01", "movies/Movie.2021"}, list("movies"))
+
+	// Only the categories of the remote can be set
+	_, err = command("set-category", "P2", "music")
+	assert.ErrorContains(t, err, `unknown category "music", need one of shows, movies, default`)
+	_, err = command("set-category", "P2")
+	assert.ErrorContains(t, err, "need exactly 2 arguments")
+	_, err = command("get-category", "GONE")
+	assert.ErrorContains(t, err, "unknown_ressource")
+
+	// Once cleared the regexes are used again
+	report, err = command("clear-category", "P2")
+	require.NoError(t, err)
+	assert.Equal(t, "shows", report.Category)
+	assert.Empty(t, report.Override)
+	assert.Equal(t, []string{"shows/Movie.Pack.S01"}, list("shows"))
+	forget()
+	assert.Empty(t, getCategoryOverrides(path).get())
+}
@@ -0,0 +1,48 @@
+package realdebrid
+
+import (
+	"time"
+
+	"github.com/rclone/rclone/lib/pacer"
+)
+
+// rampDown sets how slowly the sleep shortens after being rate limited,
+// each call which works taking 1/2^rampDown off it
+const rampDown = 4
+
+// calculator paces the API calls below the rate limit of Real-Debrid.
+//
+// A call rate limited with a Retry-After header sleeps for it,
+// otherwise each retry doubles the sleep, from at least retrySleep, up
+// to maxSleep. Once the calls work again the sleep shortens slowly
+// back to minSleep so a scan carries on just below the limit instead
+// of hitting it again.
+type calculator struct {
+	minSleep   time.Duration
+	maxSleep   time.Duration
+	retrySleep time.Duration
+}
+
+// newCalculator makes a calculator sleeping between minSleep and
+// maxSleep, and at least retrySleep before a retry
+func newCalculator(minSleep, maxSleep, retrySleep time.Duration) *calculator {
+	maxSleep = max(maxSleep, minSleep)
+	return &calculator{
+		minSleep:   minSleep,
+		maxSleep:   maxSleep,
+		retrySleep: min(max(retrySleep, minSleep), maxSleep),
+	}
+}
+
+// Calculate takes the current Pacer state and returns the sleep time
+// after which the next call will be done.
+func (c *calculator) Calculate(state pacer.State) time.Duration {
+	if retryAfter, ok := pacer.IsRetryAfter(state.LastError); ok {
+		// the window given by Real-Debrid isn't capped by maxSleep
+		return max(retryAfter, c.minSleep)
+	}
+	if state.ConsecutiveRetries > 0 {
+		return min(max(2*state.SleepTime, c.retrySleep), c.maxSleep)
+	}
+	return max(min(state.SleepTime-state.SleepTime>>rampDown, c.maxSleep), c.minSleep)
+}
@@ -0,0 +1,119 @@
+package realdebrid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculator(t *testing.T) {
+	c := newCalculator(250*time.Millisecond, 3*time.Second, 0)
+	errLimited := errors.New("too_many_requests")
+
+	// Retries double the sleep up to maxSleep
+	assert.Equal(t, 500*time.Millisecond, c.Calculate(pacer.State{SleepTime: 250 * time.Millisecond, ConsecutiveRetries: 1, LastError: errLimited}))
+	assert.Equal(t, 3*time.Second, c.Calculate(pacer.State{SleepTime: 2 * time.Second, ConsecutiveRetries: 4, LastError: errLimited}))
+
+	// unless Real-Debrid gives the time to wait
+	assert.Equal(t, 10*time.Second, c.Calculate(pacer.State{SleepTime: 250 * time.Millisecond, ConsecutiveRetries: 1, LastError: pacer.RetryAfterError(errLimited, 10*time.Second)}))
+	assert.Equal(t, 250*time.Millisecond, c.Calculate(pacer.State{SleepTime: time.Second, ConsecutiveRetries: 1, LastError: pacer.RetryAfterError(errLimited, time.Millisecond)}))
+
+	// Retries sleep at least the retry delay
+	c = newCalculator(250*time.Millisecond, 3*time.Second, time.Second)
+	assert.Equal(t, time.Second, c.Calculate(pacer.State{SleepTime: 250 * time.Millisecond, ConsecutiveRetries: 1, LastError: errLimited}))
+	assert.Equal(t, 2*time.Second, c.Calculate(pacer.State{SleepTime: time.Second, ConsecutiveRetries: 2, LastError: errLimited}))
+	assert.Equal(t, 250*time.Millisecond, c.Calculate(pacer.State{SleepTime: 250 * time.Millisecond}))
+	c = newCalculator(250*time.Millisecond, 3*time.Second, 0)
+
+	// Then it shortens slowly back to minSleep
+	sleep, calls := 10*time.Second, 0
+	for sleep > 250*time.Millisecond {
+		sleep = c.Calculate(pacer.State{SleepTime: sleep})
+		if calls == 0 {
+			assert.Equal(t, 3*time.Second, sleep)
+		}
+		calls++
+	}
+	assert.Equal(t, 250*time.Millisecond, sleep)
+	assert.Greater(t, calls, 30)
+}
+
+func TestRateLimitedBurst(t *testing.T) {
+	ctx, ci := fs.AddConfig(context.Background())
+	ci.LowLevelRetries = 5
+	const (
+		window = 100 * time.Millisecond
+		limit  = 10 // requests allowed per window
+	)
+	var (
+		mu          sync.Mutex
+		windowStart time.Time
+		inWindow    int
+		limited     atomic.Int64
+	)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		if time.Since(windowStart) > window {
+			windowStart, inWindow = time.Now(), 0
+		}
+		inWindow++
+		over := inWindow > limit
+		mu.Unlock()
+		if over {
+			limited.Add(1)
+			w.WriteHeader(http.StatusTooManyRequests)
+			writeJSON(t, w, api.Response{Message: "too_many_requests"})
+			return
+		}
+		writeJSON(t, w, api.Item{ID: r.URL.Path})
+	})
+	c.pacer = fs.NewPacer(ctx, newCalculator(time.Millisecond, 50*time.Millisecond, 0))
+
+	// A burst of calls well over the limit all work in the end
+	var wg sync.WaitGroup
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.TorrentInfo(ctx, "T1")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.NotZero(t, limited.Load())
+}
+
+func TestRetryAfter(t *testing.T) {
+	ctx := context.Background()
+	var calls atomic.Int64
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		writeJSON(t, w, api.Item{ID: "T1"})
+	})
+	c.pacer = fs.NewPacer(ctx, newCalculator(time.Millisecond, 50*time.Millisecond, 0))
+
+	start := time.Now()
+	_, err := c.TorrentInfo(ctx, "T1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), calls.Load())
+
+	// The calls after it wait for the time given by Real-Debrid
+	_, err = c.TorrentInfo(ctx, "T1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), calls.Load())
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,60 @@
+package realdebrid
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+)
+
+const (
+	listPageSize      = 2500  // torrents read per page by a complete refresh by default
+	maxListPageSize   = 5000  // most items Real-Debrid returns per page
+	minListPageSize   = 100   // smallest page size a failing page is read again with
+	maxListedTorrents = 50000 // hardcoded limit, change that at your own risk
+	listPageSleep     = time.Second
+)
+
+// listTorrentPages reads the total torrents by pages of list_page_size
+// for a complete refresh.
+//
+// Real-Debrid fails big pages with a server error when they take too
+// long to make, so a page failing with one is read again with half
+// the page size, down to minListPageSize. The pages are read by offset
+// so they follow on from the torrents read whatever the size of the
+// pages before.
+func (f *Fs) listTorrentPages(ctx context.Context, total int) (torrents []api.Item, err error) {
+	pageSize := f.opt.ListPageSize
+	if pageSize <= 0 {
+		pageSize = listPageSize
+	}
+	total = min(total, maxListedTorrents)
+	for first := true; len(torrents) < total; first = false {
+		if !first {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(listPageSleep):
+			}
+		}
+		fs.Debugf(f, "Reading the torrents from %d by pages of %d", len(torrents), pageSize)
+		read := len(torrents)
+		torrents, _, err = f.client.AppendTorrents(ctx, torrents, pageSize)
+		if err != nil {
+			if errors.Is(err, errServerError) && pageSize > minListPageSize {
+				pageSize = max(pageSize/2, minListPageSize)
+				fs.Logf(f, "Reading the torrents again by pages of %d: %v", pageSize, err)
+				continue
+			}
+			return nil, err
+		}
+		if len(torrents) == read {
+			// some were deleted while listing
+			break
+		}
+		fs.Debugf(f, "Read %d of %d torrents", len(torrents), total)
+	}
+	return torrents, nil
+}
@@ -0,0 +1,69 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListTorrentPages(t *testing.T) {
+	ctx := context.Background()
+	var torrents []api.Item
+	for i := range 250 {
+		torrents = append(torrents, api.Item{ID: strconv.Itoa(i), Name: "Torrent." + strconv.Itoa(i), Status: "downloaded"})
+	}
+	var (
+		mu       sync.Mutex
+		pages    []string // offset+limit of the pages read
+		maxLimit = 100    // bigger pages fail
+	)
+	f := newTestFs(t)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		limit, _ := strconv.Atoi(query.Get("limit"))
+		offset, _ := strconv.Atoi(query.Get("offset"))
+		mu.Lock()
+		defer mu.Unlock()
+		if limit > 1 {
+			page := query.Get("offset") + "+" + query.Get("limit")
+			if len(pages) == 0 || pages[len(pages)-1] != page {
+				pages = append(pages, page)
+			}
+		}
+		if limit > maxLimit {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(torrents)))
+		writeJSON(t, w, torrents[min(offset, len(torrents)):min(offset+limit, len(torrents))])
+	})
+	f.client.pacer.SetRetries(2)
+	f.cache.startup_cached_api_fetch = true
+	f.cache.state.Invalidate()
+	f.opt.ListPageSize = 200
+
+	// The failing page is read again by smaller pages from where the
+	// listing got to
+	require.NoError(t, f.refreshTorrents(ctx))
+	assert.Equal(t, []string{"0+200", "0+100", "100+100", "200+100"}, pages)
+	assert.Equal(t, itemIDs(torrents), itemIDs(f.cache.torrents))
+
+	// but not below the smallest page size
+	mu.Lock()
+	pages, maxLimit = nil, 50
+	torrents = append([]api.Item{{ID: "new", Name: "New", Status: "downloaded"}}, torrents...)
+	mu.Unlock()
+	f.opt.ListPageSize = minListPageSize
+	f.cache.torrents = nil
+	f.cache.state.Invalidate()
+	err := f.refreshTorrents(ctx)
+	assert.ErrorIs(t, err, errServerError)
+	assert.Equal(t, []string{"0+100"}, pages)
+	assert.Empty(t, f.cache.torrents)
+}
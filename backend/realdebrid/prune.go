@@ -0,0 +1,136 @@
+package realdebrid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+)
+
+// pruneReport is the result of pruning the downloads
+type pruneReport struct {
+	MaxAge     string           `json:"maxAge"`
+	DryRun     bool             `json:"dryRun"`
+	Checked    int              `json:"checked"`    // downloads listed
+	Referenced int              `json:"referenced"` // old downloads kept as links of current torrents
+	Pruned     []prunedDownload `json:"pruned"`     // old downloads deleted, or which would be with dry run
+	Errors     []string         `json:"errors,omitempty"`
+}
+
+// prunedDownload is a download deleted by pruneDownloads
+type prunedDownload struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Generated string `json:"generated"`
+}
+
+// pruneDownloads deletes the downloads generated more than maxAge ago
+// which aren't links of torrents, only reporting them if dryRun is
+// set.
+//
+// The deletes go through the pacer one at a time so they don't use up
+// the rate limit of the account.
+func (f *Fs) pruneDownloads(ctx context.Context, torrents []api.Item, maxAge time.Duration, dryRun bool) (report *pruneReport, err error) {
+	report = &pruneReport{
+		MaxAge: fs.Duration(maxAge).String(),
+		DryRun: dryRun || f.opt.Simulate,
+		Pruned: []prunedDownload{},
+	}
+	links := make(map[string]struct{})
+	for _, torrent := range torrents {
+		for _, link := range torrent.Links {
+			links[link] = struct{}{}
+		}
+	}
+	downloads, err := f.client.ListAllDownloads(ctx, 5000, 20)
+	if err != nil {
+		return nil, err
+	}
+	report.Checked = len(downloads)
+	cutoff := time.Now().Add(-maxAge)
+	for _, item := range downloads {
+		generated, err := time.Parse(time.RFC3339, item.Generated)
+		if err != nil || !generated.Before(cutoff) {
+			continue
+		}
+		if _, found := links[item.OriginalLink]; found {
+			// still needed to list the torrent
+			report.Referenced++
+			continue
+		}
+		if !report.DryRun {
+			err = f.client.DeleteDownload(ctx, item.ID)
+			if err != nil {
+				fs.Errorf(f, "%v", f.wrapErr("prune", item.Name, "", err))
+				report.Errors = append(report.Errors, err.Error())
+				continue
+			}
+			f.cache.forget(item.ID, "")
+		}
+		report.Pruned = append(report.Pruned, prunedDownload{ID: item.ID, Name: item.Name, Generated: item.Generated})
+	}
+	if !report.DryRun && len(report.Pruned) > 0 && f.opt.RootFolderID != "torrents" {
+		// the downloads are listed in the root
+		f.queueChanged("")
+	}
+	if ctx.Err() != nil {
+		return report, ctx.Err()
+	}
+	return report, nil
+}
+
+// autoPruneDownloads prunes the downloads older than downloads_max_age
+// after a complete refresh of torrents if auto_prune_downloads is set.
+//
+// The downloads are pruned in the background so the listing which
+// refreshed the torrents doesn't wait for it. It is skipped if the Fs
+// sharing the cache is already pruning.
+func (f *Fs) autoPruneDownloads(ctx context.Context, torrents []api.Item) {
+	if !f.opt.AutoPruneDownloads || f.opt.DownloadsMaxAge <= 0 {
+		return
+	}
+	maxAge := time.Duration(f.opt.DownloadsMaxAge)
+	f.goBackground(context.WithoutCancel(ctx), func(ctx context.Context) {
+		if !f.cache.pruning.CompareAndSwap(false, true) {
+			return
+		}
+		defer f.cache.pruning.Store(false)
+		report, err := f.pruneDownloads(ctx, torrents, maxAge, false)
+		if err != nil {
+			fs.Errorf(f, "%v", f.wrapErr("prune", "", "", err))
+			return
+		}
+		fs.Infof(f, "Pruned %d of %d downloads older than %s", len(report.Pruned), report.Checked, report.MaxAge)
+	})
+}
+
+// pruneCommand runs the prune-downloads backend command
+func (f *Fs) pruneCommand(ctx context.Context, opt map[string]string) (out any, err error) {
+	maxAge := time.Duration(f.opt.DownloadsMaxAge)
+	if value, ok := opt["max-age"]; ok {
+		var d fs.Duration
+		if err = d.Set(value); err != nil {
+			return nil, fmt.Errorf("invalid max-age value %q: %w", value, err)
+		}
+		maxAge = time.Duration(d)
+	}
+	if maxAge <= 0 {
+		return nil, errors.New("need -o max-age=duration or downloads_max_age set")
+	}
+	dryRun := false
+	if value, ok := opt["dry-run"]; ok {
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dry-run value %q: %w", value, err)
+		}
+	}
+	torrents, err := f.ensureTorrentsListed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return f.pruneDownloads(ctx, torrents, maxAge, dryRun)
+}
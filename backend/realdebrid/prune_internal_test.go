@@ -0,0 +1,134 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPruneTestFs makes an Fs with a torrent linking to l1 and downloads
+// of l1, l2 and l3 generated at the ages given, returning it and the
+// IDs of the downloads deleted
+func newPruneTestFs(t *testing.T) (f *Fs, deleted func() []string) {
+	var (
+		mu      sync.Mutex
+		deletes []string
+	)
+	generated := func(age time.Duration) string {
+		return time.Now().Add(-age).UTC().Format("2006-01-02T15:04:05.000Z")
+	}
+	torrents := []api.Item{{ID: "T1", Name: "Movie.2020", Status: "downloaded", Links: []string{"l1"}}}
+	downloads := []api.Item{
+		{ID: "d1", Name: "movie.mkv", OriginalLink: "l1", Generated: generated(60 * 24 * time.Hour)},
+		{ID: "d2", Name: "old.mkv", OriginalLink: "l2", Generated: generated(60 * 24 * time.Hour)},
+		{ID: "d3", Name: "new.mkv", OriginalLink: "l3", Generated: generated(time.Hour)},
+		{ID: "d4", Name: "unknown.mkv", OriginalLink: "l4"},
+	}
+	f = newTestFs(t)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == "DELETE" {
+			deletes = append(deletes, path.Base(r.URL.Path))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		items := torrents
+		if r.URL.Path == "/downloads" {
+			items = downloads
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(items)))
+		writeJSON(t, w, items)
+	})
+	f.cache.torrents = torrents
+	f.cache.cached = downloads
+	return f, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), deletes...)
+	}
+}
+
+func TestPruneDownloads(t *testing.T) {
+	ctx := context.Background()
+	f, deleted := newPruneTestFs(t)
+
+	// Dry run only reports the old downloads not linked to a torrent
+	out, err := f.Command(ctx, "prune-downloads", nil, map[string]string{"max-age": "30d", "dry-run": "true"})
+	require.NoError(t, err)
+	report := out.(*pruneReport)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, fs.Duration(30*24*time.Hour).String(), report.MaxAge)
+	assert.Equal(t, 4, report.Checked)
+	assert.Equal(t, 1, report.Referenced)
+	require.Len(t, report.Pruned, 1)
+	assert.Equal(t, "d2", report.Pruned[0].ID)
+	assert.Empty(t, deleted())
+	assert.Len(t, f.cache.cached, 4)
+
+	// and without it they are deleted
+	f.opt.DownloadsMaxAge = fs.Duration(30 * 24 * time.Hour)
+	out, err = f.Command(ctx, "prune-downloads", nil, nil)
+	require.NoError(t, err)
+	report = out.(*pruneReport)
+	assert.False(t, report.DryRun)
+	require.Len(t, report.Pruned, 1)
+	assert.Equal(t, []string{"d2"}, deleted())
+	assert.Equal(t, []string{"d1", "d3", "d4"}, itemIDs(f.cache.cached))
+
+	// Bad options
+	f.opt.DownloadsMaxAge = 0
+	for _, opt := range []map[string]string{
+		nil,
+		{"max-age": "soon"},
+		{"max-age": "1d", "dry-run": "maybe"},
+	} {
+		_, err = f.Command(ctx, "prune-downloads", nil, opt)
+		checkOpError(t, err, "prune", "", "", "")
+	}
+}
+
+func TestAutoPruneDownloads(t *testing.T) {
+	ctx := context.Background()
+	oldDumpDir := dumpDir
+	dumpDir = t.TempDir()
+	t.Cleanup(func() {
+		dumpDir = oldDumpDir
+	})
+	f, deleted := newPruneTestFs(t)
+	f.cache.startup_cached_api_fetch = true
+	f.opt.DownloadsMaxAge = fs.Duration(30 * 24 * time.Hour)
+
+	// Not pruned unless asked for
+	f.cache.state.Invalidate()
+	require.NoError(t, f.refreshTorrents(ctx))
+	assert.Empty(t, deleted())
+
+	// then after a complete refresh
+	f.opt.AutoPruneDownloads = true
+	require.NoError(t, f.refreshTorrents(ctx))
+	assert.Empty(t, deleted(), "torrents unchanged")
+	f.cache.state.Invalidate()
+	require.NoError(t, f.refreshTorrents(ctx))
+	require.Eventually(t, func() bool {
+		return slices.Equal([]string{"d2"}, deleted())
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// itemIDs returns the IDs of items
+func itemIDs(items []api.Item) (ids []string) {
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
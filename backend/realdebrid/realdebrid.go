@@ -11,42 +11,19 @@ stringNeedsEscaping = []rune{
 maxFileLength = 255
 canWriteUnnormalized = true
 canReadUnnormalized   = true
-canReadRenormalized   = false
+canReadRenormalized   = false (true with unicode_normalization)
 canStream = true
 */
 
 import (
-	"context"
-	"encoding/gob"
-	"encoding/json"
-	"errors"
-	"fmt"
-	"io"
-	"math"
-	"net/http"
-	"net/url"
-	"os"
-	"path"
-	"regexp"
-	"strconv"
-	"strings"
-	"sync"
 	"time"
 
-	"github.com/rclone/rclone/backend/realdebrid/api"
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/config"
-	"github.com/rclone/rclone/fs/config/configmap"
-	"github.com/rclone/rclone/fs/config/configstruct"
 	"github.com/rclone/rclone/fs/config/obscure"
-	"github.com/rclone/rclone/fs/fserrors"
-	"github.com/rclone/rclone/fs/fshttp"
-	"github.com/rclone/rclone/fs/hash"
-	"github.com/rclone/rclone/lib/dircache"
+	"github.com/rclone/rclone/fs/rc"
 	"github.com/rclone/rclone/lib/encoder"
 	"github.com/rclone/rclone/lib/oauthutil"
-	"github.com/rclone/rclone/lib/pacer"
-	"github.com/rclone/rclone/lib/rest"
 )
 
 const (
@@ -54,11 +31,12 @@ const (
 	rcloneEncryptedClientSecret = "B5YIvQoRIhcpAYs8HYeyjb9gK-ftmZEbqdh_gNfc4RgO9Q"
 	minSleep                    = 250 * time.Millisecond // chnaged from value 10 to 250, should be aligned to RD's limit of 240 per minute (so 4 per second)
 	maxSleep                    = 3 * time.Second
-	decayConstant               = 2   // bigger for slower decay, exponential
 	rootID                      = "0" // ID of root folder is always this
-	rootURL                     = "https://api.real-debrid.com/rest/1.0"
 )
 
+// rootURL is the base of the API - a var so the tests can replace it
+var rootURL = "https://api.real-debrid.com/rest/1.0"
+
 // Globals
 /*
 var (
@@ -88,23 +66,14 @@ var (
 	}
 )
 
-// Global lists of recieved content.
-// Realdebrid content is provided in pages with 100 items per page.
-// To limit api calls all pages are stored here and are only updated on changes in the total length
-var cached []api.Item
-var torrents []api.Item
-var torrentswf []api.Item
-var broken_torrents []string
-var lastcheck int64 = time.Now().Unix()
-var interval int64 = 15 * 60              // todo find a way to align to jellygrail python check
-var startup_cached_api_fetch bool = false // fetch the full /downloads API result already in this rclone session ?
-
 // Register with Fs
 func init() {
 	fs.Register(&fs.RegInfo{
 		Name:        "realdebrid",
 		Description: "real-debrid.com",
 		NewFs:       NewFs,
+		Config:      configure,
+		CommandHelp: commandHelp,
 		Options: []fs.Option{{
 			Name:    "api_key",
 			Help:    `please provide your RealDebrid API key.`,
@@ -114,11 +83,30 @@ func init() {
 			Help:     `please choose which RealDebrid directory to serve: For the /downloads page, type "downloads". For the /torrents page, type "torrents". Default: "torrents"`,
 			Advanced: true,
 			Default:  "torrents",
+			Examples: []fs.OptionExample{{
+				Value: "torrents",
+				Help:  "Serve the torrents",
+			}, {
+				Value: "downloads",
+				Help:  "Serve the downloads page",
+			}},
+			Exclusive: true,
 		}, {
 			Name:     "folder_mode",
-			Help:     `please choose wether files should be grouped in torrent folders, or all files should be displayed in the root directory. For all files in root type "files", for folder structure type "folders". Default: "folders"`,
+			Help:     `please choose wether files should be grouped in torrent folders, or all files should be displayed in the root directory. For all files in root type "files", for folder structure type "folders", for torrent folders in root without the shows/movies/default folders type "torrents". Default: "folders"`,
 			Advanced: true,
 			Default:  "folders",
+			Examples: []fs.OptionExample{{
+				Value: "folders",
+				Help:  "Torrent folders grouped in the shows, movies and default folders",
+			}, {
+				Value: "torrents",
+				Help:  "Torrent folders in the root",
+			}, {
+				Value: "files",
+				Help:  "The files of all the torrents in the root",
+			}},
+			Exclusive: true,
 		}, {
 			Name:     "regex_shows",
 			Help:     `please define the regex definition that will determine if a torrent should be classified as a show. Default: "(?i)(S[0-9]{2}|SEASON|COMPLETE|[^457a-z\W\s]-[0-9]+)"`,
@@ -130,1713 +118,642 @@ func init() {
 			Advanced: true,
 			Default:  `(?i)(19|20)([0-9]{2} ?\.?)`,
 		}, {
-			Name:     config.ConfigEncoding,
-			Help:     config.ConfigEncodingHelp,
-			Advanced: true,
-			// Encode invalid UTF-8 bytes as json doesn't handle them properly.
-			Default: (encoder.Display |
-				encoder.EncodeBackSlash |
-				encoder.EncodeDoubleQuote |
-				encoder.EncodeInvalidUtf8),
-		}},
-	})
-}
+			Name: "regex_anime",
+			Help: `The regex of the torrents listed in an anime folder in folder_mode folders.
 
-// Options defines the configuration for this backend
-type Options struct {
-	RegexShows   string               `config:"regex_shows"`
-	RegexMovies  string               `config:"regex_movies"`
-	SharedFolder string               `config:"folder_mode"`
-	RootFolderID string               `config:"download_mode"`
-	APIKey       string               `config:"api_key"`
-	Enc          encoder.MultiEncoder `config:"encoding"`
-}
-
-// Fs represents a remote cloud storage system
-type Fs struct {
-	name         string             // name of this remote
-	root         string             // the path we are working on
-	opt          Options            // parsed options
-	features     *fs.Features       // optional features
-	srv          *rest.Client       // the connection to the server
-	dirCache     *dircache.DirCache // Map of directory path to directory id
-	pacer        *fs.Pacer          // pacer for API calls
-	tokenRenewer *oauthutil.Renew   // renew the token on expiry
-
-	mu                sync.Mutex
-	torrentStatuses   map[string]string
-	torrentStatusBase bool
-}
+The torrents matching it are in the anime folder whether they match
+regex_shows or regex_movies or not, like
 
-// Object describes a file
-type Object struct {
-	fs          *Fs       // what this object is part of
-	remote      string    // The remote path
-	hasMetaData bool      // metadata is present and correct
-	size        int64     // size of the object
-	modTime     time.Time // modification time of the object
-	id          string    // ID of the object
-	ParentID    string    // ID of parent directory
-	mimeType    string    // Mime type of object
-	url         string    // URL to download file
-	TorrentHash string    // Torrent Hash
-	OriginalUrl string    // Original link
-}
+    (?i)^\[(SubsPlease|Erai-raws|HorribleSubs)\]
 
-// ------------------------------------------------------------
-
-// Name of the remote (as passed into NewFs)
-func (f *Fs) Name() string {
-	return f.name
-}
-
-// Root of the remote (as passed into NewFs)
-func (f *Fs) Root() string {
-	return f.root
-}
+There is no anime folder when it is empty.`,
+			Advanced: true,
+			Default:  "",
+		}, {
+			Name: "movies_by_year",
+			Help: `List the movies in a folder for each year in folder_mode folders.
+
+The movies are in movies/<year>/<torrent>, the year being the first four
+digits of the first capture group of regex_movies, or of its whole match
+if it hasn't any, so the default regex finds it. The movies without a
+year are in movies/unknown. With categories this is done for the
+category named movies.`,
+			Advanced: true,
+			Default:  false,
+		}, {
+			Name: "categories",
+			Help: `The folders the torrents are grouped in in folder_mode folders, as name=regex.
 
-// String converts this Fs to a string
-func (f *Fs) String() string {
-	return fmt.Sprintf("realdebrid root '%s'", f.root)
-}
+The pairs are separated by commas or newlines, like
 
-// Features returns the optional features of this Fs
-func (f *Fs) Features() *fs.Features {
-	return f.features
-}
+    kids=(?i)(bluey|paw.patrol),uhd=(?i)2160p
 
-// parsePath parses a realdebrid 'url'
-func parsePath(path string) (root string) {
-	root = strings.Trim(path, "/")
-	return
-}
+Each torrent is in the first folder whose regex matches its name, or in
+categories_fallback if none does. When set they replace the shows,
+movies and default folders, and regex_shows, regex_movies and
+regex_anime aren't used. A classify_command or classify_url must return these names.`,
+			Advanced: true,
+			Default:  "",
+		}, {
+			Name:     "categories_fallback",
+			Help:     `The folder of the torrents matching none of the categories.`,
+			Advanced: true,
+			Default:  "default",
+		}, {
+			Name: "regex_exclude",
+			Help: `The regex of the torrents to leave out of all the listings.
 
-// retryErrorCodes is a slice of error codes that we will retry
-var retryErrorCodes = []int{
-	429, // Too Many Requests.
-	500, // Internal Server Error
-	502, // Bad Gateway
-	504, // Gateway Timeout
-	509, // Bandwidth Limit Exceeded
-}
+The torrents whose name matches it, like those kept only to seed, are
+in no folder in any folder_mode, their links are never unrestricted and
+they are neither repaired nor deleted by the backend, so purging a
+category doesn't touch them.
 
-func removeDuplicates(slice []api.Item) []api.Item {
-	seen := make(map[string]bool)
-	result := []api.Item{}
+Nothing is left out when it is empty.`,
+			Advanced: true,
+			Default:  "",
+		}, {
+			Name: "min_torrent_size",
+			Help: `Hide the torrents whose selected files are smaller than this in total.
 
-	for _, item := range slice {
-		if _, found := seen[item.OriginalLink]; !found {
-			seen[item.OriginalLink] = true
-			result = append(result, item)
-		}
-	}
+Fake torrents of many tiny files clutter the categories and use up
+unrestricts when listed. The hidden torrents aren't listed or read,
+but are still shown in hidden of the status file so they can be
+reviewed. Torrents whose size isn't known yet, as they are still being
+added, aren't hidden.
 
-	return result
-}
+Set to 0 to show all the torrents.`,
+			Advanced: true,
+			Default:  fs.SizeSuffix(0),
+		}, {
+			Name: "nested_folders",
+			Help: `List the files of the torrents in the folders they have in the torrent.
 
-// torrent duplicaiton can happen on rare cases (pages are shifted so sequential requests get page-edged torrents several times) but it's taken care of with x-total changed that refreshes torrents
-// but for torrents w details, it's better to do some cleaning in the bottom of the list
-/* done directly in torrents alignements
-func removeTorrentsDuplicates(slice []api.Item) []api.Item {
-	seen := make(map[string]bool)
-	result := []api.Item{}
-
-	for _, item := range slice {
-		if _, found := seen[item.ID]; !found {
-			seen[item.ID] = true
-			result = append(result, item)
-		}
-	}
+A season pack with Season 01/E01.mkv and Subs/E01.srt then has the
+Season 01 and Subs folders in its torrent folder rather than all its
+files at the top of it. Finding the folders doesn't unrestrict the
+links, and listing one only unrestricts the links of the files in it.
 
-	return result
-}
-*/
+The torrents whose files can't be matched to their links, as they were
+packed into an archive, are listed as before.`,
+			Advanced: true,
+			Default:  false,
+		}, {
+			Name: "video_only",
+			Help: `Only list the files of the torrents with one of video_extensions.
+
+The .nfo, .txt, .exe, screenshots and proof archives many torrents
+have clutter the media servers and use up unrestricts when listed. The
+files left out are never unrestricted if they can be named from the
+torrent file info. Deleting the torrent folder still deletes the whole
+torrent.`,
+			Advanced: true,
+			Default:  false,
+		}, {
+			Name: "video_extensions",
+			Help: `The extensions of the files listed with video_only, comma separated.
 
-// shouldRetry returns a boolean as to whether this resp and err
-// deserve to be retried.  It returns the err as a convenience
-func shouldRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
-	if fserrors.ContextError(ctx, &err) {
-		return false, err
-	}
-	return fserrors.ShouldRetry(err) || fserrors.ShouldRetryHTTP(resp, retryErrorCodes), err
-}
+They are those of the videos, the audio and the subtitles by default.`,
+			Advanced: true,
+			Default:  defaultVideoExtensions,
+		}, {
+			Name: "flatten_single_file",
+			Help: `List the torrents of a single file as that file in folder_mode folders.
+
+Most movie torrents hold a single video file, so rather than in a
+folder of its own it is listed directly in the category folder, named
+from the file. A file with the same name as another entry has the ID
+of its torrent added before its extension. Removing it deletes the
+torrent. Torrents of several files keep their folder.`,
+			Advanced: true,
+			Default:  false,
+		}, {
+			Name: "prewarm_dircache",
+			Help: `Find all the category and torrent folders when the remote starts.
+
+The folders are otherwise found one at a time as they are first
+walked, which slows the first scan of a mount. With this they are all
+read from the list of torrents once it is loaded, without fetching the
+details of any torrent. Leave it off for a huge account where listing
+the torrents at start up takes too long.`,
+			Advanced: true,
+			Default:  false,
+		}, {
+			Name: "progressive_listing",
+			Help: `Pass the files of a torrent folder on as each of them is ready.
+
+The first listing of a big torrent folder unrestricts its links one
+by one. With this the listings which can take their entries in parts,
+such as rclone lsf, get each file as soon as its link is unrestricted
+rather than once all of them are. The entries are sorted by the
+caller.`,
+			Advanced: true,
+			Default:  false,
+		}, {
+			Name: "list_sort",
+			Help: `The order the entries of the directories are listed in.
+
+The API returns the torrents in an order which changes between
+refreshes. They are sorted by when they were added, newest first as on
+the Real-Debrid website, by default. The entries added at the same
+time, like the files of a torrent, are sorted by name. The category
+folders are always listed as shows, movies then default.`,
+			Advanced: true,
+			Default:  "added_desc",
+			Examples: []fs.OptionExample{{
+				Value: "added_desc",
+				Help:  "Newest first",
+			}, {
+				Value: "added_asc",
+				Help:  "Oldest first",
+			}, {
+				Value: "name",
+				Help:  "By name",
+			}},
+			Exclusive: true,
+		}, {
+			Name: "purge_categories",
+			Help: `Allow purging the category folders, like shows, movies and default.
 
-// readMetaDataForPath reads the metadata from the path
-func (f *Fs) readMetaDataForPath(ctx context.Context, path string, directoriesOnly bool, filesOnly bool) (info *api.Item, err error) {
-	// defer fs.Trace(f, "path=%q", path)("info=%+v, err=%v", &info, &err)
-	//fmt.Printf("stating '%s'", path)
-	leaf, directoryID, err := f.dirCache.FindPath(ctx, path, false)
-	if err != nil {
-		if err == fs.ErrorDirNotFound {
-			return nil, fs.ErrorObjectNotFound
-		}
-		return nil, err
-	}
+Purging a category folder deletes all the torrents in it, which is
+refused without this. The remotes rooted in a category, like
+realdebrid:shows, only see the torrents of that category in About, the
+status file and the backend commands.`,
+			Advanced: true,
+			Default:  false,
+		}, {
+			Name: "share_cache",
+			Help: `Share the torrents and links caches between remotes using the same api_key.
 
-	lcLeaf := strings.ToLower(leaf)
-	//fmt.Printf("...with listAll\n")
-	_, found, err := f.listAll(ctx, directoryID, directoriesOnly, filesOnly, func(item *api.Item) bool {
-		if strings.ToLower(item.Name) == lcLeaf {
-			info = item
-			return true
-		}
-		return false
-	})
-	if err != nil {
-		return nil, err
-	}
-	if !found {
-		return nil, fs.ErrorObjectNotFound
-	}
-	return info, nil
-}
+When set, all the remotes of an account use the same caches so the
+torrents list is only paginated once however many remotes are in use,
+for instance when several mounts or an rc server create the same remote
+concurrently.`,
+			Advanced: true,
+			Default:  true,
+		}, {
+			Name: "torrent_info_cache_size",
+			Help: `Number of torrent details to keep in memory.
 
-// errorHandler parses a non 2xx error response into an error
-func errorHandler(resp *http.Response) error {
-	body, err := rest.ReadBody(resp)
-	if err != nil {
-		body = nil
-	}
-	var e = api.Response{
-		Message: string(body),
-		Status:  fmt.Sprintf("%s (%d)", resp.Status, resp.StatusCode),
-	}
-	if body != nil {
-		_ = json.Unmarshal(body, &e)
-	}
-	return &e
-}
+The details of torrents are needed for listing them and repairing or
+reselecting them, so the most recently used ones are kept to save API
+calls. Set to 0 to disable.`,
+			Advanced: true,
+			Default:  512,
+		}, {
+			Name:     "torrent_info_cache_ttl",
+			Help:     `How long the torrent details kept in memory are used for.`,
+			Advanced: true,
+			Default:  fs.Duration(10 * time.Minute),
+		}, {
+			Name: "repair_max_attempts",
+			Help: `Number of times a dead or broken torrent is repaired before giving up.
 
-// Return a url.Values with the api key in
-func (f *Fs) baseParams() url.Values {
-	params := url.Values{}
-	if f.opt.APIKey != "" {
-		params.Add("auth_token", f.opt.APIKey)
-	}
-	return params
-}
+A torrent which can't be repaired, e.g. as it is no longer seeded, is
+otherwise added again on every refresh. Once given up on it is shown
+in repairFailed of the status file and only repaired again with the
+repair backend command. Set to 0 to never give up.`,
+			Advanced: true,
+			Default:  3,
+		}, {
+			Name: "verify_links_per_hour",
+			Help: `How many cached download links to check per hour in the background.
 
-func (f *Fs) listTorrentStatusPage(ctx context.Context) ([]api.Item, error) {
-	params := f.baseParams()
-	params.Set("page", "1")
-	params.Set("limit", "100")
-	opts := rest.Opts{
-		Method:     "GET",
-		Path:       "/torrents",
-		Parameters: params,
-	}
-	var resp *http.Response
-	var result []api.Item
-	var err error
-	fs.Debugf(f, "RealDebrid API call: GET /torrents page=1 limit=100")
-	err = f.pacer.Call(func() (bool, error) {
-		resp, err = f.srv.CallJSON(ctx, &opts, nil, &result)
-		return shouldRetry(ctx, resp, err)
-	})
-	if err != nil {
-		fs.Debugf(f, "RealDebrid API error: GET /torrents page=1 limit=100: %v", err)
-		return nil, err
-	}
-	status := 0
-	if resp != nil {
-		status = resp.StatusCode
-	}
-	fs.Debugf(f, "RealDebrid API response: GET /torrents status=%d items=%d", status, len(result))
-	return result, nil
-}
+The links are checked in turn by downloading their first byte. Dead
+links are unrestricted again so they work when next opened, and the
+torrents of links which can't be unrestricted any more are repaired on
+the next refresh.
 
-// ChangeNotify watches RealDebrid torrent statuses at the rclone poll interval.
-func (f *Fs) ChangeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
-	go f.changeNotify(ctx, notifyFunc, pollIntervalChan)
-}
+Set to 0 to disable the checks.`,
+			Advanced: true,
+			Default:  0,
+		}, {
+			Name:     "verify_pause_streams",
+			Help:     `Don't check links while at least this many files are open, 0 to always check.`,
+			Advanced: true,
+			Default:  1,
+		}, {
+			Name: "max_concurrent_streams",
+			Help: `Maximum number of files streamed at once, 0 for no limit.
 
-func (f *Fs) changeNotify(ctx context.Context, notifyFunc func(string, fs.EntryType), pollIntervalChan <-chan time.Duration) {
-	var ticker *time.Ticker
-	var tickerC <-chan time.Time
-	for {
-		select {
-		case pollInterval, ok := <-pollIntervalChan:
-			if !ok {
-				if ticker != nil {
-					ticker.Stop()
-				}
-				return
-			}
-			if ticker != nil {
-				ticker.Stop()
-				ticker, tickerC = nil, nil
-			}
-			if pollInterval > 0 {
-				ticker = time.NewTicker(pollInterval)
-				tickerC = ticker.C
-				fs.Infof(f, "RealDebrid torrent polling enabled: interval=%v", pollInterval)
-			} else {
-				fs.Infof(f, "RealDebrid torrent polling disabled")
-			}
-		case <-tickerC:
-			items, err := f.listTorrentStatusPage(ctx)
-			if err != nil {
-				fs.Debugf(f, "RealDebrid torrent polling failed: %v", err)
-				continue
-			}
-			current := make(map[string]string, len(items))
-			downloadedTransitions := 0
-			f.mu.Lock()
-			baseline := !f.torrentStatusBase
-			for _, item := range items {
-				if item.ID == "" {
-					continue
-				}
-				status := strings.ToLower(strings.TrimSpace(item.Status))
-				current[item.ID] = status
-				if baseline || status != "downloaded" {
-					continue
-				}
-				if previous := f.torrentStatuses[item.ID]; previous != "downloaded" {
-					downloadedTransitions++
-				}
-			}
-			f.torrentStatuses = current
-			f.torrentStatusBase = true
-			f.mu.Unlock()
-			if downloadedTransitions == 0 {
-				continue
-			}
-			fs.Infof(f, "RealDebrid torrent polling detected downloaded torrent(s): count=%d", downloadedTransitions)
-			lastcheck = time.Now().Unix() - interval
-			notifyFunc("", fs.EntryDirectory)
-			if f.opt.SharedFolder == "folders" {
-				notifyFunc("shows", fs.EntryDirectory)
-				notifyFunc("movies", fs.EntryDirectory)
-				notifyFunc("default", fs.EntryDirectory)
-			}
-		case <-ctx.Done():
-			if ticker != nil {
-				ticker.Stop()
-			}
-			return
-		}
-	}
-}
+Real-Debrid limits the downloads of an account running at once, and
+going over it fails all of them. Files opened over this limit fail
+with "too many streams open" until one of the open ones is closed,
+which the direct reads of the VFS wait for, see
+--vfs-hybrid-stream-wait.
 
-// NewFs constructs an Fs from the path, container:path
-func NewFs(ctx context.Context, name, root string, m configmap.Mapper) (fs.Fs, error) {
-	// Parse config into Options struct
-	opt := new(Options)
-	err := configstruct.Set(m, opt)
-	if err != nil {
-		return nil, err
-	}
+Files read by the VFS with --vfs-read-chunk-streams above 1 use that
+many streams each.`,
+			Advanced: true,
+			Default:  0,
+		}, {
+			Name: "downloads_max_age",
+			Help: `Age of the downloads deleted by the prune-downloads command.
 
-	root = parsePath(root)
-
-	var client *http.Client
-	var ts *oauthutil.TokenSource
-	if opt.APIKey == "" {
-		client, ts, err = oauthutil.NewClient(ctx, name, m, oauthConfig)
-		if err != nil {
-			return nil, fmt.Errorf("failed to configure realdebrid: %w", err)
-		}
-	} else {
-		client = fshttp.NewClient(ctx)
-	}
+Every file streamed adds an entry to the downloads page, so they can
+be pruned with the prune-downloads backend command, or automatically
+with auto_prune_downloads. The downloads of the links of the current
+torrents are always kept.
 
-	f := &Fs{
-		name:  name,
-		root:  root,
-		opt:   *opt,
-		srv:   rest.NewClient(client).SetRoot(rootURL),
-		pacer: fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(maxSleep), pacer.DecayConstant(decayConstant))),
+Set to 0 to only prune with the age given to the command.`,
+			Advanced: true,
+			Default:  fs.Duration(0),
+		}, {
+			Name:     "auto_prune_downloads",
+			Help:     `Prune the downloads older than downloads_max_age after each complete refresh of the torrents.`,
+			Advanced: true,
+			Default:  false,
+		}, {
+			Name: "scan_downloads",
+			Help: `Read the downloads page to find the links of the torrents unrestricted already.
+
+In torrents mode the downloads page is read when rclone starts, unless
+the links were dumped recently, and by the refresh command, so the
+links of the torrents unrestricted before, by rclone or elsewhere, are
+used rather than unrestricted again.
+
+Without it only the links rclone unrestricted itself, kept in its
+dump, are used. This saves the API calls reading the downloads page,
+which is worth it if it is cleared regularly, but the files whose
+links were only unrestricted elsewhere, like on the website, are
+unrestricted again when they are first listed or opened, adding to
+the downloads page.
+
+The prune-downloads command and auto_prune_downloads still read it.`,
+			Advanced: true,
+			Default:  true,
+		}, {
+			Name: "classify_command",
+			Help: `Command to classify the torrents into the category folders.
 
-		torrentStatuses: make(map[string]string),
-	}
-	f.features = (&fs.Features{
-		CaseInsensitive:         true,
-		CanHaveEmptyDirectories: true,
-		ReadMimeType:            true,
-	}).Fill(ctx, f)
-	f.srv.SetErrorHandler(errorHandler)
-
-	// Renew the token in the background
-	if ts != nil {
-		f.tokenRenewer = oauthutil.NewRenew(f.String(), ts, func() error {
-			_, err := f.About(ctx)
-			return err
-		})
-	}
+The torrents are sent in batches on stdin as JSON
 
-	// Get rootID
-	f.dirCache = dircache.New(root, rootID, f)
+` + "```json" + `
+{"torrents": [{"hash": "abc...", "name": "Show.S01", "files": ["/Show.S01E01.mkv"]}]}
+` + "```" + `
 
-	// create var/lib folder if necessary
-	pathdir := "/var/lib/rclone"
+and the command must print their categories by hash on stdout
 
-	// Create the directory, including any necessary parent directories
-	errdir := os.MkdirAll(pathdir, 0755)
-	if errdir != nil {
-		fmt.Println("Error creating the dir to store dumps", errdir)
-	}
+` + "```json" + `
+{"categories": {"abc...": "shows"}}
+` + "```" + `
 
-	fmt.Println("Data dump Directory created successfully!")
+Only the torrents without a category are sent after a complete
+refresh, and the categories returned are kept by hash in the dump
+directory. Torrents the classifier fails to classify in time or gives
+another category are classified with regex_shows and regex_movies, or
+the regexes of categories.`,
+			Advanced: true,
+			Default:  fs.SpaceSepList{},
+		}, {
+			Name:     "classify_url",
+			Help:     `URL the torrents are POSTed to for classification, see classify_command for the format.`,
+			Advanced: true,
+			Default:  "",
+		}, {
+			Name: "classify_timeout",
+			Help: `Time allowed to classify all the torrents of a refresh.
 
-	// load torrentswf from file
-	filetwf, err := os.Open("/var/lib/rclone/torrentswf.gob")
-	if err != nil {
-		fmt.Println("> torrentswf.gob dump does not exist yet (normal on very first start) or other error: ", err)
-	} else {
-		// Create a Gob decoder
-		decoder := gob.NewDecoder(filetwf)
+The torrents left are classified with the regexes until the next refresh
+so a slow or broken classifier can't stall the listings.`,
+			Advanced: true,
+			Default:  fs.Duration(10 * time.Second),
+		}, {
+			Name:     "classify_batch_size",
+			Help:     `Maximum number of torrents sent to the classifier at once.`,
+			Advanced: true,
+			Default:  100,
+		}, {
+			Name: "cache_refresh_interval",
+			Help: `How often the torrents are listed again from the API.
 
-		// Create a map to hold the decoded data
+Listings within this time of the last refresh are served from the
+cache. A longer interval saves API calls for a library which rarely
+changes. Changes made through rclone, such as removes and repairs,
+are still picked up on the next listing.
 
-		// Decode the Gob data into the map
-		err = decoder.Decode(&torrentswf)
-		if err != nil {
-			fmt.Println("Error decoding torrentswf.gob file data:", err)
-		}
+Files looked up and not found, like the sidecar files media servers
+look for, are remembered for this long too unless the torrents are
+refreshed before.
 
-		fmt.Println("> Torrent details dump successfully red from torrentswf.gob file.")
-	}
-	defer filetwf.Close()
+Set to 0 to never refresh them automatically, which doesn't remember
+the files not found either.`,
+			Advanced: true,
+			Default:  fs.Duration(15 * time.Minute),
+		}, {
+			Name: "background_refresh",
+			Help: `Refresh the torrents in the background every cache_refresh_interval.
 
-	// load cached from file
-	filecached, err := os.Open("/var/lib/rclone/cached.gob")
-	if err != nil {
-		fmt.Println("> cached.gob dump does not exist yet (normal on very first start) or other error: ", err)
-	} else {
-		// Create a Gob decoder
-		decodercached := gob.NewDecoder(filecached)
+Without it they are refreshed by the first listing after the interval,
+which has to wait for the refresh. With it the listings find them
+refreshed already, at the cost of the API calls made while rclone isn't
+used.`,
+			Advanced: true,
+			Default:  false,
+		}, {
+			Name: "refresh_budget",
+			Help: `Maximum time a refresh of the torrents may take.
 
-		// Create a map to hold the decoded data
+A refresh which takes longer, for instance because the API keeps
+failing and requests are retried, is stopped and the torrents listed
+before are kept. Set to 0 for no limit.`,
+			Advanced: true,
+			Default:  fs.Duration(2 * time.Minute),
+		}, {
+			Name: "refresh_budget_requests",
+			Help: `Maximum number of requests a refresh of the torrents may make.
 
-		// Decode the Gob data into the map
-		err = decodercached.Decode(&cached)
-		if err != nil {
-			fmt.Println("Error decoding cached.gob file data:", err)
-		}
+Retries count as requests. A refresh which makes more is stopped and
+the torrents listed before are kept. Set to 0 for no limit.`,
+			Advanced: true,
+			Default:  200,
+		}, {
+			Name: "refresh_circuit_failures",
+			Help: `Number of failed refreshes in a row which stop the refreshes.
 
-		fmt.Println("> Dl-links dump successfully red from cached.gob file.")
-	}
-	defer filecached.Close()
-
-	// Find the current root
-	err = f.dirCache.FindRoot(ctx, false)
-	if err != nil {
-		// Assume it is a file
-		newRoot, remote := dircache.SplitPath(root)
-		tempF := *f
-		tempF.dirCache = dircache.New(newRoot, rootID, &tempF)
-		tempF.root = newRoot
-		// Make new Fs which is the parent
-		err = tempF.dirCache.FindRoot(ctx, false)
-		if err != nil {
-			// No root so return old f
-			return f, nil
-		}
-		_, err := tempF.newObjectWithInfo(ctx, remote, nil)
-		if err != nil {
-			if err == fs.ErrorObjectNotFound {
-				// File doesn't exist so return old f
-				return f, nil
-			}
-			return nil, err
-		}
-		f.features.Fill(ctx, &tempF)
-		// XXX: update the old f here instead of returning tempF, since
-		// `features` were already filled with functions having *f as a receiver.
-		// See https://github.com/rclone/rclone/issues/2182
-		f.dirCache = tempF.dirCache
-		f.root = tempF.root
-		// return an error with an fs which points to the parent
-		return f, fs.ErrorIsFile
-	}
-	return f, nil
-}
+Once refreshing the torrents has failed this many times in a row the
+refreshes are skipped for refresh_circuit_backoff and the torrents
+listed before are served. A single refresh is then tried, which
+resumes the refreshes if it works. Set to 0 to never stop them.`,
+			Advanced: true,
+			Default:  3,
+		}, {
+			Name:     "refresh_circuit_backoff",
+			Help:     `How long the refreshes are stopped for after refresh_circuit_failures.`,
+			Advanced: true,
+			Default:  fs.Duration(5 * time.Minute),
+		}, {
+			Name: "serve_stale_on_error",
+			Help: `List the torrents listed before when they can't be refreshed.
+
+When Real-Debrid is down the refresh made by a listing fails. With this
+the torrents listed before are listed again, with a warning, rather
+than the listing failing, so a media server scanning the mount doesn't
+see the whole library deleted. The listings still fail if the torrents
+were never listed.`,
+			Advanced: true,
+			Default:  true,
+		}, {
+			Name:     "pacer_min_sleep",
+			Help:     `Minimum time to sleep between API calls.`,
+			Advanced: true,
+			Default:  fs.Duration(minSleep),
+		}, {
+			Name: "pacer_max_sleep",
+			Help: `Maximum time to sleep between API calls when rate limited.
 
-// Return an Object from a path
-//
-// If it can't be found it returns the error fs.ErrorObjectNotFound.
-func (f *Fs) newObjectWithInfo(ctx context.Context, remote string, info *api.Item) (fs.Object, error) {
-	o := &Object{
-		fs:     f,
-		remote: remote,
-	}
-	var err error
-	if info != nil {
-		// Set info
-		err = o.setMetaData(info)
-	} else {
-		err = o.readMetaData(ctx) // reads info and meta, returning an error
-	}
-	if err != nil {
-		return nil, err
-	}
-	return o, nil
-}
+Real-Debrid allows about 250 API calls per minute. When a call is
+rate limited the sleep doubles up to this, or is the time given by
+Real-Debrid, then shortens slowly back to pacer_min_sleep so big scans
+carry on just below the limit.`,
+			Advanced: true,
+			Default:  fs.Duration(maxSleep),
+		}, {
+			Name: "api_retries",
+			Help: `Number of times an API call is tried before failing.
 
-// NewObject finds the Object at remote.  If it can't be found
-// it returns the error fs.ErrorObjectNotFound.
-func (f *Fs) NewObject(ctx context.Context, remote string) (fs.Object, error) {
-	return f.newObjectWithInfo(ctx, remote, nil)
-}
+Raise it on a flaky connection, or lower it so failures surface fast.
+Set to 0 to use --low-level-retries.`,
+			Advanced: true,
+			Default:  0,
+		}, {
+			Name: "list_page_size",
+			Help: `Number of torrents read per page when listing them all.
 
-// FindLeaf finds a directory of name leaf in the folder with ID pathID
-func (f *Fs) FindLeaf(ctx context.Context, pathID string, leaf string) (pathIDOut string, found bool, err error) {
-	// Find the leaf in pathID
-	fmt.Printf("Finding directory named: '%s' in dir named: '%s'\n", leaf, pathID)
-	var newDirID string
-	newDirID, found, err = f.listAll(ctx, pathID, true, false, func(item *api.Item) bool {
-		if strings.EqualFold(item.Name, leaf) {
-			pathIDOut = item.ID
-			return true
-		}
-		return false
-	})
-	// Update the Root directory ID to its actual value
-	if pathID == rootID {
-		f.dirCache.SetRootIDAlias(newDirID)
-	}
-	return pathIDOut, found, err
-}
+Real-Debrid fails big pages with a 5xx error when they take too long
+to make, then the pages are read again with half the size, down to
+100, rather than failing the refresh.`,
+			Advanced: true,
+			Default:  listPageSize,
+		}, {
+			Name: "api_timeout",
+			Help: `Time each try of an API call may take.
 
-// CreateDir makes a directory with pathID as parent and name leaf
-func (f *Fs) CreateDir(ctx context.Context, pathID, leaf string) (newID string, err error) {
-	return "", nil //return info.ID, nil
-}
+A call which hangs fails after this rather than after --timeout, so
+a big --timeout for transfers doesn't hold up listings. Downloads are
+not limited by it. Set to 0 to only use --timeout.`,
+			Advanced: true,
+			Default:  fs.Duration(30 * time.Second),
+		}, {
+			Name: "api_retry_delay",
+			Help: `Time to sleep before retrying a failed API call.
 
-// Redownload a dead torrent
-func (f *Fs) redownloadTorrent(ctx context.Context, torrent api.Item) (redownloaded_torrent api.Item) {
-	fmt.Println("Redownloading dead torrent: " + torrent.Name)
-	//Get dead torrent file and hash info
-	var method = "GET"
-	var path = "/torrents/info/" + torrent.ID
-	var opts = rest.Opts{
-		Method:     method,
-		Path:       path,
-		Parameters: f.baseParams(),
-	}
-	_, _ = f.srv.CallJSON(ctx, &opts, nil, &torrent)
-	var selected_files []int64
-	var dead_torrent_id = torrent.ID
-	for _, file := range torrent.Files {
-		if file.Selected == 1 {
-			selected_files = append(selected_files, file.ID)
-		}
-	}
-	var selected_files_str = strings.Trim(strings.Join(strings.Fields(fmt.Sprint(selected_files)), ","), "[]")
-	//Delete old download links
-	for _, link := range torrent.Links {
-		for i, cachedfile := range cached {
-			if cachedfile.OriginalLink == link {
-				path = "/downloads/delete/" + cachedfile.ID
-				opts = rest.Opts{
-					Method:     "DELETE",
-					Path:       path,
-					Parameters: f.baseParams(),
-				}
-				var resp *http.Response
-				var result api.Response
-				var retries = 0
-				var err_code = 0
-				resp, _ = f.srv.CallJSON(ctx, &opts, nil, &result)
-				if resp != nil {
-					err_code = resp.StatusCode
-				}
-				for err_code == 429 && retries <= 5 {
-					time.Sleep(time.Duration(2) * time.Second)
-					resp, _ = f.srv.CallJSON(ctx, &opts, nil, &result)
-					if resp != nil {
-						err_code = resp.StatusCode
-					}
-					retries += 1
-				}
-				cached[i].OriginalLink = "this-is-not-a-link"
-			}
-		}
-	}
-	//Add torrent again
-	path = "/torrents/addMagnet"
-	method = "POST"
-	opts = rest.Opts{
-		Method: method,
-		Path:   path,
-		MultipartParams: url.Values{
-			"magnet": {"magnet:?xt=urn:btih:" + torrent.TorrentHash},
-		},
-		Parameters: f.baseParams(),
-	}
-	_, _ = f.srv.CallJSON(ctx, &opts, nil, &torrent)
-	method = "GET"
-	path = "/torrents/info/" + torrent.ID
-	opts = rest.Opts{
-		Method:     method,
-		Path:       path,
-		Parameters: f.baseParams(),
-	}
-	_, _ = f.srv.CallJSON(ctx, &opts, nil, &torrent)
-	var tries = 0
-	for torrent.Status != "waiting_files_selection" && tries < 5 {
-		time.Sleep(time.Duration(1) * time.Second)
-		_, _ = f.srv.CallJSON(ctx, &opts, nil, &torrent)
-		tries += 1
-	}
-	//Select the same files again
-	path = "/torrents/selectFiles/" + torrent.ID
-	method = "POST"
-	opts = rest.Opts{
-		Method: method,
-		Path:   path,
-		MultipartParams: url.Values{
-			"files": {selected_files_str},
-		},
-		Parameters: f.baseParams(),
-	}
-	_, _ = f.srv.CallJSON(ctx, &opts, nil, &torrent)
-	//Delete the old torrent
-	path = "/torrents/delete/" + dead_torrent_id
-	method = "DELETE"
-	opts = rest.Opts{
-		Method:     method,
-		Path:       path,
-		Parameters: f.baseParams(),
-	}
-	_, _ = f.srv.CallJSON(ctx, &opts, nil, &torrent)
-	torrent.Status = "downloaded"
-	lastcheck = time.Now().Unix() - interval
-	for i, TorrentID := range broken_torrents {
-		if dead_torrent_id == TorrentID {
-			broken_torrents[i] = broken_torrents[len(broken_torrents)-1]
-			broken_torrents = broken_torrents[:len(broken_torrents)-1]
-		}
-	}
-	return torrent
-}
+The sleep doubles with each retry up to pacer_max_sleep, unless
+Real-Debrid gives the time to wait. Set to 0 to start from
+pacer_min_sleep.`,
+			Advanced: true,
+			Default:  fs.Duration(0),
+		}, {
+			Name: "dump_dir",
+			Help: `Directory to save the torrents and links listed in.
 
-// list the objects into the function supplied
-//
-// If directories is set it only sends directories
-// User function to process a File item from listAll
-//
-// Should return true to finish processing
-type listAllFn func(*api.Item) bool
-
-func addArtificialRootFolders(result []api.Item) []api.Item {
-	result = append(result,
-		api.Item{ID: "shows", Name: "shows", Generated: "2006-01-02T15:04:05.000Z"},
-		api.Item{ID: "movies", Name: "movies", Generated: "2006-01-02T15:04:05.000Z"},
-		api.Item{ID: "default", Name: "default", Generated: "2006-01-02T15:04:05.000Z"},
-	)
-	return result
-}
+They are saved after each complete refresh and on shutdown, in files
+named after the remote, and loaded on start so the first listing
+doesn't have to fetch them all from the API again.
 
-func (f *Fs) ensureTorrentsListed(ctx context.Context) error {
-	if len(torrents) != 0 && time.Now().Unix()-lastcheck <= interval {
-		return nil
-	}
-	_, _, err := f.listAll(ctx, rootID, true, false, func(item *api.Item) bool {
-		return false
-	})
-	return err
-}
+Leave blank to use /var/lib/rclone, or set it to a directory under the
+rclone cache dir for instance.`,
+			Advanced: true,
+		}, {
+			Name: "dump_max_age",
+			Help: `Don't use the torrents and links saved longer ago than this.
 
-// Lists the directory required calling the user function on each item found
-//
-// If the user fn ever returns true then it early exits with found = true
-//
-// It returns a newDirID which is what the system returned as the directory ID
-func (f *Fs) listAll(ctx context.Context, dirID string, directoriesOnly bool, filesOnly bool, fn listAllFn) (newDirID string, found bool, err error) {
-	path := "/downloads"
-	method := "GET"
-	var partialresult []api.Item
-	var result []api.Item
-	var resp *http.Response
-	if f.opt.RootFolderID == "torrents" {
-		if dirID == rootID {
-			if f.opt.SharedFolder == "folders" {
-				result = addArtificialRootFolders(result)
-				goto processResults
-			}
-			fmt.Printf("--- LISTING RCLONE REMOTE ROOT --- \n")
-			//update global cached list
-			opts := rest.Opts{
-				Method:     method,
-				Path:       path,
-				Parameters: f.baseParams(),
-			}
-			opts.Parameters.Set("includebreadcrumbs", "false")
-			opts.Parameters.Set("limit", "1")
-			var newcached []api.Item
-			var totalcount int = 0
-			var printed = false
-			var ipage = 0
-			var totalpages = 0
-			if !startup_cached_api_fetch {
-				fmt.Printf("--> | CHECK API DL-LINKS (only on rclone load).\n")
-				for ipage <= totalpages {
-					partialresult = nil
-					var err_code = 0
-					fmt.Printf("                ~ RDAPIRequest@ /downloads\n")
-					resp, err = f.srv.CallJSON(ctx, &opts, nil, &partialresult)
-					if resp != nil {
-						err_code = resp.StatusCode
-					}
-					var retries = 0
-					for err_code == 429 && retries <= 5 {
-						partialresult = nil
-						time.Sleep(time.Duration(2) * time.Second)
-						fmt.Printf("                ~ RDAPIRequest@ /downloads !retries\n")
-						resp, err = f.srv.CallJSON(ctx, &opts, nil, &partialresult)
-						if resp != nil {
-							err_code = resp.StatusCode
-						}
-						retries += 1
-					}
-					if err == nil {
-						totalcount, err = strconv.Atoi(resp.Header["X-Total-Count"][0])
-						totalpages = int(math.Ceil(float64(totalcount) / 5000))
-						fmt.Printf("    | - RD API dl-links x-total info: %d\n", totalcount)
-						if totalpages > 20 {
-							totalpages = 20 // hardcoded limit of 100 000 dl links, change that at your own risk
-						}
-
-						if err == nil {
-							if !printed {
-								fmt.Println("    | - RD API : enriching known dl-links with externally created ones.") // fetch only on rclone restart to profit from any links there that we wouldn't already have in dump, will be deduplicated later
-								printed = true
-							}
-							if ipage > 0 {
-								newcached = append(newcached, partialresult...)
-								fmt.Printf("    | ~ New dl links fetched so far: %d.\n", len(newcached))
-							}
-							opts.Parameters.Set("limit", "5000")
-							ipage++
-							opts.Parameters.Set("page", strconv.Itoa(ipage))
-						} else {
-							break
-						}
-					} else {
-						break
-					}
-				}
-				startup_cached_api_fetch = true
-				cached = append(newcached, cached...) // so links fetched are put at top of the cached array
-				fmt.Printf("DONE| - Number of API retrieved dl-links: %d.\n", len(newcached))
-
-			}
-
-			//get torrents
-			path = "/torrents"
-			opts = rest.Opts{
-				Method:     method,
-				Path:       path,
-				Parameters: f.baseParams(),
-			}
-			opts.Parameters.Set("limit", "1")
-			var newtorrents []api.Item
-			totalcount = 0
-			var tprinted = false
-			ipage = 0
-			totalpages = 0
-			fmt.Printf("--> | CHECKS API TORRENTS\n")
-			for ipage <= totalpages {
-
-				partialresult = nil
-				var err_code = 0
-
-				if ipage > 0 {
-					time.Sleep(time.Duration(1) * time.Second)
-				}
-				fmt.Printf("                ~ RDAPIRequest@ /torrents\n")
-				resp, err = f.srv.CallJSON(ctx, &opts, nil, &partialresult)
-				if resp != nil {
-					err_code = resp.StatusCode
-				}
-				var retries = 0
-				for err_code == 429 && retries <= 5 {
-					partialresult = nil
-					time.Sleep(time.Duration(2) * time.Second)
-					fmt.Printf("                ~ RDAPIRequest@ /torrents !retries\n")
-					resp, err = f.srv.CallJSON(ctx, &opts, nil, &partialresult)
-					if resp != nil {
-						err_code = resp.StatusCode
-					}
-					retries += 1
-				}
-				if err == nil {
-					totalcount, err = strconv.Atoi(resp.Header["X-Total-Count"][0])
-					totalpages = int(math.Ceil(float64(totalcount) / 2500))
-					if totalpages > 20 {
-						totalpages = 20 // hardcoded limit of 50 000 torrents, change that at your own risk
-					}
-					fmt.Printf("    | - RD API torrents x-total info:%d\n", totalcount)
-
-					if err == nil {
-
-						//fmt.Printf("#Interval is %d\n", interval)
-						//fmt.Printf("#time is %d\n", time.Now().Unix())
-						//fmt.Printf("#last check is %d\n", lastcheck)
-						//fmt.Printf("#now - last check is %d\n", time.Now().Unix()-lastcheck)
-
-						if totalcount != len(torrents) || time.Now().Unix()-lastcheck > interval {
-							if !tprinted {
-								fmt.Printf("    | - Last RD API torrents update more than 15min ago or RD API torrents count info different from local, Updating torrents...\n")
-								tprinted = true
-							}
-							if ipage > 0 {
-								newtorrents = append(newtorrents, partialresult...)
-								fmt.Printf("    | ~ New torrents fetched so far: %d.\n", len(newtorrents))
-							}
-
-							//opts.Parameters.Set("offset", strconv.Itoa(len(newtorrents)))
-							opts.Parameters.Set("limit", "2500")
-							ipage++
-							opts.Parameters.Set("page", strconv.Itoa(ipage))
-
-						} else {
-							break
-
-						}
-					} else {
-						break
-					}
-				} else {
-					break
-				}
-			}
-
-			if tprinted {
-				fmt.Printf("DONE| - Number of retrieved Torrents: %d.\n", len(newtorrents))
-				torrents = newtorrents
-				lastcheck = time.Now().Unix()
-			}
-
-			if tprinted {
-				// ------------- CLEANING AND DUMPING IS HERE only on complete refresh -------------
-				//fmt.Println("---CLEANING AND DUMPING---")
-
-				// dont remove duplicates from torrents as count comparison will trigger a new refresh anyway ? todo verif
-
-				// remove from torrentswf where is not found in downloaded torrents
-				seen := make(map[string]bool)
-				idsInT := make(map[string]struct{})
-				for _, itemt := range torrents {
-					if itemt.Status == "downloaded" {
-						idsInT[itemt.ID] = struct{}{}
-					}
-				}
-				var filteredtswf []api.Item
-				for _, itemtwf := range torrentswf {
-					if _, exists := idsInT[itemtwf.ID]; exists {
-						if _, found := seen[itemtwf.ID]; !found {
-							seen[itemtwf.ID] = true
-							filteredtswf = append(filteredtswf, itemtwf)
-						}
-					}
-				}
-				torrentswf = filteredtswf
-
-				// remove duplicates drom torrents w details
-				//torrentswf = removeTorrentsDuplicates(torrentswf) -- done above at the same time as alignement
-
-				// for the moment,  from cached only remove deplicates
-				cached = removeDuplicates(cached)
-
-				// clean cached not corresponding to any torrentswf original link, only possible if for every ID found in torrents, torrentswf has it ! todo !!
-				/*
-					idsInTwf := make(map[string]struct{})
-					for _, itemwf := range torrentswf {
-						for _, olink := range itemwf.Links {
-							idsInTwf[olink] = struct{}{}
-						}
-					}
-					var filteredcached []api.Item
-					for _, itemcache := range cached {
-						if _, exists := idsInTwf[itemcache.OriginalLink]; exists {
-							filteredcached = append(filteredcached, itemcache)
-						}
-					}
-				*/
-
-				// dumping these torrentswf items (torrents with files (torrents with original links))
-				filetwf, err := os.Create("/var/lib/rclone/torrentswf.gob")
-				if err != nil {
-					fmt.Println("Error creating torrentswf file:", err)
-				}
-				defer filetwf.Close()
-
-				// Create a Gob encoder
-				encoder := gob.NewEncoder(filetwf)
-
-				// Encode the map and write to the file
-				err = encoder.Encode(torrentswf)
-				if err != nil {
-					fmt.Println("Error encoding torrentswf data:", err)
-				} else {
-					fmt.Println("DUMPING| Torrent details in torrentswf.gob file.")
-				}
-
-				// dumping these cached items (links from download or unrestrict)
-				filecached, err := os.Create("/var/lib/rclone/cached.gob")
-				if err != nil {
-					fmt.Println("Error creating cached.gob file:", err)
-				}
-				defer filecached.Close()
-
-				// Create a Gob encoder
-				encodercached := gob.NewEncoder(filecached)
-
-				// Encode the map and write to the file
-				err = encodercached.Encode(cached)
-				if err != nil {
-					fmt.Println("Error encoding cached links data:", err)
-				} else {
-					fmt.Println("DUMPING| dl-links dump in cached.gob file.")
-				}
-
-				fmt.Printf("STATUS| - Number of accumulated dl-links (after deduplication ; todo:alignement): %d.\n", len(cached))
-				fmt.Printf("STATUS| - Number of managed Torrents (after refresh): %d.\n", len(torrents)) // simple torrent call is not dumped
-				fmt.Printf("STATUS| - Number of managed Torrents details (after alignement to dled torrents and deduplication): %d.\n", len(torrentswf))
-
-			}
-
-			//Handle dead torrents
-			var broken = false
-			for i, torrent := range torrents {
-				broken = false
-				for _, TorrentID := range broken_torrents {
-					if torrent.ID == TorrentID {
-						broken = true
-					}
-				}
-				if torrent.Status == "dead" || broken {
-					torrents[i] = f.redownloadTorrent(ctx, torrent)
-				}
-			}
-		} else if f.opt.SharedFolder == "folders" && (dirID == "shows" || dirID == "movies" || dirID == "default") {
-			err = f.ensureTorrentsListed(ctx)
-			if err != nil {
-				return newDirID, found, err
-			}
-			//fmt.Println("Listing torrents folders")
-			var artificialType []api.Item
-			if dirID == "shows" {
-				r, _ := regexp.Compile(f.opt.RegexShows) //(?i)(S[0-9]{2}|SEASON|COMPLETE)
-				for _, torrent := range torrents {
-					match := r.MatchString(torrent.Name)
-					if match {
-						artificialType = append(artificialType, torrent)
-					}
-				}
-				result = artificialType
-			} else if dirID == "movies" {
-				r, _ := regexp.Compile(f.opt.RegexMovies) //`(?i)([0-9]{4} ?\.?)`
-				nr, _ := regexp.Compile(f.opt.RegexShows)
-				for _, torrent := range torrents {
-					match := r.MatchString(torrent.Name)
-					exclude := nr.MatchString(torrent.Name)
-					if match && !exclude {
-						artificialType = append(artificialType, torrent)
-					}
-				}
-				result = artificialType
-			} else {
-				r, _ := regexp.Compile(f.opt.RegexMovies)
-				nr, _ := regexp.Compile(f.opt.RegexShows)
-				for _, torrent := range torrents {
-					match := r.MatchString(torrent.Name)
-					exclude := nr.MatchString(torrent.Name)
-					if !match && !exclude {
-						artificialType = append(artificialType, torrent)
-					}
-				}
-				result = artificialType
-			}
-
-		} else if f.opt.SharedFolder != "folders" || dirID != rootID {
-			//fmt.Printf("Listing the contents of a torrent folder")
-			var torrent api.Item
-			for _, torrentwf := range torrentswf {
-				if dirID == torrentwf.ID && torrentwf.Status == "downloaded" {
-					torrent = torrentwf
-					//fmt.Printf("                 ~ from cache\n")
-					break
-				}
-			}
-
-			if torrent.ID == "" {
-				// it means it does not exist yet or not yet downloaded
-				var method = "GET"
-				var path = "/torrents/info/" + dirID
-				var opts = rest.Opts{
-					Method:     method,
-					Path:       path,
-					Parameters: f.baseParams(),
-				}
-				fmt.Printf("                ~ RDAPIRequest@ /torrent/info\n")
-				_, _ = f.srv.CallJSON(ctx, &opts, nil, &torrent)
-				// todo retry if http fails ? could be left as is in JellyGrail as it adds file in BindFS a transactionnal way, if empty, will got it at next scan and info will be kept
-				// put at the top, duplicates will be removed later
-				torrentswf = append([]api.Item{torrent}, torrentswf...)
-			}
-
-			/* put as comments but must be removed
-			   			for i, torrent := range torrents {
-							var broken = false
-							if f.opt.SharedFolder == "folders" {
-								if dirID != torrent.ID {
-									continue
-								}
-							}
-			*/
-			var broken = false
-			for _, link := range torrent.Links {
-				var ItemFile api.Item
-				for _, cachedfile := range cached {
-					if cachedfile.OriginalLink == link {
-						ItemFile = cachedfile
-						break
-					}
-				}
-				if ItemFile.Link == "" {
-					fmt.Printf("                ~ RDAPIRequest@ /unrestrict/link for: '%s'\n", torrent.Name)
-					path = "/unrestrict/link"
-					method = "POST"
-					opts := rest.Opts{
-						Method: method,
-						Path:   path,
-						MultipartParams: url.Values{
-							"link": {link},
-						},
-						Parameters: f.baseParams(),
-					}
-					var err_code = 0
-					resp, _ = f.srv.CallJSON(ctx, &opts, nil, &ItemFile)
-					if resp != nil {
-						err_code = resp.StatusCode
-					}
-					if err_code == 503 {
-						broken = true
-						break
-					}
-					var retries = 0
-					for err_code == 429 && retries <= 5 {
-						time.Sleep(time.Duration(2) * time.Second)
-						resp, _ = f.srv.CallJSON(ctx, &opts, nil, &ItemFile)
-						if resp != nil {
-							err_code = resp.StatusCode
-						}
-						retries += 1
-					}
-					cached = append([]api.Item{ItemFile}, cached...) // add to the cached array, at the top
-				}
-				ItemFile.ParentID = torrent.ID
-				ItemFile.TorrentHash = torrent.TorrentHash
-				ItemFile.Generated = "2006-01-02T15:04:05.000Z"
-				result = append(result, ItemFile)
-			}
-			if broken {
-				torrent = f.redownloadTorrent(ctx, torrent)
-				// and put it back in torretswf array
-				for i, torrentwf := range torrentswf {
-					if torrent.ID == torrentwf.ID {
-						torrentswf[i] = torrent
-						break
-					}
-				}
-
-				for _, link := range torrent.Links {
-					var ItemFile api.Item
-					fmt.Printf("                ~ RDAPIRequest@ /unrestrict/link - after fixing broken torrent: '%s'\n", torrent.Name)
-					path = "/unrestrict/link"
-					method = "POST"
-					opts := rest.Opts{
-						Method: method,
-						Path:   path,
-						MultipartParams: url.Values{
-							"link": {link},
-						},
-						Parameters: f.baseParams(),
-					}
-					var err_code = 0
-					resp, _ = f.srv.CallJSON(ctx, &opts, nil, &ItemFile)
-					if resp != nil {
-						err_code = resp.StatusCode
-					}
-					var retries = 0
-					for err_code == 429 && retries <= 5 {
-						time.Sleep(time.Duration(2) * time.Second)
-						resp, _ = f.srv.CallJSON(ctx, &opts, nil, &ItemFile)
-						if resp != nil {
-							err_code = resp.StatusCode
-						}
-						retries += 1
-					}
-					cached = append([]api.Item{ItemFile}, cached...) // add to the cached array, at the top
-					ItemFile.ParentID = torrent.ID
-					ItemFile.TorrentHash = torrent.TorrentHash
-					ItemFile.Generated = "2006-01-02T15:04:05.000Z"
-					result = append(result, ItemFile)
-				}
-			}
-			/*if f.opt.SharedFolder == "folders" { not needed anymore as torrent is not taken from a tested range anmore
-				break
-			}*/
-			//fmt.Printf("...torrent listing done.\n")
-		}
-	} else {
-		opts := rest.Opts{
-			Method:     method,
-			Path:       path,
-			Parameters: f.baseParams(),
-		}
-		err = f.pacer.Call(func() (bool, error) {
-			var totalcount int
-			totalcount = 1
-			for len(result) < totalcount {
-				resp, err = f.srv.CallJSON(ctx, &opts, nil, &partialresult)
-				if err == nil {
-					totalcount, err = strconv.Atoi(resp.Header["X-Total-Count"][0])
-					if err == nil {
-						result = append(result, partialresult...)
-						opts.Parameters.Set("offset", strconv.Itoa(len(result)))
-					} else {
-						break
-					}
-				} else {
-					break
-				}
-			}
-			return shouldRetry(ctx, resp, err)
-		})
-	}
-processResults:
-	if err != nil {
-		return newDirID, found, fmt.Errorf("couldn't list files: %w", err)
-	}
-	for i := range result {
-		item := &result[i]
-		layout := "2006-01-02T15:04:05.000Z"
-		if item.Generated != "" {
-			t, _ := time.Parse(layout, item.Generated)
-			item.CreatedAt = t.Unix()
-		} else if item.Ended != "" {
-			t, _ := time.Parse(layout, item.Ended)
-			item.CreatedAt = t.Unix()
-		}
-		if f.opt.SharedFolder == "folders" && (dirID == rootID || dirID == "shows" || dirID == "movies" || dirID == "default") {
-			item.Type = "folder"
-		} else {
-			item.Type = "file"
-		}
-		if item.Type == api.ItemTypeFolder {
-			if filesOnly {
-				continue
-			}
-		} else if item.Type == api.ItemTypeFile {
-			if directoriesOnly {
-				continue
-			}
-		} else {
-			fs.Debugf(f, "Ignoring %q - unknown type %q", item.Name, item.Type)
-			continue
-		}
-		item.Name = f.opt.Enc.ToStandardName(item.Name)
-		if fn(item) {
-			found = true
-			break
-		}
-	}
-	return
-}
+Torrents saved more recently are listed straight away if their total
+count still matches the API, otherwise they are all fetched again. The
+links are loaded whatever their age but the /downloads of the API are
+only fetched again when they are older than this.`,
+			Advanced: true,
+			Default:  fs.Duration(24 * time.Hour),
+		}, {
+			Name: "unicode_normalization",
+			Help: `Compose the accents of names to NFC.
 
-// List the objects and directories in dir into entries.  The
-// entries can be returned in any order but should be for a
-// complete directory.
-//
-// dir should be "" to list the root, and should not have
-// trailing slashes.
-//
-// This should return ErrDirNotFound if the directory isn't
-// found.
-func (f *Fs) List(ctx context.Context, dir string) (entries fs.DirEntries, err error) {
-	//fmt.Println("Listing Items ... ")
-	directoryID, err := f.dirCache.FindDir(ctx, dir, false)
-	if err != nil {
-		return nil, err
-	}
-	var iErr error
-	_, _, err = f.listAll(ctx, directoryID, false, false, func(info *api.Item) bool {
-		remote := path.Join(dir, info.Name)
-		if info.Type == api.ItemTypeFolder {
-			// cache the directory ID for later lookups
-			f.dirCache.Put(remote, info.ID)
-			d := fs.NewDir(remote, time.Unix(info.CreatedAt, 0)).SetID(info.ID)
-			entries = append(entries, d)
-		} else if info.Type == api.ItemTypeFile {
-			o, err := f.newObjectWithInfo(ctx, remote, info)
-			if err != nil {
-				iErr = err
-				return true
-			}
-			entries = append(entries, o)
-		}
-		return false
-	})
-	if err != nil {
-		return nil, err
-	}
-	if iErr != nil {
-		return nil, iErr
-	}
-	//fmt.Println("Done Listing Items.")
-	return entries, nil
-}
+Torrents may be named with decomposed accents, e.g. an e followed by a
+combining accent, while clients such as macOS over SMB or FUSE look
+them up composed, so the names are listed composed and looked up
+composed whatever the client sends.`,
+			Advanced: true,
+			Default:  true,
+		}, {
+			Name: "show_status_file",
+			Help: `Show a _status.json file in the root with the account status.
 
-// Creates from the parameters passed in a half finished Object which
-// must have setMetaData called on it
-//
-// # Returns the object, leaf, directoryID and error
-//
-// Used to create new objects
-func (f *Fs) createObject(ctx context.Context, remote string, modTime time.Time, size int64) (o *Object, leaf string, directoryID string, err error) {
-	// Create the directory for the object if it doesn't exist
-	leaf, directoryID, err = f.dirCache.FindPath(ctx, remote, true)
-	if err != nil {
-		return
-	}
-	// Temporary Object under construction
-	o = &Object{
-		fs:     f,
-		remote: remote,
-	}
-	return o, leaf, directoryID, nil
-}
+The file is made when it is read and holds the premium expiry, the
+number of torrents and links, the time of the last refresh, whether
+the refreshes are stopped after failures and the names of the dead
+and broken torrents. It is made at most every few seconds so reading
+it only calls the API once.
 
-// Put the object
-//
-// # Copy the reader in to the new object which is returned
-//
-// The new object may have been created if an error is returned
-func (f *Fs) Put(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
-	existingObj, err := f.newObjectWithInfo(ctx, src.Remote(), nil)
-	switch err {
-	case nil:
-		return existingObj, existingObj.Update(ctx, in, src, options...)
-	case fs.ErrorObjectNotFound:
-		// Not found so create it
-		return f.PutUnchecked(ctx, in, src, options...)
-	default:
-		return nil, err
-	}
-}
+It is padded with spaces so it has the size listed. It is skipped by
+sync and removing it does nothing.`,
+			Advanced: true,
+			Default:  false,
+		}, {
+			Name: "about_policy",
+			Help: `How the space used and free is reported by about and df.
 
-// PutUnchecked the object into the container
-//
-// # This will produce an error if the object already exists
-//
-// # Copy the reader in to the new object which is returned
-//
-// The new object may have been created if an error is returned
-func (f *Fs) PutUnchecked(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (fs.Object, error) {
-	remote := src.Remote()
-	size := src.Size()
-	modTime := src.ModTime(ctx)
-
-	o, _, _, err := f.createObject(ctx, remote, modTime, size)
-	if err != nil {
-		return nil, err
-	}
-	return o, o.Update(ctx, in, src, options...)
-}
+Real-Debrid has no storage quota so there are no real numbers for the
+total and free space, which makes df on a mount show 0 bytes and some
+applications refuse to scan it.`,
+			Advanced: true,
+			Default:  "synthetic",
+			Examples: []fs.OptionExample{{
+				Value: "synthetic",
+				Help:  "Used is the size of the torrents and 1 PiB is free while premium, none once it expired",
+			}, {
+				Value: "real",
+				Help:  "Only the size of the torrents is reported as used",
+			}, {
+				Value: "off",
+				Help:  "About isn't supported",
+			}},
+			Exclusive: true,
+		}, {
+			Name: "simulate",
+			Help: `Log the changes to the account instead of making them.
 
-// Mkdir creates the container if it doesn't exist
-func (f *Fs) Mkdir(ctx context.Context, dir string) error {
-	_, err := f.dirCache.FindDir(ctx, dir, true)
-	return err
-}
+Deleting links and torrents, and adding torrents again to repair or
+reselect them are logged and skipped. Listing and reading work as
+normal, links are still unrestricted as that is needed to read files.
 
-// purgeCheck removes the root directory, if check is set then it
-// refuses to do so if it has anything in
-func (f *Fs) purgeCheck(ctx context.Context, dir string, check bool) error {
-	//fmt.Printf("Purging torrent: '%s'\n", rootID)
-	root := path.Join(f.root, dir)
-	if root == "" {
-		return errors.New("can't purge root directory")
-	}
-	dc := f.dirCache
-	rootID, err := dc.FindDir(ctx, dir, false)
-	if err != nil {
-		return err
-	}
-	path := "/torrents/delete/" + rootID
-	opts := rest.Opts{
-		Method:     "DELETE",
-		Path:       path,
-		Parameters: f.baseParams(),
-	}
-	var resp *http.Response
-	var result api.Response
-	err = f.pacer.Call(func() (bool, error) {
-		resp, err = f.srv.CallJSON(ctx, &opts, nil, &result)
-		return shouldRetry(ctx, resp, err)
+Uploads and new directories fail as they always do so the exit code of
+a command shows what would have failed. Use this to check changes to
+the category regexes or the filters of a sync against a live account.`,
+			Advanced: true,
+			Default:  false,
+		}, {
+			Name:     config.ConfigEncoding,
+			Help:     config.ConfigEncodingHelp,
+			Advanced: true,
+			// Encode invalid UTF-8 bytes as json doesn't handle them properly.
+			Default: (encoder.Display |
+				encoder.EncodeBackSlash |
+				encoder.EncodeDoubleQuote |
+				encoder.EncodeInvalidUtf8),
+		}},
 	})
-	f.dirCache.FlushDir(dir)
-	return nil
-}
-
-// Rmdir deletes the root folder
-//
-// Returns an error if it isn't empty
-func (f *Fs) Rmdir(ctx context.Context, dir string) error {
-	//fmt.Printf("Rmdir: '%s'\n", dir)
-	return f.purgeCheck(ctx, dir, true)
-}
-
-// Precision return the precision of this Fs
-func (f *Fs) Precision() time.Duration {
-	return fs.ModTimeNotSupported
-}
-
-// Purge deletes all the files in the directory
-//
-// Optional interface: Only implement this if you have a way of
-// deleting all the files quicker than just running Remove() on the
-// result of List()
-func (f *Fs) Purge(ctx context.Context, dir string) error {
-	//fmt.Printf("Purge: '%s'\n", dir)
-	return f.purgeCheck(ctx, dir, false)
-}
-
-// move a file or folder
-//
-// This is complicated by the fact that there is an API to move files
-// between directories and a separate one to rename them.  We try to
-// call the minimum number of API calls.
-func (f *Fs) move(ctx context.Context, isFile bool, id, oldLeaf, newLeaf, oldDirectoryID, newDirectoryID string) (err error) {
-	return nil
-}
-
-// Move src to this remote using server-side move operations.
-//
-// # This is stored with the remote path given
-//
-// # It returns the destination Object and a possible error
-//
-// Will only be called if src.Fs().Name() == f.Name()
-//
-// If it isn't possible then return fs.ErrorCantMove
-func (f *Fs) Move(ctx context.Context, src fs.Object, remote string) (fs.Object, error) {
-	srcObj, ok := src.(*Object)
-	if !ok {
-		fs.Debugf(src, "Can't move - not same remote type")
-		return nil, fs.ErrorCantMove
-	}
-
-	// Create temporary object
-	dstObj, leaf, directoryID, err := f.createObject(ctx, remote, srcObj.modTime, srcObj.size)
-	if err != nil {
-		return nil, err
-	}
-
-	// Do the move
-	err = f.move(ctx, true, srcObj.id, path.Base(srcObj.remote), leaf, srcObj.ParentID, directoryID)
-	if err != nil {
-		return nil, err
-	}
-
-	err = dstObj.readMetaData(ctx)
-	if err != nil {
-		return nil, err
-	}
-	return dstObj, nil
-}
-
-// DirMove moves src, srcRemote to this remote at dstRemote
-// using server-side move operations.
-//
-// Will only be called if src.Fs().Name() == f.Name()
-//
-// If it isn't possible then return fs.ErrorCantDirMove
-//
-// If destination exists then return fs.ErrorDirExists
-func (f *Fs) DirMove(ctx context.Context, src fs.Fs, srcRemote, dstRemote string) error {
-	srcFs, ok := src.(*Fs)
-	if !ok {
-		fs.Debugf(srcFs, "Can't move directory - not same remote type")
-		return fs.ErrorCantDirMove
-	}
-
-	srcID, srcDirectoryID, srcLeaf, dstDirectoryID, dstLeaf, err := f.dirCache.DirMove(ctx, srcFs.dirCache, srcFs.root, srcRemote, f.root, dstRemote)
-	if err != nil {
-		return err
-	}
-
-	// Do the move
-	err = f.move(ctx, false, srcID, srcLeaf, dstLeaf, srcDirectoryID, dstDirectoryID)
-	if err != nil {
-		return err
-	}
-	srcFs.dirCache.FlushDir(srcRemote)
-	return nil
-}
-
-// PublicLink adds a "readable by anyone with link" permission on the given file or folder.
-func (f *Fs) PublicLink(ctx context.Context, remote string, expire fs.Duration, unlink bool) (string, error) {
-	_, err := f.dirCache.FindDir(ctx, remote, false)
-	if err == nil {
-		return "", fs.ErrorCantShareDirectories
-	}
-	o, err := f.NewObject(ctx, remote)
-	if err != nil {
-		return "", err
-	}
-	return o.(*Object).url, nil
-}
-
-// About gets quota information
-func (f *Fs) About(ctx context.Context) (usage *fs.Usage, err error) {
-	return usage, nil
-}
-
-// DirCacheFlush resets the directory cache - used in testing as an
-// optional interface
-func (f *Fs) DirCacheFlush() {
-	f.dirCache.ResetRoot()
-}
-
-// Hashes returns the supported hash sets.
-func (f *Fs) Hashes() hash.Set {
-	return hash.Set(hash.None)
-}
-
-// ------------------------------------------------------------
-
-// Fs returns the parent Fs
-func (o *Object) Fs() fs.Info {
-	return o.fs
-}
-
-// Return a string version
-func (o *Object) String() string {
-	if o == nil {
-		return "<nil>"
-	}
-	return o.remote
-}
-
-// Remote returns the remote path
-func (o *Object) Remote() string {
-	return o.remote
-}
-
-// Hash returns the SHA-1 of an object returning a lowercase hex string
-func (o *Object) Hash(ctx context.Context, t hash.Type) (string, error) {
-	return "", hash.ErrUnsupported
-}
-
-// Size returns the size of an object in bytes
-func (o *Object) Size() int64 {
-	err := o.readMetaData(context.TODO())
-	if err != nil {
-		fs.Logf(o, "Failed to read metadata: %v", err)
-		return 0
-	}
-	return o.size
-}
-
-// setMetaData sets the metadata from info
-func (o *Object) setMetaData(info *api.Item) (err error) {
-	if info.Type != "file" {
-		return fmt.Errorf("%q is %q: %w", o.remote, info.Type, fs.ErrorNotAFile)
-	}
-	o.hasMetaData = true
-	o.size = info.Size
-	o.modTime = time.Unix(info.CreatedAt, 0)
-	o.id = info.ID
-	o.mimeType = info.MimeType
-	o.url = info.Link
-	o.OriginalUrl = info.OriginalLink
-	o.ParentID = info.ParentID
-	o.TorrentHash = info.TorrentHash
-	return nil
-}
-
-// readMetaData gets the metadata if it hasn't already been fetched
-//
-// it also sets the info
-func (o *Object) readMetaData(ctx context.Context) (err error) {
-	if o.hasMetaData {
-		return nil
-	}
-	info, err := o.fs.readMetaDataForPath(ctx, o.remote, false, true)
-	if err != nil {
-		return err
-	}
-	return o.setMetaData(info)
-}
-
-// ModTime returns the modification time of the object
-//
-// It attempts to read the objects mtime and if that isn't present the
-// LastModified returned in the http headers
-func (o *Object) ModTime(ctx context.Context) time.Time {
-	err := o.readMetaData(ctx)
-	if err != nil {
-		fs.Logf(o, "Failed to read metadata: %v", err)
-		return time.Now()
-	}
-	return o.modTime
-}
-
-// SetModTime sets the modification time of the local fs object
-func (o *Object) SetModTime(ctx context.Context, modTime time.Time) error {
-	return fs.ErrorCantSetModTime
-}
-
-// Storable returns a boolean showing whether this object storable
-func (o *Object) Storable() bool {
-	return true
-}
-
-// Open an object for read
-func (o *Object) Open(ctx context.Context, options ...fs.OpenOption) (in io.ReadCloser, err error) {
-	//fmt.Printf("-- Open dl-link : %s --\n", o.url)
-	if o.url == "" {
-		fmt.Println("00 - Url is empty, should theorically not happen")
-		return nil, errors.New("can't download - no URL")
-	}
-	fs.FixRangeOption(options, o.size)
-	var resp *http.Response
-	var err_code = 0
-	opts := rest.Opts{
-		Path:    "",
-		RootURL: o.url,
-		Method:  "GET",
-		Options: options,
-	}
-	err = o.fs.pacer.Call(func() (bool, error) {
-		resp, err = o.fs.srv.Call(ctx, &opts)
-		if resp != nil {
-			err_code = resp.StatusCode
-		}
-		//fmt.Printf("-- Open HTTP code is : %d --\n", err_code)
-		if !fserrors.ShouldRetryHTTP(resp, retryErrorCodes) && err_code != 200 && err_code != 206 {
-			// it means it is a link to unrestrict again
-			fmt.Printf("0 - URL %s is down, need to unrestrict original link again\n", o.url)
-			// then go through cachedfile to find Originallink (o.OriginalUrl)
-			var broken = false
-			for i, cachedfile := range cached {
-
-				if cachedfile.Link == o.url {
-
-					// found the one badguy (could be several potentially but we break at the first one found)
-					// we don't delete it from cached, it will be replaced in place
-					//fmt.Printf("1 - Try to delete from RD API the link id : %s\n", cachedfile.ID)
-					path := "/downloads/delete/" + cachedfile.ID
-					opts = rest.Opts{
-						Method:     "DELETE",
-						Path:       path,
-						Parameters: o.fs.baseParams(),
-					}
-					var resp *http.Response
-					var result api.Response
-					var retries = 0
-					var err_code = 0
-					resp, _ = o.fs.srv.CallJSON(ctx, &opts, nil, &result)
-					if resp != nil {
-						err_code = resp.StatusCode
-					}
-					for err_code == 429 && retries <= 5 {
-						time.Sleep(time.Duration(2) * time.Second)
-						resp, _ = o.fs.srv.CallJSON(ctx, &opts, nil, &result)
-						if resp != nil {
-							err_code = resp.StatusCode
-						}
-						retries += 1
-					}
-
-					// unrestrict to have new link
-					fmt.Printf("2 - Unrestrict with original link : %s\n", cachedfile.OriginalLink)
-					var tempFile api.Item
-					path = "/unrestrict/link"
-					method := "POST"
-					opts := rest.Opts{
-						Method: method,
-						Path:   path,
-						MultipartParams: url.Values{
-							"link": {cachedfile.OriginalLink},
-						},
-						Parameters: o.fs.baseParams(),
-					}
-					err_code = 0
-					resp, _ = o.fs.srv.CallJSON(ctx, &opts, nil, &tempFile)
-					if resp != nil {
-						err_code = resp.StatusCode
-					}
-					if err_code != 200 && err_code != 429 {
-						broken = true
-						break
-					}
-					retries = 0
-					for err_code == 429 && retries <= 5 {
-						time.Sleep(time.Duration(2) * time.Second)
-						resp, _ = o.fs.srv.CallJSON(ctx, &opts, nil, &tempFile)
-						if resp != nil {
-							err_code = resp.StatusCode
-						}
-						retries += 1
-					}
-
-					// replace in: opts, o. and cachedfile (so no need to reset lastcheck var)
-
-					if !broken && tempFile.Link != "" {
-						o.url = tempFile.Link
-						opts.RootURL = tempFile.Link   // will right away retry with new rest opts
-						cached[i].Link = tempFile.Link // so no need to add it at top of cached array
-					}
-
-					break
-				}
-
-			}
-
-			alreadyTracked := false
-			if broken {
-				for _, TorrentID := range broken_torrents {
-					if o.ParentID == TorrentID {
-						alreadyTracked = true
-						fmt.Println("Live unrestriction failed for stalled link: '" + o.url + "'")
-						fmt.Println(", Torrent broken and already tracked.")
-					}
-				}
-				if !alreadyTracked {
-					fmt.Println("Live unrestriction failed for stalled link: '" + o.url + "'")
-					fmt.Println(", so Torrent broken and added to tracked broken_torrents.")
-					broken_torrents = append(broken_torrents, o.ParentID)
-				}
-			}
-
-			if !broken && opts.RootURL != "" {
-				return true, err // if unrestrict not broken and link not empty, it will retry with new URL value
-				// issue here if unrestricting is ok but dl-link provided fails over and over again TODO fix
-			}
-
-		}
-
-		return shouldRetry(ctx, resp, err)
+	rc.Add(rc.Call{
+		Path:   "realdebrid/stats",
+		NoAuth: true,
+		Fn:     rcStats,
+		Title:  "Get Real-Debrid backend stats.",
+		Help: `
+This returns counters of the requests made to Real-Debrid by all the
+realdebrid remotes since rclone started. Apart from the generation the
+counters only increase so they can be scraped as is.
+
+    {
+        // requests to the API
+        "apiCalls": 1234,
+        // API requests which failed other than with 429
+        "apiErrors": 2,
+        // requests for file content
+        "downloads": 567,
+        // requests for file content which failed
+        "downloadErrors": 1,
+        // expired download links replaced when opening a file
+        "relinks": 3,
+        // dead or broken torrents added again
+        "repairs": 0,
+        // repairs left for a later refresh as files of the torrent were open
+        "repairsDeferred": 0,
+        // torrents given up on after repair_max_attempts
+        "repairsFailed": 0,
+        // download links checked in the background, see verify_links_per_hour
+        "verifiedLinks": 24,
+        // checked download links which failed
+        "deadLinks": 1,
+        // dead links which couldn't be unrestricted again
+        "brokenLinks": 0,
+        // checks skipped while files were streamed
+        "verifyPaused": 5,
+        // API calls skipped by the simulate option
+        "simulated": 0,
+        // generation of the latest list of torrents, which increases
+        // each time the torrents are refreshed, deleted or repaired
+        "generation": 42,
+        // refreshes of the torrents which failed or ran out of
+        // refresh_budget
+        "refreshErrors": 1,
+        // times the refreshes were stopped after
+        // refresh_circuit_failures failed refreshes in a row
+        "circuitOpened": 0,
+        // refreshes skipped while they were stopped
+        "refreshSkipped": 0,
+        // refreshes made in the background, see background_refresh
+        "autoRefreshes": 4,
+        // lookups of missing files answered without listing their
+        // directory
+        "missHits": 130,
+        // listings of the torrents listed before as they couldn't be
+        // refreshed, see serve_stale_on_error
+        "staleListings": 2,
+        // files not opened as max_concurrent_streams were open
+        "streamLimitHits": 3,
+        // API responses with 429 Too Many Requests
+        "tooManyRequests": 12,
+        // requests to /unrestrict/link
+        "unrestricts": 40
+    }
+
+The bytes read through the VFS in each mode of the hybrid read path
+are returned by vfs/stats.
+`,
 	})
-	if err != nil {
-		/* this is already happened in above code
-		if err_code == 503 {
-			for _, TorrentID := range broken_torrents {
-				if o.ParentID == TorrentID {
-					return nil, err
-				}
-			}
-			fmt.Println("Error opening file: '" + o.url + "'.")
-			fmt.Println("This link seems to be broken. Torrent will be re-downloaded on next refresh.")
-			broken_torrents = append(broken_torrents, o.ParentID)
-		}
-		*/
-		return nil, err
-	}
-	return resp.Body, err
-}
-
-// Update the object with the contents of the io.Reader, modTime and size
-//
-// # If existing is set then it updates the object rather than creating a new one
-//
-// The new object may have been created if an error is returned
-func (o *Object) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) (err error) {
-	return nil
-}
-
-// Remove an object by ID
-func (f *Fs) remove(ctx context.Context, id ...string) (err error) {
-	//fmt.Printf("Removing direct link id: '%s'\n", id[0])
-	//if f.opt.RootFolderID == "torrents" {
-	//	fmt.Printf("Removing torrent id: '%s'\n", id[1])
-	//}
-	path := "/downloads/delete/" + id[0]
-	opts := rest.Opts{
-		Method:     "DELETE",
-		Path:       path,
-		Parameters: f.baseParams(),
-	}
-	var resp *http.Response
-	var result api.Response
-	var retries = 0
-	var err_code = 0
-	resp, _ = f.srv.CallJSON(ctx, &opts, nil, &result)
-	if resp != nil {
-		err_code = resp.StatusCode
-	}
-	for err_code == 429 && retries <= 5 {
-		time.Sleep(time.Duration(2) * time.Second)
-		resp, _ = f.srv.CallJSON(ctx, &opts, nil, &result)
-		if resp != nil {
-			err_code = resp.StatusCode
-		}
-		retries += 1
-	}
-	if f.opt.RootFolderID == "torrents" {
-		path = "/torrents/delete/" + id[1]
-		opts := rest.Opts{
-			Method:     "DELETE",
-			Path:       path,
-			Parameters: f.baseParams(),
-		}
-		var resp *http.Response
-		var result api.Response
-		var err_code = 0
-		resp, _ = f.srv.CallJSON(ctx, &opts, nil, &result)
-		if resp != nil {
-			err_code = resp.StatusCode
-		}
-		if err_code == 429 {
-			time.Sleep(time.Duration(2) * time.Second)
-			_, _ = f.srv.CallJSON(ctx, &opts, nil, &result)
-		}
-	}
-	lastcheck = time.Now().Unix() - interval
-	return nil
-}
-
-// Remove an object
-func (o *Object) Remove(ctx context.Context) error {
-	//fmt.Printf("Removing: '%s'\n", o.remote)
-	err := o.readMetaData(ctx)
-	if err != nil {
-		return fmt.Errorf("Remove: Failed to read metadata: %w", err)
-	}
-	if o.ParentID != "" {
-		return o.fs.remove(ctx, o.id, o.ParentID)
-	} else {
-		return o.fs.remove(ctx, o.id)
-	}
 }
 
-// MimeType of an Object if known, "" otherwise
-func (o *Object) MimeType(ctx context.Context) string {
-	return o.mimeType
-}
-
-// ID returns the ID of the Object if known, or "" if not
-func (o *Object) ID() string {
-	return o.id
+// Options defines the configuration for this backend
+type Options struct {
+	RegexShows             string               `config:"regex_shows"`
+	RegexMovies            string               `config:"regex_movies"`
+	RegexAnime             string               `config:"regex_anime"`
+	RegexExclude           string               `config:"regex_exclude"`
+	MoviesByYear           bool                 `config:"movies_by_year"`
+	Categories             string               `config:"categories"`
+	CategoriesFallback     string               `config:"categories_fallback"`
+	SharedFolder           string               `config:"folder_mode"`
+	RootFolderID           string               `config:"download_mode"`
+	APIKey                 string               `config:"api_key"`
+	ShareCache             bool                 `config:"share_cache"`
+	MinTorrentSize         fs.SizeSuffix        `config:"min_torrent_size"`
+	FlattenSingleFile      bool                 `config:"flatten_single_file"`
+	NestedFolders          bool                 `config:"nested_folders"`
+	VideoOnly              bool                 `config:"video_only"`
+	VideoExtensions        fs.CommaSepList      `config:"video_extensions"`
+	PrewarmDirCache        bool                 `config:"prewarm_dircache"`
+	ProgressiveListing     bool                 `config:"progressive_listing"`
+	ListSort               string               `config:"list_sort"`
+	PurgeCategories        bool                 `config:"purge_categories"`
+	InfoCacheSize          int                  `config:"torrent_info_cache_size"`
+	InfoCacheTTL           fs.Duration          `config:"torrent_info_cache_ttl"`
+	VerifyLinksPerHour     int                  `config:"verify_links_per_hour"`
+	VerifyPauseStreams     int                  `config:"verify_pause_streams"`
+	MaxConcurrentStreams   int                  `config:"max_concurrent_streams"`
+	DownloadsMaxAge        fs.Duration          `config:"downloads_max_age"`
+	AutoPruneDownloads     bool                 `config:"auto_prune_downloads"`
+	ScanDownloads          bool                 `config:"scan_downloads"`
+	Simulate               bool                 `config:"simulate"`
+	ClassifyCommand        fs.SpaceSepList      `config:"classify_command"`
+	ClassifyURL            string               `config:"classify_url"`
+	ClassifyTimeout        fs.Duration          `config:"classify_timeout"`
+	ClassifyBatchSize      int                  `config:"classify_batch_size"`
+	CacheRefreshInterval   fs.Duration          `config:"cache_refresh_interval"`
+	BackgroundRefresh      bool                 `config:"background_refresh"`
+	RefreshBudget          fs.Duration          `config:"refresh_budget"`
+	RefreshBudgetRequests  int                  `config:"refresh_budget_requests"`
+	RefreshCircuitFailures int                  `config:"refresh_circuit_failures"`
+	RepairMaxAttempts      int                  `config:"repair_max_attempts"`
+	RefreshCircuitBackoff  fs.Duration          `config:"refresh_circuit_backoff"`
+	ServeStaleOnError      bool                 `config:"serve_stale_on_error"`
+	PacerMinSleep          fs.Duration          `config:"pacer_min_sleep"`
+	PacerMaxSleep          fs.Duration          `config:"pacer_max_sleep"`
+	APIRetries             int                  `config:"api_retries"`
+	ListPageSize           int                  `config:"list_page_size"`
+	APITimeout             fs.Duration          `config:"api_timeout"`
+	APIRetryDelay          fs.Duration          `config:"api_retry_delay"`
+	DumpDir                string               `config:"dump_dir"`
+	DumpMaxAge             fs.Duration          `config:"dump_max_age"`
+	UnicodeNormalization   bool                 `config:"unicode_normalization"`
+	ShowStatusFile         bool                 `config:"show_status_file"`
+	AboutPolicy            string               `config:"about_policy"`
+	Enc                    encoder.MultiEncoder `config:"encoding"`
 }
-
-// Check the interfaces are satisfied
-var (
-	_ fs.Fs              = (*Fs)(nil)
-	_ fs.Purger          = (*Fs)(nil)
-	_ fs.Mover           = (*Fs)(nil)
-	_ fs.DirMover        = (*Fs)(nil)
-	_ fs.DirCacheFlusher = (*Fs)(nil)
-	_ fs.Abouter         = (*Fs)(nil)
-	_ fs.PublicLinker    = (*Fs)(nil)
-	_ fs.Object          = (*Object)(nil)
-	_ fs.MimeTyper       = (*Object)(nil)
-	_ fs.IDer            = (*Object)(nil)
-)
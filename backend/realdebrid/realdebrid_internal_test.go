@@ -0,0 +1,1413 @@
+package realdebrid
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/object"
+	"github.com/rclone/rclone/lib/dircache"
+	"github.com/rclone/rclone/lib/pacer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFs makes an Fs in torrents/folders mode which serves
+// listings from its cache without calling the API.
+func newTestFs(t *testing.T) *Fs {
+	f := &Fs{
+		name: "test",
+		opt: Options{
+			RootFolderID: "torrents",
+			SharedFolder: "folders",
+			RegexShows:   `(?i)(S[0-9]{2}|SEASON|COMPLETE|[^457a-z\W\s]-[0-9]+)`,
+			RegexMovies:  `(?i)(19|20)([0-9]{2} ?\.?)`,
+
+			UnicodeNormalization: true,
+			CacheRefreshInterval: fs.Duration(15 * time.Minute),
+			ScanDownloads:        true,
+		},
+	}
+	f.categoryList = newCategories(&f.opt)
+	f.dirCache = dircache.New("", rootID, f)
+	f.cache = &sharedCache{dir: t.TempDir()}
+	f.cache.state.MarkFresh(0)
+	f.infos = newInfoCache(512, 10*time.Minute)
+	f.misses = newMissCache(time.Duration(f.opt.CacheRefreshInterval))
+	f.cooldowns = newCooldowns()
+	f.features = (&fs.Features{}).Fill(context.Background(), f)
+	f.ctx, f.stop = context.WithCancel(context.Background())
+	t.Cleanup(f.stop)
+	return f
+}
+
+func entryNames(entries fs.DirEntries) (names []string) {
+	for _, entry := range entries {
+		names = append(names, entry.Remote())
+	}
+	return names
+}
+
+func TestUniqueTorrentNames(t *testing.T) {
+	// newest first as returned by the API
+	items := uniqueTorrentNames([]api.Item{
+		{ID: "C", Name: "Show.S01"},
+		{ID: "B", Name: "Movie.2020"},
+		{ID: "A", Name: "show.s01"},
+	})
+	var names []string
+	for _, item := range items {
+		names = append(names, item.Name)
+	}
+	assert.Equal(t, []string{"Show.S01 (C)", "Movie.2020", "show.s01"}, names)
+}
+
+func TestClassify(t *testing.T) {
+	f := newTestFs(t)
+	f.opt.RegexShows = `(?i)(S[0-9]{2})`
+	f.opt.RegexMovies = `(?i)(19|20)([0-9]{2})`
+	f.categoryList = newCategories(&f.opt)
+	torrents := []api.Item{
+		{ID: "1", Name: "Show.S01.2020"},
+		{ID: "2", Name: "Movie.2020"},
+		{ID: "3", Name: "Something"},
+	}
+	for _, test := range []struct {
+		dirID string
+		want  string
+	}{
+		{"shows", "1"},
+		{"movies", "2"},
+		{"default", "3"},
+	} {
+		items := f.classify(torrents, test.dirID, nil)
+		require.Len(t, items, 1, test.dirID)
+		assert.Equal(t, test.want, items[0].ID, test.dirID)
+	}
+}
+
+func TestListSorted(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	apiTorrents := []api.Item{
+		{ID: "1", Name: "Zebra.S01", Status: "downloaded", Links: []string{"l3", "l1", "l2"}},
+		{ID: "2", Name: "Alpha.S02", Status: "downloaded"},
+		{ID: "3", Name: "middle.S01", Status: "downloaded"},
+		{ID: "4", Name: "Beta.S03", Status: "downloaded"},
+	}
+	f.cache.torrentswf = []api.Item{apiTorrents[0]}
+	f.cache.cached = []api.Item{
+		{ID: "c2", Name: "b.mkv", OriginalLink: "l2", Link: "https://example.com/2"},
+		{ID: "c3", Name: "c.mkv", OriginalLink: "l3", Link: "https://example.com/3"},
+		{ID: "c1", Name: "a.mkv", OriginalLink: "l1", Link: "https://example.com/1"},
+	}
+
+	// Category folders are in a fixed order
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shows", "movies", "default"}, entryNames(entries))
+
+	// Two refreshes returning the torrents in a different order
+	// list the same
+	var listings [][]string
+	for range 2 {
+		rand.Shuffle(len(apiTorrents), func(i, j int) {
+			apiTorrents[i], apiTorrents[j] = apiTorrents[j], apiTorrents[i]
+		})
+		f.cache.torrents = append([]api.Item(nil), apiTorrents...)
+		entries, err := f.List(ctx, "shows")
+		require.NoError(t, err)
+		listings = append(listings, entryNames(entries))
+	}
+	want := []string{"shows/Alpha.S02", "shows/Beta.S03", "shows/Zebra.S01", "shows/middle.S01"}
+	assert.Equal(t, want, listings[0])
+	assert.Equal(t, want, listings[1])
+
+	// Files in a torrent are sorted too
+	entries, err = f.List(ctx, "shows/Zebra.S01")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shows/Zebra.S01/a.mkv", "shows/Zebra.S01/b.mkv", "shows/Zebra.S01/c.mkv"}, entryNames(entries))
+}
+
+func TestListDates(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	added := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	f.cache.torrents = []api.Item{
+		{ID: "1", Name: "Zebra.S01", Status: "downloaded", Links: []string{"l1"}, Ended: "2024-03-01T12:30:00.000Z"},
+		{ID: "2", Name: "Alpha.S02", Status: "downloaded", Ended: "2023-01-01T00:00:00.000Z"},
+	}
+	f.cache.torrentswf = []api.Item{f.cache.torrents[0]}
+	f.cache.cached = []api.Item{
+		{ID: "c1", Name: "a.mkv", OriginalLink: "l1", Link: "https://example.com/1", Generated: "2025-06-01T08:00:00.000Z"},
+	}
+
+	// The category folders have the time of their newest torrent
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.True(t, entries[0].ModTime(ctx).Equal(added), entries[0].ModTime(ctx))
+	placeholder := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	assert.True(t, entries[1].ModTime(ctx).Equal(placeholder), entries[1].ModTime(ctx))
+
+	// and the torrent folders and their files when it was added
+	entries, err = f.List(ctx, "shows")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shows/Zebra.S01", "shows/Alpha.S02"}, entryNames(entries))
+	assert.True(t, entries[0].ModTime(ctx).Equal(added), entries[0].ModTime(ctx))
+	entries, err = f.List(ctx, "shows/Zebra.S01")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.True(t, entries[0].ModTime(ctx).Equal(added), entries[0].ModTime(ctx))
+
+	// falling back to when the link was unrestricted
+	f.cache.torrents[0].Ended = ""
+	f.cache.torrentswf[0].Ended = ""
+	entries, err = f.List(ctx, "shows/Zebra.S01")
+	require.NoError(t, err)
+	want := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	assert.True(t, entries[0].ModTime(ctx).Equal(want), entries[0].ModTime(ctx))
+}
+
+func TestTorrentsFolderMode(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.opt.SharedFolder = "torrents"
+	f.cache.torrents = []api.Item{
+		{ID: "1", Name: "Zebra.S01", Status: "downloaded", Links: []string{"l1", "l2"}},
+		{ID: "2", Name: "Alpha.2020", Status: "downloaded"},
+		{ID: "3", Name: "zebra.s01", Status: "downloaded"},
+	}
+	f.cache.torrentswf = []api.Item{f.cache.torrents[0]}
+	f.cache.cached = []api.Item{
+		{ID: "c2", Name: "b.mkv", OriginalLink: "l2", Link: "https://example.com/2", Size: 2},
+		{ID: "c1", Name: "a.mkv", OriginalLink: "l1", Link: "https://example.com/1", Size: 1},
+	}
+
+	// The torrents are at the root with no category folders
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alpha.2020", "Zebra.S01 (1)", "zebra.s01"}, entryNames(entries))
+	for _, entry := range entries {
+		_, isDir := entry.(fs.Directory)
+		assert.True(t, isDir, entry.Remote())
+	}
+
+	// Files of a torrent
+	entries, err = f.List(ctx, "Zebra.S01 (1)")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Zebra.S01 (1)/a.mkv", "Zebra.S01 (1)/b.mkv"}, entryNames(entries))
+
+	// Resolve root -> torrent -> file from a cold directory cache
+	f.dirCache.Flush()
+	o, err := f.NewObject(ctx, "Zebra.S01 (1)/b.mkv")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), o.Size())
+	_, err = f.NewObject(ctx, "Zebra.S01 (1)/missing.mkv")
+	assert.ErrorIs(t, err, fs.ErrorObjectNotFound)
+	_, err = f.List(ctx, "shows")
+	assert.ErrorIs(t, err, fs.ErrorDirNotFound)
+}
+
+func TestRemove(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.opt.SharedFolder = "torrents"
+	var (
+		mu       sync.Mutex
+		requests = map[string]int{}
+		live     = map[string]bool{}
+	)
+	for i := range 50 {
+		torrentID := "t" + strconv.Itoa(i)
+		torrent := api.Item{ID: torrentID, Name: "Torrent" + strconv.Itoa(i), Status: "downloaded"}
+		for j := range 2 {
+			linkID := torrentID + "-" + strconv.Itoa(j)
+			torrent.Links = append(torrent.Links, "link-"+linkID)
+			f.cache.cached = append(f.cache.cached, api.Item{ID: linkID, Name: linkID + ".mkv", OriginalLink: "link-" + linkID, Link: "https://example.com/" + linkID})
+			live[linkID] = true
+		}
+		f.cache.torrents = append(f.cache.torrents, torrent)
+		f.cache.torrentswf = append(f.cache.torrentswf, torrent)
+		live[torrentID] = true
+	}
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests[r.Method+" "+path.Dir(r.URL.Path)+"?page="+r.URL.Query().Get("page")+"&offset="+r.URL.Query().Get("offset")]++
+		if r.Method == "DELETE" {
+			id := path.Base(r.URL.Path)
+			if !live[id] {
+				w.WriteHeader(http.StatusNotFound)
+				writeJSON(t, w, api.Response{Status: "error", Message: "unknown_ressource"})
+				return
+			}
+			delete(live, id)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		var items []api.Item
+		for _, torrent := range f.cache.torrents {
+			if live[torrent.ID] {
+				items = append(items, torrent)
+			}
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(items)))
+		writeJSON(t, w, items)
+	})
+
+	// Prune all the files
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 50)
+	for _, dir := range entries {
+		files, err := f.List(ctx, dir.Remote())
+		require.NoError(t, err)
+		require.Len(t, files, 2)
+		for _, file := range files {
+			require.NoError(t, file.(fs.Object).Remove(ctx))
+		}
+	}
+	entries, err = f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, entries, 0)
+
+	assert.Equal(t, 100, requests["DELETE /downloads/delete?page=&offset="])
+	assert.Equal(t, 50, requests["DELETE /torrents/delete?page=&offset="])
+	assert.Equal(t, 0, requests["GET /torrents?page=&offset=0"], "full refreshes")
+	assert.Empty(t, live)
+	assert.Empty(t, f.cache.cached)
+	assert.Empty(t, f.cache.torrentswf)
+
+	// Failed deletes return the API error
+	o, err := f.newObjectWithInfo(ctx, "Torrent0/gone.mkv", &api.Item{ID: "gone", ParentID: "t0", Type: api.ItemTypeFile})
+	require.NoError(t, err)
+	err = o.Remove(ctx)
+	var apiErr *api.Response
+	require.True(t, errors.As(err, &apiErr), err)
+	assert.Equal(t, "unknown_ressource", apiErr.Message)
+	assert.ErrorIs(t, f.Purge(ctx, "Torrent0"), fs.ErrorDirNotFound)
+}
+
+func TestRemoveConcurrent(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	var (
+		mu       sync.Mutex
+		requests int
+		deletes  = map[string]int{}
+		objects  []fs.Object
+	)
+	for i := range 25 {
+		torrentID := "t" + strconv.Itoa(i)
+		f.cache.torrents = append(f.cache.torrents, api.Item{ID: torrentID, Status: "downloaded"})
+		for j := range 2 {
+			linkID := torrentID + "-" + strconv.Itoa(j)
+			o, err := f.newObjectWithInfo(ctx, linkID+".mkv", &api.Item{ID: linkID, ParentID: torrentID, Type: api.ItemTypeFile})
+			require.NoError(t, err)
+			objects = append(objects, o)
+		}
+	}
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		// over quota every 5th request
+		if requests%5 == 0 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			writeJSON(t, w, api.Response{Status: "error", Message: "too_many_requests"})
+			return
+		}
+		deletes[r.URL.Path]++
+		w.WriteHeader(http.StatusNoContent)
+	})
+	const minSleep = 5 * time.Millisecond
+	f.client.pacer = fs.NewPacer(ctx, pacer.NewDefault(pacer.MinSleep(minSleep), pacer.MaxSleep(20*time.Millisecond)))
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, o := range objects {
+		wg.Add(1)
+		go func(o fs.Object) {
+			defer wg.Done()
+			assert.NoError(t, o.Remove(ctx))
+		}(o)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	// Each link and torrent was deleted exactly once
+	assert.Len(t, deletes, 75)
+	for path, n := range deletes {
+		assert.Equal(t, 1, n, path)
+	}
+	assert.Empty(t, f.cache.torrents)
+	assert.Empty(t, f.cache.deletingTorrents)
+
+	// The pacer spaced out all the requests including the retries
+	assert.GreaterOrEqual(t, elapsed, time.Duration(requests-1)*minSleep)
+}
+
+func TestMissingPathsNoAPICalls(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.cache.torrents = []api.Item{
+		{ID: "1", Name: "Movie.2020", Status: "downloaded", Links: []string{"l1"}},
+	}
+	var requests atomic.Int32
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	for _, remote := range []string{
+		"movies/NotThere/file.mkv",
+		"movies/NotThere/deeper/still/file.mkv",
+		"nocategory/Movie.2020/file.mkv",
+		"movies/Movie.2020/sub/file.mkv",
+		"movies/Movie.2020/sub/deeper/file.mkv",
+		"movies/file.mkv",
+		"file.mkv",
+	} {
+		_, err := f.NewObject(ctx, remote)
+		assert.ErrorIs(t, err, fs.ErrorObjectNotFound, remote)
+	}
+	for _, dir := range []string{"movies/NotThere", "movies/NotThere/deeper", "movies/Movie.2020/sub"} {
+		_, err := f.List(ctx, dir)
+		assert.ErrorIs(t, err, fs.ErrorDirNotFound, dir)
+	}
+	assert.Equal(t, int32(0), requests.Load())
+}
+
+func TestFilesModeNoUnrestrict(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.opt.SharedFolder = "files"
+	var (
+		mu       sync.Mutex
+		requests = map[string]int{}
+	)
+	count := func(r *http.Request) {
+		mu.Lock()
+		requests[r.Method+" "+r.URL.Path]++
+		mu.Unlock()
+	}
+	downloads := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count(r)
+		_, _ = w.Write(make([]byte, 200))
+	}))
+	t.Cleanup(downloads.Close)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		count(r)
+		switch r.URL.Path {
+		case "/torrents/info/2":
+			writeJSON(t, w, api.Item{ID: "2", Name: "Movie.2021", Status: "downloaded", Links: []string{"l2"}, Files: []api.File{
+				{ID: 1, Path: "/Movie.2021/sample.mkv", Bytes: 10},
+				{ID: 2, Path: "/Movie.2021/Movie.2021.mkv", Bytes: 200, Selected: 1},
+			}})
+		case "/unrestrict/link":
+			assert.Equal(t, "l2", r.FormValue("link"))
+			writeJSON(t, w, api.Item{ID: "d2", Name: "Movie.2021.mkv", Link: downloads.URL + "/d/Movie.2021.mkv", Size: 200})
+		default:
+			t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	calls := func() map[string]int {
+		mu.Lock()
+		defer mu.Unlock()
+		calls := requests
+		requests = map[string]int{}
+		return calls
+	}
+	one := api.Item{ID: "1", Name: "Movie.2020", Status: "downloaded", Links: []string{"l1a", "l1b"}, Files: []api.File{
+		{ID: 1, Path: "/Movie.2020/Movie.2020.mkv", Bytes: 100, Selected: 1},
+		{ID: 2, Path: "/Movie.2020/Movie.2020.srt", Bytes: 1, Selected: 1},
+	}}
+	f.cache.torrents = []api.Item{
+		one,
+		{ID: "2", Name: "Movie.2021", Status: "downloaded", Links: []string{"l2"}},
+		{ID: "3", Name: "Movie.2022", Status: "downloading"},
+	}
+	f.cache.torrentswf = []api.Item{one}
+	// l1b was unrestricted before so keeps its name from then
+	f.cache.cached = []api.Item{{ID: "d1", Name: "Movie.2020.en.srt", OriginalLink: "l1b", Link: "https://example.com/1", Size: 1}}
+
+	// Only the torrent without cached details is looked up
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Movie.2020.mkv", "Movie.2020.en.srt", "Movie.2021.mkv"}, entryNames(entries))
+	assert.Equal(t, map[string]int{"GET /torrents/info/2": 1}, calls())
+
+	// and only once
+	f.dirCache.Flush()
+	_, err = f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Empty(t, calls())
+
+	// Completing paths doesn't call the API at all
+	for _, remote := range []string{"Movie", "Movie.2021", "Movie.2021.mk", "Movie.2020/Movie.2020.mkv"} {
+		_, err = f.NewObject(ctx, remote)
+		assert.ErrorIs(t, err, fs.ErrorObjectNotFound, remote)
+	}
+	o, err := f.NewObject(ctx, "Movie.2021.mkv")
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), o.Size())
+	assert.Empty(t, calls())
+
+	// The link is unrestricted when the file is opened
+	in, err := o.Open(ctx)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, map[string]int{"POST /unrestrict/link": 1, "GET /d/Movie.2021.mkv": 1}, calls())
+	in, err = o.Open(ctx)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, map[string]int{"GET /d/Movie.2021.mkv": 1}, calls())
+}
+
+func TestFingerprintStable(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.opt.SharedFolder = "torrents"
+	fingerprint := func(remote string) string {
+		o, err := f.NewObject(ctx, remote)
+		require.NoError(t, err)
+		fingerprint := fs.Fingerprint(ctx, o, false)
+		assert.Equal(t, fingerprint, fs.Fingerprint(ctx, o, true))
+		return fingerprint
+	}
+	torrent := api.Item{ID: "1", Name: "Movie.2020", Status: "downloaded", TorrentHash: "abc", Links: []string{"l1"}}
+	f.cache.torrents = []api.Item{torrent}
+	f.cache.torrentswf = []api.Item{torrent}
+	f.cache.cached = []api.Item{{ID: "c1", Name: "movie.mkv", OriginalLink: "l1", Link: "https://example.com/1", Size: 100, Generated: "2024-01-01T00:00:00.000Z"}}
+	before := fingerprint("Movie.2020/movie.mkv")
+
+	// Repairing adds the torrent again so it has a new ID and the
+	// file a new link and download link generated later
+	torrent.ID, torrent.Links = "2", []string{"l2"}
+	f.cache.torrents = []api.Item{torrent}
+	f.cache.torrentswf = []api.Item{torrent}
+	f.cache.cached = []api.Item{{ID: "c2", Name: "movie.mkv", OriginalLink: "l2", Link: "https://example.com/2", Size: 100, Generated: "2024-06-01T00:00:00.000Z"}}
+	f.dirCache.Flush()
+	assert.Equal(t, before, fingerprint("Movie.2020/movie.mkv"))
+
+	// but different content changes it
+	f.cache.cached[0].Size = 101
+	assert.NotEqual(t, before, fingerprint("Movie.2020/movie.mkv"))
+}
+
+func TestSharedCache(t *testing.T) {
+	ctx := context.Background()
+	var (
+		mu       sync.Mutex
+		requests = map[string]int{}
+	)
+	apiTorrents := []api.Item{
+		{ID: "1", Name: "Show.S01", Status: "downloaded"},
+		{ID: "2", Name: "Other.S02", Status: "downloaded"},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path+"?page="+r.URL.Query().Get("page")+"&offset="+r.URL.Query().Get("offset")]++
+		mu.Unlock()
+		var items []api.Item
+		if r.URL.Path == "/torrents" {
+			items = apiTorrents
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(items)))
+		_ = json.NewEncoder(w).Encode(items)
+	}))
+	defer srv.Close()
+	oldRootURL, oldDumpDir := rootURL, dumpDir
+	rootURL, dumpDir = srv.URL, t.TempDir()
+	defer func() {
+		rootURL, dumpDir = oldRootURL, oldDumpDir
+	}()
+
+	m := configmap.Simple{
+		"api_key":        "shared-cache-test",
+		"download_mode":  "torrents",
+		"folder_mode":    "folders",
+		"regex_shows":    `(?i)(S[0-9]{2})`,
+		"regex_movies":   `(?i)(19|20)([0-9]{2})`,
+		"share_cache":    "true",
+		"scan_downloads": "true",
+	}
+	var fses [2]*Fs
+	for i := range fses {
+		f, err := NewFs(ctx, "test", "", m)
+		require.NoError(t, err)
+		fses[i] = f.(*Fs)
+	}
+	require.True(t, fses[0].cache == fses[1].cache)
+	assert.Equal(t, 2, fses[0].cache.refs)
+
+	// List both concurrently
+	var wg sync.WaitGroup
+	for _, f := range fses {
+		wg.Add(1)
+		go func(f *Fs) {
+			defer wg.Done()
+			entries, err := f.List(ctx, "shows")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"shows/Other.S02", "shows/Show.S01"}, entryNames(entries))
+		}(f)
+	}
+	wg.Wait()
+
+	// One pagination pass served both
+	assert.Equal(t, 1, requests["/downloads?page=1&offset="])
+	assert.Equal(t, 1, requests["/torrents?page=&offset=0"])
+	assert.Equal(t, 0, requests["/torrents?page=&offset=2500"])
+
+	// Shutdown releases the references
+	require.NoError(t, fses[0].Shutdown(ctx))
+	require.NoError(t, fses[0].Shutdown(ctx))
+	assert.Equal(t, 1, fses[1].cache.refs)
+	require.NoError(t, fses[1].Shutdown(ctx))
+	sharedCachesMu.Lock()
+	_, found := sharedCaches["shared-cache-test"]
+	sharedCachesMu.Unlock()
+	assert.False(t, found)
+
+	// Without share_cache each Fs has its own cache
+	m["share_cache"] = "false"
+	f1, err := NewFs(ctx, "test", "", m)
+	require.NoError(t, err)
+	f2, err := NewFs(ctx, "test", "", m)
+	require.NoError(t, err)
+	assert.False(t, f1.(*Fs).cache == f2.(*Fs).cache)
+}
+
+// newAccountServer serves an account with one torrent called name
+func newAccountServer(t *testing.T, name string) *httptest.Server {
+	torrent := api.Item{ID: name, Name: name, Status: "downloaded", Links: []string{name + "-link"}}
+	download := api.Item{ID: name + "-dl", Name: name + ".mkv", OriginalLink: name + "-link", Link: "https://example.com/" + name, Generated: "2024-01-01T00:00:00.000Z"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var result any = []api.Item{}
+		switch r.URL.Path {
+		case "/torrents":
+			result = []api.Item{torrent}
+			w.Header().Set("X-Total-Count", "1")
+		case "/downloads":
+			result = []api.Item{download}
+			w.Header().Set("X-Total-Count", "1")
+		case "/torrents/info/" + name:
+			result = torrent
+		default:
+			w.Header().Set("X-Total-Count", "0")
+		}
+		writeJSON(t, w, result)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRemotesDontShareCaches(t *testing.T) {
+	ctx := context.Background()
+	oldRootURL, oldDumpDir := rootURL, dumpDir
+	dumpDir = t.TempDir()
+	t.Cleanup(func() {
+		rootURL, dumpDir = oldRootURL, oldDumpDir
+	})
+	remotes := []struct {
+		name   string
+		config configmap.Simple
+	}{
+		{"account1", configmap.Simple{"api_key": "account-1", "download_mode": "torrents", "folder_mode": "torrents", "scan_downloads": "true"}},
+		{"account2", configmap.Simple{"api_key": "account-2", "download_mode": "downloads"}},
+	}
+	newFses := func() (fses []*Fs) {
+		for _, remote := range remotes {
+			rootURL = newAccountServer(t, remote.name).URL
+			f, err := NewFs(ctx, remote.name, "", remote.config)
+			require.NoError(t, err)
+			fses = append(fses, f.(*Fs))
+		}
+		return fses
+	}
+
+	fses := newFses()
+	assert.False(t, fses[0].cache == fses[1].cache)
+	entries, err := fses[0].List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"account1"}, entryNames(entries))
+	entries, err = fses[1].List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"account2.mkv"}, entryNames(entries))
+	for _, f := range fses {
+		require.NoError(t, f.Shutdown(ctx))
+	}
+
+	// The torrents refresh of the first was dumped under its name
+	dumps, err := filepath.Glob(filepath.Join(dumpDir, "*.gob"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dumpDir, "account1-cached.gob"),
+		filepath.Join(dumpDir, "account1-torrents.gob"),
+		filepath.Join(dumpDir, "account1-torrentswf.gob"),
+	}, dumps)
+
+	// so only it loads it on restart
+	fses = newFses()
+	assert.Len(t, fses[0].cache.cached, 1)
+	assert.Empty(t, fses[1].cache.cached)
+	for i, f := range fses {
+		name := remotes[i].name
+		for _, item := range f.cache.cached {
+			assert.Equal(t, name+"-link", item.OriginalLink, name)
+		}
+		for _, item := range f.cache.torrentswf {
+			assert.Equal(t, name, item.ID, name)
+		}
+		require.NoError(t, f.Shutdown(ctx))
+	}
+}
+
+func TestLegacyDumpsRenamed(t *testing.T) {
+	oldDumpDir := dumpDir
+	dumpDir = t.TempDir()
+	t.Cleanup(func() {
+		dumpDir = oldDumpDir
+	})
+	// dumps written before they were named after the remote
+	legacy := &sharedCache{
+		cached:     []api.Item{{ID: "D1", OriginalLink: "l1"}},
+		categories: map[string]string{"h1": "movies"},
+	}
+	legacy.dump()
+
+	// are loaded and kept by the first remote started
+	c := &sharedCache{dumpName: "account1"}
+	c.loadDumps(0)
+	assert.Equal(t, legacy.cached, c.cached)
+	assert.Equal(t, legacy.categories, c.categories)
+	dumps, err := filepath.Glob(filepath.Join(dumpDir, "*.gob"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dumpDir, "account1-cached.gob"),
+		filepath.Join(dumpDir, "account1-categories.gob"),
+		filepath.Join(dumpDir, "account1-torrentswf.gob"),
+	}, dumps)
+
+	// but not by the next one
+	c = &sharedCache{dumpName: "account2"}
+	c.loadDumps(0)
+	assert.Empty(t, c.cached)
+	assert.Empty(t, c.categories)
+}
+
+func TestOpenTailRanges(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	const content = "0123456789"
+	var (
+		honourRanges atomic.Bool
+		requests     atomic.Int32
+	)
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if honourRanges.Load() {
+			http.ServeContent(w, r, "file.mkv", time.Time{}, strings.NewReader(content))
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	t.Cleanup(cdn.Close)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+	})
+	o, err := f.newObjectWithInfo(ctx, "file.mkv", &api.Item{Name: "file.mkv", Type: api.ItemTypeFile, Link: cdn.URL, Size: int64(len(content))})
+	require.NoError(t, err)
+
+	read := func(options ...fs.OpenOption) string {
+		in, err := o.Open(ctx, options...)
+		require.NoError(t, err)
+		data, err := io.ReadAll(in)
+		require.NoError(t, err)
+		require.NoError(t, in.Close())
+		return string(data)
+	}
+	for _, honour := range []bool{true, false} {
+		t.Run(fmt.Sprintf("honourRanges=%v", honour), func(t *testing.T) {
+			honourRanges.Store(honour)
+			requests.Store(0)
+			assert.Equal(t, "9", read(&fs.RangeOption{Start: 9, End: 9}))
+			assert.Equal(t, "9", read(&fs.RangeOption{Start: 9, End: 10}))
+			assert.Equal(t, "6789", read(&fs.RangeOption{Start: 6, End: -1}))
+			assert.Equal(t, "789", read(&fs.RangeOption{Start: -1, End: 3}))
+			assert.Equal(t, "6789", read(&fs.SeekOption{Offset: 6}))
+			assert.Equal(t, content, read())
+			assert.Equal(t, int32(6), requests.Load())
+
+			// nothing to read at the end so no request is made
+			assert.Equal(t, "", read(&fs.RangeOption{Start: 10, End: -1}))
+			assert.Equal(t, "", read(&fs.SeekOption{Offset: 10}))
+			assert.Equal(t, int32(6), requests.Load())
+		})
+	}
+}
+
+func TestOpenUncompressed(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	content := strings.Repeat("1\n00:00:01,000 --> 00:00:02,000\nHello\n\n", 100)
+	var acceptEncoding atomic.Value
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding.Store(r.Header.Get("Accept-Encoding"))
+		// gzips when allowed, like some CDN nodes do
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			_, _ = gz.Write([]byte(content))
+			_ = gz.Close()
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	t.Cleanup(cdn.Close)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+	})
+	o, err := f.newObjectWithInfo(ctx, "Movie.2020/Movie.2020.srt", &api.Item{Name: "Movie.2020.srt", Type: api.ItemTypeFile, Link: cdn.URL, Size: int64(len(content))})
+	require.NoError(t, err)
+
+	in, err := o.Open(ctx)
+	require.NoError(t, err)
+	n, err := io.Copy(io.Discard, in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, o.Size(), n)
+	assert.Equal(t, "identity", acceptEncoding.Load())
+}
+
+func TestOpenSharesUnrestrict(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	const content = "0123456789"
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(content))
+	}))
+	t.Cleanup(cdn.Close)
+	var unrestricts atomic.Int32
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/unrestrict/link" {
+			t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+			return
+		}
+		unrestricts.Add(1)
+		// long enough for the other opens to wait for it
+		time.Sleep(50 * time.Millisecond)
+		link := r.FormValue("link")
+		if link == "dead" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writeJSON(t, w, api.Item{ID: "d-" + link, OriginalLink: link, Link: cdn.URL + "/" + link})
+	})
+	f.client.pacer.SetRetries(1)
+	open := func(link string) error {
+		o, err := f.newObjectWithInfo(ctx, "Movie.2020/file.mkv", &api.Item{Name: "file.mkv", Type: api.ItemTypeFile, OriginalLink: link, ParentID: "T1", Size: int64(len(content))})
+		if err != nil {
+			return err
+		}
+		in, err := o.Open(ctx)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(in)
+		assert.Equal(t, content, string(data))
+		assert.NoError(t, in.Close())
+		return err
+	}
+	openAll := func(link string) (errs []error) {
+		var (
+			wg sync.WaitGroup
+			mu sync.Mutex
+		)
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := open(link)
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+		return errs
+	}
+
+	// Concurrent opens of a file unrestrict it once
+	for _, err := range openAll("l1") {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), unrestricts.Load())
+	assert.Len(t, f.cache.cached, 1)
+
+	// and all get its error
+	unrestricts.Store(0)
+	for _, err := range openAll("dead") {
+		assert.ErrorIs(t, err, errLinkUnavailable)
+	}
+	assert.Equal(t, int32(1), unrestricts.Load())
+}
+
+func TestSimulate(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.opt.SharedFolder = "torrents"
+	f.opt.Simulate = true
+	content := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("content"))
+	}))
+	t.Cleanup(content.Close)
+	var requests []string
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		writeJSON(t, w, api.Item{ID: "d2", Link: content.URL})
+	})
+	f.client.simulate = true
+	torrent := api.Item{ID: "T1", Name: "Movie.2020", Status: "downloaded", TorrentHash: "abc", Links: []string{"l1"}}
+	f.cache.torrents = []api.Item{torrent}
+	f.cache.torrentswf = []api.Item{torrent}
+	f.cache.cached = []api.Item{{ID: "d1", Name: "movie.mkv", OriginalLink: "l1", Link: content.URL, Size: 7}}
+	before := stats.simulated.Load()
+
+	// Deletes are skipped and the files are still listed
+	o, err := f.NewObject(ctx, "Movie.2020/movie.mkv")
+	require.NoError(t, err)
+	require.NoError(t, o.Remove(ctx))
+	require.NoError(t, f.Purge(ctx, "Movie.2020"))
+	entries, err := f.List(ctx, "Movie.2020")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Movie.2020/movie.mkv"}, entryNames(entries))
+	assert.Equal(t, int64(3), stats.simulated.Load()-before, "download, torrent and torrent again")
+
+	// Repairs aren't started
+	assert.Equal(t, torrent, f.redownloadTorrent(ctx, torrent))
+	assert.Empty(t, requests)
+
+	// Reads and the unrestricts they need still work
+	in, err := o.Open(ctx)
+	require.NoError(t, err)
+	data, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, "content", string(data))
+	o, err = f.newObjectWithInfo(ctx, "Movie.2020/extra.mkv", &api.Item{Name: "extra.mkv", Type: api.ItemTypeFile, OriginalLink: "l2", ParentID: "T1", Size: 7})
+	require.NoError(t, err)
+	in, err = o.Open(ctx)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, []string{"POST /unrestrict/link"}, requests)
+}
+
+func TestReadOnly(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.opt.SharedFolder = "torrents"
+	torrent := api.Item{ID: "T1", Name: "Movie.2020", Status: "downloaded", Links: []string{"l1"}}
+	f.cache.torrents = []api.Item{torrent}
+	f.cache.torrentswf = []api.Item{torrent}
+	f.cache.cached = []api.Item{{ID: "d1", Name: "movie.mkv", OriginalLink: "l1", Link: "https://example.com/1", Size: 7}}
+
+	// Uploads and new directories fail rather than being lost
+	src := object.NewStaticObjectInfo("Movie.2020/new.mkv", time.Now(), 3, true, nil, nil)
+	_, err := f.Put(ctx, strings.NewReader("new"), src)
+	assert.ErrorIs(t, err, errReadOnly)
+	checkOpError(t, err, "put", "Movie.2020/new.mkv", "", "")
+	_, err = f.PutUnchecked(ctx, strings.NewReader("new"), src)
+	assert.ErrorIs(t, err, errReadOnly)
+	assert.ErrorIs(t, f.Mkdir(ctx, "New.2021"), errReadOnly)
+	assert.NoError(t, f.Mkdir(ctx, "Movie.2020"))
+
+	o, err := f.NewObject(ctx, "Movie.2020/movie.mkv")
+	require.NoError(t, err)
+	err = o.Update(ctx, strings.NewReader("new"), src)
+	assert.ErrorIs(t, err, errReadOnly)
+	checkOpError(t, err, "update", "Movie.2020/movie.mkv", "T1", "Movie.2020")
+
+	// and so do moves
+	_, err = f.Move(ctx, o, "Movie.2020/moved.mkv")
+	assert.Equal(t, fs.ErrorCantMove, err)
+	assert.Equal(t, fs.ErrorCantDirMove, f.DirMove(ctx, f, "Movie.2020", "Moved.2020"))
+}
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	var links []string
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/unrestrict/link", r.URL.Path)
+		links = append(links, r.FormValue("link"))
+		writeJSON(t, w, api.Item{ID: "d2", Name: "movie.mkv", OriginalLink: r.FormValue("link"), Link: "https://example.com/d2", Size: 7})
+	})
+	src, err := f.newObjectWithInfo(ctx, "movie.mkv", &api.Item{ID: "d1", Name: "movie.mkv", Type: api.ItemTypeFile, OriginalLink: "l1", Link: "https://example.com/d1", Size: 7})
+	require.NoError(t, err)
+
+	// Torrents can't be copied
+	_, err = f.Copy(ctx, src, "copy.mkv")
+	assert.Equal(t, fs.ErrorCantCopy, err)
+	assert.Empty(t, links)
+
+	// but downloads are by unrestricting their link again
+	f.opt.RootFolderID = "downloads"
+	dst, err := f.Copy(ctx, src, "copy.mkv")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"l1"}, links)
+	assert.Equal(t, "copy.mkv", dst.Remote())
+	assert.Equal(t, int64(7), dst.Size())
+	assert.Equal(t, "d2", dst.(*Object).ID())
+	assert.Equal(t, "https://example.com/d2", dst.(*Object).url)
+
+	// only to the root
+	_, err = f.Copy(ctx, src, "dir/copy.mkv")
+	assert.Equal(t, fs.ErrorCantCopy, err)
+	assert.Len(t, links, 1)
+}
+
+func TestUnicodeNormalization(t *testing.T) {
+	ctx := context.Background()
+	const (
+		nfd = "Ame\u0301lie.2001" // e + combining acute accent
+		nfc = "Am\u00e9lie.2001"
+	)
+	f := newTestFs(t)
+	f.opt.SharedFolder = "torrents"
+	torrent := api.Item{ID: "T1", Name: nfd, Status: "downloaded", Links: []string{"l1"}}
+	f.cache.torrents = []api.Item{torrent}
+	f.cache.torrentswf = []api.Item{torrent}
+	f.cache.cached = []api.Item{{ID: "d1", Name: nfd + ".mkv", OriginalLink: "l1", Link: "https://example.com/1", Size: 7}}
+
+	// Listed composed and found composed or not
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{nfc}, entryNames(entries))
+	for _, name := range []string{nfc, nfd} {
+		f.dirCache.Flush()
+		o, err := f.NewObject(ctx, name+"/"+name+".mkv")
+		require.NoError(t, err, name)
+		assert.Equal(t, int64(7), o.Size())
+	}
+
+	// Only as named by Real-Debrid without normalization
+	f.opt.UnicodeNormalization = false
+	f.dirCache.Flush()
+	entries, err = f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{nfd}, entryNames(entries))
+	_, err = f.NewObject(ctx, nfd+"/"+nfd+".mkv")
+	require.NoError(t, err)
+	f.dirCache.Flush()
+	_, err = f.NewObject(ctx, nfc+"/"+nfc+".mkv")
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+}
+
+func TestCaseFolding(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.opt.SharedFolder = "torrents"
+	var torrents, cached []api.Item
+	for i, name := range []string{"Straße.2001", "Istanbul.2005"} {
+		id := fmt.Sprint(i)
+		torrents = append(torrents, api.Item{ID: "T" + id, Name: name, Status: "downloaded", Links: []string{"l" + id}})
+		cached = append(cached, api.Item{ID: "d" + id, Name: name + ".mkv", OriginalLink: "l" + id, Link: "https://example.com/" + id, Size: 7})
+	}
+	f.cache.torrents = torrents
+	f.cache.torrentswf = torrents
+	f.cache.cached = cached
+
+	for _, test := range []struct {
+		name  string
+		found bool
+	}{
+		{"STRASSE.2001", true},
+		{"strasse.2001", true},
+		{"STRAẞE.2001", true}, // capital sharp s
+		{"ISTANBUL.2005", true},
+		{"istanbul.2005", true},
+		{"ıstanbul.2005", false}, // Turkish dotless i
+		{"İSTANBUL.2005", false}, // Turkish dotted I
+	} {
+		// the directory and file lookups agree
+		f.dirCache.Flush()
+		_, found, err := f.FindLeaf(ctx, rootID, test.name)
+		require.NoError(t, err, test.name)
+		assert.Equal(t, test.found, found, test.name)
+		_, err = f.readMetaDataForPath(ctx, test.name, true, false)
+		assert.Equal(t, test.found, err == nil, test.name)
+		f.dirCache.Flush()
+		_, err = f.NewObject(ctx, test.name+"/"+test.name+".mkv")
+		assert.Equal(t, test.found, err == nil, test.name)
+	}
+
+	// and names which fold the same are made unique
+	items := uniqueTorrentNames([]api.Item{
+		{ID: "C", Name: "STRASSE"},
+		{ID: "B", Name: "ıstanbul"},
+		{ID: "A", Name: "Straße"},
+		{ID: "Z", Name: "Istanbul"},
+	})
+	var names []string
+	for _, item := range items {
+		names = append(names, item.Name)
+	}
+	assert.Equal(t, []string{"STRASSE (C)", "ıstanbul", "Straße", "Istanbul"}, names)
+}
+
+// newFileRootServer serves an account with a show, a movie and the
+// same show added again
+func newFileRootServer(t *testing.T) {
+	torrents := []api.Item{
+		{ID: "SHOW2", Name: "Show.S01", Status: "downloaded", Links: []string{"l3"}},
+		{ID: "MOVIE", Name: "Movie.2020", Status: "downloaded", Links: []string{"l2"}},
+		{ID: "SHOW1", Name: "Show.S01", Status: "downloaded", Links: []string{"l1"}},
+	}
+	files := map[string]string{"l1": "Show.S01E01.mkv", "l2": "movies", "l3": "Show.S01E02.mkv"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var result any = []api.Item{}
+		switch {
+		case r.URL.Path == "/torrents":
+			result = torrents
+			w.Header().Set("X-Total-Count", strconv.Itoa(len(torrents)))
+		case strings.HasPrefix(r.URL.Path, "/torrents/info/"):
+			for _, torrent := range torrents {
+				if torrent.ID == path.Base(r.URL.Path) {
+					torrent.Files = []api.File{{ID: 1, Path: "/" + files[torrent.Links[0]], Bytes: 10, Selected: 1}}
+					result = torrent
+				}
+			}
+		case r.URL.Path == "/unrestrict/link":
+			link := r.FormValue("link")
+			result = api.Item{ID: "d" + link, Name: files[link], OriginalLink: link, Link: "https://example.com/" + link, Size: 10}
+		default:
+			w.Header().Set("X-Total-Count", "0")
+		}
+		writeJSON(t, w, result)
+	}))
+	t.Cleanup(srv.Close)
+	oldRootURL, oldDumpDir := rootURL, dumpDir
+	rootURL, dumpDir = srv.URL, t.TempDir()
+	t.Cleanup(func() {
+		rootURL, dumpDir = oldRootURL, oldDumpDir
+	})
+}
+
+// TestNewFsRoots checks roots at each depth list what is below them
+func TestNewFsRoots(t *testing.T) {
+	ctx := context.Background()
+	newFileRootServer(t)
+	for _, test := range []struct {
+		mode string
+		root string
+		want []string
+	}{
+		{"folders", "", []string{"shows", "movies", "default"}},
+		{"folders", "shows", []string{"Show.S01", "Show.S01 (SHOW2)"}},
+		{"folders", "SHOWS/show.s01", []string{"Show.S01E01.mkv"}},
+		{"folders", "shows/Show.S01 (SHOW2)", []string{"Show.S01E02.mkv"}},
+		{"folders", "movies/Movie.2020", []string{"movies"}},
+		{"torrents", "", []string{"Movie.2020", "Show.S01", "Show.S01 (SHOW2)"}},
+		{"torrents", "show.s01", []string{"Show.S01E01.mkv"}},
+		{"torrents", "Movie.2020", []string{"movies"}},
+	} {
+		t.Run(test.mode+":"+test.root, func(t *testing.T) {
+			f, err := NewFs(ctx, "test", test.root, configmap.Simple{
+				"api_key":       "roots-test",
+				"download_mode": "torrents",
+				"folder_mode":   test.mode,
+				"regex_shows":   `(?i)(S[0-9]{2})`,
+				"regex_movies":  `(?i)(19|20)([0-9]{2})`,
+			})
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				require.NoError(t, f.(*Fs).Shutdown(ctx))
+			})
+			entries, err := f.List(ctx, "")
+			require.NoError(t, err)
+			assert.Equal(t, test.want, entryNames(entries))
+		})
+	}
+}
+
+// TestFolderModeSwitch checks the dumps of a remote list cleanly after
+// its folder_mode is changed as they hold nothing keyed by path
+func TestFolderModeSwitch(t *testing.T) {
+	ctx := context.Background()
+	newFileRootServer(t)
+	list := func(mode string, dirs ...string) (listings [][]string) {
+		f, err := NewFs(ctx, "test", "", configmap.Simple{
+			"api_key":       "mode-switch-test",
+			"download_mode": "torrents",
+			"folder_mode":   mode,
+			"regex_shows":   `(?i)(S[0-9]{2})`,
+			"regex_movies":  `(?i)(19|20)([0-9]{2})`,
+		})
+		require.NoError(t, err)
+		for _, dir := range dirs {
+			entries, err := f.List(ctx, dir)
+			require.NoError(t, err)
+			listings = append(listings, entryNames(entries))
+		}
+		// dumping the caches for the next one
+		require.NoError(t, f.(*Fs).Shutdown(ctx))
+		return listings
+	}
+	folders := [][]string{
+		{"shows", "movies", "default"},
+		{"shows/Show.S01", "shows/Show.S01 (SHOW2)"},
+		{"shows/Show.S01/Show.S01E01.mkv"},
+	}
+	torrents := [][]string{
+		{"Movie.2020", "Show.S01", "Show.S01 (SHOW2)"},
+		{"Show.S01/Show.S01E01.mkv"},
+	}
+	assert.Equal(t, folders, list("folders", "", "shows", "shows/Show.S01"))
+	assert.Equal(t, torrents, list("torrents", "", "Show.S01"))
+	assert.Equal(t, folders, list("folders", "", "shows", "shows/Show.S01"))
+	assert.Equal(t, [][]string{{"Show.S01E01.mkv", "Show.S01E02.mkv", "movies"}}, list("files", ""))
+	assert.Equal(t, torrents, list("torrents", "", "Show.S01"))
+}
+
+func TestNewFsFileRoot(t *testing.T) {
+	ctx := context.Background()
+	newFileRootServer(t)
+	for _, test := range []struct {
+		mode   string
+		root   string
+		parent string // root of the Fs returned, "" if not a file
+		leaf   string
+	}{
+		{"folders", "shows/Show.S01/Show.S01E01.mkv", "shows/Show.S01", "Show.S01E01.mkv"},
+		{"folders", "/SHOWS/show.s01/SHOW.S01E01.MKV/", "SHOWS/show.s01", "SHOW.S01E01.MKV"},
+		{"folders", "shows/Show.S01 (SHOW2)/Show.S01E02.mkv", "shows/Show.S01 (SHOW2)", "Show.S01E02.mkv"},
+		{"folders", "movies/Movie.2020/movies", "movies/Movie.2020", "movies"},
+		{"folders", "shows/Show.S01/Show.S01E02.mkv", "", ""},
+		{"folders", "shows/Show.S01", "", ""},
+		{"folders", "movies", "", ""},
+		{"torrents", "Show.S01/Show.S01E01.mkv", "Show.S01", "Show.S01E01.mkv"},
+		{"torrents", "Show.S01 (SHOW2)/Show.S01E02.mkv", "Show.S01 (SHOW2)", "Show.S01E02.mkv"},
+		{"torrents", "Movie.2020/movies", "Movie.2020", "movies"},
+		{"torrents", "Movie.2020/missing.mkv", "", ""},
+		{"files", "movies", "", "movies"},
+		{"files", "Show.S01E02.mkv", "", "Show.S01E02.mkv"},
+		{"files", statusFileName, "", statusFileName},
+	} {
+		t.Run(test.mode+":"+test.root, func(t *testing.T) {
+			m := configmap.Simple{
+				"api_key":       "file-root-test",
+				"download_mode": "torrents",
+				"folder_mode":   test.mode,
+				"regex_shows":   `(?i)(S[0-9]{2})`,
+				"regex_movies":  `(?i)(19|20)([0-9]{2})`,
+
+				"show_status_file": "true",
+			}
+			f, err := NewFs(ctx, "test", test.root, m)
+			if test.leaf == "" {
+				require.NoError(t, err)
+				assert.Equal(t, strings.Trim(test.root, "/"), f.Root())
+				return
+			}
+			require.Equal(t, fs.ErrorIsFile, err)
+			assert.Equal(t, test.parent, f.Root())
+			// the file is found as the mount would find it
+			o, err := f.NewObject(ctx, test.leaf)
+			require.NoError(t, err)
+			entries, err := f.List(ctx, "")
+			require.NoError(t, err)
+			var listed fs.Object
+			for _, entry := range entries {
+				if foldName(entry.Remote()) == foldName(test.leaf) {
+					listed, _ = entry.(fs.Object)
+				}
+			}
+			require.NotNil(t, listed, "not listed")
+			assert.Equal(t, listed.Size(), o.Size())
+			// and the features act on the returned Fs
+			assert.True(t, f.Features().Shutdown != nil)
+			require.NoError(t, f.Features().Shutdown(ctx))
+		})
+	}
+}
+
+// TestNewFsRootListError checks a root below the top level fails if
+// the torrents can't be listed to find it
+func TestNewFsRootListError(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		writeJSON(t, w, api.Response{Message: "permission_denied"})
+	}))
+	t.Cleanup(srv.Close)
+	oldRootURL, oldDumpDir := rootURL, dumpDir
+	rootURL, dumpDir = srv.URL, t.TempDir()
+	t.Cleanup(func() {
+		rootURL, dumpDir = oldRootURL, oldDumpDir
+	})
+	m := configmap.Simple{
+		"api_key":       "root-error-test",
+		"download_mode": "torrents",
+		"folder_mode":   "folders",
+	}
+
+	// The top level needs no listing
+	f, err := NewFs(ctx, "test", "", m)
+	require.NoError(t, err)
+	require.NoError(t, f.(*Fs).Shutdown(ctx))
+
+	for _, root := range []string{"shows", "shows/Show.S01", "shows/Show.S01/Show.S01E01.mkv"} {
+		_, err = NewFs(ctx, "test", root, m)
+		assert.ErrorContains(t, err, "permission_denied", root)
+	}
+	assert.Empty(t, backgroundGoroutines())
+}
+
+// backgroundGoroutines returns the stacks of the goroutines running
+// methods of an Fs
+func backgroundGoroutines() (stacks []string) {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		if strings.Contains(stack, "realdebrid.(*Fs).") {
+			stacks = append(stacks, stack)
+		}
+	}
+	return stacks
+}
+
+func TestShutdownStopsBackground(t *testing.T) {
+	ctx := context.Background()
+	var requests atomic.Int64
+	f := newTestFs(t)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		// slow enough for the calls to be in flight at Shutdown
+		select {
+		case <-r.Context().Done():
+		case <-time.After(50 * time.Millisecond):
+		}
+		w.Header().Set("X-Total-Count", "0")
+		writeJSON(t, w, []api.Item{})
+	})
+	f.cache.cached = []api.Item{{ID: "d1", Link: "http://127.0.0.1:1/never", OriginalLink: "l1"}}
+	f.opt.VerifyLinksPerHour = int(time.Hour / time.Millisecond)
+	require.True(t, f.startVerifier())
+	pollInterval := make(chan time.Duration, 1)
+	pollInterval <- time.Millisecond
+	f.ChangeNotify(ctx, func(string, fs.EntryType) {}, pollInterval)
+	f.queueChanged("movies")
+	require.Eventually(t, func() bool {
+		return requests.Load() > 0
+	}, 5*time.Second, time.Millisecond)
+
+	// No goroutine survives Shutdown and no more calls are made
+	require.NoError(t, f.Shutdown(ctx))
+	assert.Empty(t, backgroundGoroutines())
+	assert.Nil(t, f.flushes.timer)
+	made := requests.Load()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, made, requests.Load())
+
+	// nor starts after it
+	f.ChangeNotify(ctx, func(string, fs.EntryType) {}, pollInterval)
+	f.queueChanged("movies")
+	assert.Empty(t, backgroundGoroutines())
+	assert.Nil(t, f.flushes.timer)
+	require.NoError(t, f.Shutdown(ctx))
+}
+
+func TestProgressiveListing(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.opt.ProgressiveListing = true
+	release := make(chan struct{})
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/unrestrict/link" {
+			t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		link := r.FormValue("link")
+		if link == "L2" {
+			// slow until the first file is listed
+			select {
+			case <-release:
+			case <-time.After(5 * time.Second):
+				t.Error("the first file wasn't listed before the second was unrestricted")
+			}
+		}
+		writeJSON(t, w, api.Item{ID: "D" + link, Name: map[string]string{"L1": "b.mkv", "L2": "a.mkv", "L3": "c.mkv"}[link], OriginalLink: link, Link: "https://dl/" + link, Size: 1})
+	})
+	torrent := api.Item{ID: "1", Name: "Collection.2019", Status: "downloaded", Links: []string{"L1", "L2", "L3"}}
+	f.cache.torrents = []api.Item{torrent}
+	f.cache.addTorrentDetails(torrent)
+	_, err := f.List(ctx, "movies")
+	require.NoError(t, err)
+
+	// Each file is passed on as soon as it is unrestricted
+	var tranches [][]string
+	err = f.ListP(ctx, "movies/Collection.2019", func(entries fs.DirEntries) error {
+		if len(tranches) == 0 {
+			close(release)
+		}
+		tranches = append(tranches, entryNames(entries))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"movies/Collection.2019/b.mkv"}, {"movies/Collection.2019/a.mkv"}, {"movies/Collection.2019/c.mkv"}}, tranches)
+
+	// and List still sorts them
+	entries, err := f.List(ctx, "movies/Collection.2019")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"movies/Collection.2019/a.mkv", "movies/Collection.2019/b.mkv", "movies/Collection.2019/c.mkv"}, entryNames(entries))
+
+	// An error from the callback stops the listing
+	errStop := errors.New("stop")
+	calls := 0
+	err = f.ListP(ctx, "movies/Collection.2019", func(entries fs.DirEntries) error {
+		calls++
+		return errStop
+	})
+	assert.ErrorIs(t, err, errStop)
+	assert.Equal(t, 1, calls)
+}
+
+func TestProgressiveListingOption(t *testing.T) {
+	ctx := context.Background()
+	newFileRootServer(t)
+	for _, progressive := range []bool{false, true} {
+		f, err := NewFs(ctx, "test", "", configmap.Simple{
+			"api_key":             "progressive-test",
+			"download_mode":       "torrents",
+			"folder_mode":         "folders",
+			"progressive_listing": strconv.FormatBool(progressive),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, progressive, f.Features().ListP != nil)
+		require.NoError(t, f.(*Fs).Shutdown(ctx))
+	}
+}
@@ -0,0 +1,202 @@
+package realdebrid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+var (
+	// errRefreshBudget is returned when a refresh of the torrents took
+	// longer or made more requests than refresh_budget allows
+	errRefreshBudget = errors.New("refresh of the torrents ran out of budget")
+
+	// errCircuitOpen is returned instead of refreshing the torrents
+	// after too many failed refreshes if none are listed yet
+	errCircuitOpen = errors.New("refresh of the torrents skipped after too many failures")
+)
+
+// refreshBudgetKey is the context key of the *refreshBudget
+type refreshBudgetKey struct{}
+
+// refreshBudget is the number of requests a refresh may still make,
+// retries included
+type refreshBudget struct {
+	requests atomic.Int64
+}
+
+// withRefreshBudget returns ctx limited to the time and requests of
+// refresh_budget and refresh_budget_requests.
+func (f *Fs) withRefreshBudget(ctx context.Context) (context.Context, context.CancelFunc) {
+	if f.opt.RefreshBudgetRequests > 0 {
+		budget := &refreshBudget{}
+		budget.requests.Store(int64(f.opt.RefreshBudgetRequests))
+		ctx = context.WithValue(ctx, refreshBudgetKey{}, budget)
+	}
+	if f.opt.RefreshBudget <= 0 {
+		return context.WithCancel(ctx)
+	}
+	cause := fmt.Errorf("%w: took longer than %v", errRefreshBudget, f.opt.RefreshBudget)
+	return context.WithTimeoutCause(ctx, time.Duration(f.opt.RefreshBudget), cause)
+}
+
+// takeRequest uses up a request of the refresh budget in ctx if any,
+// returning errRefreshBudget if there are none left.
+func takeRequest(ctx context.Context) error {
+	budget, ok := ctx.Value(refreshBudgetKey{}).(*refreshBudget)
+	if !ok {
+		return nil
+	}
+	if budget.requests.Add(-1) < 0 {
+		return fmt.Errorf("%w: too many requests", errRefreshBudget)
+	}
+	return nil
+}
+
+// circuitOpen returns whether refreshes are skipped after too many
+// failures.
+//
+// It must be called with the refreshMu held.
+func (c *sharedCache) circuitOpen() bool {
+	return time.Now().Before(c.circuitOpenUntil)
+}
+
+// refreshFinished records the result of the refresh made with ctx,
+// opening the circuit for refresh_circuit_backoff once
+// refresh_circuit_failures refreshes in a row have failed. Once it has
+// passed a single refresh is tried again, a success closing the circuit
+// and a failure opening it again.
+//
+// It returns err, telling the refresh ran out of budget if it did.
+//
+// It must be called with the refreshMu held.
+func (f *Fs) refreshFinished(ctx context.Context, err error) error {
+	c := f.cache
+	if err == nil {
+		if c.refreshFailures >= f.opt.RefreshCircuitFailures && f.opt.RefreshCircuitFailures > 0 {
+			fs.Infof(f, "Refreshing the torrents works again")
+		}
+		c.refreshFailures = 0
+		return nil
+	}
+	if cause := context.Cause(ctx); errors.Is(cause, errRefreshBudget) && !errors.Is(err, errRefreshBudget) {
+		err = fmt.Errorf("%w: %w", cause, err)
+	}
+	stats.refreshErrors.Add(1)
+	c.refreshFailures++
+	if f.opt.RefreshCircuitFailures > 0 && c.refreshFailures >= f.opt.RefreshCircuitFailures {
+		c.circuitOpenUntil = time.Now().Add(time.Duration(f.opt.RefreshCircuitBackoff))
+		stats.circuitOpened.Add(1)
+		fs.Errorf(f, "Skipping refreshes of the torrents for %v after %d failures: %v", f.opt.RefreshCircuitBackoff, c.refreshFailures, err)
+	}
+	return err
+}
+
+// circuitState describes the state of the refresh circuit for the
+// status file.
+//
+// It must be called with the refreshMu held.
+func (c *sharedCache) circuitState() string {
+	if c.circuitOpen() {
+		return "open until " + c.circuitOpenUntil.UTC().Format(time.RFC3339)
+	}
+	return "closed"
+}
+
+// refreshReport is the result of the refresh command
+type refreshReport struct {
+	Torrents   int    `json:"torrents"`
+	Downloads  int    `json:"downloads"`
+	Generation int64  `json:"generation"`
+	Took       string `json:"took"`
+}
+
+// refreshCommand refreshes the torrents and downloads now whatever
+// cache_refresh_interval, for the refresh command.
+//
+// A listing refreshing them at the same time is waited for, and
+// listings waiting for this refresh use it rather than making another.
+func (f *Fs) refreshCommand(ctx context.Context) (report *refreshReport, err error) {
+	start := time.Now()
+	c := f.cache
+	c.refreshMu.Lock()
+	if c.circuitOpen() {
+		c.refreshMu.Unlock()
+		return nil, errCircuitOpen
+	}
+	c.startup_cached_api_fetch = false
+	c.refreshMu.Unlock()
+	c.state.Invalidate()
+	err = f.refreshTorrents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return &refreshReport{
+		Torrents:   len(f.scopedTorrents(c.torrents, c.categories)),
+		Downloads:  len(c.cached),
+		Generation: c.generation,
+		Took:       time.Since(start).Round(time.Millisecond).String(),
+	}, nil
+}
+
+// startBackgroundRefresh starts refreshing the torrents in the
+// background as they go stale if background_refresh is set, returning
+// whether it did.
+//
+// It is stopped by Shutdown.
+func (f *Fs) startBackgroundRefresh() bool {
+	interval := time.Duration(f.opt.CacheRefreshInterval)
+	if !f.opt.BackgroundRefresh || interval <= 0 {
+		return false
+	}
+	fs.Infof(f, "Refreshing the torrents in the background every %v", f.opt.CacheRefreshInterval)
+	f.goBackground(f.ctx, func(ctx context.Context) {
+		f.refreshInBackground(ctx, interval)
+	})
+	return true
+}
+
+// refreshInBackground refreshes the torrents each time they have been
+// cached for interval until ctx is cancelled.
+//
+// A refresh made by a listing in the meantime pushes the next one back
+// and one in progress is left to finish rather than waited for. Failed
+// refreshes are tried again after a tenth of the interval.
+func (f *Fs) refreshInBackground(ctx context.Context, interval time.Duration) {
+	timer := time.NewTimer(f.untilStale(interval))
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return
+		}
+		if f.cache.refreshMu.TryLock() {
+			stale := f.cache.state.IsStale(interval)
+			f.cache.refreshMu.Unlock()
+			if stale {
+				stats.autoRefreshes.Add(1)
+				if err := f.refreshTorrents(ctx); err != nil && ctx.Err() == nil {
+					fs.Errorf(f, "Background refresh of the torrents failed: %v", err)
+				}
+			}
+		}
+		timer.Reset(max(f.untilStale(interval), interval/10))
+	}
+}
+
+// untilStale returns how long until the torrents were refreshed
+// interval ago
+func (f *Fs) untilStale(interval time.Duration) time.Duration {
+	f.cache.refreshMu.Lock()
+	defer f.cache.refreshMu.Unlock()
+	return time.Until(f.cache.state.StaleAt(interval))
+}
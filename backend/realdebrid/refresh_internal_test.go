@@ -0,0 +1,383 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRefreshTestFs makes an Fs listing torrents from an API which
+// fails with 500 while failing is set, returning it and the number of
+// requests made
+func newRefreshTestFs(t *testing.T, failing *atomic.Bool) (f *Fs, requests *atomic.Int64) {
+	oldDumpDir := dumpDir
+	dumpDir = t.TempDir()
+	t.Cleanup(func() {
+		dumpDir = oldDumpDir
+	})
+	requests = new(atomic.Int64)
+	torrents := []api.Item{
+		{ID: "1", Name: "Show.S01", Status: "downloaded"},
+		{ID: "2", Name: "Movie.2020", Status: "downloaded"},
+	}
+	f = newTestFs(t)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		// the first page which only reads the total always works
+		if failing.Load() && r.URL.Query().Get("limit") != "1" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(torrents)))
+		writeJSON(t, w, torrents)
+	})
+	f.cache.startup_cached_api_fetch = true
+	f.cache.torrents = []api.Item{{ID: "old", Name: "Old.2019", Status: "downloaded"}}
+	f.cache.state.checked = time.Time{}
+	return f, requests
+}
+
+func TestRefreshBudget(t *testing.T) {
+	ctx := context.Background()
+	var failing atomic.Bool
+	failing.Store(true)
+	f, requests := newRefreshTestFs(t, &failing)
+
+	// Out of requests while retrying the second page
+	f.opt.RefreshBudgetRequests = 5
+	err := f.refreshTorrents(ctx)
+	assert.ErrorIs(t, err, errRefreshBudget)
+	assert.Equal(t, int64(5), requests.Load())
+	assert.Equal(t, []string{"old"}, itemIDs(f.cache.torrents))
+
+	// Out of time
+	f.opt.RefreshBudgetRequests = 0
+	f.opt.RefreshBudget = fs.Duration(100 * time.Millisecond)
+	f.client.pacer.SetRetries(1000000) // so only the budget stops it
+	start := time.Now()
+	err = f.refreshTorrents(ctx)
+	assert.ErrorIs(t, err, errRefreshBudget)
+	assert.True(t, strings.Contains(err.Error(), "took longer than"), err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, []string{"old"}, itemIDs(f.cache.torrents))
+
+	// Within budget
+	f.opt.RefreshBudget = fs.Duration(time.Minute)
+	failing.Store(false)
+	require.NoError(t, f.refreshTorrents(ctx))
+	assert.Equal(t, []string{"1", "2"}, itemIDs(f.cache.torrents))
+}
+
+func TestRefreshCircuit(t *testing.T) {
+	ctx := context.Background()
+	var failing atomic.Bool
+	failing.Store(true)
+	f, requests := newRefreshTestFs(t, &failing)
+	f.opt.RefreshBudgetRequests = 3
+	f.opt.RefreshCircuitFailures = 2
+	f.opt.RefreshCircuitBackoff = fs.Duration(time.Hour)
+	before := stats.params()
+	delta := func(name string) int64 {
+		return stats.params()[name].(int64) - before[name].(int64)
+	}
+
+	// Opens after the failures
+	for range 2 {
+		assert.Error(t, f.refreshTorrents(ctx))
+	}
+	assert.Equal(t, int64(2), delta("refreshErrors"))
+	assert.Equal(t, int64(1), delta("circuitOpened"))
+	assert.True(t, strings.HasPrefix(f.cache.circuitState(), "open until "), f.cache.circuitState())
+
+	// then the torrents listed are served without calling the API
+	requests.Store(0)
+	require.NoError(t, f.refreshTorrents(ctx))
+	entries, err := f.List(ctx, "movies")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"movies/Old.2019"}, entryNames(entries))
+	assert.Equal(t, int64(0), requests.Load())
+	assert.Equal(t, int64(2), delta("refreshSkipped"))
+
+	// or an error if there are none
+	torrents := f.cache.torrents
+	f.cache.torrents = nil
+	assert.ErrorIs(t, f.refreshTorrents(ctx), errCircuitOpen)
+	f.cache.torrents = torrents
+
+	// A failure after the backoff opens it again straight away
+	f.cache.circuitOpenUntil = time.Now()
+	assert.Error(t, f.refreshTorrents(ctx))
+	assert.Equal(t, int64(2), delta("circuitOpened"))
+
+	// and a success closes it
+	f.cache.circuitOpenUntil = time.Now()
+	failing.Store(false)
+	require.NoError(t, f.refreshTorrents(ctx))
+	assert.Equal(t, "closed", f.cache.circuitState())
+	assert.Equal(t, 0, f.cache.refreshFailures)
+	assert.Equal(t, []string{"1", "2"}, itemIDs(f.cache.torrents))
+}
+
+func TestServeStaleOnError(t *testing.T) {
+	ctx := context.Background()
+	var failing atomic.Bool
+	failing.Store(true)
+	f, _ := newRefreshTestFs(t, &failing)
+	f.opt.RefreshBudgetRequests = 3
+	f.opt.ServeStaleOnError = true
+	before := stats.staleListings.Load()
+
+	// The torrents listed before are listed when the refresh fails
+	entries, err := f.List(ctx, "movies")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"movies/Old.2019"}, entryNames(entries))
+	assert.Equal(t, before+1, stats.staleListings.Load())
+	assert.True(t, f.servingStale.Load())
+
+	// but the listings fail without them
+	torrents := f.cache.torrents
+	f.cache.torrents = nil
+	_, err = f.List(ctx, "movies")
+	assert.Error(t, err)
+	f.cache.torrents = torrents
+
+	// or if it is off
+	f.opt.ServeStaleOnError = false
+	_, err = f.List(ctx, "movies")
+	assert.Error(t, err)
+	f.opt.ServeStaleOnError = true
+
+	// The refreshed torrents are listed once the API is back
+	failing.Store(false)
+	entries, err = f.List(ctx, "movies")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"movies/Movie.2020"}, entryNames(entries))
+	assert.False(t, f.servingStale.Load())
+	assert.Equal(t, before+1, stats.staleListings.Load())
+}
+
+func TestCacheRefreshInterval(t *testing.T) {
+	ctx := context.Background()
+	var failing atomic.Bool
+	f, requests := newRefreshTestFs(t, &failing)
+	list := func() []string {
+		entries, err := f.List(ctx, "movies")
+		require.NoError(t, err)
+		return entryNames(entries)
+	}
+
+	// Served from the cache within the interval
+	f.opt.CacheRefreshInterval = fs.Duration(time.Hour)
+	f.cache.state.checked = time.Now().Add(-30 * time.Minute)
+	assert.Equal(t, []string{"movies/Old.2019"}, list())
+	assert.Equal(t, int64(0), requests.Load())
+
+	// and refreshed after it
+	f.cache.state.checked = time.Now().Add(-2 * time.Hour)
+	assert.Equal(t, []string{"movies/Movie.2020"}, list())
+	assert.NotZero(t, requests.Load())
+
+	// Never refreshed automatically with 0
+	f.opt.CacheRefreshInterval = 0
+	f.cache.state.checked = time.Now().Add(-30 * 24 * time.Hour)
+	f.cache.torrents = []api.Item{{ID: "old", Name: "Old.2019", Status: "downloaded"}}
+	requests.Store(0)
+	assert.Equal(t, []string{"movies/Old.2019"}, list())
+	assert.Equal(t, int64(0), requests.Load())
+
+	// unless changed through rclone
+	f.cache.state.Invalidate()
+	assert.Equal(t, []string{"movies/Movie.2020"}, list())
+	assert.NotZero(t, requests.Load())
+	assert.False(t, f.cache.state.invalid.Load())
+}
+
+func TestRefreshCommand(t *testing.T) {
+	ctx := context.Background()
+	var failing atomic.Bool
+	f, _ := newRefreshTestFs(t, &failing)
+	var (
+		mu       sync.Mutex
+		requests = map[string]int{}
+	)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path+"?limit="+r.URL.Query().Get("limit")]++
+		mu.Unlock()
+		items := []api.Item{}
+		switch r.URL.Path {
+		case "/torrents":
+			items = []api.Item{
+				{ID: "1", Name: "Show.S01", Status: "downloaded"},
+				{ID: "2", Name: "Movie.2020", Status: "downloaded"},
+			}
+		case "/downloads":
+			items = []api.Item{{ID: "d1", Name: "Movie.2020.mkv", OriginalLink: "l1"}}
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(items)))
+		writeJSON(t, w, items)
+	})
+	f.opt.CacheRefreshInterval = fs.Duration(time.Hour)
+	f.cache.state.checked = time.Now()
+
+	// Refreshes the torrents listed within the interval, with a
+	// listing at the same time using the refresh
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := f.List(ctx, "movies")
+		assert.NoError(t, err)
+	}()
+	out, err := f.Command(ctx, "refresh", nil, nil)
+	require.NoError(t, err)
+	wg.Wait()
+	report := out.(*refreshReport)
+	assert.Equal(t, 2, report.Torrents)
+	assert.Equal(t, 1, report.Downloads)
+	assert.Equal(t, f.cache.generation, report.Generation)
+	assert.NotEmpty(t, report.Took)
+	assert.Equal(t, []string{"1", "2"}, itemIDs(f.cache.torrents))
+	// whichever refreshes first, the torrents are only fetched once
+	assert.Equal(t, 1, requests["/downloads?limit=5000"])
+	assert.Equal(t, 1, requests["/torrents?limit=100"])
+	entries, err := f.List(ctx, "movies")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"movies/Movie.2020"}, entryNames(entries))
+
+	// Not while refreshes are skipped
+	f.cache.circuitOpenUntil = time.Now().Add(time.Hour)
+	_, err = f.Command(ctx, "refresh", nil, nil)
+	assert.ErrorIs(t, err, errCircuitOpen)
+	checkOpError(t, err, "refresh", "", "", "")
+}
+
+func TestBackgroundRefresh(t *testing.T) {
+	var failing atomic.Bool
+	f, requests := newRefreshTestFs(t, &failing)
+	f.opt.CacheRefreshInterval = fs.Duration(100 * time.Millisecond)
+	listed := func() int {
+		return len(f.cache.torrentsList())
+	}
+
+	// Only started if asked for with an interval
+	assert.False(t, f.startBackgroundRefresh())
+	f.opt.BackgroundRefresh = true
+	f.opt.CacheRefreshInterval = 0
+	assert.False(t, f.startBackgroundRefresh())
+	f.opt.CacheRefreshInterval = fs.Duration(100 * time.Millisecond)
+
+	// A refresh in progress isn't run alongside
+	f.cache.refreshMu.Lock()
+	before := stats.autoRefreshes.Load()
+	require.True(t, f.startBackgroundRefresh())
+	time.Sleep(50 * time.Millisecond)
+	assert.Zero(t, requests.Load())
+	assert.Equal(t, 1, listed())
+	f.cache.refreshMu.Unlock()
+
+	// The stale torrents are refreshed without a listing, then again
+	// each time they go stale
+	require.Eventually(t, func() bool { return listed() == 2 }, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return stats.autoRefreshes.Load()-before >= 2 }, 5*time.Second, 10*time.Millisecond)
+
+	// and the refreshes stop with the Fs
+	f.stop()
+	f.background.Wait()
+	stopped := requests.Load()
+	time.Sleep(250 * time.Millisecond)
+	assert.Equal(t, stopped, requests.Load())
+}
+
+func TestRefreshRemovedTorrents(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	var (
+		mu       sync.Mutex
+		torrents = []api.Item{
+			{ID: "1", Name: "Show.S01", Status: "downloaded", Links: []string{"L1"}},
+			{ID: "2", Name: "Movie.2020", Status: "downloaded", Links: []string{"L2"}},
+		}
+	)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(torrents)))
+		writeJSON(t, w, torrents)
+	})
+	f.cache.startup_cached_api_fetch = true
+	f.cache.torrents = torrents
+	for i, torrent := range torrents {
+		torrent.Files = []api.File{{ID: 1, Path: "/" + torrent.Name + ".mkv", Bytes: 100, Selected: 1}}
+		f.cache.addTorrentDetails(torrent)
+		f.cache.addLink(api.Item{ID: "D" + torrent.ID, Name: torrent.Name + ".mkv", Link: "https://dl/" + strconv.Itoa(i), OriginalLink: torrent.Links[0], Size: 100})
+	}
+	entries, err := f.List(ctx, "movies/Movie.2020")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"movies/Movie.2020/Movie.2020.mkv"}, entryNames(entries))
+
+	// The movie is deleted on the website
+	mu.Lock()
+	torrents = torrents[:1]
+	mu.Unlock()
+	f.cache.state.Invalidate()
+	require.NoError(t, f.refreshTorrents(ctx))
+
+	// so its folder is gone at once, with its link
+	_, err = f.List(ctx, "movies/Movie.2020")
+	assert.ErrorIs(t, err, fs.ErrorDirNotFound)
+	entries, err = f.List(ctx, "movies")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	_, found := f.cache.links()["L2"]
+	assert.False(t, found)
+	_, found = f.cache.links()["L1"]
+	assert.True(t, found)
+	f.flushes.mu.Lock()
+	assert.Contains(t, f.flushes.pending, "movies")
+	f.flushes.mu.Unlock()
+}
+
+func TestRefreshDownloadsFailed(t *testing.T) {
+	ctx := context.Background()
+	var failing atomic.Bool
+	failing.Store(true)
+	f, _ := newRefreshTestFs(t, &failing)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/downloads" && failing.Load():
+			w.WriteHeader(http.StatusInternalServerError)
+		case r.URL.Path == "/downloads":
+			w.Header().Set("X-Total-Count", "1")
+			writeJSON(t, w, []api.Item{{ID: "D1", OriginalLink: "L1", Link: "https://dl/L1"}})
+		default:
+			w.Header().Set("X-Total-Count", "1")
+			writeJSON(t, w, []api.Item{{ID: "1", Name: "Show.S01", Status: "downloaded"}})
+		}
+	})
+	f.client.pacer.SetRetries(1)
+	f.cache.startup_cached_api_fetch = false
+	before := stats.params()
+
+	// The failed scan is counted
+	assert.ErrorContains(t, f.refreshTorrents(ctx), "failed to list the downloads")
+	assert.Equal(t, int64(1), stats.params()["refreshErrors"].(int64)-before["refreshErrors"].(int64))
+	assert.False(t, f.cache.startup_cached_api_fetch)
+	assert.Empty(t, f.cache.cached)
+
+	// and retried by the next refresh
+	failing.Store(false)
+	require.NoError(t, f.refreshTorrents(ctx))
+	assert.True(t, f.cache.startup_cached_api_fetch)
+	assert.Equal(t, []string{"D1"}, itemIDs(f.cache.cached))
+}
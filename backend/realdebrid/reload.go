@@ -0,0 +1,189 @@
+package realdebrid
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/rclone/rclone/fs/config/configstruct"
+)
+
+// reloadEffect is what has to be done once an option is reloaded for
+// the Fs to use its new value
+type reloadEffect int
+
+const (
+	reloadNothing    reloadEffect = iota // read each time it is used
+	reloadRelist                         // decides which torrents are listed where
+	reloadClient                         // copied to the API client or its pacer
+	reloadInterval                       // the validity of the cached misses
+	reloadCategories                     // decides which category folders there are too
+)
+
+// reloadableOptions are the options the reload-options command can
+// change, with what has to be done after. The others are used when the
+// Fs is made, like the account or the mode deciding the whole layout,
+// so can only be changed by making the remote again.
+var reloadableOptions = map[string]reloadEffect{
+	"regex_shows":              reloadRelist,
+	"regex_movies":             reloadRelist,
+	"movies_by_year":           reloadRelist,
+	"regex_anime":              reloadCategories,
+	"categories":               reloadCategories,
+	"categories_fallback":      reloadCategories,
+	"regex_exclude":            reloadRelist,
+	"min_torrent_size":         reloadRelist,
+	"flatten_single_file":      reloadRelist,
+	"nested_folders":           reloadRelist,
+	"video_only":               reloadRelist,
+	"video_extensions":         reloadRelist,
+	"unicode_normalization":    reloadRelist,
+	"show_status_file":         reloadRelist,
+	"list_sort":                reloadNothing,
+	"purge_categories":         reloadNothing,
+	"repair_max_attempts":      reloadNothing,
+	"verify_pause_streams":     reloadNothing,
+	"max_concurrent_streams":   reloadNothing,
+	"downloads_max_age":        reloadNothing,
+	"auto_prune_downloads":     reloadNothing,
+	"scan_downloads":           reloadNothing,
+	"classify_command":         reloadNothing,
+	"classify_timeout":         reloadNothing,
+	"classify_batch_size":      reloadNothing,
+	"refresh_budget":           reloadNothing,
+	"refresh_budget_requests":  reloadNothing,
+	"refresh_circuit_failures": reloadNothing,
+	"refresh_circuit_backoff":  reloadNothing,
+	"list_page_size":           reloadNothing,
+	"serve_stale_on_error":     reloadNothing,
+	"simulate":                 reloadClient,
+	"pacer_min_sleep":          reloadClient,
+	"pacer_max_sleep":          reloadClient,
+	"api_retry_delay":          reloadClient,
+	"api_retries":              reloadClient,
+	"api_timeout":              reloadClient,
+	"cache_refresh_interval":   reloadInterval,
+}
+
+// reloadReport is the result of the reload-options command
+type reloadReport struct {
+	Changed []string `json:"changed"` // the options changed, sorted
+}
+
+// reloadCommand reads the options of the remote from its config again,
+// with the values of opt on top which are kept for the later reloads,
+// and applies those which changed without making the remote again.
+//
+// Nothing is changed if the new options aren't valid or if any that
+// can't be reloaded changed.
+func (f *Fs) reloadCommand(opt map[string]string) (*reloadReport, error) {
+	f.reloadMu.Lock()
+	defer f.reloadMu.Unlock()
+	overrides := configmap.Simple{}
+	maps.Copy(overrides, f.overrides)
+	maps.Copy(overrides, opt)
+	m := configmap.New().AddGetter(overrides, configmap.PriorityNormal)
+	if f.m != nil {
+		m.AddGetter(f.m, configmap.PriorityNormal)
+	}
+	// the options not found keep their value
+	newOpt := f.opt
+	if err := configstruct.Set(m, &newOpt); err != nil {
+		return nil, err
+	}
+	if err := validateOptions(&newOpt); err != nil {
+		return nil, err
+	}
+	changed, err := changedOptions(&f.opt, &newOpt)
+	if err != nil {
+		return nil, err
+	}
+	var frozen []string
+	for _, name := range changed {
+		_, ok := reloadableOptions[name]
+		// the background refresh keeps the interval it started with
+		if !ok || name == "cache_refresh_interval" && f.opt.BackgroundRefresh {
+			frozen = append(frozen, name)
+		}
+	}
+	if len(frozen) > 0 {
+		return nil, fmt.Errorf("realdebrid: %s can't be changed without making the remote again", strings.Join(frozen, ", "))
+	}
+	f.opt = newOpt
+	f.categoryList = newCategories(&newOpt)
+	f.overrides = overrides
+	effects := map[reloadEffect]bool{}
+	for _, name := range changed {
+		effects[reloadableOptions[name]] = true
+	}
+	if effects[reloadClient] {
+		f.client.simulate = newOpt.Simulate
+		f.client.timeout = time.Duration(newOpt.APITimeout)
+		f.client.pacer.SetCalculator(newCalculator(time.Duration(newOpt.PacerMinSleep), time.Duration(newOpt.PacerMaxSleep), time.Duration(newOpt.APIRetryDelay)))
+		if newOpt.APIRetries > 0 {
+			f.client.pacer.SetRetries(newOpt.APIRetries)
+		}
+	}
+	if effects[reloadInterval] {
+		f.misses.setTTL(time.Duration(newOpt.CacheRefreshInterval))
+	}
+	if effects[reloadRelist] || effects[reloadCategories] {
+		f.relist()
+	}
+	if effects[reloadCategories] && f.opt.RootFolderID == "torrents" && f.opt.SharedFolder == "folders" {
+		// the root lists the category folders
+		f.queueChanged("")
+		f.dirCache.ResetRoot()
+	}
+	return &reloadReport{Changed: changed}, nil
+}
+
+// changedOptions returns the config names of the options which differ
+// between before and after, sorted
+func changedOptions(before, after *Options) (changed []string, err error) {
+	beforeItems, err := configstruct.Items(before)
+	if err != nil {
+		return nil, err
+	}
+	afterItems, err := configstruct.Items(after)
+	if err != nil {
+		return nil, err
+	}
+	for i := range beforeItems {
+		if !reflect.DeepEqual(beforeItems[i].Value, afterItems[i].Value) {
+			changed = append(changed, beforeItems[i].Name)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// relist forgets the folders listing torrents after the options
+// deciding which are listed where changed, like the regexes sorting
+// them into the category folders, so their torrents are found where
+// they are listed now, and notifies them.
+func (f *Fs) relist() {
+	f.misses.clear()
+	// queued before they are flushed from the directory cache
+	f.torrentsDirsChanged()
+	dirs := []api.Item{{ID: rootID}}
+	if f.opt.RootFolderID == "torrents" && f.opt.SharedFolder == "folders" {
+		dirs = f.addArtificialRootFolders(nil)
+	}
+	for _, dir := range dirs {
+		path, ok := f.dirCache.GetInv(dir.ID)
+		switch {
+		case !ok:
+			// not listed yet
+		case path == "":
+			f.dirCache.ResetRoot()
+		default:
+			f.dirCache.FlushDir(path)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadOptions(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	config := configmap.Simple{}
+	f.m = config
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	f.cache.torrents = []api.Item{
+		{ID: "A1", Name: "Some.Anime.Ep01", Status: "downloaded"},
+		{ID: "S1", Name: "Show.S01", Status: "downloaded"},
+	}
+	reload := func(opt map[string]string) (*reloadReport, error) {
+		out, err := f.Command(ctx, "reload-options", nil, opt)
+		if err != nil {
+			return nil, err
+		}
+		return out.(*reloadReport), nil
+	}
+	entries, err := f.List(ctx, "default")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default/Some.Anime.Ep01"}, entryNames(entries))
+	_, ok := f.dirCache.GetInv("A1")
+	require.True(t, ok)
+
+	// A new regex is used by the next listing
+	report, err := reload(map[string]string{"regex_shows": `(?i)(S[0-9]{2}|Ep[0-9]{2})`})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"regex_shows"}, report.Changed)
+	entries, err = f.List(ctx, "shows")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shows/Show.S01", "shows/Some.Anime.Ep01"}, entryNames(entries))
+	entries, err = f.List(ctx, "default")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	dir, _ := f.dirCache.GetInv("A1")
+	assert.Equal(t, "shows/Some.Anime.Ep01", dir)
+	_, ok = f.dirCache.Get("default/Some.Anime.Ep01")
+	assert.False(t, ok, "the old path of the torrent is forgotten")
+
+	// and kept by the later reloads, which read the config again
+	config["list_sort"] = "name"
+	report, err = reload(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"list_sort"}, report.Changed)
+	assert.Equal(t, `(?i)(S[0-9]{2}|Ep[0-9]{2})`, f.opt.RegexShows)
+	assert.Equal(t, "name", f.opt.ListSort)
+
+	// New categories are listed in the root
+	report, err = reload(map[string]string{"categories": `anime=(?i)Ep[0-9]{2}`, "categories_fallback": "other"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"categories", "categories_fallback"}, report.Changed)
+	entries, err = f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"anime", "other"}, entryNames(entries))
+	entries, err = f.List(ctx, "anime")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"anime/Some.Anime.Ep01"}, entryNames(entries))
+
+	// The options of the API client are passed on
+	report, err = reload(map[string]string{"api_timeout": "5s"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api_timeout"}, report.Changed)
+	assert.Equal(t, 5*time.Second, f.client.timeout)
+
+	// Nothing is reloaded if an option which can't be changed did
+	_, err = reload(map[string]string{"download_mode": "downloads", "api_key": "other", "list_sort": "added_asc"})
+	assert.ErrorContains(t, err, "api_key, download_mode can't be changed without making the remote again")
+	assert.Equal(t, "torrents", f.opt.RootFolderID)
+	assert.Equal(t, "name", f.opt.ListSort)
+
+	// nor if one isn't valid
+	_, err = reload(map[string]string{"regex_movies": "(", "list_sort": "added_asc"})
+	assert.ErrorContains(t, err, "invalid regex_movies")
+	assert.Equal(t, "name", f.opt.ListSort)
+
+	// The interval can't change under the background refresh
+	f.opt.BackgroundRefresh = true
+	_, err = reload(map[string]string{"cache_refresh_interval": "1h"})
+	assert.ErrorContains(t, err, "cache_refresh_interval can't be changed")
+	f.opt.BackgroundRefresh = false
+	report, err = reload(map[string]string{"cache_refresh_interval": "1h"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cache_refresh_interval"}, report.Changed)
+	assert.Equal(t, fs.Duration(time.Hour), f.opt.CacheRefreshInterval)
+}
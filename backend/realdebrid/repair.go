@@ -0,0 +1,776 @@
+package realdebrid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+)
+
+// repairTorrents redownloads the dead and broken torrents except
+// those with files being read, which are left for the next refresh.
+//
+// It must be called with the refreshMu held.
+func (f *Fs) repairTorrents(ctx context.Context) {
+	f.cache.collectRepairs()
+	var repaired []api.Item
+	exclude := f.excludeRegexp()
+	for i, torrent := range f.cache.torrents {
+		broken := f.cache.isBroken(torrent.ID)
+		if torrent.Status != "dead" && !broken || f.cache.repairFailed(&torrent) || excludedBy(exclude, &torrent) {
+			continue
+		}
+		if f.cache.deferRepair(torrent.ID) {
+			stats.repairsDeferred.Add(1)
+			fs.Debugf(f, "Not repairing torrent %s %q while its files are being read", torrent.ID, torrent.Name)
+			continue
+		}
+		if repaired == nil {
+			repaired = slices.Clone(f.cache.torrents)
+		}
+		repaired[i] = f.redownloadTorrent(ctx, torrent)
+	}
+	if repaired != nil {
+		f.cache.setTorrents(repaired)
+	}
+}
+
+// reresolve finds the link of o again after its torrent has been
+// repaired, which gives it a new ID and new links, so open files keep
+// working. The torrent is found by hash, refreshing the torrents if it
+// isn't known yet, and the file by name among the selected ones.
+func (o *Object) reresolve(ctx context.Context) error {
+	f := o.fs
+	if o.TorrentHash == "" {
+		return errors.New("can't find the torrent again without its hash")
+	}
+	find := func() (id string) {
+		f.cache.mu.RLock()
+		defer f.cache.mu.RUnlock()
+		// torrents repaired while listing them are only in torrentswf
+		for _, torrents := range [][]api.Item{f.cache.torrents, f.cache.torrentswf} {
+			for _, torrent := range torrents {
+				if strings.EqualFold(torrent.TorrentHash, o.TorrentHash) && torrent.ID != o.ParentID {
+					return torrent.ID
+				}
+			}
+		}
+		return ""
+	}
+	id := find()
+	if id == "" {
+		if err := f.refreshTorrents(ctx); err != nil {
+			return err
+		}
+		id = find()
+	}
+	if id == "" {
+		return fmt.Errorf("torrent %s hasn't been repaired", o.ParentID)
+	}
+	torrent, err := f.torrentInfo(ctx, id, false)
+	if err != nil {
+		return err
+	}
+	leaf := foldName(f.normalize(path.Base(o.remote)))
+	selected := 0
+	for _, file := range torrent.Files {
+		if file.Selected != 1 {
+			continue
+		}
+		if foldName(f.normalize(path.Base(file.Path))) == leaf && selected < len(torrent.Links) {
+			item, err := f.unrestrict(ctx, torrent.Links[selected])
+			if err != nil {
+				return err
+			}
+			fs.Debugf(o, "Found again in repaired torrent %s", id)
+			o.url, o.id, o.OriginalUrl, o.ParentID = item.Link, item.ID, item.OriginalLink, id
+			return nil
+		}
+		selected++
+	}
+	return fmt.Errorf("file not found in repaired torrent %s", id)
+}
+
+// Redownload a dead torrent
+func (f *Fs) redownloadTorrent(ctx context.Context, torrent api.Item) (redownloaded_torrent api.Item) {
+	if f.opt.Simulate {
+		fs.Logf(f, "simulate: would redownload dead torrent %s %q", torrent.ID, torrent.Name)
+		return torrent
+	}
+	if !f.startRepair(&torrent) {
+		f.cache.unmarkBroken(torrent.ID)
+		return torrent
+	}
+	fmt.Println("Redownloading dead torrent: " + torrent.Name)
+	stats.repairs.Add(1)
+	//Get dead torrent file and hash info
+	info, err := f.torrentInfo(ctx, torrent.ID, false)
+	if err != nil {
+		// without its files the wrong ones would be selected, so keep
+		// the dead torrent to try again on the next refresh
+		fs.Errorf(f, "%v", f.wrapErr("redownload", "", torrent.ID, err))
+		return torrent
+	}
+	torrent = *info
+	var selected_files []int64
+	var dead_torrent_id = torrent.ID
+	for _, file := range torrent.Files {
+		if file.Selected == 1 {
+			selected_files = append(selected_files, file.ID)
+		}
+	}
+	//Delete old download links
+	for _, cachedfile := range f.cache.linksOf(torrent.Links) {
+		if err := f.client.DeleteDownload(ctx, cachedfile.ID); err != nil {
+			fs.Errorf(f, "%v", f.wrapErr("redownload", "", dead_torrent_id, err))
+		}
+	}
+	f.cache.unlink(torrent.Links)
+	//Add torrent again
+	newID, err := f.client.AddMagnet(ctx, torrent.TorrentHash)
+	if err != nil {
+		// keep the dead torrent to try again on the next refresh
+		fs.Errorf(f, "%v", f.wrapErr("redownload", "", dead_torrent_id, err))
+		return torrent
+	}
+	dead_torrent := torrent
+	for tries := 0; tries <= 5; tries++ {
+		if tries > 0 {
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+				// keep the dead torrent to try again on the next refresh
+				fs.Errorf(f, "%v", f.wrapErr("redownload", "", dead_torrent_id, fmt.Errorf("torrent %s added but not selected: %w", newID, ctx.Err())))
+				return dead_torrent
+			}
+		}
+		info, err := f.torrentInfo(ctx, newID, true)
+		if err != nil {
+			fs.Errorf(f, "%v", f.wrapErr("redownload", "", dead_torrent_id, err))
+			continue
+		}
+		torrent = *info
+		if torrent.Status == "waiting_files_selection" {
+			break
+		}
+	}
+	//Select the same files again
+	if err := f.client.SelectFiles(ctx, newID, selected_files); err != nil {
+		// keep the dead torrent to try again on the next refresh
+		// rather than the new one which has no files
+		fs.Errorf(f, "%v", f.wrapErr("redownload", "", dead_torrent_id, err))
+		if err := f.client.DeleteTorrent(ctx, newID); err != nil {
+			fs.Errorf(f, "%v", f.wrapErr("redownload", "", newID, err))
+		}
+		f.infos.remove(newID)
+		return dead_torrent
+	}
+	//Delete the old torrent
+	if err := f.client.DeleteTorrent(ctx, dead_torrent_id); err != nil {
+		fs.Errorf(f, "%v", f.wrapErr("redownload", "", dead_torrent_id, err))
+	}
+	f.infos.remove(dead_torrent_id, newID)
+	torrent.Status = "downloaded"
+	f.cache.state.Invalidate()
+	f.cache.unmarkBroken(dead_torrent_id)
+	f.cache.repaired(dead_torrent_id)
+	f.torrentChanged(dead_torrent_id)
+	return torrent
+}
+
+var commandHelp = []fs.CommandHelp{{
+	Name:  "reselect",
+	Short: "Change the files selected in a torrent.",
+	Long: `This command changes the files selected in a torrent to the ones
+whose path matches the regular expression given with -o files.
+
+Usage examples:
+
+` + "```console" + `
+rclone backend reselect realdebrid: torrentID -o files='(?i)S01E05'
+rclone backend reselect realdebrid: torrentID -o files='(?i)S01E05' -o clone=true
+` + "```" + `
+
+Real-Debrid only lets files be selected once so the torrent is added
+again from its magnet with the new selection. With clone=true the
+original torrent is left untouched and both are listed, the newer one
+with its ID appended to its name. Without it the original torrent is
+deleted once the new one has been added.
+
+It returns the ID of the new torrent.
+
+` + "```json" + `
+{
+    "id": "ABCDEFGHIJKLM"
+}
+` + "```",
+	Opts: map[string]string{
+		"files": "Regular expression matching the paths of the files to select (required).",
+		"clone": "Set to true to keep the original torrent.",
+	},
+}, {
+	Name:  "export",
+	Short: "Export the torrents and their files.",
+	Long: `This command returns the torrents with the files listed for them.
+
+Usage example:
+
+` + "```console" + `
+rclone backend export realdebrid: > torrents.json
+` + "```" + `
+
+The torrents are exported as they were at one refresh, whose generation
+is returned, even if they are refreshed while exporting. The files of
+the torrents which haven't been listed yet are left out.
+
+` + "```json" + `
+{
+    "generation": 42,
+    "torrents": [
+        {
+            "id": "ABCDEFGHIJKLM",
+            "name": "Show.S01",
+            "hash": "0123456789abcdef0123456789abcdef01234567",
+            "status": "downloaded",
+            "files": [
+                {
+                    "name": "Show.S01E01.mkv",
+                    "size": 1234567890,
+                    "link": "https://download.real-debrid.com/d/..."
+                }
+            ]
+        }
+    ]
+}
+` + "```",
+}, {
+	Name:  "prune-downloads",
+	Short: "Delete the old downloads which aren't links of torrents.",
+	Long: `This command deletes the downloads generated longer ago than
+-o max-age, or downloads_max_age if not given, as every file streamed
+adds one to the downloads page.
+
+Usage examples:
+
+` + "```console" + `
+rclone backend prune-downloads realdebrid: -o max-age=30d -o dry-run=true
+rclone backend prune-downloads realdebrid: -o max-age=30d
+` + "```" + `
+
+The downloads of the links of the current torrents are kept as they
+are needed to list them. The deletes are paced like the other API
+calls so they can take a while. With dry-run=true, or the simulate
+option set, nothing is deleted.
+
+It returns a report of the downloads deleted, or which would be.
+
+` + "```json" + `
+{
+    "maxAge": "1M",
+    "dryRun": false,
+    "checked": 1200,
+    "referenced": 310,
+    "pruned": [
+        {
+            "id": "ABCDEFGHIJKLM",
+            "name": "file.mkv",
+            "generated": "2024-01-02T03:04:05.000Z"
+        }
+    ]
+}
+` + "```",
+	Opts: map[string]string{
+		"max-age": "Delete the downloads older than this, e.g. 30d.",
+		"dry-run": "Set to true to only report the downloads to delete.",
+	},
+}, {
+	Name:  "repair",
+	Short: "Repair a torrent now.",
+	Long: `This command adds the torrent with the ID given again, even if its
+repair was given up on after repair_max_attempts, and counts its
+attempts from 0 again.
+
+Usage example:
+
+` + "```console" + `
+rclone backend repair realdebrid: ABCDEFGHIJKLM
+` + "```" + `
+
+It returns the ID of the torrent added.
+
+` + "```json" + `
+{
+    "id": "NOPQRSTUVWXYZ"
+}
+` + "```",
+}, {
+	Name:  "refresh",
+	Short: "Refresh the torrents and downloads now.",
+	Long: `This command lists the torrents and downloads from the API again
+without waiting for cache_refresh_interval, for instance after adding
+a torrent on the website.
+
+Usage example:
+
+` + "```console" + `
+rclone backend refresh realdebrid:
+` + "```" + `
+
+It returns the number of torrents and downloads listed, the generation
+of the torrents and how long it took.
+
+` + "```json" + `
+{
+    "torrents": 1234,
+    "downloads": 5678,
+    "generation": 43,
+    "took": "12.345s"
+}
+` + "```",
+}, {
+	Name:  "changes",
+	Short: "Show what changed in the latest generations of the torrents.",
+	Long: `This command returns the torrents added, removed, whose status
+changed or whose number of links changed each time the torrents were
+replaced, for the last 10 generations, newest first. Up to 100 torrents
+are listed for each generation with the number of the others in more.
+The counts are also logged at INFO level and each torrent at DEBUG.
+
+Use it to find out why an item disappeared from a media library, for
+instance a torrent which went dead.
+
+Usage example:
+
+` + "```console" + `
+rclone backend changes realdebrid:
+` + "```" + `
+
+` + "```json" + `
+[
+    {
+        "generation": 43,
+        "time": "2024-03-12T03:12:00Z",
+        "added": 1,
+        "removed": 0,
+        "statusChanged": 1,
+        "linksChanged": 0,
+        "changes": [
+            {"id": "ABCDEFGHIJKLM", "name": "Movie.2020", "change": "added", "to": "downloading"},
+            {"id": "NOPQRSTUVWXYZ", "name": "Show.S01", "change": "status", "from": "downloaded", "to": "dead"}
+        ],
+        "more": 0
+    }
+]
+` + "```",
+}, {
+	Name:  "link",
+	Short: "Make a download link for a file, optionally for other IPs.",
+	Long: `This command returns a download link for the file at the path given.
+
+Usage examples:
+
+` + "```console" + `
+rclone backend link realdebrid: movies/Movie.2020/movie.mkv
+rclone backend link realdebrid: movies/Movie.2020/movie.mkv -o remote=true
+` + "```" + `
+
+Download links only work from the IP they were made from. With
+remote=true the link works from any IP, for instance to send it to a
+friend or a cloud transcoder, but its downloads use the remote traffic
+quota of the account. ` + "`rclone link --expire`" + ` makes the same link.
+The links for other IPs are cached apart so they are never streamed.
+
+` + "```json" + `
+{
+    "link": "https://download.real-debrid.com/d/...",
+    "remote": true,
+    "warning": "the link works from any IP and its downloads use the remote traffic quota of the account"
+}
+` + "```",
+	Opts: map[string]string{
+		"remote": "Set to true to make a link which works from other IPs.",
+	},
+}, {
+	Name:  "top",
+	Short: "Show the torrents streamed the most.",
+	Long: `This command returns the torrents with the most bytes read from
+them over the window given with -o window, or the last 90 days which
+are all kept if not given, most first.
+
+Use it to decide which torrents to keep a local copy of.
+
+Usage examples:
+
+` + "```console" + `
+rclone backend top realdebrid:
+rclone backend top realdebrid: -o window=7d -o n=20
+` + "```" + `
+
+The bytes are counted by the hour and dumped to dump_dir with the
+other caches so they survive restarts. Those read since the last
+refresh are lost if rclone is killed. The torrents which have gone
+since are returned with an empty name.
+
+` + "```json" + `
+{
+    "window": "1w",
+    "torrents": [
+        {"id": "ABCDEFGHIJKLM", "name": "Show.S01", "bytes": 12345678901}
+    ]
+}
+` + "```",
+	Opts: map[string]string{
+		"window": "Count the bytes read over this, e.g. 7d.",
+		"n":      "Number of torrents to return, 10 if not given.",
+	},
+}, {
+	Name:  "api-stats",
+	Short: "Show the API calls made by the remote.",
+	Long: `This command returns the API calls the remote made since rclone
+started or they were last reset, by endpoint, with those answered with
+429 Too Many Requests and the other failures. Retries are counted too.
+
+Use it to see how close the remote is to the rate limit of Real-Debrid
+and what uses up its calls. Unlike realdebrid/stats the calls are
+those of this remote only.
+
+Usage examples:
+
+` + "```console" + `
+rclone backend api-stats realdebrid:
+rclone backend api-stats realdebrid: -o reset=true
+` + "```" + `
+
+With reset=true the counts are returned then set back to 0.
+
+` + "```json" + `
+{
+    "since": "2024-03-12T03:12:00Z",
+    "calls": 312,
+    "callsPerMinute": 2.6,
+    "tooManyRequests": 3,
+    "endpoints": {
+        "torrents": {"calls": 40, "tooManyRequests": 0, "errors": 0},
+        "torrentInfo": {"calls": 150, "tooManyRequests": 2, "errors": 0},
+        "unrestrict": {"calls": 110, "tooManyRequests": 1, "errors": 4},
+        "deletes": {"calls": 2, "tooManyRequests": 0, "errors": 0},
+        "downloads": {"calls": 8, "tooManyRequests": 0, "errors": 0},
+        "other": {"calls": 2, "tooManyRequests": 0, "errors": 0}
+    }
+}
+` + "```",
+	Opts: map[string]string{
+		"reset": "Set to true to set the counts back to 0 after returning them.",
+	},
+}, {
+	Name:  "reload-options",
+	Short: "Read the options of the remote again without remounting.",
+	Long: `This command reads the options of the remote from the config file
+again, with those given with -o on top, and uses them from now on, so
+tweaking the regexes doesn't need a remount which stops playback.
+The options given with -o are kept for the later reloads.
+
+Usage examples:
+
+` + "```console" + `
+rclone backend reload-options realdebrid:
+rclone rc backend/command command=reload-options fs=realdebrid: -o regex_shows='(?i)(S[0-9]{2}|SEASON)'
+` + "```" + `
+
+The folders listing torrents are listed again after the options
+deciding which torrents they list change, like regex_shows,
+regex_movies, min_torrent_size or flatten_single_file. The other
+options are used from their next use.
+
+The options used when the remote is made, like api_key,
+download_mode, folder_mode or background_refresh, can't be changed
+this way and nothing is reloaded if one of them changed, nor if an
+option isn't valid. Neither can cache_refresh_interval with
+background_refresh set.
+
+It returns the options which changed.
+
+` + "```json" + `
+{
+    "changed": ["regex_shows"]
+}
+` + "```",
+}, {
+	Name:  "doctor",
+	Short: "Check the remote works from end to end.",
+	Long: `This command checks each part of the remote in turn, timing them: the
+API key, reading a page of torrents and of downloads, the links of a
+downloaded torrent picked at random, unrestricting one of them and
+reading its first byte from the CDN.
+
+Usage example:
+
+` + "```console" + `
+rclone backend doctor realdebrid:
+` + "```" + `
+
+The steps after one which failed are skipped. Attach the result to bug
+reports about an empty or failing mount.
+
+It is safe to run on the account in use: the calls are paced as the
+others are, and a download link made to check the CDN, rather than
+found already made, is deleted at the end.
+
+` + "```json" + `
+{
+    "ok": false,
+    "took": "1.52s",
+    "steps": [
+        {"name": "user", "ok": true, "took": "212ms", "detail": "someone (premium, premium left 30d)"},
+        {"name": "torrents", "ok": true, "took": "340ms", "detail": "50 of 812 torrents listed"},
+        {"name": "downloads", "ok": true, "took": "298ms", "detail": "50 of 2040 downloads listed"},
+        {"name": "torrentInfo", "ok": true, "took": "187ms", "detail": "\"Movie.2020\" (ABCDEFGHIJKLM) has 1 links"},
+        {"name": "unrestrict", "ok": true, "took": "402ms", "detail": "\"Movie.2020.mkv\" unrestricted as NOPQRSTUVWXYZ"},
+        {"name": "cdn", "ok": false, "took": "81ms", "error": "HTTP error 503"},
+        {"name": "cleanup", "ok": true, "took": "95ms", "detail": "download NOPQRSTUVWXYZ deleted"}
+    ]
+}
+` + "```",
+}, {
+	Name:  "set-category",
+	Short: "Set the category folder a torrent is listed in.",
+	Long: `This command lists the torrent with the ID given in the category
+given whatever the classifier or the regexes make of it, for the
+torrents they get wrong.
+
+Usage example:
+
+` + "```console" + `
+rclone backend set-category realdebrid: ABCDEFGHIJKLM shows
+` + "```" + `
+
+The categories set are kept by torrent hash, so they survive the
+torrent being repaired, in a file named after the remote in the
+realdebrid directory of the rclone cache dir. They are only used in
+folder_mode folders.
+
+It returns the category of the torrent as get-category does.`,
+}, {
+	Name:  "get-category",
+	Short: "Show the category folder a torrent is listed in.",
+	Long: `This command returns the category of the torrent with the ID given,
+and the one set with set-category if any.
+
+Usage example:
+
+` + "```console" + `
+rclone backend get-category realdebrid: ABCDEFGHIJKLM
+` + "```" + `
+
+` + "```json" + `
+{
+    "id": "ABCDEFGHIJKLM",
+    "name": "Movie.Pack.S01",
+    "hash": "0123456789abcdef0123456789abcdef01234567",
+    "category": "movies",
+    "override": "movies"
+}
+` + "```",
+}, {
+	Name:  "clear-category",
+	Short: "Forget the category set for a torrent.",
+	Long: `This command forgets the category set with set-category for the
+torrent with the ID given, so it is classified as the others are again.
+
+Usage example:
+
+` + "```console" + `
+rclone backend clear-category realdebrid: ABCDEFGHIJKLM
+` + "```" + `
+
+It returns the category of the torrent as get-category does.`,
+}}
+
+// Command the backend to run a named command
+//
+// The command run is name
+// args may be used to read arguments from
+// opts may be used to read optional arguments from
+//
+// The result should be capable of being JSON encoded
+// If it is a string or a []string it will be shown to the user
+// otherwise it will be JSON encoded and shown to the user like that
+func (f *Fs) Command(ctx context.Context, name string, arg []string, opt map[string]string) (out any, err error) {
+	switch name {
+	case "reselect":
+		if len(arg) != 1 {
+			return nil, errors.New("need exactly 1 argument: the torrent ID")
+		}
+		files, ok := opt["files"]
+		if !ok || files == "" {
+			return nil, errors.New("need -o files=regex")
+		}
+		re, err := regexp.Compile(files)
+		if err != nil {
+			return nil, fmt.Errorf("invalid files regex: %w", err)
+		}
+		clone := false
+		if value, ok := opt["clone"]; ok {
+			clone, err = strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid clone value %q: %w", value, err)
+			}
+		}
+		if err := f.checkScope(ctx, arg[0]); err != nil {
+			return nil, f.wrapErr("reselect", "", arg[0], err)
+		}
+		id, err := f.reselect(ctx, arg[0], re, clone)
+		if err != nil {
+			return nil, f.wrapErr("reselect", "", arg[0], err)
+		}
+		return map[string]string{"id": id}, nil
+	case "export":
+		return f.export(), nil
+	case "prune-downloads":
+		out, err := f.pruneCommand(ctx, opt)
+		if err != nil {
+			return nil, f.wrapErr("prune", "", "", err)
+		}
+		return out, nil
+	case "repair":
+		if len(arg) != 1 {
+			return nil, errors.New("need exactly 1 argument: the torrent ID")
+		}
+		if err := f.checkScope(ctx, arg[0]); err != nil {
+			return nil, f.wrapErr("repair", "", arg[0], err)
+		}
+		id, err := f.repairCommand(ctx, arg[0])
+		if err != nil {
+			return nil, f.wrapErr("repair", "", arg[0], err)
+		}
+		return map[string]string{"id": id}, nil
+	case "refresh":
+		out, err := f.refreshCommand(ctx)
+		if err != nil {
+			return nil, f.wrapErr("refresh", "", "", err)
+		}
+		return out, nil
+	case "changes":
+		return f.changesCommand(), nil
+	case "link":
+		if len(arg) != 1 {
+			return nil, errors.New("need exactly 1 argument: the path of the file")
+		}
+		out, err := f.linkCommand(ctx, arg[0], opt)
+		if err != nil {
+			return nil, err
+		}
+		return out, nil
+	case "top":
+		out, err := f.topCommand(opt)
+		if err != nil {
+			return nil, err
+		}
+		return out, nil
+	case "api-stats":
+		out, err := f.apiStatsCommand(opt)
+		if err != nil {
+			return nil, err
+		}
+		return out, nil
+	case "reload-options":
+		out, err := f.reloadCommand(opt)
+		if err != nil {
+			return nil, err
+		}
+		return out, nil
+	case "doctor":
+		return f.doctor(ctx), nil
+	case "set-category":
+		if len(arg) != 2 {
+			return nil, errors.New("need exactly 2 arguments: the torrent ID and the category")
+		}
+		out, err := f.categoryCommand(ctx, name, arg[0], arg[1])
+		if err != nil {
+			return nil, f.wrapErr(name, "", arg[0], err)
+		}
+		return out, nil
+	case "get-category", "clear-category":
+		if len(arg) != 1 {
+			return nil, errors.New("need exactly 1 argument: the torrent ID")
+		}
+		out, err := f.categoryCommand(ctx, name, arg[0], "")
+		if err != nil {
+			return nil, f.wrapErr(name, "", arg[0], err)
+		}
+		return out, nil
+	default:
+		return nil, fs.ErrorCommandNotFound
+	}
+}
+
+// reselect adds the torrent with id again selecting the files whose
+// path match re, deleting the original unless clone is set. It
+// returns the ID of the new torrent.
+func (f *Fs) reselect(ctx context.Context, id string, re *regexp.Regexp, clone bool) (newID string, err error) {
+	torrent, err := f.torrentInfo(ctx, id, false)
+	if err != nil {
+		return "", err
+	}
+	if torrent.TorrentHash == "" {
+		return "", fmt.Errorf("torrent %q has no hash", id)
+	}
+	var fileIDs []int64
+	for _, file := range torrent.Files {
+		if re.MatchString(file.Path) {
+			fileIDs = append(fileIDs, file.ID)
+		}
+	}
+	if len(fileIDs) == 0 {
+		return "", fmt.Errorf("no files in torrent %q match %q", id, re)
+	}
+	if f.opt.Simulate {
+		fs.Logf(f, "simulate: would add torrent %q again with %d of %d files selected (clone=%v)", id, len(fileIDs), len(torrent.Files), clone)
+		return "", nil
+	}
+	newID, err = f.client.AddMagnet(ctx, torrent.TorrentHash)
+	if err != nil {
+		return "", err
+	}
+	// wait for the magnet to be converted before selecting
+	for tries := 0; ; tries++ {
+		info, err := f.torrentInfo(ctx, newID, true)
+		if err != nil {
+			return newID, err
+		}
+		if info.Status == "waiting_files_selection" {
+			break
+		}
+		if tries >= 10 {
+			return newID, fmt.Errorf("torrent %q still %q after adding magnet", newID, info.Status)
+		}
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return newID, ctx.Err()
+		}
+	}
+	err = f.client.SelectFiles(ctx, newID, fileIDs)
+	f.infos.remove(newID)
+	if err != nil {
+		return newID, err
+	}
+	fs.Infof(f, "Added torrent %q from %q with %d of %d files selected", newID, id, len(fileIDs), len(torrent.Files))
+	if !clone {
+		err = f.deleteTorrent(ctx, id)
+		if err != nil {
+			return newID, err
+		}
+	}
+	// pick the changes up on the next listing
+	f.cache.state.Invalidate()
+	return newID, nil
+}
@@ -0,0 +1,248 @@
+package realdebrid
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/vfs"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepairDeferredWhileReading(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.opt.SharedFolder = "torrents"
+	content := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("content"))
+	}))
+	t.Cleanup(content.Close)
+	var (
+		mu      sync.Mutex
+		added   int
+		magnets = map[string]string{"T1": "T2", "T2": "T3"}
+	)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/unrestrict/link":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			writeJSON(t, w, api.Response{Message: "hoster_unavailable"})
+		case r.URL.Path == "/torrents/addMagnet":
+			added++
+			writeJSON(t, w, api.Item{ID: magnets[r.FormValue("magnet")[len("magnet:?xt=urn:btih:"):]]})
+		case r.Method == "GET":
+			id := r.URL.Path[len("/torrents/info/"):]
+			writeJSON(t, w, api.Item{ID: id, TorrentHash: id, Status: "waiting_files_selection", Files: []api.File{{ID: 1, Selected: 1}}})
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	torrent := api.Item{ID: "T1", Name: "Movie.2020", Status: "downloaded", TorrentHash: "T1", Links: []string{"l1"}}
+	f.cache.torrents = []api.Item{torrent}
+	f.cache.torrentswf = []api.Item{torrent}
+	f.cache.cached = []api.Item{{ID: "d1", Name: "movie.mkv", OriginalLink: "l1", Link: content.URL, Size: 7}}
+	repairs := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return added
+	}
+	before := stats.repairsDeferred.Load()
+
+	o, err := f.NewObject(ctx, "Movie.2020/movie.mkv")
+	require.NoError(t, err)
+	in, err := o.Open(ctx)
+	require.NoError(t, err)
+	in2, err := o.Open(ctx)
+	require.NoError(t, err)
+
+	// The verifier finds the torrent broken but it isn't repaired
+	// while its file is being read
+	f.markBroken("T1")
+	f.repairTorrents(ctx)
+	assert.Equal(t, 0, repairs())
+	assert.Equal(t, "T1", f.cache.torrents[0].ID)
+	assert.Equal(t, []string{"T1"}, f.cache.broken_torrents)
+	require.NoError(t, in.Close())
+	require.NoError(t, in.Close())
+	f.repairTorrents(ctx)
+	assert.Equal(t, 0, repairs())
+	assert.Equal(t, int64(2), stats.repairsDeferred.Load()-before)
+
+	// It is once the files are closed
+	require.NoError(t, in2.Close())
+	f.repairTorrents(ctx)
+	assert.Equal(t, 1, repairs())
+	assert.Equal(t, "T2", f.cache.torrents[0].ID)
+	assert.Empty(t, f.cache.broken_torrents)
+
+	// unless the torrent was found broken by a read, then the open
+	// files are most likely the streams which failed
+	o, err = f.newObjectWithInfo(ctx, "Movie.2020/movie.mkv", &api.Item{Name: "movie.mkv", Type: api.ItemTypeFile, Link: content.URL, ParentID: "T2", Size: 7})
+	require.NoError(t, err)
+	in, err = o.Open(ctx)
+	require.NoError(t, err)
+	broken, err := f.newObjectWithInfo(ctx, "Movie.2020/extra.mkv", &api.Item{Name: "extra.mkv", Type: api.ItemTypeFile, OriginalLink: "l2", ParentID: "T2", Size: 7})
+	require.NoError(t, err)
+	_, err = broken.Open(ctx)
+	assert.ErrorIs(t, err, errLinkUnavailable)
+	f.repairTorrents(ctx)
+	assert.Equal(t, 2, repairs())
+	assert.Equal(t, "T3", f.cache.torrents[0].ID)
+	assert.Empty(t, f.cache.readBroken)
+	require.NoError(t, in.Close())
+}
+
+func TestRedownloadCancelled(t *testing.T) {
+	f := newTestFs(t)
+	var (
+		mu       sync.Mutex
+		requests = map[string]int{}
+	)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.Method+" "+r.URL.Path]++
+		mu.Unlock()
+		switch {
+		case r.URL.Path == "/torrents/addMagnet":
+			writeJSON(t, w, api.Item{ID: "T2"})
+		case r.Method == "GET":
+			id := r.URL.Path[len("/torrents/info/"):]
+			status := map[string]string{"T1": "dead", "T2": "magnet_conversion"}[id]
+			writeJSON(t, w, api.Item{ID: id, TorrentHash: "H", Status: status})
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// Cancelling stops waiting for the magnet and keeps the dead torrent
+	start := time.Now()
+	torrent := f.redownloadTorrent(ctx, api.Item{ID: "T1", Name: "Movie.2020", Status: "dead", TorrentHash: "H"})
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, "T1", torrent.ID)
+	assert.Equal(t, "dead", torrent.Status)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Zero(t, requests["POST /torrents/selectFiles/T2"])
+	assert.Zero(t, requests["DELETE /torrents/delete/T1"])
+}
+
+func TestRedownloadErrors(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	requests := map[string]int{}
+	infoFails := true
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests[r.Method+" "+r.URL.Path]++
+		switch {
+		case r.URL.Path == "/torrents/addMagnet":
+			writeJSON(t, w, api.Item{ID: "T2"})
+		case r.Method == "GET" && infoFails:
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(t, w, api.Response{Message: "bad_request"})
+		case r.Method == "GET":
+			id := path.Base(r.URL.Path)
+			writeJSON(t, w, api.Item{ID: id, TorrentHash: "H", Status: "waiting_files_selection",
+				Files: []api.File{{ID: 1, Path: "/movie.mkv", Selected: 1}}})
+		case strings.HasPrefix(r.URL.Path, "/torrents/selectFiles/"):
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(t, w, api.Response{Message: "bad_request"})
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	dead := api.Item{ID: "T1", Name: "Movie.2020", Status: "dead", TorrentHash: "H"}
+
+	// The dead torrent is kept if its files can't be read
+	torrent := f.redownloadTorrent(ctx, dead)
+	assert.Equal(t, "T1", torrent.ID)
+	assert.Zero(t, requests["POST /torrents/addMagnet"])
+
+	// or if they can't be selected in the new one, which is deleted
+	infoFails = false
+	f.infos.remove("T1")
+	torrent = f.redownloadTorrent(ctx, dead)
+	assert.Equal(t, "T1", torrent.ID)
+	assert.Equal(t, 1, requests["POST /torrents/addMagnet"])
+	assert.Equal(t, 1, requests["DELETE /torrents/delete/T2"])
+	assert.Zero(t, requests["DELETE /torrents/delete/T1"])
+}
+
+func TestStreamSurvivesRepair(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.opt.SharedFolder = "torrents"
+	data := []byte(strings.Repeat("0123456789abcdef", 4096))
+	var repaired atomic.Bool
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" && repaired.Load() {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		http.ServeContent(w, r, "movie.mkv", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(cdn.Close)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "DELETE" || strings.HasPrefix(r.URL.Path, "/torrents/selectFiles/"):
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/torrents/addMagnet":
+			writeJSON(t, w, api.Item{ID: "T2"})
+		case strings.HasPrefix(r.URL.Path, "/torrents/info/"):
+			id := path.Base(r.URL.Path)
+			writeJSON(t, w, api.Item{ID: id, TorrentHash: "H", Status: "waiting_files_selection", Links: []string{"l-" + id},
+				Files: []api.File{{ID: 1, Path: "/sample.mkv"}, {ID: 2, Path: "/movie.mkv", Selected: 1}}})
+		case r.URL.Path == "/unrestrict/link" && r.FormValue("link") == "l-T2":
+			writeJSON(t, w, api.Item{ID: "d2", Name: "movie.mkv", OriginalLink: "l-T2", Link: cdn.URL + "/new", Size: int64(len(data))})
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			writeJSON(t, w, api.Response{Message: "hoster_unavailable"})
+		}
+	})
+	torrent := api.Item{ID: "T1", Name: "Movie.2020", TorrentHash: "H", Status: "downloaded", Links: []string{"l-T1"}}
+	f.cache.torrents = []api.Item{torrent}
+	f.cache.torrentswf = []api.Item{torrent}
+	f.cache.cached = []api.Item{{ID: "d1", Name: "movie.mkv", OriginalLink: "l-T1", Link: cdn.URL + "/old", Size: int64(len(data))}}
+
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeOff
+	opt.ChunkSize = 4096
+	opt.ChunkSizeLimit = 4096
+	opt.PollInterval = 0
+	vfs := vfs.New(ctx, f, &opt)
+	t.Cleanup(vfs.Shutdown)
+	fh, err := vfs.OpenFile("Movie.2020/movie.mkv", os.O_RDONLY, 0)
+	require.NoError(t, err)
+	got := make([]byte, len(data))
+	_, err = io.ReadFull(fh, got[:len(data)/2])
+	require.NoError(t, err)
+
+	// The torrent is repaired while the file is read, deleting its link
+	f.cache.refreshMu.Lock()
+	f.cache.setTorrents([]api.Item{f.redownloadTorrent(ctx, torrent)})
+	f.cache.refreshMu.Unlock()
+	repaired.Store(true)
+
+	// and the stream continues from the repaired torrent
+	start := time.Now()
+	_, err = io.ReadFull(fh, got[len(data)/2:])
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second, "stalled")
+	assert.Equal(t, data, got)
+	require.NoError(t, fh.Close())
+}
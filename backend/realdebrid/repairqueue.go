@@ -0,0 +1,149 @@
+package realdebrid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+)
+
+// repairWorkedAge is how long a torrent repaired has to stay downloaded
+// for its repair attempts to be forgotten, so a torrent which breaks
+// again soon after each repair is still given up on
+const repairWorkedAge = 24 * time.Hour
+
+// repairAttempts counts the repairs of a torrent which haven't worked
+// yet. They are kept by hash as each repair gives the torrent a new ID.
+type repairAttempts struct {
+	ID       string    // ID of the torrent at the last attempt
+	Name     string    // name of the torrent
+	Attempts int       // repairs tried so far
+	First    time.Time // when the first repair was tried
+	Last     time.Time // when the last repair was tried
+}
+
+// repairKey returns the key of the repair attempts of torrent
+func repairKey(torrent *api.Item) string {
+	if torrent.TorrentHash != "" {
+		return torrent.TorrentHash
+	}
+	return torrent.ID
+}
+
+// startRepair counts an attempt to repair torrent, returning false if
+// it shouldn't be tried as repair_max_attempts have already failed.
+// The torrent is then moved to the failed repairs, which aren't tried
+// again unless asked for with the repair command.
+func (f *Fs) startRepair(torrent *api.Item) bool {
+	c := f.cache
+	key := repairKey(torrent)
+	c.repairsMu.Lock()
+	defer c.repairsMu.Unlock()
+	if _, failed := c.repairsFailed[key]; failed {
+		return false
+	}
+	if c.repairs == nil {
+		c.repairs = make(map[string]*repairAttempts)
+	}
+	attempts := c.repairs[key]
+	if attempts == nil {
+		attempts = &repairAttempts{First: time.Now()}
+		c.repairs[key] = attempts
+	}
+	attempts.ID, attempts.Name = torrent.ID, torrent.Name
+	if f.opt.RepairMaxAttempts > 0 && attempts.Attempts >= f.opt.RepairMaxAttempts {
+		delete(c.repairs, key)
+		if c.repairsFailed == nil {
+			c.repairsFailed = make(map[string]*repairAttempts)
+		}
+		c.repairsFailed[key] = attempts
+		stats.repairsFailed.Add(1)
+		fs.Errorf(f, "%v", f.wrapErr("repair", "", torrent.ID, fmt.Errorf("giving up after %d attempts since %v, use the repair command to try again", attempts.Attempts, attempts.First.Format(time.RFC3339))))
+		return false
+	}
+	attempts.Attempts++
+	attempts.Last = time.Now()
+	return true
+}
+
+// repairFailed returns whether the repairs of torrent were given up
+func (c *sharedCache) repairFailed(torrent *api.Item) bool {
+	c.repairsMu.Lock()
+	defer c.repairsMu.Unlock()
+	_, failed := c.repairsFailed[repairKey(torrent)]
+	return failed
+}
+
+// resetRepair forgets the repair attempts of torrent so it is tried
+// again
+func (c *sharedCache) resetRepair(torrent *api.Item) {
+	key := repairKey(torrent)
+	c.repairsMu.Lock()
+	defer c.repairsMu.Unlock()
+	delete(c.repairs, key)
+	delete(c.repairsFailed, key)
+}
+
+// collectRepairs forgets the repair attempts of the torrents which
+// have been downloaded and not broken for repairWorkedAge since the
+// last one, so their repair worked, and those of the torrents which
+// are no longer listed.
+//
+// It must be called with the refreshMu held after a refresh.
+func (c *sharedCache) collectRepairs() {
+	listed := make(map[string]bool, len(c.torrents))
+	for i := range c.torrents {
+		torrent := &c.torrents[i]
+		key := repairKey(torrent)
+		listed[key] = listed[key] || torrent.Status == "downloaded" && !c.isBroken(torrent.ID)
+	}
+	c.repairsMu.Lock()
+	defer c.repairsMu.Unlock()
+	for key, attempts := range c.repairs {
+		if repaired, found := listed[key]; repaired && time.Since(attempts.Last) > repairWorkedAge || !found {
+			delete(c.repairs, key)
+		}
+	}
+	for key := range c.repairsFailed {
+		if _, found := listed[key]; !found {
+			delete(c.repairsFailed, key)
+		}
+	}
+}
+
+// failedRepairs returns the names of the torrents whose repair was
+// given up
+func (c *sharedCache) failedRepairs() (names []string) {
+	c.repairsMu.Lock()
+	defer c.repairsMu.Unlock()
+	for _, attempts := range c.repairsFailed {
+		names = append(names, attempts.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// repairCommand repairs the torrent with id whatever its attempts so
+// far, for the repair command, returning the ID of the torrent added.
+func (f *Fs) repairCommand(ctx context.Context, id string) (newID string, err error) {
+	var torrent *api.Item
+	for _, listed := range f.cache.torrentsList() {
+		if listed.ID == id {
+			torrent = &listed
+			break
+		}
+	}
+	if torrent == nil {
+		return "", errors.New("torrent not found")
+	}
+	f.cache.resetRepair(torrent)
+	repaired := f.redownloadTorrent(ctx, *torrent)
+	if repaired.ID == id && !f.opt.Simulate {
+		return "", errors.New("couldn't add the torrent again")
+	}
+	return repaired.ID, nil
+}
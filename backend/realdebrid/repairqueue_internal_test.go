@@ -0,0 +1,104 @@
+package realdebrid
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepairGivenUp(t *testing.T) {
+	ctx := context.Background()
+	oldDumpDir := dumpDir
+	dumpDir = t.TempDir()
+	t.Cleanup(func() {
+		dumpDir = oldDumpDir
+	})
+	var (
+		mu        sync.Mutex
+		added     int
+		unseeded  = true
+		addMagnet = func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return added
+		}
+	)
+	f := newTestFs(t)
+	f.opt.SharedFolder = "torrents"
+	f.opt.RepairMaxAttempts = 2
+	f.opt.ShowStatusFile = true
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/torrents/addMagnet":
+			added++
+			if unseeded {
+				w.WriteHeader(http.StatusBadRequest)
+				writeJSON(t, w, api.Response{Message: "magnet_conversion_failed"})
+				return
+			}
+			writeJSON(t, w, api.Item{ID: "T2"})
+		case r.URL.Path == "/user":
+			writeJSON(t, w, api.User{Username: "jelly"})
+		case r.Method == "GET":
+			id := r.URL.Path[len("/torrents/info/"):]
+			status := "waiting_files_selection"
+			if id == "T1" {
+				status = "dead"
+			}
+			writeJSON(t, w, api.Item{ID: id, Name: "Movie.2020", TorrentHash: "H", Status: status, Files: []api.File{{ID: 1, Selected: 1}}})
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	dead := api.Item{ID: "T1", Name: "Movie.2020", Status: "dead", TorrentHash: "H"}
+	f.cache.torrents = []api.Item{dead}
+	before := stats.repairsFailed.Load()
+
+	// Repaired on each refresh until repair_max_attempts fail
+	for range 4 {
+		f.repairTorrents(ctx)
+	}
+	assert.Equal(t, 2, addMagnet())
+	assert.Equal(t, int64(1), stats.repairsFailed.Load()-before)
+	assert.True(t, f.cache.repairFailed(&dead))
+
+	// which is shown in the status file
+	data, _ := f.status(ctx)
+	var report statusReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, []string{"Movie.2020"}, report.RepairFailed)
+
+	// and survives restarts
+	f.cache.dump()
+	loaded := &sharedCache{dir: f.cache.dir}
+	loaded.loadDumps(0)
+	assert.True(t, loaded.repairFailed(&dead))
+
+	// The repair command tries again
+	mu.Lock()
+	unseeded = false
+	mu.Unlock()
+	out, err := f.Command(ctx, "repair", []string{"T1"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"id": "T2"}, out)
+	assert.Equal(t, 3, addMagnet())
+	assert.False(t, f.cache.repairFailed(&dead))
+	assert.Equal(t, 1, f.cache.repairs["H"].Attempts)
+	_, err = f.Command(ctx, "repair", []string{"T3"}, nil)
+	checkOpError(t, err, "repair", "", "T3", "")
+
+	// The attempts of torrents no longer listed are forgotten
+	f.cache.repairsFailed = map[string]*repairAttempts{"H": {}}
+	f.cache.torrents = nil
+	f.repairTorrents(ctx)
+	assert.Empty(t, f.cache.repairs)
+	assert.Empty(t, f.cache.repairsFailed)
+}
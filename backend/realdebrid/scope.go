@@ -0,0 +1,100 @@
+package realdebrid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+)
+
+// scopeCategory returns the ID of the category folder the root of f is
+// in, if it is rooted at or below one, like "realdebrid:shows".
+//
+// The backend commands, About and the status file of such an Fs only
+// see the torrents of its category.
+func (f *Fs) scopeCategory() (dirID string, ok bool) {
+	if f.opt.RootFolderID != "torrents" || f.opt.SharedFolder != "folders" {
+		return "", false
+	}
+	first, _, _ := strings.Cut(f.root, "/")
+	if !slices.Contains(f.categoryNames(), first) {
+		return "", false
+	}
+	return categoryID(first), true
+}
+
+// scopedTorrents returns the torrents in the scope of f, classified
+// with categories, which are all of them unless it is rooted in a
+// category.
+//
+// The torrents are returned as they are, not renamed as when listed.
+func (f *Fs) scopedTorrents(torrents []api.Item, categories map[string]string) []api.Item {
+	dirID, ok := f.scopeCategory()
+	if !ok {
+		return torrents
+	}
+	in := make(map[string]struct{})
+	for _, torrent := range f.classify(torrents, dirID, categories) {
+		in[torrent.ID] = struct{}{}
+	}
+	scoped := make([]api.Item, 0, len(in))
+	for _, torrent := range torrents {
+		if _, found := in[torrent.ID]; found {
+			scoped = append(scoped, torrent)
+		}
+	}
+	return scoped
+}
+
+// scoped returns the cached torrents in the scope of f
+func (f *Fs) scoped() []api.Item {
+	f.cache.refreshMu.Lock()
+	torrents, categories := f.cache.torrents, f.cache.categories
+	f.cache.refreshMu.Unlock()
+	return f.scopedTorrents(torrents, categories)
+}
+
+// checkScope returns an error if the torrent with id isn't in the
+// scope of f, so the commands of a remote rooted in a category can't
+// change the torrents of the others.
+func (f *Fs) checkScope(ctx context.Context, id string) error {
+	dirID, ok := f.scopeCategory()
+	if !ok {
+		return nil
+	}
+	if _, err := f.listedTorrents(ctx); err != nil {
+		return err
+	}
+	for _, torrent := range f.scoped() {
+		if torrent.ID == id {
+			return nil
+		}
+	}
+	name, _ := f.categoryName(dirID)
+	return fmt.Errorf("torrent %q isn't in the %s category of the remote", id, name)
+}
+
+// purgeCategory deletes the torrents of the category or year folder
+// dirID at dir, carrying on past the torrents which fail. The folder
+// itself stays as the categories always exist.
+func (f *Fs) purgeCategory(ctx context.Context, dir, dirID string) error {
+	// not the torrents hidden, which aren't in the folder
+	torrents, err := f.listedTorrents(ctx)
+	if err != nil {
+		return f.wrapErr("purge", dir, "", err)
+	}
+	f.cache.refreshMu.Lock()
+	categories := f.cache.categories
+	f.cache.refreshMu.Unlock()
+	var errs []error
+	for _, torrent := range f.classify(torrents, dirID, categories) {
+		if err := f.deleteTorrent(ctx, torrent.ID); err != nil {
+			errs = append(errs, f.wrapErr("purge", path.Join(dir, torrent.Name), torrent.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
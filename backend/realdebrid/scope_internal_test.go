@@ -0,0 +1,143 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/lib/dircache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newScopeTestFs makes an Fs rooted at root with a torrent in each
+// category, counting the API requests made
+func newScopeTestFs(t *testing.T, root string) (*Fs, map[string]int) {
+	ctx := context.Background()
+	oldDumpDir := dumpDir
+	dumpDir = t.TempDir()
+	t.Cleanup(func() {
+		dumpDir = oldDumpDir
+	})
+	f := newTestFs(t)
+	f.opt.AboutPolicy = "real"
+	torrents := []api.Item{
+		{ID: "S1", Name: "Show.S01", Status: "downloaded", Bytes: 2e9},
+		{ID: "M1", Name: "Movie.2020", Status: "downloaded", Bytes: 1e9},
+		{ID: "D1", Name: "Other", Status: "downloaded", Bytes: 5e8},
+	}
+	requests := map[string]int{}
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests[r.Method+" "+r.URL.Path]++
+		switch {
+		case r.URL.Path == "/torrents":
+			w.Header().Set("X-Total-Count", strconv.Itoa(len(torrents)))
+			writeJSON(t, w, torrents)
+		case r.URL.Path == "/downloads":
+			w.Header().Set("X-Total-Count", "0")
+			writeJSON(t, w, []api.Item{})
+		case r.URL.Path == "/user":
+			writeJSON(t, w, api.User{Username: "jelly", Premium: 3600})
+		case r.Method == "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	f.cache.startup_cached_api_fetch = true
+	f.cache.torrents = torrents
+	f.root = root
+	f.dirCache = dircache.New(root, rootID, f)
+	require.NoError(t, f.dirCache.FindRoot(ctx, false))
+	return f, requests
+}
+
+func TestScopeCategory(t *testing.T) {
+	f, _ := newScopeTestFs(t, "")
+	_, ok := f.scopeCategory()
+	assert.False(t, ok)
+	f.root = "shows/Show.S01"
+	dirID, ok := f.scopeCategory()
+	assert.True(t, ok)
+	assert.Equal(t, categoryID("shows"), dirID)
+	f.opt.SharedFolder = "torrents"
+	_, ok = f.scopeCategory()
+	assert.False(t, ok)
+}
+
+func TestCategoryRootScope(t *testing.T) {
+	ctx := context.Background()
+	f, requests := newScopeTestFs(t, "shows")
+
+	// About only counts the torrents of the category
+	usage, err := f.About(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2e9), *usage.Used)
+
+	// as do the export, top and refresh commands
+	out, err := f.Command(ctx, "export", nil, nil)
+	require.NoError(t, err)
+	require.Len(t, out.(*exportReport).Torrents, 1)
+	assert.Equal(t, "S1", out.(*exportReport).Torrents[0].ID)
+
+	now := time.Now()
+	for _, id := range []string{"S1", "M1", "D1", "gone"} {
+		f.cache.traffic.add(id, 100, now)
+	}
+	assert.Equal(t, []torrentTraffic{{ID: "S1", Name: "Show.S01", Bytes: 100}}, f.topTorrents(time.Hour, 10, now))
+
+	out, err = f.Command(ctx, "refresh", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, out.(*refreshReport).Torrents)
+
+	// and the changes
+	f.cache.refreshMu.Lock()
+	f.cache.setTorrents([]api.Item{{ID: "S2", Name: "Show.S02", Status: "downloaded"}, {ID: "M2", Name: "Movie.2021", Status: "downloaded"}})
+	f.cache.refreshMu.Unlock()
+	out, err = f.Command(ctx, "changes", nil, nil)
+	require.NoError(t, err)
+	changes := out.([]*torrentChanges)
+	require.Len(t, changes, 1)
+	assert.Equal(t, []torrentChange{
+		{ID: "S2", Name: "Show.S02", Change: "added", To: "downloaded"},
+		{ID: "S1", Name: "Show.S01", Change: "removed", From: "downloaded"},
+	}, changes[0].Changes)
+	assert.Equal(t, 1, changes[0].Added)
+	assert.Equal(t, 1, changes[0].Removed)
+
+	// The torrents of the other categories can't be changed
+	_, err = f.Command(ctx, "repair", []string{"M1"}, nil)
+	assert.ErrorContains(t, err, `torrent "M1" isn't in the shows category`)
+	_, err = f.Command(ctx, "reselect", []string{"D1"}, map[string]string{"files": "."})
+	assert.ErrorContains(t, err, `torrent "D1" isn't in the shows category`)
+	assert.Zero(t, requests["GET /torrents/info/M1"])
+
+	// The status file only counts the category
+	data, _ := f.status(ctx)
+	assert.Contains(t, string(data), `"torrents": 1,`)
+}
+
+func TestPurgeCategory(t *testing.T) {
+	ctx := context.Background()
+	f, requests := newScopeTestFs(t, "shows")
+
+	// Refused at the category root
+	assert.ErrorIs(t, f.Purge(ctx, ""), errPurgeCategory)
+	assert.ErrorIs(t, f.Rmdir(ctx, ""), errPurgeCategory)
+	root, _ := newScopeTestFs(t, "")
+	assert.ErrorIs(t, root.Purge(ctx, "movies"), errPurgeCategory)
+	assert.Zero(t, requests["DELETE /torrents/delete/S1"])
+
+	// unless allowed, when only the torrents of the category go
+	f.opt.PurgeCategories = true
+	assert.ErrorIs(t, f.Rmdir(ctx, ""), errPurgeCategory)
+	require.NoError(t, f.Purge(ctx, ""))
+	assert.Equal(t, 1, requests["DELETE /torrents/delete/S1"])
+	assert.Zero(t, requests["DELETE /torrents/delete/M1"])
+	assert.Zero(t, requests["DELETE /torrents/delete/D1"])
+	assert.Equal(t, []string{"M1", "D1"}, itemIDs(f.cache.torrents))
+}
@@ -0,0 +1,78 @@
+package realdebrid
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// snapshotState is how fresh the torrents listed in a cache are, which
+// decides when they are listed from the API again.
+//
+// It is used with the refreshMu held apart from Invalidate, which is
+// called after changes made through rclone.
+type snapshotState struct {
+	checked  time.Time   // when the torrents were last checked against the API
+	count    int         // number of torrents listed then
+	fromDump bool        // loaded from a dump and not checked yet
+	invalid  atomic.Bool // changed through rclone since they were checked
+}
+
+// IsStale returns true if the torrents were checked more than interval
+// ago, or were changed through rclone or loaded from a dump since. With
+// an interval <= 0 they only go stale those other ways.
+func (s *snapshotState) IsStale(interval time.Duration) bool {
+	if s.invalid.Load() || s.fromDump {
+		return true
+	}
+	return interval > 0 && time.Since(s.checked) > interval
+}
+
+// UpToDate returns true if the torrents needn't be listed again given
+// the total number of torrents the API has.
+//
+// A different count always needs a listing but the same one doesn't
+// mean the same torrents, as after a repair deleted one and added
+// another, so they mustn't be stale either. Those loaded from a dump
+// only need the count to match.
+func (s *snapshotState) UpToDate(total int, interval time.Duration) bool {
+	if total != s.count {
+		return false
+	}
+	return s.fromDump || !s.IsStale(interval)
+}
+
+// StaleAt returns when the torrents go stale after interval unless
+// they are changed first
+func (s *snapshotState) StaleAt(interval time.Duration) time.Time {
+	return s.checked.Add(interval)
+}
+
+// Invalidate makes the torrents stale so the next listing lists them
+// again after they were changed through rclone
+func (s *snapshotState) Invalidate() {
+	s.invalid.Store(true)
+}
+
+// MarkFresh records that the count torrents listed were just checked
+// against the API
+func (s *snapshotState) MarkFresh(count int) {
+	s.checked = time.Now()
+	s.count = count
+	s.fromDump = false
+	s.invalid.Store(false)
+}
+
+// MarkDumped records that the count torrents listed were loaded from a
+// dump, so are checked against the API before they are used
+func (s *snapshotState) MarkDumped(count int) {
+	s.checked = time.Time{}
+	s.count = count
+	s.fromDump = true
+}
+
+// Recount records that count torrents are listed after a change made
+// through rclone which the API follows, like a delete, so it doesn't
+// make them stale
+func (s *snapshotState) Recount(count int) {
+	s.count = count
+}
@@ -0,0 +1,59 @@
+package realdebrid
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotState(t *testing.T) {
+	var s snapshotState
+
+	// Never checked
+	assert.True(t, s.IsStale(time.Hour))
+	assert.False(t, s.UpToDate(2, time.Hour))
+
+	// Fresh within the interval while the count matches
+	s.MarkFresh(2)
+	assert.False(t, s.IsStale(time.Hour))
+	assert.True(t, s.UpToDate(2, time.Hour))
+	assert.False(t, s.UpToDate(3, time.Hour))
+
+	// and stale after it
+	s.checked = time.Now().Add(-2 * time.Hour)
+	assert.True(t, s.IsStale(time.Hour))
+	assert.False(t, s.UpToDate(2, time.Hour))
+	assert.WithinDuration(t, time.Now().Add(-time.Hour), s.StaleAt(time.Hour), time.Second)
+
+	// Never stale by time with an interval of 0
+	assert.False(t, s.IsStale(0))
+	assert.True(t, s.UpToDate(2, 0))
+
+	// A repair deleting a torrent and adding another keeps the count
+	// but the torrents must be listed again, whatever the interval
+	s.MarkFresh(2)
+	s.Invalidate()
+	assert.True(t, s.IsStale(time.Hour))
+	assert.True(t, s.IsStale(0))
+	assert.False(t, s.UpToDate(2, time.Hour))
+	assert.False(t, s.UpToDate(2, 0))
+
+	// until they are
+	s.MarkFresh(2)
+	assert.False(t, s.IsStale(0))
+	assert.True(t, s.UpToDate(2, 0))
+
+	// A delete through rclone doesn't make them stale
+	s.Recount(1)
+	assert.False(t, s.IsStale(time.Hour))
+	assert.True(t, s.UpToDate(1, time.Hour))
+
+	// Torrents loaded from a dump are stale but only need their count
+	// checked
+	s.MarkDumped(5)
+	assert.True(t, s.IsStale(0))
+	assert.True(t, s.UpToDate(5, time.Hour))
+	assert.False(t, s.UpToDate(4, time.Hour))
+	assert.True(t, s.checked.IsZero())
+}
@@ -0,0 +1,205 @@
+package realdebrid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fs/hash"
+	"github.com/rclone/rclone/fs/object"
+)
+
+const (
+	statusFileName  = "_status.json"  // name of the status file in the root
+	statusCacheTime = 5 * time.Second // how long the status is reused for
+	statusSlack     = 512             // bytes the status may grow by between listing and reading it
+)
+
+// statusReport is the content of the status file
+type statusReport struct {
+	Generated      string   `json:"generated"`
+	User           string   `json:"user,omitempty"`
+	Type           string   `json:"type,omitempty"`
+	PremiumUntil   string   `json:"premiumUntil,omitempty"`
+	PremiumLeft    string   `json:"premiumLeft,omitempty"`
+	Points         int64    `json:"points,omitempty"`
+	Torrents       int      `json:"torrents"`   // torrents listed
+	Downloaded     int      `json:"downloaded"` // of which are downloaded
+	Downloads      int      `json:"downloads"`  // unrestricted links listed
+	Generation     int64    `json:"generation"`
+	LastRefresh    string   `json:"lastRefresh,omitempty"`
+	RefreshCircuit string   `json:"refreshCircuit"`       // closed, or open until the refreshes are tried again
+	RefreshFails   int      `json:"refreshFailures"`      // refreshes which failed in a row
+	Streams        int64    `json:"streams"`              // files open for reading
+	MaxStreams     int      `json:"maxStreams,omitempty"` // max_concurrent_streams if set
+	Dead           []string `json:"dead"`                 // names of the dead torrents
+	Broken         []string `json:"broken"`               // names of the torrents waiting to be repaired
+	RepairFailed   []string `json:"repairFailed"`         // names of the torrents given up on after repair_max_attempts
+	Hidden         []string `json:"hidden"`               // names of the torrents hidden by min_torrent_size
+	Error          string   `json:"error,omitempty"`
+}
+
+// status returns the status file content and when it was made.
+//
+// The content is reused for statusCacheTime so a burst of reads, such
+// as a listing followed by the chunks of a read, only gets the user
+// from the API once.
+func (f *Fs) status(ctx context.Context) (data []byte, generated time.Time) {
+	f.statusMu.Lock()
+	defer f.statusMu.Unlock()
+	if f.statusData != nil && time.Since(f.statusTime) < statusCacheTime {
+		return f.statusData, f.statusTime
+	}
+	now := time.Now()
+	report := statusReport{
+		Generated: now.UTC().Format(time.RFC3339),
+		Dead:      []string{},
+		Broken:    []string{},
+		Hidden:    []string{},
+	}
+	user, err := f.client.User(ctx)
+	if err != nil {
+		report.Error = err.Error()
+	} else {
+		report.User = user.Username
+		report.Type = user.Type
+		report.PremiumUntil = user.Expiration
+		report.PremiumLeft = fs.Duration(time.Duration(user.Premium) * time.Second).String()
+		report.Points = user.Points
+	}
+	c := f.cache
+	c.refreshMu.Lock()
+	c.mu.RLock()
+	torrents := f.scopedTorrents(c.torrents, c.categories)
+	names := make(map[string]string, len(torrents))
+	report.Torrents = len(torrents)
+	for _, torrent := range torrents {
+		names[torrent.ID] = torrent.Name
+		switch torrent.Status {
+		case "downloaded":
+			report.Downloaded++
+		case "dead":
+			report.Dead = append(report.Dead, torrent.Name)
+		}
+	}
+	for _, id := range c.broken_torrents {
+		if name, found := names[id]; found {
+			report.Broken = append(report.Broken, name)
+		}
+	}
+	report.Hidden = append(report.Hidden, f.smallTorrents(torrents)...)
+	report.Downloads = len(c.cached)
+	report.RepairFailed = append([]string{}, c.failedRepairs()...)
+	report.Generation = c.generation
+	report.RefreshCircuit = c.circuitState()
+	report.RefreshFails = c.refreshFailures
+	report.Streams = f.streams.Load()
+	report.MaxStreams = f.opt.MaxConcurrentStreams
+	if !c.state.checked.IsZero() {
+		report.LastRefresh = c.state.checked.UTC().Format(time.RFC3339)
+	}
+	c.mu.RUnlock()
+	c.refreshMu.Unlock()
+	data, err = json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		// can't happen with the types above
+		data = []byte("{}")
+	}
+	f.statusData = append(data, '\n')
+	f.statusTime = now
+	return f.statusData, f.statusTime
+}
+
+// statusObject is the status file shown in the root if
+// show_status_file is set.
+//
+// It isn't storable so sync doesn't copy it and removing it does
+// nothing.
+type statusObject struct {
+	fs      *Fs
+	size    int64     // size reported, the content is padded to it
+	modTime time.Time // when the content listed was made
+	data    []byte    // the content listed
+}
+
+// newStatusObject makes the status file from the current status
+func (f *Fs) newStatusObject(ctx context.Context) *statusObject {
+	data, generated := f.status(ctx)
+	return &statusObject{
+		fs:      f,
+		size:    int64(len(data) + statusSlack),
+		modTime: generated,
+		data:    data,
+	}
+}
+
+// isStatusFile returns whether remote is the status file
+func (f *Fs) isStatusFile(remote string) bool {
+	return f.opt.ShowStatusFile && f.root == "" && remote == statusFileName
+}
+
+// Fs returns the parent Fs
+func (o *statusObject) Fs() fs.Info {
+	return o.fs
+}
+
+// String returns a description of the Object
+func (o *statusObject) String() string {
+	return statusFileName
+}
+
+// Remote returns the remote path
+func (o *statusObject) Remote() string {
+	return statusFileName
+}
+
+// Hash is unsupported as the content changes on each read
+func (o *statusObject) Hash(ctx context.Context, t hash.Type) (string, error) {
+	return "", hash.ErrUnsupported
+}
+
+// Size returns the size of the content padded with room to grow
+func (o *statusObject) Size() int64 {
+	return o.size
+}
+
+// ModTime returns when the content was made
+func (o *statusObject) ModTime(ctx context.Context) time.Time {
+	return o.modTime
+}
+
+// SetModTime is not supported
+func (o *statusObject) SetModTime(ctx context.Context, modTime time.Time) error {
+	return fs.ErrorCantSetModTime
+}
+
+// Storable returns false so the status file isn't synced
+func (o *statusObject) Storable() bool {
+	return false
+}
+
+// Open returns the current status padded with spaces to the size
+// listed, or the status listed if the current one no longer fits.
+func (o *statusObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	data, generated := o.fs.status(ctx)
+	if int64(len(data)) > o.size {
+		data, generated = o.data, o.modTime
+	}
+	padded := append(bytes.Clone(data), bytes.Repeat([]byte{' '}, int(o.size)-len(data))...)
+	return object.NewMemoryObject(statusFileName, generated, padded).Open(ctx, options...)
+}
+
+// Update fails as the status file can't be written
+func (o *statusObject) Update(ctx context.Context, in io.Reader, src fs.ObjectInfo, options ...fs.OpenOption) error {
+	return o.fs.wrapErr("update", statusFileName, "", errReadOnly)
+}
+
+// Remove does nothing so deletes and syncs to the remote leave the
+// status file alone
+func (o *statusObject) Remove(ctx context.Context) error {
+	fs.Debugf(o, "Not removing the status file")
+	return nil
+}
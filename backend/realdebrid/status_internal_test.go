@@ -0,0 +1,101 @@
+package realdebrid
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStatusTestFs makes an Fs showing the status file, returning it and
+// the number of calls to /user
+func newStatusTestFs(t *testing.T) (f *Fs, userCalls *atomic.Int64) {
+	userCalls = new(atomic.Int64)
+	f = newTestFs(t)
+	f.opt.ShowStatusFile = true
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" {
+			w.Header().Set("X-Total-Count", "0")
+			writeJSON(t, w, []api.Item{})
+			return
+		}
+		userCalls.Add(1)
+		writeJSON(t, w, api.User{Username: "jelly", Type: "premium", Premium: 86400, Expiration: "2030-01-02T03:04:05.000Z", Points: 1000})
+	})
+	f.cache.torrents = []api.Item{
+		{ID: "1", Name: "Show.S01", Status: "downloaded"},
+		{ID: "2", Name: "Movie.2020", Status: "dead"},
+		{ID: "3", Name: "Concert", Status: "downloaded"},
+	}
+	f.cache.broken_torrents = []string{"3"}
+	f.cache.cached = []api.Item{{ID: "d1"}}
+	return f, userCalls
+}
+
+func TestStatusFile(t *testing.T) {
+	ctx := context.Background()
+	f, userCalls := newStatusTestFs(t)
+
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.Contains(t, entryNames(entries), statusFileName)
+	o, err := f.NewObject(ctx, statusFileName)
+	require.NoError(t, err)
+	assert.False(t, o.Storable())
+
+	// The content read has the size listed
+	in, err := o.Open(ctx)
+	require.NoError(t, err)
+	data, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, o.Size(), int64(len(data)))
+	var report statusReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, "jelly", report.User)
+	assert.Equal(t, "2030-01-02T03:04:05.000Z", report.PremiumUntil)
+	assert.Equal(t, fs.Duration(24*time.Hour).String(), report.PremiumLeft)
+	assert.Equal(t, 3, report.Torrents)
+	assert.Equal(t, 2, report.Downloaded)
+	assert.Equal(t, 1, report.Downloads)
+	assert.Equal(t, []string{"Movie.2020"}, report.Dead)
+	assert.Equal(t, []string{"Concert"}, report.Broken)
+	assert.NotEmpty(t, report.LastRefresh)
+	assert.Empty(t, report.Error)
+
+	// Ranges are served from the same content
+	in, err = o.Open(ctx, &fs.RangeOption{Start: 2, End: 9})
+	require.NoError(t, err)
+	part, err := io.ReadAll(in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Equal(t, data[2:10], part)
+
+	// The listing and reads of the burst only called the API once
+	assert.Equal(t, int64(1), userCalls.Load())
+
+	// It can't be written and isn't removed
+	checkOpError(t, o.Update(ctx, nil, o), "update", statusFileName, "", "")
+	require.NoError(t, o.Remove(ctx))
+}
+
+func TestStatusFileHidden(t *testing.T) {
+	ctx := context.Background()
+	f, userCalls := newStatusTestFs(t)
+	f.opt.ShowStatusFile = false
+
+	entries, err := f.List(ctx, "")
+	require.NoError(t, err)
+	assert.NotContains(t, entryNames(entries), statusFileName)
+	_, err = f.NewObject(ctx, statusFileName)
+	assert.Equal(t, fs.ErrorObjectNotFound, err)
+	assert.Equal(t, int64(0), userCalls.Load())
+}
@@ -0,0 +1,65 @@
+package realdebrid
+
+import (
+	"slices"
+	"strings"
+)
+
+// The folders which aren't Real-Debrid torrents, like the categories,
+// have synthetic IDs "syn:<kind>:<key>". The key is canonical rather
+// than the folder name so the IDs don't change with the encoding or
+// the options, and Real-Debrid IDs never contain ':' so they can't be
+// taken for one.
+const synPrefix = "syn:"
+
+// The kinds of synthetic folders
+const (
+	synCategory = "category" // key is the category name
+	synYear     = "year"     // key is the year of the movies, or unknown
+	synFolder   = "folder"   // key is the torrent ID then the path in its folder
+)
+
+// defaultCategoryNames are the categories the torrents are grouped in
+// in folders mode when the categories option isn't set, in listing
+// order
+var defaultCategoryNames = []string{"shows", "movies", "default"}
+
+// synID returns the ID of the synthetic folder of kind with key
+func synID(kind, key string) string {
+	return synPrefix + kind + ":" + key
+}
+
+// parseSynID returns the kind and key of the synthetic folder ID id,
+// or ok false if it isn't one.
+//
+// The categories used their names as IDs before, which are still
+// parsed for the callers which haven't moved on.
+func parseSynID(id string) (kind, key string, ok bool) {
+	rest, found := strings.CutPrefix(id, synPrefix)
+	if !found {
+		if slices.Contains(defaultCategoryNames, id) {
+			return synCategory, id, true
+		}
+		return "", "", false
+	}
+	kind, key, found = strings.Cut(rest, ":")
+	if !found || kind == "" || key == "" {
+		return "", "", false
+	}
+	return kind, key, true
+}
+
+// categoryID returns the ID of the folder of the category name
+func categoryID(name string) string {
+	return synID(synCategory, name)
+}
+
+// categoryName returns the name of the category with folder ID dirID,
+// or ok false if it isn't a category folder of f
+func (f *Fs) categoryName(dirID string) (name string, ok bool) {
+	kind, key, ok := parseSynID(dirID)
+	if !ok || kind != synCategory || !slices.Contains(f.categoryNames(), key) {
+		return "", false
+	}
+	return key, true
+}
@@ -0,0 +1,52 @@
+package realdebrid
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSynID(t *testing.T) {
+	// Real-Debrid torrent IDs, e.g. "ABCDEFGHIJ234"
+	realID := regexp.MustCompile(`^[A-Z0-9]+$`)
+	f := newTestFs(t)
+	seen := map[string]struct{}{}
+	for _, folder := range f.addArtificialRootFolders(nil) {
+		kind, key, ok := parseSynID(folder.ID)
+		require.True(t, ok, folder.ID)
+		assert.Equal(t, synCategory, kind)
+		assert.Equal(t, folder.Name, key)
+		assert.Equal(t, folder.ID, synID(kind, key))
+		assert.Equal(t, folder.ID, categoryID(folder.Name))
+		name, ok := f.categoryName(folder.ID)
+		assert.True(t, ok)
+		assert.Equal(t, folder.Name, name)
+		assert.False(t, realID.MatchString(folder.ID), folder.ID)
+		assert.NotEqual(t, rootID, folder.ID)
+		_, found := seen[folder.ID]
+		assert.False(t, found, folder.ID)
+		seen[folder.ID] = struct{}{}
+	}
+	assert.Len(t, seen, len(defaultCategoryNames))
+
+	// The IDs of the categories from before still parse
+	for _, name := range defaultCategoryNames {
+		kind, key, ok := parseSynID(name)
+		require.True(t, ok, name)
+		assert.Equal(t, synCategory, kind)
+		assert.Equal(t, name, key)
+		assert.True(t, f.isCategory(name))
+	}
+
+	// but not the Real-Debrid IDs nor malformed ones
+	for _, id := range []string{"ABCDEFGHIJ234", rootID, "", "syn:", "syn:category", "syn:category:", "syn::shows", "Shows"} {
+		_, _, ok := parseSynID(id)
+		assert.False(t, ok, id)
+	}
+	_, ok := f.categoryName(synID(synCategory, "music"))
+	assert.False(t, ok)
+	_, ok = f.categoryName(synID("host", "shows"))
+	assert.False(t, ok)
+}
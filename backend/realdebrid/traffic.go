@@ -0,0 +1,186 @@
+package realdebrid
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+const (
+	trafficPeriod = time.Hour           // length of the periods the bytes served are counted by
+	trafficKept   = 90 * 24 * time.Hour // how long the counts are kept
+	trafficBatch  = 8 * 1024 * 1024     // bytes read from a stream before they are counted
+	trafficTopN   = 10                  // torrents returned by the top command by default
+)
+
+// trafficCounts are the bytes served by torrent ID then by the start
+// of the period they were served in, in Unix seconds
+type trafficCounts map[string]map[int64]int64
+
+// traffic counts the bytes served of each torrent so the ones streamed
+// the most are known. It has its own lock as it is added to by reads.
+type traffic struct {
+	mu     sync.Mutex
+	counts trafficCounts
+}
+
+// add counts n bytes of the torrent with id served at now
+func (t *traffic) add(id string, n int64, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.counts == nil {
+		t.counts = make(trafficCounts)
+	}
+	periods := t.counts[id]
+	if periods == nil {
+		periods = make(map[int64]int64)
+		t.counts[id] = periods
+	}
+	periods[now.Truncate(trafficPeriod).Unix()] += n
+}
+
+// served returns the bytes served of each torrent since the period
+// holding since
+func (t *traffic) served(since time.Time) map[string]int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	start := since.Truncate(trafficPeriod).Unix()
+	served := make(map[string]int64, len(t.counts))
+	for id, periods := range t.counts {
+		for period, n := range periods {
+			if period >= start {
+				served[id] += n
+			}
+		}
+	}
+	return served
+}
+
+// prune forgets the counts older than trafficKept
+func (t *traffic) prune(now time.Time) {
+	start := now.Add(-trafficKept).Truncate(trafficPeriod).Unix()
+	for id, periods := range t.counts {
+		for period := range periods {
+			if period < start {
+				delete(periods, period)
+			}
+		}
+		if len(periods) == 0 {
+			delete(t.counts, id)
+		}
+	}
+}
+
+// dumpTraffic writes the bytes served to the dump directory so they
+// survive restarts
+func (c *sharedCache) dumpTraffic() {
+	c.traffic.mu.Lock()
+	defer c.traffic.mu.Unlock()
+	if c.traffic.counts == nil {
+		return
+	}
+	c.traffic.prune(time.Now())
+	filetraffic, err := os.Create(c.dumpPath("traffic.gob"))
+	if err != nil {
+		fs.Errorf(nil, "realdebrid: failed to create traffic.gob: %v", err)
+		return
+	}
+	defer filetraffic.Close()
+	err = gob.NewEncoder(filetraffic).Encode(c.traffic.counts)
+	if err != nil {
+		fs.Errorf(nil, "realdebrid: failed to encode the traffic: %v", err)
+	}
+}
+
+// loadTraffic reads the bytes served dumped by a previous run
+func (c *sharedCache) loadTraffic() {
+	filetraffic, err := os.Open(c.dumpPath("traffic.gob"))
+	if err != nil {
+		return
+	}
+	defer filetraffic.Close()
+	c.traffic.mu.Lock()
+	defer c.traffic.mu.Unlock()
+	err = gob.NewDecoder(filetraffic).Decode(&c.traffic.counts)
+	if err != nil {
+		fs.Errorf(nil, "realdebrid: failed to decode traffic.gob: %v", err)
+		return
+	}
+	c.traffic.prune(time.Now())
+}
+
+// torrentTraffic is a torrent with the bytes served of it
+type torrentTraffic struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"` // "" if the torrent has gone
+	Bytes int64  `json:"bytes"`
+}
+
+// trafficReport is the result of the top command
+type trafficReport struct {
+	Window   string           `json:"window"`
+	Torrents []torrentTraffic `json:"torrents"`
+}
+
+// topTorrents returns the n torrents in the scope of f with the most
+// bytes served over the window before now, most first
+func (f *Fs) topTorrents(window time.Duration, n int, now time.Time) []torrentTraffic {
+	served := f.cache.traffic.served(now.Add(-window))
+	names := make(map[string]string)
+	for _, torrent := range f.scoped() {
+		names[torrent.ID] = torrent.Name
+	}
+	_, scoped := f.scopeCategory()
+	top := make([]torrentTraffic, 0, len(served))
+	for id, bytes := range served {
+		name, found := names[id]
+		// the category of the torrents which have gone isn't known
+		if scoped && !found {
+			continue
+		}
+		top = append(top, torrentTraffic{ID: id, Name: name, Bytes: bytes})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Bytes != top[j].Bytes {
+			return top[i].Bytes > top[j].Bytes
+		}
+		return top[i].ID < top[j].ID
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+// topCommand runs the top backend command
+func (f *Fs) topCommand(opt map[string]string) (out *trafficReport, err error) {
+	window := trafficKept
+	if value, ok := opt["window"]; ok {
+		var d fs.Duration
+		if err = d.Set(value); err != nil {
+			return nil, fmt.Errorf("invalid window value %q: %w", value, err)
+		}
+		window = time.Duration(d)
+	}
+	if window <= 0 {
+		return nil, errors.New("need a window longer than 0")
+	}
+	n := trafficTopN
+	if value, ok := opt["n"]; ok {
+		n, err = strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid n value %q", value)
+		}
+	}
+	return &trafficReport{
+		Window:   fs.Duration(window).String(),
+		Torrents: f.topTorrents(window, n, time.Now()),
+	}, nil
+}
@@ -0,0 +1,91 @@
+package realdebrid
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamTraffic(t *testing.T) {
+	f := newTestFs(t)
+	served := func() int64 {
+		return f.cache.traffic.served(time.Now().Add(-time.Hour))["T1"]
+	}
+	require.NoError(t, f.takeStream())
+	in := newStreamReader(f, "T1", io.NopCloser(bytes.NewReader(make([]byte, trafficBatch+20))))
+
+	// The bytes read are counted in batches
+	_, err := io.CopyN(io.Discard, in, 10)
+	require.NoError(t, err)
+	assert.Zero(t, served())
+	_, err = io.CopyN(io.Discard, in, trafficBatch)
+	require.NoError(t, err)
+	assert.Equal(t, int64(trafficBatch+10), served())
+
+	// and the rest when the stream is closed
+	_, err = io.CopyN(io.Discard, in, 5)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	require.NoError(t, in.Close())
+	assert.Equal(t, int64(trafficBatch+15), served())
+
+	// Streams of no torrent aren't counted
+	require.NoError(t, f.takeStream())
+	in = newStreamReader(f, "", io.NopCloser(bytes.NewReader(make([]byte, 10))))
+	_, err = io.Copy(io.Discard, in)
+	require.NoError(t, err)
+	require.NoError(t, in.Close())
+	assert.Len(t, f.cache.traffic.served(time.Now().Add(-time.Hour)), 1)
+	assert.Zero(t, f.streams.Load())
+}
+
+func TestTopTorrents(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.cache.torrents = []api.Item{{ID: "T1", Name: "Movie.2020"}, {ID: "T2", Name: "Show.S01"}}
+	now := time.Now()
+	f.cache.traffic.add("T1", 100, now.Add(-48*time.Hour))
+	f.cache.traffic.add("T1", 10, now)
+	f.cache.traffic.add("T2", 50, now.Add(-time.Minute))
+	f.cache.traffic.add("T3", 20, now)
+
+	top := func(opt map[string]string) *trafficReport {
+		out, err := f.Command(ctx, "top", nil, opt)
+		require.NoError(t, err)
+		return out.(*trafficReport)
+	}
+	assert.Equal(t, &trafficReport{Window: "3M", Torrents: []torrentTraffic{
+		{ID: "T1", Name: "Movie.2020", Bytes: 110},
+		{ID: "T2", Name: "Show.S01", Bytes: 50},
+		{ID: "T3", Bytes: 20},
+	}}, top(nil))
+	assert.Equal(t, &trafficReport{Window: "1d", Torrents: []torrentTraffic{
+		{ID: "T2", Name: "Show.S01", Bytes: 50},
+		{ID: "T3", Bytes: 20},
+	}}, top(map[string]string{"window": "1d", "n": "2"}))
+
+	for _, opt := range []map[string]string{{"window": "soon"}, {"window": "0"}, {"n": "0"}, {"n": "ten"}} {
+		_, err := f.Command(ctx, "top", nil, opt)
+		assert.Error(t, err, opt)
+	}
+}
+
+func TestTrafficDump(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	c := &sharedCache{dumpName: "test", dir: dir}
+	c.traffic.add("T1", 100, now)
+	c.traffic.add("T2", 50, now.Add(-trafficKept-2*trafficPeriod))
+
+	// The traffic is dumped even without listed torrents
+	c.dumpListed()
+	loaded := &sharedCache{dumpName: "test", dir: dir}
+	loaded.loadDumps(time.Hour)
+	assert.Equal(t, trafficCounts{"T1": {now.Truncate(trafficPeriod).Unix(): 100}}, loaded.traffic.counts)
+}
@@ -0,0 +1,195 @@
+package realdebrid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+)
+
+// startVerifier starts checking the cached download links in the
+// background if verify_links_per_hour is set, returning whether it did.
+//
+// It is stopped by Shutdown.
+func (f *Fs) startVerifier() bool {
+	if f.opt.VerifyLinksPerHour <= 0 {
+		return false
+	}
+	interval := time.Hour / time.Duration(f.opt.VerifyLinksPerHour)
+	fs.Infof(f, "Verifying %d download links per hour", f.opt.VerifyLinksPerHour)
+	f.goBackground(f.ctx, func(ctx context.Context) {
+		f.verifyLinks(ctx, interval)
+	})
+	return true
+}
+
+// verifyLinks checks the next cached download link every interval
+// until ctx is cancelled, skipping the checks while files are being
+// streamed.
+func (f *Fs) verifyLinks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		if f.streaming() {
+			stats.verifyPaused.Add(1)
+			continue
+		}
+		f.verifyNext(ctx)
+	}
+}
+
+// streaming returns true if enough files are open for the verifier to
+// leave the bandwidth to them
+func (f *Fs) streaming() bool {
+	return f.opt.VerifyPauseStreams > 0 && f.streams.Load() >= int64(f.opt.VerifyPauseStreams)
+}
+
+// verifyNext checks the next cached download link, unrestricting its
+// original link again if it is dead and marking its torrent broken
+// if that fails too.
+func (f *Fs) verifyNext(ctx context.Context) {
+	c := f.cache
+	c.mu.Lock()
+	if len(c.cached) == 0 {
+		c.mu.Unlock()
+		return
+	}
+	f.verifyCursor %= len(c.cached)
+	item := c.cached[f.verifyCursor]
+	f.verifyCursor++
+	c.mu.Unlock()
+	// the links for other IPs aren't streamed and reading them would
+	// use the remote traffic quota
+	if item.Link == "" || item.Remote {
+		return
+	}
+
+	stats.verifiedLinks.Add(1)
+	err := f.checkLink(ctx, item.Link)
+	if err == nil || ctx.Err() != nil {
+		return
+	}
+	stats.deadLinks.Add(1)
+	fs.Debugf(f, "%v", f.wrapErr("verify", item.Name, "", err))
+	if item.OriginalLink == "" {
+		return
+	}
+	relinked, err := f.unrestrictLink(ctx, item.OriginalLink, false, true)
+	if err != nil {
+		if !errors.Is(err, errLinkUnavailable) {
+			// try again on the next round
+			fs.Debugf(f, "%v", f.wrapErr("verify", item.Name, "", err))
+			return
+		}
+		stats.brokenLinks.Add(1)
+		for _, torrent := range c.torrentsList() {
+			if slices.Contains(torrent.Links, item.OriginalLink) {
+				fs.Logf(f, "%v", f.wrapErr("verify", item.Name, torrent.ID, err))
+				f.markBroken(torrent.ID)
+			}
+		}
+		return
+	}
+	c.relink(item.Link, relinked.Link)
+	fs.Debugf(f, "Replaced dead download link of %q", item.Name)
+}
+
+// checkLink requests the first byte of link to check it can be
+// downloaded, only once so it doesn't add to the load on the CDN
+func (f *Fs) checkLink(ctx context.Context, link string) error {
+	var resp *http.Response
+	err := f.client.pacer.CallNoRetry(func() (bool, error) {
+		var err error
+		resp, err = f.client.Download(ctx, link, []fs.OpenOption{&fs.RangeOption{Start: 0, End: 0}})
+		return shouldRetry(ctx, resp, err)
+	})
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// streamReader counts the files of an Fs and of their torrent open
+// for reading until it is closed, and the bytes read of the torrent
+type streamReader struct {
+	io.ReadCloser
+	f         *Fs
+	torrentID string
+	once      sync.Once
+	unsent    atomic.Int64 // bytes read not added to the traffic yet
+}
+
+// takeStream counts a file about to be opened for reading, failing with
+// fs.ErrorStreamLimit if max_concurrent_streams are open already. The
+// stream is given back by closing the streamReader made for it, or with
+// giveStream if the file couldn't be opened.
+func (f *Fs) takeStream() error {
+	for {
+		open := f.streams.Load()
+		if limit := int64(f.opt.MaxConcurrentStreams); limit > 0 && open >= limit {
+			stats.streamLimitHits.Add(1)
+			return fmt.Errorf("%w: %d of max_concurrent_streams %d", fs.ErrorStreamLimit, open, limit)
+		}
+		if f.streams.CompareAndSwap(open, open+1) {
+			return nil
+		}
+	}
+}
+
+// giveStream gives back a stream taken by takeStream
+func (f *Fs) giveStream() {
+	f.streams.Add(-1)
+}
+
+// newStreamReader counts in as an open file of the torrent with
+// torrentID, which may be "", until it is closed, giving back the
+// stream of f taken for it by takeStream
+func newStreamReader(f *Fs, torrentID string, in io.ReadCloser) io.ReadCloser {
+	if torrentID != "" {
+		f.cache.openReader(torrentID)
+	}
+	return &streamReader{ReadCloser: in, f: f, torrentID: torrentID}
+}
+
+// Read reads from the stream, adding the bytes read to the traffic of
+// the torrent in batches so reads rarely wait for its lock
+func (s *streamReader) Read(p []byte) (n int, err error) {
+	n, err = s.ReadCloser.Read(p)
+	if s.torrentID != "" && n > 0 && s.unsent.Add(int64(n)) >= trafficBatch {
+		s.sendTraffic()
+	}
+	return n, err
+}
+
+// sendTraffic adds the bytes read since it was last called to the
+// traffic of the torrent
+func (s *streamReader) sendTraffic() {
+	if n := s.unsent.Swap(0); n > 0 {
+		s.f.cache.traffic.add(s.torrentID, n, time.Now())
+	}
+}
+
+// Close the stream, no longer counting it
+func (s *streamReader) Close() error {
+	s.once.Do(func() {
+		s.sendTraffic()
+		s.f.giveStream()
+		if s.torrentID != "" {
+			s.f.cache.closeReader(s.torrentID)
+		}
+	})
+	return s.ReadCloser.Close()
+}
@@ -0,0 +1,231 @@
+package realdebrid
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCDN makes a CDN serving the paths in alive and failing the
+// others, returning its URL and the number of requests for each path
+func newTestCDN(t *testing.T, alive ...string) (url string, requests func() map[string]int) {
+	var (
+		mu   sync.Mutex
+		seen = map[string]int{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.URL.Path]++
+		mu.Unlock()
+		for _, path := range alive {
+			if r.URL.Path == path {
+				assert.Equal(t, "bytes=0-0", r.Header.Get("Range"))
+				w.WriteHeader(http.StatusPartialContent)
+				_, _ = w.Write([]byte{0})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, func() map[string]int {
+		mu.Lock()
+		defer mu.Unlock()
+		requests := seen
+		seen = map[string]int{}
+		return requests
+	}
+}
+
+func TestVerifyLinks(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	cdn, cdnRequests := newTestCDN(t, "/alive", "/relinked")
+	unrestricts := map[string]int{}
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/unrestrict/link", r.URL.Path)
+		link := r.FormValue("link")
+		unrestricts[link]++
+		switch link {
+		case "l2":
+			writeJSON(t, w, api.Item{ID: "d2", Link: cdn + "/relinked"})
+		case "l3":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			writeJSON(t, w, api.Response{Message: "hoster_unavailable"})
+		}
+	})
+	f.cache.torrents = []api.Item{
+		{ID: "T1", Name: "One", Status: "downloaded", Links: []string{"l1", "l2"}},
+		{ID: "T3", Name: "Three", Status: "downloaded", Links: []string{"l3"}},
+	}
+	f.cache.cached = []api.Item{
+		{ID: "d1", Name: "one.mkv", OriginalLink: "l1", Link: cdn + "/alive"},
+		{ID: "d2", Name: "two.mkv", OriginalLink: "l2", Link: cdn + "/expired"},
+		{ID: "d3", Name: "three.mkv", OriginalLink: "l3", Link: cdn + "/gone"},
+	}
+	before := stats.params()
+	delta := func(key string) int64 {
+		return stats.params()[key].(int64) - before[key].(int64)
+	}
+
+	// Live links are only requested from the CDN
+	f.verifyNext(ctx)
+	assert.Equal(t, map[string]int{"/alive": 1}, cdnRequests())
+	assert.Empty(t, unrestricts)
+
+	// Dead links are replaced
+	f.verifyNext(ctx)
+	assert.Equal(t, map[string]int{"/expired": 1}, cdnRequests())
+	assert.Equal(t, map[string]int{"l2": 1}, unrestricts)
+	assert.Equal(t, cdn+"/relinked", f.cache.cached[1].Link)
+	assert.Empty(t, f.cache.broken_torrents)
+
+	// and the torrents of those which can't be unrestricted are
+	// queued for repair
+	f.verifyNext(ctx)
+	assert.Equal(t, map[string]int{"/gone": 1}, cdnRequests())
+	assert.Equal(t, 1, unrestricts["l3"])
+	assert.Equal(t, []string{"T3"}, f.cache.broken_torrents)
+
+	// Then it starts again from the first link
+	f.verifyNext(ctx)
+	f.verifyNext(ctx)
+	assert.Equal(t, map[string]int{"/alive": 1, "/relinked": 1}, cdnRequests())
+
+	assert.Equal(t, int64(5), delta("verifiedLinks"))
+	assert.Equal(t, int64(2), delta("deadLinks"))
+	assert.Equal(t, int64(1), delta("brokenLinks"))
+}
+
+func TestVerifyPausedWhileStreaming(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.opt.VerifyPauseStreams = 1
+	cdn, cdnRequests := newTestCDN(t)
+	content := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("content"))
+	}))
+	t.Cleanup(content.Close)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+	})
+	f.cache.cached = []api.Item{{ID: "d1", Name: "one.mkv", OriginalLink: "l1", Link: cdn + "/gone"}}
+
+	o, err := f.newObjectWithInfo(ctx, "one.mkv", &api.Item{Name: "one.mkv", Type: api.ItemTypeFile, Link: content.URL, Size: 7})
+	require.NoError(t, err)
+	in, err := o.Open(ctx)
+	require.NoError(t, err)
+	assert.True(t, f.streaming())
+
+	before := stats.verifyPaused.Load()
+	loopCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		f.verifyLinks(loopCtx, time.Millisecond)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool {
+		return stats.verifyPaused.Load() > before
+	}, 5*time.Second, time.Millisecond)
+	cancel()
+	<-done
+	assert.Empty(t, cdnRequests())
+
+	data, err := io.ReadAll(in)
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+	require.NoError(t, in.Close())
+	require.NoError(t, in.Close())
+	assert.False(t, f.streaming())
+
+	// 0 never pauses
+	in, err = o.Open(ctx)
+	require.NoError(t, err)
+	f.opt.VerifyPauseStreams = 0
+	assert.False(t, f.streaming())
+	require.NoError(t, in.Close())
+}
+
+func TestMaxConcurrentStreams(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.opt.MaxConcurrentStreams = 2
+	content := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/short" {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		_, _ = w.Write([]byte("content"))
+	}))
+	t.Cleanup(content.Close)
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	o, err := f.newObjectWithInfo(ctx, "one.mkv", &api.Item{Name: "one.mkv", Type: api.ItemTypeFile, Link: content.URL, Size: 7})
+	require.NoError(t, err)
+	status := func() (report statusReport) {
+		f.statusData = nil
+		data, _ := f.status(ctx)
+		require.NoError(t, json.Unmarshal(data, &report))
+		return report
+	}
+
+	var ins []io.ReadCloser
+	for range 2 {
+		in, err := o.Open(ctx)
+		require.NoError(t, err)
+		ins = append(ins, in)
+	}
+	report := status()
+	assert.Equal(t, int64(2), report.Streams)
+	assert.Equal(t, 2, report.MaxStreams)
+
+	// Files opened over the limit fail until a stream is closed
+	before := stats.streamLimitHits.Load()
+	_, err = o.Open(ctx)
+	assert.ErrorIs(t, err, fs.ErrorStreamLimit)
+	assert.Equal(t, before+1, stats.streamLimitHits.Load())
+	require.NoError(t, ins[0].Close())
+	in, err := o.Open(ctx)
+	require.NoError(t, err)
+	data, err := io.ReadAll(in)
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+	require.NoError(t, in.Close())
+	require.NoError(t, ins[1].Close())
+	assert.Zero(t, status().Streams)
+
+	// The stream of a file which failed to open is given back
+	short, err := f.newObjectWithInfo(ctx, "short.mkv", &api.Item{Name: "short.mkv", Type: api.ItemTypeFile, Link: content.URL + "/short", Size: 7})
+	require.NoError(t, err)
+	_, err = short.Open(ctx)
+	assert.ErrorIs(t, err, fs.ErrorRangeNotSatisfiable)
+	assert.Zero(t, f.streams.Load())
+
+	// 0 is no limit
+	f.opt.MaxConcurrentStreams = 0
+	for range 3 {
+		in, err := o.Open(ctx)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, in.Close()) }()
+	}
+}
+
+func TestVerifierDisabledByDefault(t *testing.T) {
+	f := newTestFs(t)
+	assert.False(t, f.startVerifier())
+
+	f.opt.VerifyLinksPerHour = 60
+	assert.True(t, f.startVerifier())
+	require.NoError(t, f.Shutdown(context.Background()))
+}
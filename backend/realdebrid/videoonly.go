@@ -0,0 +1,46 @@
+package realdebrid
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+)
+
+// defaultVideoExtensions are the extensions of the files listed with
+// video_only by default: the videos, the audio and the subtitles
+var defaultVideoExtensions = fs.CommaSepList{
+	"mkv", "mp4", "m4v", "avi", "mov", "wmv", "mpg", "mpeg", "ts", "m2ts", "webm", "iso",
+	"mp3", "flac", "m4a", "aac", "ogg", "opus", "wav",
+	"srt", "ass", "ssa", "sub", "idx", "sup", "vtt",
+}
+
+// shownFile returns true if the file called name is listed, which with
+// video_only is if its extension is one of video_extensions
+func (f *Fs) shownFile(name string) bool {
+	if !f.opt.VideoOnly {
+		return true
+	}
+	ext := strings.TrimPrefix(path.Ext(name), ".")
+	return ext != "" && slices.ContainsFunc(f.opt.VideoExtensions, func(allowed string) bool {
+		return strings.EqualFold(strings.TrimPrefix(strings.TrimSpace(allowed), "."), ext)
+	})
+}
+
+// shownDirs returns the folders of dirs, those of the selected files
+// of a torrent, with a file listed in them so the folders of
+// nested_folders only holding hidden files aren't listed
+func (f *Fs) shownDirs(selected []api.File, dirs []string) []string {
+	if !f.opt.VideoOnly || dirs == nil {
+		return dirs
+	}
+	shown := make([]string, 0, len(dirs))
+	for i, dir := range dirs {
+		if f.shownFile(selected[i].Path) {
+			shown = append(shown, dir)
+		}
+	}
+	return shown
+}
@@ -0,0 +1,95 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShownFile(t *testing.T) {
+	f := newTestFs(t)
+	f.opt.VideoExtensions = fs.CommaSepList{"mkv", ".SRT"}
+	assert.True(t, f.shownFile("Movie.nfo"))
+	f.opt.VideoOnly = true
+	for name, want := range map[string]bool{
+		"Movie.2020.mkv":  true,
+		"Movie.2020.MKV":  true,
+		"Subs/en.srt":     true,
+		"Movie.2020.nfo":  false,
+		"Proof/proof.rar": false,
+		"README":          false,
+		"mkv":             false,
+	} {
+		assert.Equal(t, want, f.shownFile(name), name)
+	}
+}
+
+func TestVideoOnly(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.opt.VideoOnly = true
+	f.opt.VideoExtensions = defaultVideoExtensions
+	var mu sync.Mutex
+	unrestricted := map[string]int{}
+	var deleted []string
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/torrents/info/1":
+			writeJSON(t, w, api.Item{
+				ID:     "1",
+				Name:   "Movie.2020",
+				Status: "downloaded",
+				Links:  []string{"L1", "L2", "L3", "L4"},
+				Files: []api.File{
+					{ID: 1, Path: "/Movie.2020.mkv", Bytes: 100, Selected: 1},
+					{ID: 2, Path: "/Movie.2020.nfo", Bytes: 1, Selected: 1},
+					{ID: 3, Path: "/Proof/proof.rar", Bytes: 2, Selected: 1},
+					{ID: 4, Path: "/Subs/en.srt", Bytes: 3, Selected: 1},
+				},
+			})
+		case r.URL.Path == "/unrestrict/link":
+			link := r.FormValue("link")
+			mu.Lock()
+			unrestricted[link]++
+			mu.Unlock()
+			writeJSON(t, w, api.Item{ID: "D" + link, Name: link + ".mkv", OriginalLink: link, Link: "https://dl/" + link, Size: 1})
+		case r.Method == "DELETE":
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	f.cache.torrents = []api.Item{
+		{ID: "1", Name: "Movie.2020", Status: "downloaded", Links: []string{"L1", "L2", "L3", "L4"}},
+	}
+
+	// The files without a video extension aren't unrestricted
+	entries, err := f.List(ctx, "movies/Movie.2020")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"movies/Movie.2020/L1.mkv", "movies/Movie.2020/L4.mkv"}, entryNames(entries))
+	assert.Equal(t, map[string]int{"L1": 1, "L4": 1}, unrestricted)
+
+	// nor are the folders of nested_folders with none
+	f.opt.NestedFolders = true
+	f.dirCache.ResetRoot()
+	entries, err = f.List(ctx, "movies/Movie.2020")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"movies/Movie.2020/Movie.2020.mkv", "movies/Movie.2020/Subs"}, entryNames(entries))
+	_, err = f.List(ctx, "movies/Movie.2020/Proof")
+	assert.ErrorIs(t, err, fs.ErrorDirNotFound)
+	_, err = f.NewObject(ctx, "movies/Movie.2020/Movie.2020.nfo")
+	assert.ErrorIs(t, err, fs.ErrorObjectNotFound)
+	assert.Equal(t, map[string]int{"L1": 1, "L4": 1}, unrestricted)
+
+	// The hidden files are deleted with their torrent
+	require.NoError(t, f.Purge(ctx, "movies/Movie.2020"))
+	assert.Equal(t, []string{"/torrents/delete/1"}, deleted)
+}
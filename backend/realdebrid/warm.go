@@ -0,0 +1,52 @@
+package realdebrid
+
+import (
+	"context"
+	"path"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+)
+
+// warmDirCache puts the category and torrent folders below the root
+// in the directory cache, see prewarm_dircache.
+//
+// They are read from the listed torrents without fetching the details
+// of any torrent, so the first walk of the remote doesn't have to find
+// each folder one at a time.
+func (f *Fs) warmDirCache(ctx context.Context) error {
+	rootDirID, err := f.dirCache.RootID(ctx, false)
+	if err != nil {
+		return err
+	}
+	n, err := f.warmDir(ctx, "", rootDirID)
+	fs.Debugf(f, "Pre-warmed the directory cache with %d folders", n)
+	return err
+}
+
+// warmDir puts the folders in the directory dir with ID dirID and
+// below in the directory cache, returning how many were put
+func (f *Fs) warmDir(ctx context.Context, dir, dirID string) (n int, err error) {
+	if !f.torrentFolders(dirID) {
+		return 0, nil
+	}
+	var folders []api.Item
+	_, _, err = f.listAll(ctx, dirID, true, false, func(item *api.Item) bool {
+		folders = append(folders, *item)
+		return false
+	})
+	if err != nil {
+		return n, err
+	}
+	for _, folder := range folders {
+		remote := path.Join(dir, folder.Name)
+		f.dirCache.Put(remote, folder.ID)
+		n++
+		below, err := f.warmDir(ctx, remote, folder.ID)
+		n += below
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,68 @@
+package realdebrid
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/rclone/rclone/fs/config/configmap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrewarmDirCache(t *testing.T) {
+	ctx := context.Background()
+	newFileRootServer(t)
+	for _, test := range []struct {
+		mode    string
+		root    string
+		prewarm bool
+		want    map[string]string // folder path to ID
+	}{
+		{"folders", "", true, map[string]string{
+			"shows":                  categoryID("shows"),
+			"movies":                 categoryID("movies"),
+			"default":                categoryID("default"),
+			"shows/Show.S01":         "SHOW1",
+			"shows/Show.S01 (SHOW2)": "SHOW2",
+			"movies/Movie.2020":      "MOVIE",
+		}},
+		{"folders", "shows", true, map[string]string{
+			"Show.S01":         "SHOW1",
+			"Show.S01 (SHOW2)": "SHOW2",
+		}},
+		{"torrents", "", true, map[string]string{
+			"Movie.2020":       "MOVIE",
+			"Show.S01":         "SHOW1",
+			"Show.S01 (SHOW2)": "SHOW2",
+		}},
+		{"folders", "", false, map[string]string{}},
+	} {
+		t.Run(test.mode+":"+test.root+":"+strconv.FormatBool(test.prewarm), func(t *testing.T) {
+			f, err := NewFs(ctx, "test", test.root, configmap.Simple{
+				"api_key":          "prewarm-test",
+				"download_mode":    "torrents",
+				"folder_mode":      test.mode,
+				"regex_shows":      `(?i)(S[0-9]{2})`,
+				"regex_movies":     `(?i)(19|20)([0-9]{2})`,
+				"prewarm_dircache": strconv.FormatBool(test.prewarm),
+			})
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				require.NoError(t, f.(*Fs).Shutdown(ctx))
+			})
+			dirCache := f.(*Fs).dirCache
+			for dir, want := range test.want {
+				id, ok := dirCache.Get(dir)
+				assert.True(t, ok, dir)
+				assert.Equal(t, want, id, dir)
+			}
+			if !test.prewarm {
+				_, ok := dirCache.Get("shows")
+				assert.False(t, ok)
+			}
+			// no torrent was looked into
+			assert.Empty(t, f.(*Fs).cache.torrentswf)
+		})
+	}
+}
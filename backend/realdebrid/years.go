@@ -0,0 +1,125 @@
+package realdebrid
+
+import (
+	"regexp"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+)
+
+// unknownYear is the year folder of the movies whose year isn't found
+const unknownYear = "unknown"
+
+// yearPattern finds the year in what the movies regex matched
+var yearPattern = regexp.MustCompile(`[0-9]{4}`)
+
+// yearID returns the ID of the folder of the movies of year
+func yearID(year string) string {
+	return synID(synYear, year)
+}
+
+// moviesRegexp returns the regex of the movies category if its
+// torrents are in year folders, or nil otherwise
+func (f *Fs) moviesRegexp() *regexp.Regexp {
+	if !f.opt.MoviesByYear || f.opt.SharedFolder != "folders" {
+		return nil
+	}
+	for _, c := range f.categories() {
+		if c.name == "movies" {
+			return c.re
+		}
+	}
+	return nil
+}
+
+// byYear returns true if dirID is the movies category folder and lists
+// the year folders rather than the torrents
+func (f *Fs) byYear(dirID string) bool {
+	name, ok := f.categoryName(dirID)
+	return ok && name == "movies" && f.moviesRegexp() != nil
+}
+
+// yearOf returns the year of the movie named name from the match of re,
+// or unknownYear if it hasn't got one.
+//
+// It is the first four digits of the first capture group, or of the
+// whole match if they aren't there, so the default regex_movies which
+// captures the century finds the year too.
+func yearOf(re *regexp.Regexp, name string) string {
+	match := re.FindStringSubmatch(name)
+	if match == nil {
+		return unknownYear
+	}
+	for _, found := range []string{match[min(1, len(match)-1)], match[0]} {
+		if year := yearPattern.FindString(found); year != "" {
+			return year
+		}
+	}
+	return unknownYear
+}
+
+// yearName returns the year of the year folder dirID, or ok false if
+// it isn't one of f
+func (f *Fs) yearName(dirID string) (year string, ok bool) {
+	kind, key, ok := parseSynID(dirID)
+	if !ok || kind != synYear || f.moviesRegexp() == nil {
+		return "", false
+	}
+	if key != unknownYear && yearPattern.FindString(key) != key {
+		return "", false
+	}
+	return key, true
+}
+
+// isYear returns true if dirID is a year folder of the movies category
+func (f *Fs) isYear(dirID string) bool {
+	_, ok := f.yearName(dirID)
+	return ok
+}
+
+// inYear returns the movies in the year folder dirID
+func (f *Fs) inYear(movies []api.Item, dirID string) []api.Item {
+	year, _ := f.yearName(dirID)
+	re := f.moviesRegexp()
+	var result []api.Item
+	for _, torrent := range movies {
+		if yearOf(re, torrent.Name) == year {
+			result = append(result, torrent)
+		}
+	}
+	return result
+}
+
+// yearFolders returns the year folders of movies, dated by their
+// newest movie
+func (f *Fs) yearFolders(movies []api.Item) []api.Item {
+	re := f.moviesRegexp()
+	var folders []api.Item
+	index := map[string]int{}
+	for _, torrent := range movies {
+		year := yearOf(re, torrent.Name)
+		i, found := index[year]
+		if !found {
+			i = len(folders)
+			index[year] = i
+			folders = append(folders, api.Item{ID: yearID(year), Name: year, Generated: "2006-01-02T15:04:05.000Z"})
+		}
+		if torrent.Ended > folders[i].Ended {
+			folders[i].Ended = torrent.Ended
+		}
+	}
+	return folders
+}
+
+// torrentsFolders returns the category folders of f and the year
+// folders of the cached movies, which are the folders listing torrents
+// in folders mode
+func (f *Fs) torrentsFolders() []api.Item {
+	folders := f.addArtificialRootFolders(nil)
+	if f.moviesRegexp() == nil {
+		return folders
+	}
+	f.cache.refreshMu.Lock()
+	torrents, categories := f.cache.torrents, f.cache.categories
+	f.cache.refreshMu.Unlock()
+	return append(folders, f.yearFolders(f.classify(torrents, categoryID("movies"), categories))...)
+}
@@ -0,0 +1,91 @@
+package realdebrid
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/rclone/rclone/backend/realdebrid/api"
+	"github.com/rclone/rclone/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestYearOf(t *testing.T) {
+	for _, test := range []struct {
+		regex, name, want string
+	}{
+		// the default regex_movies captures the century
+		{`(?i)(19|20)([0-9]{2} ?\.?)`, "Movie.2021.1080p", "2021"},
+		{`(?i)(19|20)([0-9]{2} ?\.?)`, "Movie", unknownYear},
+		{`(?i)\b((?:19|20)[0-9]{2})\b`, "Movie.1080p.1999", "1999"},
+		{`(?i)film`, "Some.Film.2020", unknownYear},
+		{`(?i)film ([0-9]+)`, "Film 12", unknownYear},
+	} {
+		assert.Equal(t, test.want, yearOf(regexp.MustCompile(test.regex), test.name), test.name)
+	}
+}
+
+func TestMoviesByYear(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFs(t)
+	f.opt.MoviesByYear = true
+	f.client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	f.cache.torrents = []api.Item{
+		{ID: "1", Name: "Movie.2021.1080p", Status: "downloaded", Ended: "2024-03-01T00:00:00.000Z"},
+		{ID: "2", Name: "Other.2021", Status: "downloaded", Ended: "2024-01-01T00:00:00.000Z"},
+		{ID: "3", Name: "Old.1999", Status: "downloaded", Ended: "2024-02-01T00:00:00.000Z"},
+		{ID: "4", Name: "Show.S01", Status: "downloaded", Ended: "2024-04-01T00:00:00.000Z"},
+	}
+
+	entries, err := f.List(ctx, "movies")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"movies/2021", "movies/1999"}, entryNames(entries))
+	_, isDir := entries[0].(fs.Directory)
+	assert.True(t, isDir)
+	entries, err = f.List(ctx, "movies/2021")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"movies/2021/Movie.2021.1080p", "movies/2021/Other.2021"}, entryNames(entries))
+	entries, err = f.List(ctx, "shows")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shows/Show.S01"}, entryNames(entries))
+
+	// The IDs of the year folders are synthetic and stable
+	id, err := f.dirCache.FindDir(ctx, "movies/1999", false)
+	require.NoError(t, err)
+	assert.Equal(t, "syn:year:1999", id)
+	id, err = f.dirCache.FindDir(ctx, "movies/1999/Old.1999", false)
+	require.NoError(t, err)
+	assert.Equal(t, "3", id)
+	assert.Equal(t, yearID("2021"), f.torrentsDir(&f.cache.torrents[1]))
+	_, err = f.List(ctx, "movies/2000")
+	assert.ErrorIs(t, err, fs.ErrorDirNotFound)
+
+	// A year folder isn't a torrent to delete
+	assert.ErrorIs(t, f.Purge(ctx, "movies/2021"), errPurgeCategory)
+
+	// The movies of the categories without a year are in unknown
+	f.opt.Categories = "movies=(?i)(film|(19|20)[0-9]{2})"
+	f.opt.CategoriesFallback = "other"
+	f.categoryList = newCategories(&f.opt)
+	f.cache.torrents = append(f.cache.torrents, api.Item{ID: "5", Name: "Some.Film", Status: "downloaded"})
+	f.dirCache.ResetRoot()
+	entries, err = f.List(ctx, "movies")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"movies/2021", "movies/1999", "movies/unknown"}, entryNames(entries))
+	entries, err = f.List(ctx, "movies/unknown")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"movies/unknown/Some.Film"}, entryNames(entries))
+
+	// Without the option the movies are listed as before
+	f.opt.MoviesByYear = false
+	f.dirCache.ResetRoot()
+	entries, err = f.List(ctx, "movies")
+	require.NoError(t, err)
+	assert.Len(t, entries, 4)
+	assert.False(t, f.isYear(yearID("2021")))
+}
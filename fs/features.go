@@ -40,6 +40,7 @@ type Features struct {
 	Overlay                  bool // this wraps one or more backends to add functionality
 	ChunkWriterDoesntSeek    bool // set if the chunk writer doesn't need to read the data more than once
 	DoubleSlash              bool // set if backend supports double slashes in paths
+	Immutable                bool // set if object contents never change once created, even if the modtime does
 
 	// Purge all files in the directory specified
 	//
@@ -404,6 +405,7 @@ func (ft *Features) Mask(ctx context.Context, f Fs) *Features {
 	// ft.Overlay = ft.Overlay && mask.Overlay don't propagate Overlay
 	ft.ChunkWriterDoesntSeek = ft.ChunkWriterDoesntSeek && mask.ChunkWriterDoesntSeek
 	ft.DoubleSlash = ft.DoubleSlash && mask.DoubleSlash
+	ft.Immutable = ft.Immutable && mask.Immutable
 
 	if mask.Purge == nil {
 		ft.Purge = nil
@@ -53,6 +53,9 @@ var (
 	ErrorFileNameTooLong             = errors.New("file name too long")
 	ErrorCantListRoot                = errors.New("can't list root")
 	ErrorFileTooSmall                = errors.New("file too small for multipart upload")
+	ErrorRangeNotSatisfiable         = errors.New("requested range not satisfiable")
+	ErrorContentNotReady             = errors.New("content not ready")
+	ErrorStreamLimit                 = errors.New("too many streams open")
 )
 
 // FileTooSmallError is returned by OpenChunkWriter when a file is below the
@@ -72,6 +75,24 @@ func (e *FileTooSmallError) Unwrap() error {
 	return ErrorFileTooSmall
 }
 
+// ContentNotReadyError is returned by Open when the remote still
+// has to fetch the content of the object, for example a torrent which
+// is still downloading. It wraps ErrorContentNotReady and carries the
+// progress so callers can try again later rather than failing.
+type ContentNotReadyError struct {
+	Progress float64 // percentage of the content available
+}
+
+// Error implements the error interface.
+func (e *ContentNotReadyError) Error() string {
+	return fmt.Sprintf("content not ready (%.0f%% downloaded)", e.Progress)
+}
+
+// Unwrap returns ErrorContentNotReady so errors.Is works.
+func (e *ContentNotReadyError) Unwrap() error {
+	return ErrorContentNotReady
+}
+
 // CheckClose is a utility function used to check the return from
 // Close in a defer statement.
 func CheckClose(c io.Closer, err *error) {
@@ -2,6 +2,8 @@ package fs
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -78,3 +80,15 @@ func TestFeaturesDisableList(t *testing.T) {
 	assert.False(t, ft.CaseInsensitive)
 	assert.False(t, ft.DuplicateFiles)
 }
+
+func TestContentNotReadyError(t *testing.T) {
+	var err error = &ContentNotReadyError{Progress: 42.4}
+	assert.Equal(t, "content not ready (42% downloaded)", err.Error())
+	assert.True(t, errors.Is(err, ErrorContentNotReady))
+
+	wrapped := fmt.Errorf("open %q: %w", "file.mkv", err)
+	assert.True(t, errors.Is(wrapped, ErrorContentNotReady))
+	var notReady *ContentNotReadyError
+	assert.True(t, errors.As(wrapped, &notReady))
+	assert.Equal(t, 42.4, notReady.Progress)
+}
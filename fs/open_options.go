@@ -160,13 +160,14 @@ func FixRangeOption(options []OpenOption, size int64) {
 	for i, option := range options {
 		switch x := option.(type) {
 		case *RangeOption:
-			// If start is < 0 then fetch from the end
+			// If start is < 0 then fetch from the end, but no
+			// further back than the start
 			if x.Start < 0 {
-				x = &RangeOption{Start: size - x.End, End: -1}
+				x = &RangeOption{Start: max(size-x.End, 0), End: -1}
 				options[i] = x
 			}
-			// If end is too big or undefined, fetch to the end
-			if x.End > size || x.End < 0 {
+			// If end is past the last byte or undefined, fetch to the end
+			if x.End >= size || x.End < 0 {
 				x = &RangeOption{Start: x.Start, End: size - 1}
 				options[i] = x
 			}
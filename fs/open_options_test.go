@@ -227,6 +227,36 @@ func TestFixRangeOptions(t *testing.T) {
 			},
 			size: 100,
 		},
+		{
+			name: "Fetch with end equal to size",
+			in: []OpenOption{
+				&RangeOption{Start: 10, End: 100},
+			},
+			want: []OpenOption{
+				&RangeOption{Start: 10, End: 99},
+			},
+			size: 100,
+		},
+		{
+			name: "Fetch the last byte",
+			in: []OpenOption{
+				&RangeOption{Start: 99, End: 99},
+			},
+			want: []OpenOption{
+				&RangeOption{Start: 99, End: 99},
+			},
+			size: 100,
+		},
+		{
+			name: "Fetch more of the end than the size",
+			in: []OpenOption{
+				&RangeOption{Start: -1, End: 200},
+			},
+			want: []OpenOption{
+				&RangeOption{Start: 0, End: 99},
+			},
+			size: 100,
+		},
 		{
 			name: "SeekOption",
 			in: []OpenOption{
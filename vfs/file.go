@@ -52,6 +52,7 @@ type File struct {
 	o                fs.Object                       // NB o may be nil if file is being written
 	leaf             string                          // leaf name of the object
 	writers          []Handle                        // writers for this file
+	rwHandles        []*RWFileHandle                 // open RW handles for this file - for diagnostics
 	virtualModTime   *time.Time                      // modtime for backends with Precision == fs.ModTimeNotSupported
 	pendingModTime   time.Time                       // will be applied once o becomes available, i.e. after file was written
 	pendingRenameFun func(ctx context.Context) error // will be run/renamed after all writers close
@@ -354,6 +355,29 @@ func (f *File) delWriter(h Handle) {
 	}
 }
 
+// addRWHandle records an open RW handle on the file
+func (f *File) addRWHandle(fh *RWFileHandle) {
+	f.mu.Lock()
+	f.rwHandles = append(f.rwHandles, fh)
+	f.mu.Unlock()
+}
+
+// delRWHandle removes an RW handle from the file
+func (f *File) delRWHandle(fh *RWFileHandle) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if i := slices.Index(f.rwHandles, fh); i >= 0 {
+		f.rwHandles = slices.Delete(f.rwHandles, i, i+1)
+	}
+}
+
+// openRWHandles returns a copy of the open RW handles on the file
+func (f *File) openRWHandles() []*RWFileHandle {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return slices.Clone(f.rwHandles)
+}
+
 // activeWriters returns the number of writers on the file
 //
 // Note that we don't take the mutex here.  If we do then we can get a
@@ -0,0 +1,334 @@
+// Playback simulation for the hybrid read path
+//
+// This drives a VFS over a synthetic slow remote with the access
+// pattern of a media player so regressions in ReadAt routing or seek
+// handling show up as numbers. Run the benchmark with
+//
+//	go test ./vfs -run '^$' -bench Playback -benchtime 3x
+
+package vfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rclone/rclone/fs"
+	"github.com/rclone/rclone/fstest/mockfs"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/vfs/vfscommon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// playbackObject is a mock object which waits latency before each
+// Open and then serves its content at bandwidth bytes/s, counting
+// what it sends.
+type playbackObject struct {
+	*mockobject.ContentMockObject
+	latency   time.Duration // delay before each Open returns
+	bandwidth int64         // bytes per second, 0 for unlimited
+	opens     atomic.Int64  // number of Open calls
+	served    atomic.Int64  // bytes sent to readers
+}
+
+// Open opens the object after the latency has elapsed
+func (o *playbackObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	o.opens.Add(1)
+	select {
+	case <-time.After(o.latency):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	in, err := o.ContentMockObject.Open(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &playbackReader{in: in, o: o}, nil
+}
+
+// playbackReader throttles the reads from a playbackObject
+type playbackReader struct {
+	in io.ReadCloser
+	o  *playbackObject
+}
+
+func (r *playbackReader) Read(p []byte) (n int, err error) {
+	const maxRead = 32 * 1024
+	if len(p) > maxRead {
+		p = p[:maxRead]
+	}
+	n, err = r.in.Read(p)
+	r.o.served.Add(int64(n))
+	if r.o.bandwidth > 0 && n > 0 {
+		time.Sleep(time.Duration(int64(n) * int64(time.Second) / r.o.bandwidth))
+	}
+	return n, err
+}
+
+func (r *playbackReader) Close() error {
+	return r.in.Close()
+}
+
+// playbackPattern describes the remote and how the player reads it
+type playbackPattern struct {
+	name         string
+	size         int64         // size of the object
+	latency      time.Duration // latency of opening the object
+	bandwidth    int64         // bytes/s of the remote, 0 for unlimited
+	probeSize    int           // bytes read at the head and the tail before playing
+	chunkSize    int           // bytes per player read
+	seekEvery    int           // seek forwards after this many reads, 0 for never
+	seekDistance int64         // how far each seek skips
+	rebuffer     time.Duration // a read slower than this counts as a rebuffer
+}
+
+// playbackMode is how the VFS is configured for the playback
+type playbackMode struct {
+	name   string
+	direct bool // read through to the remote bypassing the cache
+}
+
+var playbackModes = []playbackMode{
+	{name: "cache"},
+	{name: "direct", direct: true},
+}
+
+// playbackResult is what a single playback measured
+type playbackResult struct {
+	ttfb        time.Duration // time to get the head probe
+	rebuffers   int           // reads slower than the rebuffer threshold
+	readBytes   int64         // bytes returned to the player
+	sourceBytes int64         // bytes sent by the remote
+	sourceOpens int64         // number of times the remote was opened
+	cacheBytes  int64         // bytes returned by the VFS from the cache
+	directBytes int64         // bytes returned by the VFS from the remote
+}
+
+// playbackSetup is a VFS over a single playbackObject
+type playbackSetup struct {
+	vfs     *VFS
+	o       *playbackObject
+	content []byte
+}
+
+var playbackRoots atomic.Int64
+
+// newPlaybackSetup makes a VFS in mode serving content with the
+// remote characteristics of p
+//
+// Call cleanup on the VFS when finished
+func newPlaybackSetup(t testing.TB, p playbackPattern, mode playbackMode, content []byte) *playbackSetup {
+	ctx := context.Background()
+	o := &playbackObject{
+		ContentMockObject: mockobject.New("video.mkv").WithContent(content, mockobject.SeekModeNone),
+		latency:           p.latency,
+		bandwidth:         p.bandwidth,
+	}
+	root := fmt.Sprintf("playback%d", playbackRoots.Add(1))
+	fMock, err := mockfs.NewFs(ctx, "playback", root, nil)
+	require.NoError(t, err)
+	f := fMock.(*mockfs.Fs)
+	f.AddObject(o)
+
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	opt.CachePollInterval = 0
+	opt.HandleCaching = 0
+	if mode.direct {
+		opt.CacheMaxFileSize = 0
+	}
+	return &playbackSetup{
+		vfs:     New(ctx, f, &opt),
+		o:       o,
+		content: content,
+	}
+}
+
+// cleanup removes the cache and shuts down the VFS
+func (s *playbackSetup) cleanup() error {
+	err := s.vfs.CleanUp()
+	s.vfs.Shutdown()
+	return err
+}
+
+// play reads the object in the way a player would, checking the data
+// it gets back
+func (s *playbackSetup) play(p playbackPattern) (res playbackResult, err error) {
+	var (
+		startServed = s.o.served.Load()
+		startOpens  = s.o.opens.Load()
+		startCache  = s.vfs.hybridStats.cacheBytes.Load()
+		startDirect = s.vfs.hybridStats.directBytes.Load()
+		size        = int64(len(s.content))
+		buf         = make([]byte, max(p.probeSize, p.chunkSize))
+	)
+	start := time.Now()
+	h, err := s.vfs.OpenFile("video.mkv", os.O_RDONLY, 0)
+	if err != nil {
+		return res, err
+	}
+	readAt := func(n int, off int64) (int, error) {
+		n, err := h.ReadAt(buf[:n], off)
+		if err == io.EOF {
+			err = nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if !bytes.Equal(buf[:n], s.content[off:off+int64(n)]) {
+			return n, fmt.Errorf("bad data read at offset %d", off)
+		}
+		res.readBytes += int64(n)
+		return n, nil
+	}
+
+	// Probe the head then the tail of the container
+	if _, err = readAt(p.probeSize, 0); err != nil {
+		return res, err
+	}
+	res.ttfb = time.Since(start)
+	if _, err = readAt(p.probeSize, max(size-int64(p.probeSize), 0)); err != nil {
+		return res, err
+	}
+
+	// Play from the start, skipping forwards every so often
+	for reads, off := 1, int64(0); off < size; reads++ {
+		readStart := time.Now()
+		n, err := readAt(min(p.chunkSize, int(size-off)), off)
+		if err != nil {
+			return res, err
+		}
+		if n == 0 {
+			return res, fmt.Errorf("short read at offset %d", off)
+		}
+		if time.Since(readStart) > p.rebuffer {
+			res.rebuffers++
+		}
+		off += int64(n)
+		if p.seekEvery > 0 && reads%p.seekEvery == 0 {
+			off += p.seekDistance
+		}
+	}
+	if err = h.Close(); err != nil {
+		return res, err
+	}
+
+	res.sourceBytes = s.o.served.Load() - startServed
+	res.sourceOpens = s.o.opens.Load() - startOpens
+	res.cacheBytes = s.vfs.hybridStats.cacheBytes.Load() - startCache
+	res.directBytes = s.vfs.hybridStats.directBytes.Load() - startDirect
+	return res, nil
+}
+
+// playbackContent returns size bytes of reproducible random data
+func playbackContent(size int64) []byte {
+	content := make([]byte, size)
+	_, _ = rand.New(rand.NewSource(1)).Read(content)
+	return content
+}
+
+func TestPlaybackSimulation(t *testing.T) {
+	patterns := []playbackPattern{{
+		name:      "sequential",
+		size:      1 << 20,
+		probeSize: 64 * 1024,
+		chunkSize: 32 * 1024,
+		rebuffer:  time.Second,
+	}, {
+		name:         "seeking",
+		size:         1 << 20,
+		probeSize:    64 * 1024,
+		chunkSize:    32 * 1024,
+		seekEvery:    4,
+		seekDistance: 100 * 1024,
+		rebuffer:     time.Second,
+	}}
+	for _, p := range patterns {
+		content := playbackContent(p.size)
+		for _, mode := range playbackModes {
+			t.Run(p.name+"/"+mode.name, func(t *testing.T) {
+				s := newPlaybackSetup(t, p, mode, content)
+				defer func() {
+					assert.NoError(t, s.cleanup())
+				}()
+
+				first, err := s.play(p)
+				require.NoError(t, err)
+				assert.NotZero(t, first.sourceBytes)
+				assert.Equal(t, first.readBytes, first.cacheBytes+first.directBytes)
+
+				second, err := s.play(p)
+				require.NoError(t, err)
+				assert.Equal(t, first.readBytes, second.readBytes)
+				if mode.direct {
+					assert.Equal(t, second.readBytes, second.directBytes)
+					assert.NotZero(t, second.sourceBytes)
+				} else {
+					// Everything played is in the cache now
+					assert.Equal(t, second.readBytes, second.cacheBytes)
+					assert.Equal(t, int64(0), second.sourceBytes)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkPlayback(b *testing.B) {
+	patterns := []playbackPattern{{
+		name:      "sequential",
+		size:      32 << 20,
+		latency:   20 * time.Millisecond,
+		bandwidth: 200 << 20,
+		probeSize: 256 * 1024,
+		chunkSize: 128 * 1024,
+		rebuffer:  50 * time.Millisecond,
+	}, {
+		name:         "seeking",
+		size:         32 << 20,
+		latency:      20 * time.Millisecond,
+		bandwidth:    200 << 20,
+		probeSize:    256 * 1024,
+		chunkSize:    128 * 1024,
+		seekEvery:    16,
+		seekDistance: 4 << 20,
+		rebuffer:     50 * time.Millisecond,
+	}}
+	for _, p := range patterns {
+		content := playbackContent(p.size)
+		for _, mode := range playbackModes {
+			b.Run(p.name+"/"+mode.name, func(b *testing.B) {
+				var total playbackResult
+				for range b.N {
+					b.StopTimer()
+					s := newPlaybackSetup(b, p, mode, content)
+					b.StartTimer()
+					res, err := s.play(p)
+					b.StopTimer()
+					require.NoError(b, err)
+					require.NoError(b, s.cleanup())
+					b.StartTimer()
+					total.ttfb += res.ttfb
+					total.rebuffers += res.rebuffers
+					total.sourceBytes += res.sourceBytes
+					total.sourceOpens += res.sourceOpens
+					total.cacheBytes += res.cacheBytes
+					total.directBytes += res.directBytes
+				}
+				n := float64(b.N)
+				b.ReportMetric(float64(total.ttfb.Milliseconds())/n, "ttfb-ms")
+				b.ReportMetric(float64(total.rebuffers)/n, "rebuffers")
+				b.ReportMetric(float64(total.sourceBytes)/n/(1<<20), "source-MiB")
+				b.ReportMetric(float64(total.sourceOpens)/n, "source-opens")
+				b.ReportMetric(float64(total.cacheBytes)/n/(1<<20), "cache-MiB")
+				b.ReportMetric(float64(total.directBytes)/n/(1<<20), "direct-MiB")
+			})
+		}
+	}
+}
@@ -414,6 +414,19 @@ This returns stats for the selected VFS.
             "uploadsQueued": 0
         },
         "fs": "/mnt/a",
+        // Bytes read by the hybrid read path since the VFS started
+        "hybrid": {
+            // bytes returned to readers from the disk cache
+            "cacheBytes": 0,
+            // bytes returned to readers by direct reads of the source
+            "directBytes": 0,
+            // bytes fetched from the source by direct reads, which
+            // is less than directBytes when reads are shared
+            "directFetchedBytes": 0,
+            // direct reads which waited for a stream as the remote
+            // had as many open as it allows
+            "directStreamWaits": 0
+        },
         "inUse": 1,
         // Status of the in memory metadata cache
         "metadataCache": {
@@ -441,6 +454,198 @@ func rcStats(ctx context.Context, in rc.Params) (out rc.Params, err error) {
 	return vfs.Stats(), nil
 }
 
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/warm",
+		Fn:    rcWarm,
+		Title: "Warm up the files under a path ready for playback.",
+		Help: strings.ReplaceAll(`
+This resolves all the objects under the path, which makes backends
+which resolve their links lazily do so, and then downloads the head
+and the tail of each file into the VFS cache and pins them there so
+they aren't removed for age by the cache cleaner.
+
+It is safe to run while the files are being read.
+
+This takes the following parameters
+
+- |fs| - select the VFS in use (optional)
+- |path| - the file or directory to warm up (default the root)
+- |depth| - the number of directory levels below |path| to recurse into, -1 for all (default 0)
+- |edge| - the size of the head and tail of each file to pin, e.g. |32M| (default |--vfs-hybrid-pin-size|)
+
+    rclone rc vfs/warm path=movies/Title depth=1 edge=32M
+
+It returns the outcome for each file under the key |result|, either
+|OK| or the error.
+
+    {
+        "result": {
+            "movies/Title/Title.mkv": "OK"
+        }
+    }
+
+If |--vfs-cache-mode| is off then the objects are resolved but
+nothing is pinned.
+`, "|", "`") + getVFSHelp,
+	})
+}
+
+func rcWarm(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	path, err := in.GetString("path")
+	if err != nil && !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	depth, err := in.GetInt64("depth")
+	if err != nil && !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	edge := vfs.Opt.HybridPinSize
+	edgeString, err := in.GetString("edge")
+	if err == nil {
+		err = edge.Set(edgeString)
+		if err != nil {
+			return nil, rc.NewErrParamInvalid(fmt.Errorf("invalid edge %q: %w", edgeString, err))
+		}
+	} else if !rc.IsErrParamNotFound(err) {
+		return nil, err
+	}
+	node, err := vfs.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]string{}
+	vfs.warm(ctx, node, depth, int64(edge), result)
+	return rc.Params{
+		"result": result,
+	}, nil
+}
+
+// warm resolves the objects under node, recursing depth directory
+// levels (-1 for all), and pins the first and last edge bytes of each
+// file into the cache, recording the outcome for each file in result.
+func (vfs *VFS) warm(ctx context.Context, node Node, depth int64, edge int64, result map[string]string) {
+	switch x := node.(type) {
+	case *File:
+		err := vfs.warmFile(x, edge)
+		if err != nil {
+			fs.Errorf(x.Path(), "vfs/warm failed: %v", err)
+			result[x.Path()] = err.Error()
+		} else {
+			result[x.Path()] = "OK"
+		}
+	case *Dir:
+		items, err := x.ReadDirAll()
+		if err != nil {
+			result[x.Path()] = err.Error()
+			return
+		}
+		for _, item := range items {
+			if ctx.Err() != nil {
+				result[x.Path()] = ctx.Err().Error()
+				return
+			}
+			if item.IsDir() && depth == 0 {
+				continue
+			}
+			vfs.warm(ctx, item, depth-1, edge, result)
+		}
+	}
+}
+
+// warmFile pins the first and last edge bytes of f into the cache
+func (vfs *VFS) warmFile(f *File, edge int64) error {
+	o := f.getObject()
+	if o == nil {
+		return errors.New("file is being written")
+	}
+	if vfs.cache == nil || edge <= 0 {
+		return nil
+	}
+	item := vfs.cache.Item(f.CachePath())
+	err := item.EnsureRange(o, 0, edge)
+	if err != nil {
+		return err
+	}
+	if size := o.Size(); size > edge {
+		return item.EnsureRange(o, size-edge, edge)
+	}
+	return nil
+}
+
+func init() {
+	rc.Add(rc.Call{
+		Path:  "vfs/file-status",
+		Title: "Diagnostics for a file open in a VFS.",
+		Help: strings.ReplaceAll(`
+This returns diagnostics about the handles open on a file in the
+selected VFS, including the most recent read errors on each handle
+with the time and the read mode (|cache| or |direct|) they occurred in.
+
+This takes the following parameters
+
+- |fs| - select the VFS in use (optional)
+- |file| - the path of the file in the VFS
+
+    {
+        "file": "dir/file",
+        "size": 1234,
+        "handles": [
+            {
+                "handle": "0xc000123456",   // string: identifies the handle
+                "flags": "O_RDONLY",        // string: open flags
+                "errorsTotal": 1,           // integer: number of errors ever
+                "errors": [                 // most recent errors, oldest first
+                    {
+                        "time": "2024-01-02T15:04:05.000Z",
+                        "mode": "direct",
+                        "offset": 0,
+                        "error": "unexpected EOF"
+                    }
+                ]
+            }
+        ]
+    }
+
+Only handles opened with |--vfs-cache-mode| > off are reported.
+
+`, "|", "`") + getVFSHelp,
+		Fn: rcFileStatus,
+	})
+}
+
+func rcFileStatus(ctx context.Context, in rc.Params) (out rc.Params, err error) {
+	vfs, err := getVFS(in)
+	if err != nil {
+		return nil, err
+	}
+	name, err := in.GetString("file")
+	if err != nil {
+		return nil, err
+	}
+	node, err := vfs.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	file, ok := node.(*File)
+	if !ok {
+		return nil, rc.NewErrParamInvalid(fmt.Errorf("%q is not a file", name))
+	}
+	handles := []rc.Params{}
+	for _, fh := range file.openRWHandles() {
+		handles = append(handles, fh.status())
+	}
+	return rc.Params{
+		"file":    file.Path(),
+		"size":    file.Size(),
+		"handles": handles,
+	}, nil
+}
+
 func init() {
 	rc.Add(rc.Call{
 		Path:   "vfs/queue",
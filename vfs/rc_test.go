@@ -2,6 +2,7 @@ package vfs
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/rclone/rclone/fs"
@@ -125,3 +126,128 @@ func TestRcStats(t *testing.T) {
 	assert.Equal(t, 1, out["metadataCache"].(rc.Params)["dirs"])
 	assert.Equal(t, vfs.Opt, out["opt"].(vfscommon.Options))
 }
+
+func TestRcOptionsHybrid(t *testing.T) {
+	_, vfs, _ := rcNewRun(t, "vfs/stats")
+	oldOpt := vfscommon.Opt
+	t.Cleanup(func() {
+		vfscommon.Opt = oldOpt
+	})
+	get := rc.Calls.Get("options/get")
+	set := rc.Calls.Get("options/set")
+	require.NotNil(t, get)
+	require.NotNil(t, set)
+
+	// The hybrid options are reported by options/get
+	out, err := get.Fn(context.Background(), rc.Params{"blocks": "vfs"})
+	require.NoError(t, err)
+	opt := *out["vfs"].(*vfscommon.Options)
+	assert.True(t, opt.Hybrid)
+	assert.Equal(t, "/Cache_Check_Video_Library/cache_check", opt.HybridCheckDir)
+	assert.Equal(t, 16*fs.Mebi, opt.HybridPinSize)
+	assert.Equal(t, fs.SizeSuffix(0), opt.HybridBwLimit)
+
+	// Set the dynamic ones
+	_, err = set.Fn(context.Background(), rc.Params{
+		"vfs": rc.Params{
+			"HybridBwLimit":   "2M",
+			"HybridReadAhead": 64 * fs.Mebi,
+		},
+	})
+	require.NoError(t, err)
+
+	// and check they read back from options/get
+	out, err = get.Fn(context.Background(), rc.Params{"blocks": "vfs"})
+	require.NoError(t, err)
+	opt = *out["vfs"].(*vfscommon.Options)
+	assert.Equal(t, 2*fs.Mebi, opt.HybridBwLimit)
+	assert.Equal(t, 64*fs.Mebi, opt.HybridReadAhead)
+
+	// and from vfs/stats of the running VFS
+	stats := vfs.Stats()["opt"].(vfscommon.Options)
+	assert.Equal(t, 2*fs.Mebi, stats.HybridBwLimit)
+	assert.Equal(t, 64*fs.Mebi, stats.HybridReadAhead)
+	assert.Equal(t, int64(2*fs.Mebi), vfs.hybridBwLimit.Load())
+	assert.Equal(t, int64(64*fs.Mebi), vfs.hybridReadAhead.Load())
+
+	// A new VFS with the new options reuses the running one
+	vfs2 := New(context.Background(), vfs.f, nil)
+	defer vfs2.Shutdown()
+	assert.True(t, vfs == vfs2)
+}
+
+func TestRcFileStatus(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping test on non local remote")
+	}
+	r, _, fh := rwHandleCreateReadOnly(t)
+	call := rc.Calls.Get("vfs/file-status")
+	require.NotNil(t, call)
+
+	fh.errs.add("direct", 42, errors.New("potato"))
+
+	in := rc.Params{"fs": fs.ConfigString(r.Fremote), "file": "dir/file1"}
+	out, err := call.Fn(context.Background(), in)
+	require.NoError(t, err)
+	assert.Equal(t, "dir/file1", out["file"])
+	assert.Equal(t, int64(16), out["size"])
+	handles := out["handles"].([]rc.Params)
+	require.Len(t, handles, 1)
+	assert.Equal(t, 1, handles[0]["errorsTotal"])
+	errs := handles[0]["errors"].([]rc.Params)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "direct", errs[0]["mode"])
+	assert.Equal(t, int64(42), errs[0]["offset"])
+	assert.Equal(t, "potato", errs[0]["error"])
+
+	// Closed handles are not reported
+	require.NoError(t, fh.Close())
+	out, err = call.Fn(context.Background(), rc.Params{"file": "dir/file1"})
+	require.NoError(t, err)
+	assert.Len(t, out["handles"].([]rc.Params), 0)
+
+	// Directories are rejected
+	_, err = call.Fn(context.Background(), rc.Params{"file": "dir"})
+	require.Error(t, err)
+}
+
+func TestRcWarm(t *testing.T) {
+	if *fstest.RemoteName != "" {
+		t.Skip("Skipping test on non local remote")
+	}
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	r, vfs := newTestVFSOpt(t, &opt)
+	call := rc.Calls.Get("vfs/warm")
+	require.NotNil(t, call)
+
+	ctx := context.Background()
+	file1 := r.WriteObject(ctx, "dir/file1", "0123456789abcdef", t1)
+	file2 := r.WriteObject(ctx, "dir/sub/file2", "0123456789", t1)
+	r.CheckRemoteItems(t, file1, file2)
+
+	out, err := call.Fn(ctx, rc.Params{"path": "dir", "edge": "4"})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{
+		"result": map[string]string{
+			"dir/file1": "OK",
+		},
+	}, out)
+	assert.True(t, vfs.cache.Exists("dir/file1"))
+	assert.False(t, vfs.cache.Exists("dir/sub/file2"))
+
+	out, err = call.Fn(ctx, rc.Params{"path": "dir", "depth": -1})
+	require.NoError(t, err)
+	assert.Equal(t, rc.Params{
+		"result": map[string]string{
+			"dir/file1":     "OK",
+			"dir/sub/file2": "OK",
+		},
+	}, out)
+	assert.True(t, vfs.cache.Exists("dir/sub/file2"))
+
+	_, err = call.Fn(ctx, rc.Params{"path": "dir", "edge": "potato"})
+	require.Error(t, err)
+	_, err = call.Fn(ctx, rc.Params{"path": "notfound"})
+	require.Error(t, err)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -121,7 +122,7 @@ func (fh *ReadFileHandle) seek(offset int64, reopen bool) (err error) {
 	if !reopen {
 		ar := fh.r.GetAsyncReader()
 		// try to fulfill the seek with buffer discard
-		if ar != nil && ar.SkipBytes(int(offset-fh.offset)) {
+		if skip, ok := toInt(offset - fh.offset); ok && ar != nil && ar.SkipBytes(skip) {
 			fh.offset = offset
 			return nil
 		}
@@ -216,6 +217,34 @@ func (fh *ReadFileHandle) ReadAt(p []byte, off int64) (n int, err error) {
 	return fh.readAt(p, off)
 }
 
+// inSequence returns true if a read of size bytes at off is close
+// enough after the current offset to wait for the reads in progress
+// to get there rather than seeking.
+//
+// This is done in int64 so it works for files over 2 GiB on 32 bit
+// platforms.
+func inSequence(offset, off, size int64) bool {
+	maxBuf := min(size, 1024*1024)
+	gap := off - offset
+	return gap > 0 && gap < 8*maxBuf
+}
+
+// fitsInt returns true if n can be stored in a signed integer of bits
+// bits.
+func fitsInt(n int64, bits int) bool {
+	if bits >= 64 {
+		return true
+	}
+	limit := int64(1) << (bits - 1)
+	return n >= -limit && n < limit
+}
+
+// toInt converts n to an int returning false if it doesn't fit, which
+// can happen with the offsets of large files on 32 bit platforms.
+func toInt(n int64) (int, bool) {
+	return int(n), fitsInt(n, strconv.IntSize)
+}
+
 // This waits for *poff to equal off or aborts after the timeout.
 //
 // Waits here potentially affect all seeks so need to keep them short.
@@ -265,8 +294,7 @@ func (fh *ReadFileHandle) readAt(p []byte, off int64) (n int, err error) {
 		fs.Errorf(fh.remote, "ReadFileHandle.Read error: %v", EBADF)
 		return 0, ECLOSED
 	}
-	maxBuf := min(len(p), 1024*1024)
-	if gap := off - fh.offset; gap > 0 && gap < int64(8*maxBuf) {
+	if inSequence(fh.offset, off, int64(len(p))) {
 		waitSequential("read", fh.remote, &fh.cond, time.Duration(fh.file.VFS().Opt.ReadWait), &fh.offset, off)
 	}
 	doSeek := off != fh.offset
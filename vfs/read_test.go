@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"os"
+	"strconv"
 	"testing"
 
 	"github.com/rclone/rclone/fstest"
@@ -230,3 +231,64 @@ func TestReadFileHandleRelease(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, fh.closed)
 }
+
+func TestInSequence(t *testing.T) {
+	const (
+		GiB = int64(1) << 30
+		TiB = int64(1) << 40
+	)
+	for _, test := range []struct {
+		offset int64
+		off    int64
+		size   int64
+		want   bool
+	}{
+		{offset: 0, off: 0, size: 4096, want: false},
+		{offset: 0, off: 4096, size: 4096, want: true},
+		{offset: 0, off: 8 * 4096, size: 4096, want: false},
+		{offset: 0, off: 4 << 20, size: 128 << 20, want: true},
+		{offset: 0, off: 8 << 20, size: 128 << 20, want: false},
+		{offset: 5*GiB - 8192, off: 5*GiB - 4096, size: 4096, want: true},
+		{offset: 5*GiB - 4096, off: 5*GiB - 8192, size: 4096, want: false},
+		{offset: 1 << 20, off: 5*GiB + 1<<20, size: 4096, want: false},
+		{offset: 3*TiB - 1<<20, off: 3*TiB - 1<<19, size: 1 << 20, want: true},
+		{offset: 0, off: 3 * TiB, size: 1 << 20, want: false},
+	} {
+		got := inSequence(test.offset, test.off, test.size)
+		assert.Equal(t, test.want, got, "offset=%d off=%d size=%d", test.offset, test.off, test.size)
+	}
+}
+
+func TestFitsInt(t *testing.T) {
+	const (
+		GiB = int64(1) << 30
+		TiB = int64(1) << 40
+	)
+	for _, test := range []struct {
+		n      int64
+		want32 bool
+	}{
+		{n: 0, want32: true},
+		{n: 1 << 20, want32: true},
+		{n: -(1 << 20), want32: true},
+		{n: 2*GiB - 1, want32: true},
+		{n: 2 * GiB, want32: false},
+		{n: -2 * GiB, want32: true},
+		{n: -2*GiB - 1, want32: false},
+		{n: 5*GiB + 1, want32: false},
+		{n: 3 * TiB, want32: false},
+		{n: -3 * TiB, want32: false},
+	} {
+		assert.Equal(t, test.want32, fitsInt(test.n, 32), "n=%d", test.n)
+		assert.True(t, fitsInt(test.n, 64), "n=%d", test.n)
+		if !test.want32 {
+			// simulate a 32 bit int to show what would have been skipped
+			assert.NotEqual(t, test.n, int64(int32(test.n)), "n=%d", test.n)
+		}
+		got, ok := toInt(test.n)
+		assert.Equal(t, fitsInt(test.n, strconv.IntSize), ok, "n=%d", test.n)
+		if ok {
+			assert.Equal(t, test.n, int64(got))
+		}
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/log"
+	"github.com/rclone/rclone/fs/rc"
 	"github.com/rclone/rclone/vfs/vfscache"
 
 	// delta from read.go
@@ -19,6 +22,7 @@ import (
 	"github.com/rclone/rclone/fs/accounting"
 	"github.com/rclone/rclone/fs/chunkedreader"
 	"github.com/rclone/rclone/fs/hash"
+	"golang.org/x/time/rate"
 	// from vfscache/item.go
 	// "github.com/rclone/rclone/lib/ranges" DEPRECATED
 )
@@ -57,12 +61,115 @@ type RWFileHandle struct {
 	currentDirectReadMode bool
 	openedSource          bool
 	openedCache           bool
+	openingSource         bool // set while a read waits for a stream with the lock released
 
-	closed      bool // set if handle has been closed
-	readCalled  bool // set if read has been called
+	closed      bool          // set if handle has been closed
+	closing     chan struct{} // closed when the handle is closed
+	readCalled  bool          // set if read has been called
 	noSeek      bool
 	sizeUnknown bool // set if size of source is not known
 	opened      bool
+
+	sourceReads   []*sourceRead // source reads in flight or recently completed
+	sourceFetched int64         // number of bytes fetched from the source
+
+	errs handleErrors // most recent read errors - has its own lock
+
+	limiter *rate.Limiter // bandwidth limiter for source reads, nil if off
+
+	bypassCache bool // set if the file is too big to cache so is always read directly
+}
+
+// maxHandleErrors is the number of errors remembered per handle
+const maxHandleErrors = 8
+
+// handleError is a read error which occurred on a handle
+type handleError struct {
+	when time.Time // when the error occurred
+	mode string    // read mode in use - "cache" or "direct"
+	off  int64     // offset of the read
+	err  error     // the error
+}
+
+// handleErrors is a ring of the most recent errors on a handle.
+//
+// It has its own mutex so it can be read without waiting for the
+// handle lock which is held during IO.
+type handleErrors struct {
+	mu    sync.Mutex
+	ring  [maxHandleErrors]handleError
+	next  int // index of the next error to write
+	total int // number of errors recorded ever
+}
+
+// add records err which occurred reading at off in mode
+func (he *handleErrors) add(mode string, off int64, err error) {
+	he.mu.Lock()
+	he.ring[he.next] = handleError{when: time.Now(), mode: mode, off: off, err: err}
+	he.next = (he.next + 1) % maxHandleErrors
+	he.total++
+	he.mu.Unlock()
+}
+
+// get returns the errors remembered, oldest first, and the number of
+// errors recorded ever
+func (he *handleErrors) get() (errs []handleError, total int) {
+	he.mu.Lock()
+	defer he.mu.Unlock()
+	n := min(he.total, maxHandleErrors)
+	errs = make([]handleError, 0, n)
+	for i := range n {
+		errs = append(errs, he.ring[(he.next-n+i+maxHandleErrors)%maxHandleErrors])
+	}
+	return errs, he.total
+}
+
+// String returns the errors remembered in a form suitable for logging
+func (he *handleErrors) String() string {
+	errs, total := he.get()
+	if total == 0 {
+		return "no errors"
+	}
+	var out strings.Builder
+	fmt.Fprintf(&out, "%d errors", total)
+	for _, e := range errs {
+		fmt.Fprintf(&out, "; %s %s off=%d: %v", e.when.Format("15:04:05.000"), e.mode, e.off, e.err)
+	}
+	return out.String()
+}
+
+// readMode returns the name of the read mode
+func readMode(direct bool) string {
+	if direct {
+		return "direct"
+	}
+	return "cache"
+}
+
+// maxSourceReads is the number of completed source reads kept on a
+// handle to serve overlapping reads from
+const maxSourceReads = 4
+
+// sourceRead is a read from the source which is in flight or has
+// recently completed on a handle.
+//
+// Kernel readahead issues overlapping reads concurrently on the same
+// handle, so these are used to serve the overlap from the earlier
+// read rather than fetching it from the source twice.
+type sourceRead struct {
+	off  int64  // offset of the read
+	size int64  // number of bytes requested
+	buf  []byte // data read - only valid when done is set
+	done bool   // set when the read has finished
+}
+
+// covers returns true if off is within the read
+func (sr *sourceRead) covers(off int64) bool {
+	end := sr.off + sr.size
+	if sr.done {
+		end = sr.off + int64(len(sr.buf))
+	}
+	return off >= sr.off && off < end
 }
 
 // Check interfaces
@@ -90,7 +197,7 @@ func newRWFileHandle(d *Dir, f *File, flags int) (fh *RWFileHandle, err error) {
 	// get an item to represent this from the cache
 	item := d.vfs.cache.Item(f.CachePath())
 
-	if !f.VFS().Opt.NoChecksum {
+	if o != nil && !f.VFS().Opt.NoChecksum {
 		hashes := hash.NewHashSet(o.Fs().Hashes().GetOne()) // just pick one hash
 		mhash, err = hash.NewMultiHasherTypes(hashes)
 		if err != nil {
@@ -112,12 +219,19 @@ func newRWFileHandle(d *Dir, f *File, flags int) (fh *RWFileHandle, err error) {
 		item:  item,
 
 		// from read.go
-		remote: o.Remote(),
+		remote: f.Path(),
 		noSeek: f.VFS().Opt.NoSeek,
 		// file:        f,
-		hash:        mhash,
-		size:        nonNegative(o.Size()),
-		sizeUnknown: o.Size() < 0,
+		hash:    mhash,
+		closing: make(chan struct{}),
+	}
+	if o != nil {
+		// o is nil for files created through this handle
+		fh.remote = o.Remote()
+		fh.size = nonNegative(o.Size())
+		fh.sizeUnknown = o.Size() < 0
+		maxFileSize := int64(f.VFS().Opt.CacheMaxFileSize)
+		fh.bypassCache = maxFileSize >= 0 && fh.readOnly() && (fh.sizeUnknown || o.Size() > maxFileSize)
 	}
 
 	// truncate immediately if O_TRUNC is set or O_CREATE is set and file doesn't exist
@@ -136,6 +250,7 @@ func newRWFileHandle(d *Dir, f *File, flags int) (fh *RWFileHandle, err error) {
 
 	// from read.go :
 	fh.cond = sync.Cond{L: &fh.mu}
+	fh.file.addRWHandle(fh)
 	return fh, nil
 }
 
@@ -184,12 +299,14 @@ func (fh *RWFileHandle) openPending() (err error) {
 // openPending opens the file if there is a pending open
 // call with the lock held
 func (fh *RWFileHandle) openPendingSource() (err error) {
+	if fh.waitOpeningSource() {
+		return ECLOSED
+	}
 	if fh.openedSource && fh.r != nil {
 		return nil
 	}
 	o := fh.file.getObject()
-	opt := &fh.file.VFS().Opt
-	r, err := chunkedreader.New(fh.file.ctx, o, int64(opt.ChunkSize), int64(opt.ChunkSizeLimit), opt.ChunkStreams).Open()
+	r, err := fh.openSource(o, 0)
 	if err != nil {
 		return err
 	}
@@ -237,6 +354,9 @@ func (fh *RWFileHandle) updateSize() {
 // close the file handle returning EBADF if it has been
 // closed already.
 //
+// It closes the cache item and the source, whichever of them are
+// open, whatever the read mode the handle was in.
+//
 // Must be called with fh.mu held.
 //
 // Note that we leave the file around in the cache on error conditions
@@ -250,17 +370,22 @@ func (fh *RWFileHandle) close() (err error) {
 		return ECLOSED
 	}
 
-	fh.closed = true
+	fh.markClosed()
+	fh.waitOpeningSource()
 
 	fh.updateSize()
 	if fh.openedCache {
 		err = fh.item.Close(fh.file.setObject)
-		fh.opened = false
 		fh.openedCache = false
 	} else {
 		// apply any pending mod times if any
 		_ = fh.file.applyPendingModTime()
 	}
+	sourceErr := fh.closeSource()
+	if err == nil {
+		err = sourceErr
+	}
+	fh.opened = false
 
 	if !fh.readOnly() {
 		fh.file.delWriter(fh)
@@ -269,95 +394,117 @@ func (fh *RWFileHandle) close() (err error) {
 	return err
 }
 
-func (fh *RWFileHandle) closeSource() error {
-	if fh.closed {
-		return ECLOSED
+// closeSource closes the source reader and checks the hash of the
+// data read from it.
+//
+// It does nothing if the source isn't open, which may be because it
+// failed to open, so it is safe to call more than once.
+//
+// Must be called with fh.mu held.
+func (fh *RWFileHandle) closeSource() (err error) {
+	if !fh.openedSource {
+		return nil
+	}
+	fh.openedSource = false
+	if fh.r != nil {
+		// Close first so that we have hashes
+		err = fh.r.Close()
+		fh.r = nil
+	}
+	if err == nil {
+		// Now check the hash
+		err = fh.checkHash()
+	}
+	if fh.done != nil {
+		fh.done(fh.file.ctx, err)
+		fh.done = nil
 	}
+	return err
+}
+
+// markClosed marks the handle as closed, waking up any reads waiting
+// on it and stopping their timeout goroutines.
+//
+// Must be called with fh.mu held and only once.
+func (fh *RWFileHandle) markClosed() {
 	fh.closed = true
+	close(fh.closing)
+	fh.cond.Broadcast()
+	fh.closeSummary()
+}
 
-	if fh.opened {
-		var err error
-		// in dyn mode, deal with fh.openedCache as well
-		if fh.openedCache {
-			err = fh.item.Close(fh.file.setObject)
-			fh.opened = false
-			fh.openedCache = false
-			if !fh.readOnly() {
-				fh.file.delWriter(fh)
-			}
-		}
-		// TODO-jellygrail: err overwrritten below, tofix
+// waitOpeningSource waits for a read opening the source with the lock
+// released to finish so that only one read uses the source at a time.
+//
+// It returns true if the handle has been closed.
+//
+// Must be called with fh.mu held.
+func (fh *RWFileHandle) waitOpeningSource() (closed bool) {
+	for fh.openingSource {
+		fh.cond.Wait()
+	}
+	return fh.closed
+}
 
-		if fh.openedSource {
-			fh.opened = false
-			fh.openedSource = false
-			defer func() {
-				fh.done(fh.file.ctx, err)
-			}()
-			// Close first so that we have hashes
-			err = fh.r.Close()
-			if err != nil {
-				return err
-			}
-			// Now check the hash
-			err = fh.checkHash()
-			if err != nil {
-				return err
-			}
-		}
+// closeSummary logs a summary of the handle's activity as it is
+// closed and stops tracking it on the file.
+//
+// Must be called with fh.mu held.
+func (fh *RWFileHandle) closeSummary() {
+	fh.file.delRWHandle(fh)
+	_, total := fh.errs.get()
+	logf := fs.Debugf
+	if total > 0 {
+		logf = fs.Infof
+	}
+	logf(fh.logPrefix(), "RWFileHandle closed: mode=%s, fetched=%d, %v", readMode(fh.currentDirectReadMode), fh.sourceFetched, &fh.errs)
+}
 
+// status returns diagnostics about the handle for the rc.
+//
+// It doesn't take fh.mu so it doesn't block on reads in progress.
+func (fh *RWFileHandle) status() rc.Params {
+	errs, total := fh.errs.get()
+	outErrs := make([]rc.Params, 0, len(errs))
+	for _, e := range errs {
+		outErrs = append(outErrs, rc.Params{
+			"time":   e.when,
+			"mode":   e.mode,
+			"offset": e.off,
+			"error":  e.err.Error(),
+		})
+	}
+	return rc.Params{
+		"handle":      fmt.Sprintf("%p", fh),
+		"flags":       decodeOpenFlags(fh.flags),
+		"errors":      outErrs,
+		"errorsTotal": total,
 	}
-	return nil
 }
 
 // Close closes the file
 func (fh *RWFileHandle) Close() error {
 	fh.mu.Lock()
 	defer fh.mu.Unlock()
-
-	if !fh.currentDirectReadMode {
-		return fh.close()
-	} else {
-		return fh.closeSource()
-	}
-
+	return fh.close()
 }
 
 // Flush is called each time the file or directory is closed.
 // Because there can be multiple file descriptors referring to a
 // single opened file, Flush can be called multiple times.
 func (fh *RWFileHandle) Flush() error {
-
-	if !fh.currentDirectReadMode {
-
-		fh.mu.Lock()
-		fs.Debugf(fh.logPrefix(), "RWFileHandle.Flush")
-		fh.updateSize()
-		fh.mu.Unlock()
-		return nil
-
-	} else {
-		if fh.openedCache {
-			fh.updateSize()
-		}
-
-		fh.mu.Lock()
-		defer fh.mu.Unlock()
-		if !fh.opened {
-			return nil
-		}
-		// fs.Debugf(fh.remote, "ReadFileHandle.Flush")
-
-		if err := fh.checkHash(); err != nil {
-			fs.Errorf(fh.remote, "ReadFileHandle.Flush error: %v", err)
-			return err
-		}
-
-		// fs.Debugf(fh.remote, "ReadFileHandle.Flush OK")
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	fs.Debugf(fh.logPrefix(), "RWFileHandle.Flush")
+	fh.updateSize()
+	if !fh.openedSource {
 		return nil
-
 	}
-
+	if err := fh.checkHash(); err != nil {
+		fs.Errorf(fh.remote, "ReadFileHandle.Flush error: %v", err)
+		return err
+	}
+	return nil
 }
 
 // Release is called when we are finished with the file handle
@@ -365,43 +512,18 @@ func (fh *RWFileHandle) Flush() error {
 // It isn't called directly from userspace so the error is ignored by
 // the kernel
 func (fh *RWFileHandle) Release() error {
-
 	fh.mu.Lock()
 	defer fh.mu.Unlock()
-
-	if !fh.currentDirectReadMode {
-
-		fs.Debugf(fh.logPrefix(), "RWFileHandle.Release")
-		if fh.closed {
-			// Don't return an error if called twice
-			return nil
-		}
-		err := fh.close()
-		if err != nil {
-			fs.Errorf(fh.logPrefix(), "RWFileHandle.Release error: %v", err)
-		}
-		return err
-
-	} else {
-
-		if !fh.opened {
-			return nil
-		}
-		if fh.closed {
-			fs.Debugf(fh.remote, "ReadFileHandle.Release nothing to do")
-			return nil
-		}
-		fs.Debugf(fh.remote, "ReadFileHandle.Release closing")
-		err := fh.closeSource()
-		if err != nil {
-			fs.Errorf(fh.remote, "ReadFileHandle.Release error: %v", err)
-			//} else {
-			// fs.Debugf(fh.remote, "ReadFileHandle.Release OK")
-		}
-		return err
-
+	fs.Debugf(fh.logPrefix(), "RWFileHandle.Release")
+	if fh.closed {
+		// Don't return an error if called twice
+		return nil
 	}
-
+	err := fh.close()
+	if err != nil {
+		fs.Errorf(fh.logPrefix(), "RWFileHandle.Release error: %v", err)
+	}
+	return err
 }
 
 // _size returns the size of the underlying file and also sets it in
@@ -423,6 +545,55 @@ func (fh *RWFileHandle) _size() int64 {
 	return size
 }
 
+// readSize returns the size which reads are bounded by in both the
+// cache and the direct read modes.
+//
+// For read only handles this is the size of the File, which is the
+// size of the object reported by the backend unless the file is being
+// written, and it is refreshed by refreshSize when the data disagrees
+// with it. Otherwise it is the size of the cache file as it may have
+// been written to.
+//
+// call with the lock held
+func (fh *RWFileHandle) readSize() int64 {
+	if !fh.readOnly() || fh.sizeUnknown {
+		return fh._size()
+	}
+	fh.size = fh.file.Size()
+	return fh.size
+}
+
+// refreshSize reads the size of the object from the backend again
+// after a discrepancy between the size and the data read was detected
+// (eg a short read or a range not satisfiable error) which happens if
+// the remote object was replaced with one of a different size.
+//
+// It updates the handle and the file with the new object and returns
+// the size.
+//
+// call with the lock held
+func (fh *RWFileHandle) refreshSize() int64 {
+	o := fh.file.getObject()
+	if o == nil {
+		return fh.size
+	}
+	newObj, err := fh.file.Fs().NewObject(fh.file.ctx, o.Remote())
+	if err != nil {
+		fs.Debugf(fh.remote, "RWFileHandle.refreshSize failed: %v", err)
+		return fh.size
+	}
+	size := newObj.Size()
+	if size < 0 || (size == fh.size && !fh.sizeUnknown) {
+		return fh.size
+	}
+	fs.Infof(fh.remote, "RWFileHandle.refreshSize: size changed from %d to %d", fh.size, size)
+	fh.size = size
+	fh.sizeUnknown = false
+	fh.file.setObjectNoUpdate(newObj)
+	fh.file.setSize(size)
+	return size
+}
+
 // Size returns the size of the underlying file
 func (fh *RWFileHandle) Size() int64 {
 	fh.mu.Lock()
@@ -451,7 +622,7 @@ func (fh *RWFileHandle) _readAt(b []byte, off int64, release bool, DirectReadMod
 	if fh.writeOnly() {
 		return n, EBADF
 	}
-	if off >= fh._size() {
+	if off >= fh.readSize() {
 		return n, io.EOF
 	}
 	if err = fh.openPending(); err != nil {
@@ -502,7 +673,11 @@ func (fh *RWFileHandle) checkHash() error {
 	return nil
 }
 
-func waitSequentialSource(what string, remote string, cond *sync.Cond, maxWait time.Duration, poff *int64, off int64) {
+// waitSequentialSource waits for up to maxWait for the stream at *poff
+// to reach off so the read can be done without seeking.
+//
+// It returns early if closing is closed. cond.L must be held.
+func waitSequentialSource(what string, remote string, cond *sync.Cond, maxWait time.Duration, poff *int64, off int64, closing <-chan struct{}) {
 	var (
 		timeout = time.NewTimer(maxWait)
 		done    = make(chan struct{})
@@ -520,20 +695,122 @@ func waitSequentialSource(what string, remote string, cond *sync.Cond, maxWait t
 			cond.Broadcast()
 			cond.L.Unlock()
 		case <-done:
+		case <-closing:
+			// the handle broadcasts when it is closed so there
+			// is no need to take the lock which the closer holds
 		}
 	}()
-	for *poff != off && abort.Load() == 0 {
+	isClosing := func() bool {
+		select {
+		case <-closing:
+			return true
+		default:
+			return false
+		}
+	}
+	// Stop waiting once the stream has reached off - if it has gone
+	// past it the overlap can be served from the recent source reads.
+	for *poff < off && abort.Load() == 0 && !isClosing() {
 		fs.Debugf(remote, "waiting for in-sequence %s to %d for %v", what, off, maxWait)
 		cond.Wait()
 	}
 	// tidy up end timer
 	close(done)
 	timeout.Stop()
-	if *poff != off {
+	if *poff < off {
 		fs.Debugf(remote, "failed to wait for in-sequence %s to %d", what, off)
 	}
 }
 
+// addSourceRead records a source read of size bytes at off as in flight
+//
+// call with lock held
+func (fh *RWFileHandle) addSourceRead(off, size int64) *sourceRead {
+	sr := &sourceRead{off: off, size: size}
+	fh.sourceReads = append(fh.sourceReads, sr)
+	return sr
+}
+
+// finishSourceRead marks sr as done with the data in p, drops the
+// oldest completed reads and wakes up anyone waiting for it.
+//
+// call with lock held
+func (fh *RWFileHandle) finishSourceRead(sr *sourceRead, p []byte) {
+	sr.buf = append([]byte(nil), p...)
+	sr.done = true
+	completed := 0
+	for _, r := range fh.sourceReads {
+		if r.done {
+			completed++
+		}
+	}
+	kept := fh.sourceReads[:0]
+	for _, r := range fh.sourceReads {
+		if r.done && (completed > maxSourceReads || len(r.buf) == 0) {
+			// oldest first so the most recent reads are kept
+			completed--
+			continue
+		}
+		kept = append(kept, r)
+	}
+	clear(fh.sourceReads[len(kept):])
+	fh.sourceReads = kept
+	fh.cond.Broadcast()
+}
+
+// findSourceRead returns the source read other than self covering off
+// or nil if none was found
+//
+// The completed reads are preferred. Of those still in flight only the
+// ones registered before self are returned, so two reads in flight at
+// the same offset never wait for each other.
+//
+// call with lock held
+func (fh *RWFileHandle) findSourceRead(off int64, self *sourceRead) *sourceRead {
+	older := len(fh.sourceReads)
+	if i := slices.Index(fh.sourceReads, self); i >= 0 {
+		older = i
+	}
+	var inFlight *sourceRead
+	// search most recent first as it is the most likely to match
+	for i := len(fh.sourceReads) - 1; i >= 0; i-- {
+		sr := fh.sourceReads[i]
+		if sr == self || !sr.covers(off) {
+			continue
+		}
+		if sr.done {
+			return sr
+		}
+		if i < older && inFlight == nil {
+			inFlight = sr
+		}
+	}
+	return inFlight
+}
+
+// readRecentSource fills the start of p from the source reads covering
+// off, waiting for them to finish if they are still in flight.
+//
+// It returns the number of bytes served which may be 0.
+//
+// call with lock held
+func (fh *RWFileHandle) readRecentSource(p []byte, off int64, self *sourceRead) (n int) {
+	for n < len(p) && !fh.closed {
+		pos := off + int64(n)
+		sr := fh.findSourceRead(pos, self)
+		if sr == nil {
+			break
+		}
+		if !sr.done {
+			fs.Debugf(fh.remote, "waiting for in flight source read at %d to serve %d", sr.off, pos)
+			fh.cond.Wait()
+			continue
+		}
+		n += copy(p[n:], sr.buf[pos-sr.off:])
+	}
+	return n
+}
+
 // from read.go
 func (fh *RWFileHandle) seek(offset int64, reopen bool) (err error) {
 	if fh.noSeek {
@@ -546,7 +823,7 @@ func (fh *RWFileHandle) seek(offset int64, reopen bool) (err error) {
 	if !reopen {
 		ar := fh.r.GetAsyncReader()
 		// try to fulfill the seek with buffer discard
-		if ar != nil && ar.SkipBytes(int(offset-fh.offset)) {
+		if skip, ok := toInt(offset - fh.offset); ok && ar != nil && ar.SkipBytes(skip) {
 			fh.offset = offset
 			return nil
 		}
@@ -573,15 +850,7 @@ func (fh *RWFileHandle) seek(offset int64, reopen bool) (err error) {
 			fs.Debugf(fh.remote, "ReadFileHandle.Read seek close old failed: %v", err)
 		}
 		// re-open with a seek
-		o := fh.file.getObject()
-		opt := &fh.file.VFS().Opt
-		r = chunkedreader.New(fh.file.ctx, o, int64(opt.ChunkSize), int64(opt.ChunkSizeLimit), opt.ChunkStreams)
-		_, err := r.Seek(offset, 0)
-		if err != nil {
-			fs.Debugf(fh.remote, "ReadFileHandle.Read seek failed: %v", err)
-			return err
-		}
-		r, err = r.Open()
+		r, err = fh.openSource(fh.file.getObject(), offset)
 		if err != nil {
 			fs.Debugf(fh.remote, "ReadFileHandle.Read seek failed: %v", err)
 			return err
@@ -593,8 +862,16 @@ func (fh *RWFileHandle) seek(offset int64, reopen bool) (err error) {
 }
 
 // added from read.go and renamed with +Source
+//
+// Overlapping reads share the data of the source reads in flight or
+// recently completed so only the part not covered by them is fetched.
 func (fh *RWFileHandle) readAtSource(p []byte, off int64) (n int, err error) {
 	// defer log.Trace(fh.remote, "p[%d], off=%d", len(p), off)("n=%d, err=%v", &n, &err)
+	// fs.Debugf(fh.remote, "ReadFileHandle.Read size %d offset %d", reqSize, off)
+	if fh.closed {
+		fs.Errorf(fh.remote, "ReadFileHandle.Read error: %v", EBADF)
+		return 0, ECLOSED
+	}
 	err = fh.openPendingSource() // FIXME pending open could be more efficient in the presence of seek (and retries)
 	if err != nil {
 		return 0, err
@@ -602,14 +879,138 @@ func (fh *RWFileHandle) readAtSource(p []byte, off int64) (n int, err error) {
 	if fh.r == nil {
 		return 0, errors.New("read source is not open")
 	}
-	// fs.Debugf(fh.remote, "ReadFileHandle.Read size %d offset %d", reqSize, off)
+	sr := fh.addSourceRead(off, int64(len(p)))
+	defer func() {
+		fh.finishSourceRead(sr, p[:n])
+	}()
+	n = fh.readRecentSource(p, off, sr)
+	if n < len(p) {
+		if inSequence(fh.offset, off+int64(n), int64(len(p)-n)) {
+			waitSequentialSource("read", fh.remote, &fh.cond, time.Duration(fh.file.VFS().Opt.ReadWait), &fh.offset, off+int64(n), fh.closing)
+			n += fh.readRecentSource(p[n:], off+int64(n), sr)
+		}
+	}
 	if fh.closed {
-		fs.Errorf(fh.remote, "ReadFileHandle.Read error: %v", EBADF)
-		return 0, ECLOSED
+		// closed while waiting
+		return n, ECLOSED
+	}
+	if n == len(p) {
+		if n > 0 {
+			fs.Debugf(fh.remote, "ReadFileHandle.Read served %d bytes at %d from recent source reads", n, off)
+		}
+		return n, nil
+	}
+	var m int
+	m, err = fh.fetchSource(p[n:], off+int64(n))
+	n += m
+	return n, err
+}
+
+// Bounds of the pauses between the tries to open the source while the
+// remote has as many streams open as it allows
+const (
+	streamWaitMin = 100 * time.Millisecond
+	streamWaitMax = time.Second
+)
+
+// openSource opens o at offset for direct reads.
+//
+// If the remote has as many streams open as it allows the open is
+// tried again until one is closed, for up to Opt.HybridStreamWait, so
+// the reads queue up rather than fail. The lock is released while
+// waiting so the handle can still be read from the cache and closed,
+// which stops the wait.
+//
+// call with lock held
+func (fh *RWFileHandle) openSource(o fs.Object, offset int64) (r chunkedreader.ChunkedReader, err error) {
+	opt := &fh.file.VFS().Opt
+	deadline := time.Now().Add(time.Duration(opt.HybridStreamWait))
+	pause := streamWaitMin
+	waited := false
+	for {
+		r = chunkedreader.New(fh.file.ctx, o, fh.sourceChunkSize(), int64(opt.ChunkSizeLimit), opt.ChunkStreams)
+		if offset != 0 {
+			if _, err = r.Seek(offset, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+		r, err = r.Open()
+		if !errors.Is(err, fs.ErrorStreamLimit) || time.Now().Add(pause).After(deadline) {
+			return r, err
+		}
+		if !waited {
+			waited = true
+			fh.file.VFS().hybridStats.streamWaits.Add(1)
+			fs.Debugf(fh.remote, "ReadFileHandle.Read waiting for a stream: %v", err)
+			fh.openingSource = true
+			defer func() {
+				fh.openingSource = false
+				fh.cond.Broadcast()
+			}()
+		}
+		var stop error
+		fh.mu.Unlock()
+		select {
+		case <-time.After(pause):
+		case <-fh.closing:
+			stop = ECLOSED
+		case <-fh.file.ctx.Done():
+			stop = fh.file.ctx.Err()
+		}
+		fh.mu.Lock()
+		if stop == nil && fh.closed {
+			stop = ECLOSED
+		}
+		if stop != nil {
+			return nil, stop
+		}
+		pause = min(2*pause, streamWaitMax)
+	}
+}
+
+// sourceChunkSize returns the size of the first chunk to request from
+// the source, Opt.HybridReadAhead if set or the chunk size otherwise.
+func (fh *RWFileHandle) sourceChunkSize() int64 {
+	vfs := fh.file.VFS()
+	if readAhead := vfs.hybridReadAhead.Load(); readAhead > 0 {
+		return readAhead
+	}
+	return int64(vfs.Opt.ChunkSize)
+}
+
+// limitSource waits until n bytes read from the source are allowed
+// by the current Opt.HybridBwLimit. The limit is read each time so
+// changes made with options/set apply to open handles.
+//
+// call with lock held
+func (fh *RWFileHandle) limitSource(n int) {
+	limit := fh.file.VFS().hybridBwLimit.Load()
+	if limit <= 0 {
+		fh.limiter = nil
+		return
+	}
+	burst := int(min(limit, 1024*1024))
+	if fh.limiter == nil {
+		fh.limiter = rate.NewLimiter(rate.Limit(limit), burst)
+	} else if fh.limiter.Limit() != rate.Limit(limit) {
+		fh.limiter.SetLimit(rate.Limit(limit))
+		fh.limiter.SetBurst(burst)
+	}
+	for n > 0 {
+		chunk := min(n, burst)
+		if err := fh.limiter.WaitN(fh.file.ctx, chunk); err != nil {
+			return
+		}
+		n -= chunk
 	}
-	maxBuf := min(len(p), 1024*1024)
-	if gap := off - fh.offset; gap > 0 && gap < int64(8*maxBuf) {
-		waitSequentialSource("read", fh.remote, &fh.cond, time.Duration(fh.file.VFS().Opt.ReadWait), &fh.offset, off)
+}
+
+// fetchSource reads p at off from the source, seeking if necessary
+//
+// call with lock held
+func (fh *RWFileHandle) fetchSource(p []byte, off int64) (n int, err error) {
+	if fh.waitOpeningSource() {
+		return 0, ECLOSED
 	}
 	doSeek := off != fh.offset
 	if doSeek && fh.noSeek {
@@ -634,15 +1035,26 @@ func (fh *RWFileHandle) readAtSource(p []byte, off int64) (n int, err error) {
 		} else {
 			err = nil
 		}
+		if errors.Is(err, fs.ErrorRangeNotSatisfiable) && off >= fh.refreshSize() {
+			fs.Debugf(fh.remote, "ReadFileHandle.Read range not satisfiable, object shrunk to %d", fh.size)
+			return 0, io.EOF
+		}
 		if err == nil {
 			if reqSize > 0 {
 				fh.readCalled = true
 			}
 			n, err = io.ReadFull(fh.r, p)
+			fh.sourceFetched += int64(n)
+			fh.file.VFS().hybridStats.fetchedBytes.Add(int64(n))
+			fh.limitSource(n)
 			newOffset = fh.offset + int64(n)
 			// if err == nil && rand.Intn(10) == 0 {
 			// 	err = errors.New("random error")
 			// }
+			if (err == io.ErrUnexpectedEOF || err == io.EOF) && newOffset != fh.size && !fh.sizeUnknown {
+				// short read - check whether the object changed size
+				fh.refreshSize()
+			}
 			if err == nil {
 				break
 			} else if (err == io.ErrUnexpectedEOF || err == io.EOF) && (newOffset == fh.size || fh.sizeUnknown) {
@@ -656,7 +1068,7 @@ func (fh *RWFileHandle) readAtSource(p []byte, off int64) (n int, err error) {
 				break
 			}
 		}
-		if retries >= lowLevelRetries {
+		if retries >= lowLevelRetries || errors.Is(err, ECLOSED) {
 			break
 		}
 		retries++
@@ -693,6 +1105,13 @@ func (fh *RWFileHandle) ReadAt(b []byte, off int64) (n int, err error) {
 	fh.mu.Lock()
 	defer fh.mu.Unlock()
 	fs.Debugf("### read_write.go ReadAt CALLED / BEFORE-SWITCH ### ", "")
+	fromSource := false
+	defer func() {
+		if err != nil && err != io.EOF {
+			fh.errs.add(readMode(fh.currentDirectReadMode), off, err)
+		}
+		fh.file.VFS().hybridStats.addRead(fromSource, n)
+	}()
 
 	// jellygrail custom
 	// ----- univeral switch
@@ -700,14 +1119,35 @@ func (fh *RWFileHandle) ReadAt(b []byte, off int64) (n int, err error) {
 	// ------between fd.read RW ahead and RW simple
 	offset := off
 	size := int64(len(b))
-	itemSize := fh._size()
+	// use the same size for EOF whichever mode the read is done in
+	itemSize := fh.readSize()
+	if offset >= itemSize && fh.readOnly() && !fh.closed {
+		return 0, io.EOF
+	}
 	if offset+size > itemSize {
 		size = itemSize - offset
 	}
+	if size >= 0 && size < int64(len(b)) && !fh.sizeUnknown {
+		// Don't read past the end of the file in any mode as the
+		// cache file or the source may be longer if the size is stale
+		defer func() {
+			if err == nil {
+				err = io.EOF
+			}
+		}()
+		b = b[:size]
+	}
 	// r := ranges.Range{Pos: offset, Size: size} DEPRECATED
 
 	// present := fh.item.info.Rs.Present(r) DEPRECATED
 
+	if fh.bypassCache {
+		// too big to cache so don't touch the cache at all
+		fh.currentDirectReadMode = true
+		fromSource = true
+		return fh.readAtSource(b, off)
+	}
+
 	present := fh.item.GetInfoRsPresent(offset, size)
 
 	if !fh.item.AllowDirectReadUpdate() {
@@ -735,6 +1175,7 @@ func (fh *RWFileHandle) ReadAt(b []byte, off int64) (n int, err error) {
 		fs.Debugf("### read_write.go ReadAt CALLED / FLAG-MODE : Reads source only (slice missing) ### %s", "")
 		// ---- jellygrail custom
 
+		fromSource = true
 		return fh.readAtSource(b, off)
 	}
 }
@@ -744,7 +1185,7 @@ func (fh *RWFileHandle) Read(b []byte) (n int, err error) {
 	fh.mu.Lock()
 	defer fh.mu.Unlock()
 	fs.Debugf("### read_write.go Read CALLED NOT SUPPOSED TO BE ! ### %s", "")
-	if !fh.item.AllowDirectReadUpdate() {
+	if !fh.bypassCache && !fh.item.AllowDirectReadUpdate() {
 		fh.currentDirectReadMode = false
 		n, err = fh._readAt(b, fh.offset, false, false)
 		fh.offset += int64(n)
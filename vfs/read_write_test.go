@@ -5,13 +5,19 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/operations"
+	"github.com/rclone/rclone/fs/rc"
 	"github.com/rclone/rclone/fstest"
+	"github.com/rclone/rclone/fstest/mockobject"
+	"github.com/rclone/rclone/lib/random"
 	"github.com/rclone/rclone/vfs/vfscommon"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -729,6 +735,9 @@ func TestRWCacheUpdate(t *testing.T) {
 	if r.Fremote.Precision() == fs.ModTimeNotSupported {
 		t.Skip("skip as modtime not supported")
 	}
+	// JGCUSTOM: the cached data of files changed on the remote is kept,
+	// see Item._checkObject
+	t.Skip("skip as the cache keeps the data of files changed on the remote")
 
 	const filename = "TestRWCacheUpdate"
 
@@ -759,3 +768,715 @@ func TestRWCacheUpdate(t *testing.T) {
 		fstest.AssertTimeEqualWithPrecision(t, filename, modTime, fi.ModTime(), r.Fremote.Precision())
 	}
 }
+
+// Test overlapping reads from the source issued concurrently, as
+// kernel readahead does, only fetch the shared region once
+func TestRWFileHandleReadAtSourceOverlapping(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	opt.WriteBack = writeBackDelay
+	opt.ReadWait = fs.Duration(5 * time.Second)
+	r, vfs := newTestVFSOpt(t, &opt)
+
+	const blockSize = 128 * 1024
+	contents := random.String(4 * blockSize)
+	file1 := r.WriteObject(context.Background(), "file1", contents, t1)
+	r.CheckRemoteItems(t, file1)
+
+	h, err := vfs.OpenFile("file1", os.O_RDONLY, 0777)
+	require.NoError(t, err)
+	fh, ok := h.(*RWFileHandle)
+	require.True(t, ok)
+
+	readAtSource := func(off int64, done chan<- []byte) {
+		buf := make([]byte, blockSize)
+		fh.mu.Lock()
+		n, err := fh.readAtSource(buf, off)
+		fh.mu.Unlock()
+		assert.NoError(t, err)
+		done <- buf[:n]
+	}
+
+	// The later request arrives first and waits for the stream to
+	// reach it, the earlier one then fetches the shared region
+	later := make(chan []byte, 1)
+	go readAtSource(blockSize/2, later)
+	require.Eventually(t, func() bool {
+		fh.mu.Lock()
+		defer fh.mu.Unlock()
+		return len(fh.sourceReads) == 1
+	}, 5*time.Second, time.Millisecond)
+	earlier := make(chan []byte, 1)
+	go readAtSource(0, earlier)
+
+	assert.Equal(t, contents[:blockSize], string(<-earlier))
+	assert.Equal(t, contents[blockSize/2:blockSize/2+blockSize], string(<-later))
+
+	// The shared region should only have been fetched once
+	fh.mu.Lock()
+	assert.Equal(t, int64(blockSize+blockSize/2), fh.sourceFetched)
+	assert.Equal(t, int64(blockSize+blockSize/2), fh.offset)
+	fh.mu.Unlock()
+
+	// A sequential overlapping read is served from the recent reads
+	buf := make([]byte, blockSize)
+	fh.mu.Lock()
+	n, err := fh.readAtSource(buf, blockSize)
+	fetched := fh.sourceFetched
+	fh.mu.Unlock()
+	require.NoError(t, err)
+	assert.Equal(t, contents[blockSize:2*blockSize], string(buf[:n]))
+	assert.Equal(t, int64(2*blockSize), fetched)
+
+	require.NoError(t, fh.Close())
+}
+
+// Test two source reads in flight at the same offset don't wait for
+// each other and only fetch the data once
+func TestRWFileHandleReadAtSourceSameOffset(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	opt.WriteBack = writeBackDelay
+	opt.ReadWait = fs.Duration(100 * time.Millisecond)
+	r, vfs := newTestVFSOpt(t, &opt)
+
+	const blockSize = 64 * 1024
+	contents := random.String(2 * blockSize)
+	file1 := r.WriteObject(context.Background(), "file1", contents, t1)
+	r.CheckRemoteItems(t, file1)
+
+	h, err := vfs.OpenFile("file1", os.O_RDONLY, 0777)
+	require.NoError(t, err)
+	fh, ok := h.(*RWFileHandle)
+	require.True(t, ok)
+
+	readAtSource := func(done chan<- []byte) {
+		buf := make([]byte, blockSize)
+		fh.mu.Lock()
+		n, err := fh.readAtSource(buf, blockSize/2)
+		fh.mu.Unlock()
+		assert.NoError(t, err)
+		done <- buf[:n]
+	}
+
+	// The first read waits for the stream to reach it, the second
+	// one waits for the first
+	done := make(chan []byte, 2)
+	go readAtSource(done)
+	require.Eventually(t, func() bool {
+		fh.mu.Lock()
+		defer fh.mu.Unlock()
+		return len(fh.sourceReads) == 1
+	}, 5*time.Second, time.Millisecond)
+	go readAtSource(done)
+
+	for range 2 {
+		select {
+		case data := <-done:
+			assert.Equal(t, contents[blockSize/2:blockSize/2+blockSize], string(data))
+		case <-time.After(5 * time.Second):
+			t.Fatal("source reads at the same offset deadlocked")
+		}
+	}
+	fh.mu.Lock()
+	assert.Equal(t, int64(blockSize), fh.sourceFetched)
+	fh.mu.Unlock()
+
+	require.NoError(t, fh.Close())
+}
+
+// Test the errors remembered on a handle are bounded
+func TestRWFileHandleErrors(t *testing.T) {
+	var he handleErrors
+	assert.Equal(t, "no errors", he.String())
+
+	for i := range maxHandleErrors + 3 {
+		he.add(readMode(i%2 == 0), int64(i), fmt.Errorf("error %d", i))
+	}
+	errs, total := he.get()
+	assert.Equal(t, maxHandleErrors+3, total)
+	require.Len(t, errs, maxHandleErrors)
+	assert.Equal(t, int64(3), errs[0].off)
+	assert.Equal(t, "direct", errs[1].mode)
+	assert.Equal(t, fmt.Sprintf("error %d", maxHandleErrors+2), errs[maxHandleErrors-1].err.Error())
+	assert.Contains(t, he.String(), fmt.Sprintf("%d errors", maxHandleErrors+3))
+}
+
+// Test a remote size change between open and read is detected by the
+// direct read and the new size used for EOF in both modes
+func TestRWFileHandleSizeChanged(t *testing.T) {
+	r, vfs, fh := rwHandleCreateReadOnly(t)
+
+	// Replace the object with a smaller one after the open
+	file1 := r.WriteObject(context.Background(), "dir/file1", "0123456789", t2)
+	r.CheckRemoteItems(t, file1)
+
+	// Direct read sees a short read and refreshes the size
+	buf := make([]byte, 16)
+	fh.mu.Lock()
+	n, err := fh.readAtSource(buf, 0)
+	size := fh.size
+	fh.mu.Unlock()
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, "0123456789", string(buf[:n]))
+	assert.Equal(t, int64(10), size)
+
+	// The file has the new size
+	node, err := vfs.Stat("dir/file1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), node.Size())
+
+	// Reads past the new size are EOF whichever the mode
+	n, err = fh.ReadAt(buf, 12)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 0, n)
+	fh.mu.Lock()
+	n, err = fh._readAt(buf, 12, false, true)
+	fh.mu.Unlock()
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 0, n)
+
+	require.NoError(t, fh.Close())
+}
+
+// Test closing handles while reads are waiting for in-sequence data
+// doesn't deadlock and wakes the waiting reads - run with -race
+func TestRWFileHandleCloseWhileWaiting(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	opt.WriteBack = writeBackDelay
+	// longer than the test timeout so only the close wakes the reads
+	opt.ReadWait = fs.Duration(time.Hour)
+	r, vfs := newTestVFSOpt(t, &opt)
+
+	contents := random.String(64 * 1024)
+	file1 := r.WriteObject(context.Background(), "file1", contents, t1)
+	r.CheckRemoteItems(t, file1)
+
+	for i := range 50 {
+		h, err := vfs.OpenFile("file1", os.O_RDONLY, 0777)
+		require.NoError(t, err)
+		fh, ok := h.(*RWFileHandle)
+		require.True(t, ok)
+		fh.mu.Lock()
+		fh.currentDirectReadMode = i%2 == 0
+		fh.mu.Unlock()
+
+		var wg sync.WaitGroup
+		for j := range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// out of order so the later reads wait in sequence
+				buf := make([]byte, 1024)
+				fh.mu.Lock()
+				_, err := fh.readAtSource(buf, int64((4-j)*1024))
+				fh.mu.Unlock()
+				if err != nil && err != ECLOSED && err != io.EOF {
+					assert.NoError(t, err)
+				}
+			}()
+		}
+		if i%3 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		if i%2 == 0 {
+			assert.NoError(t, fh.Release())
+		} else {
+			assert.NoError(t, fh.Close())
+		}
+
+		waited := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(waited)
+		}()
+		select {
+		case <-waited:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("reads still waiting %d after close", i)
+		}
+	}
+}
+
+// Test the hybrid stats count the bytes read in each mode
+func TestRWFileHandleHybridStats(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	opt.WriteBack = writeBackDelay
+	opt.HybridCheckDir = t.TempDir()
+	r, vfs := newTestVFSOpt(t, &opt)
+	ctx := context.Background()
+	file1 := r.WriteObject(ctx, "direct", "0123456789abcdef", t1)
+	file2 := r.WriteObject(ctx, "cached", "0123456789", t1)
+	r.CheckRemoteItems(t, file1, file2)
+
+	// flag "direct" for direct reads
+	flagDir := filepath.Join(opt.HybridCheckDir, r.Fremote.Name())
+	require.NoError(t, os.MkdirAll(flagDir, 0777))
+	require.NoError(t, os.WriteFile(filepath.Join(flagDir, "direct"), nil, 0666))
+
+	read := func(name string) {
+		h, err := vfs.OpenFile(name, os.O_RDONLY, 0777)
+		require.NoError(t, err)
+		buf := make([]byte, 32)
+		_, err = h.ReadAt(buf, 0)
+		assert.Equal(t, io.EOF, err)
+		require.NoError(t, h.Close())
+	}
+	read("direct")
+	read("cached")
+
+	assert.Equal(t, rc.Params{
+		"cacheBytes":         int64(10),
+		"directBytes":        int64(16),
+		"directFetchedBytes": int64(16),
+		"directStreamWaits":  int64(0),
+	}, vfs.Stats()["hybrid"])
+}
+
+// Test the reads of a flagged handle served from the cache never see
+// a range half written while the ranges are filled in by the
+// downloaders of the item - run with -race
+func TestRWFileHandleHybridReadWhileCaching(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	opt.WriteBack = writeBackDelay
+	opt.Hybrid = true
+	opt.HybridCheckDir = t.TempDir()
+	r, vfs := newTestVFSOpt(t, &opt)
+	const (
+		size  = 1 << 20
+		chunk = 64 << 10
+	)
+	contents := random.String(size)
+	file1 := r.WriteObject(context.Background(), "file1", contents, t1)
+	r.CheckRemoteItems(t, file1)
+
+	// flag the file for direct reads
+	flagDir := filepath.Join(opt.HybridCheckDir, r.Fremote.Name())
+	require.NoError(t, os.MkdirAll(flagDir, 0777))
+	require.NoError(t, os.WriteFile(filepath.Join(flagDir, "file1"), nil, 0666))
+
+	h, err := vfs.OpenFile("file1", os.O_RDONLY, 0777)
+	require.NoError(t, err)
+	fh, ok := h.(*RWFileHandle)
+	require.True(t, ok)
+	require.NoError(t, fh.openPending())
+	require.True(t, fh.item.AllowDirectReadUpdate())
+	before := vfs.Stats()["hybrid"].(rc.Params)["cacheBytes"].(int64)
+
+	// The chunks are cached in any order by the downloaders as they
+	// are for the handles which opened the file before it was flagged
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		buf := make([]byte, chunk)
+		for _, i := range rand.Perm(size / chunk) {
+			_, err := fh.item.ReadAt(buf, int64(i*chunk), false)
+			assert.NoError(t, err)
+		}
+	}()
+	// while the handle reads the same ranges, from the cache once
+	// they are present
+	buf := make([]byte, chunk/4)
+	for reading := true; reading; {
+		select {
+		case <-done:
+			reading = false
+		default:
+		}
+		off := rand.Int63n(size - int64(len(buf)))
+		n, err := fh.ReadAt(buf, off)
+		require.NoError(t, err)
+		require.Equal(t, contents[off:off+int64(n)], string(buf[:n]))
+	}
+	wg.Wait()
+	assert.True(t, fh.item.GetInfoRsPresent(0, size))
+
+	// the last reads were from the cache
+	n, err := fh.ReadAt(buf, 0)
+	require.NoError(t, err)
+	assert.Equal(t, contents[:n], string(buf[:n]))
+	assert.Greater(t, vfs.Stats()["hybrid"].(rc.Params)["cacheBytes"].(int64), before)
+
+	require.NoError(t, fh.Close())
+}
+
+// streamLimitObject is an object of a remote which allows limit
+// streams open at once
+type streamLimitObject struct {
+	*mockobject.ContentMockObject
+	mu    sync.Mutex
+	open  int
+	limit int
+}
+
+// Open the object, failing with fs.ErrorStreamLimit if limit streams
+// are open already
+func (o *streamLimitObject) Open(ctx context.Context, options ...fs.OpenOption) (io.ReadCloser, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.open >= o.limit {
+		return nil, fs.ErrorStreamLimit
+	}
+	in, err := o.ContentMockObject.Open(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	o.open++
+	return &streamLimitReader{ReadCloser: in, o: o}, nil
+}
+
+// streamLimitReader gives back its stream when closed
+type streamLimitReader struct {
+	io.ReadCloser
+	o    *streamLimitObject
+	once sync.Once
+}
+
+// Close the stream
+func (r *streamLimitReader) Close() error {
+	r.once.Do(func() {
+		r.o.mu.Lock()
+		r.o.open--
+		r.o.mu.Unlock()
+	})
+	return r.ReadCloser.Close()
+}
+
+// Test direct reads opened over the stream limit of the remote wait
+// for a stream rather than fail
+func TestRWFileHandleStreamLimit(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	opt.WriteBack = writeBackDelay
+	opt.HybridStreamWait = fs.Duration(10 * time.Second)
+	opt.NoChecksum = true // the mock object has no Fs
+	r, vfs := newTestVFSOpt(t, &opt)
+	contents := "0123456789abcdef"
+	file1 := r.WriteObject(context.Background(), "file1", contents, t1)
+	r.CheckRemoteItems(t, file1)
+	o := &streamLimitObject{
+		ContentMockObject: mockobject.New("file1").WithContent([]byte(contents), mockobject.SeekModeNone),
+		limit:             2,
+	}
+
+	readAtSource := func(fh *RWFileHandle, done chan<- error) {
+		buf := make([]byte, 4)
+		fh.mu.Lock()
+		n, err := fh.readAtSource(buf, 4)
+		fh.mu.Unlock()
+		if err == nil && string(buf[:n]) != contents[4:8] {
+			err = fmt.Errorf("read %q", buf[:n])
+		}
+		done <- err
+	}
+	var fhs []*RWFileHandle
+	for range 3 {
+		h, err := vfs.OpenFile("file1", os.O_RDONLY, 0777)
+		require.NoError(t, err)
+		fh, ok := h.(*RWFileHandle)
+		require.True(t, ok)
+		fh.file.setObjectNoUpdate(o)
+		fhs = append(fhs, fh)
+	}
+
+	// The first handles take the streams
+	for _, fh := range fhs[:2] {
+		done := make(chan error, 1)
+		readAtSource(fh, done)
+		require.NoError(t, <-done)
+	}
+
+	// the next one waits for one of them
+	done := make(chan error, 1)
+	go readAtSource(fhs[2], done)
+	require.Eventually(t, func() bool {
+		return vfs.hybridStats.streamWaits.Load() == 1
+	}, 5*time.Second, time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("read didn't wait for a stream: %v", err)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	// and reads once it is closed
+	require.NoError(t, fhs[0].Close())
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("read still waiting after a stream was closed")
+	}
+	for _, fh := range fhs[1:] {
+		require.NoError(t, fh.Close())
+	}
+	o.mu.Lock()
+	assert.Zero(t, o.open)
+	o.mu.Unlock()
+
+	// Reads which can't get a stream in time fail
+	vfs.Opt.HybridStreamWait = 0
+	o.limit = 0
+	h, err := vfs.OpenFile("file1", os.O_RDONLY, 0777)
+	require.NoError(t, err)
+	fh := h.(*RWFileHandle)
+	fh.mu.Lock()
+	_, err = fh.readAtSource(make([]byte, 4), 0)
+	fh.mu.Unlock()
+	assert.ErrorIs(t, err, fs.ErrorStreamLimit)
+	require.NoError(t, h.Close())
+}
+
+// Test reads waiting for a stream don't hold the lock of the handle
+// and stop waiting when it is closed
+func TestRWFileHandleStreamLimitClose(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	opt.WriteBack = writeBackDelay
+	opt.HybridStreamWait = fs.Duration(10 * time.Second)
+	opt.NoChecksum = true // the mock object has no Fs
+	r, vfs := newTestVFSOpt(t, &opt)
+	contents := "0123456789abcdef"
+	file1 := r.WriteObject(context.Background(), "file1", contents, t1)
+	r.CheckRemoteItems(t, file1)
+	o := &streamLimitObject{
+		ContentMockObject: mockobject.New("file1").WithContent([]byte(contents), mockobject.SeekModeNone),
+		limit:             0,
+	}
+
+	h, err := vfs.OpenFile("file1", os.O_RDONLY, 0777)
+	require.NoError(t, err)
+	fh, ok := h.(*RWFileHandle)
+	require.True(t, ok)
+	fh.file.setObjectNoUpdate(o)
+
+	done := make(chan error, 1)
+	go func() {
+		fh.mu.Lock()
+		_, err := fh.readAtSource(make([]byte, 4), 4)
+		fh.mu.Unlock()
+		done <- err
+	}()
+	require.Eventually(t, func() bool {
+		return vfs.hybridStats.streamWaits.Load() == 1
+	}, 5*time.Second, time.Millisecond)
+
+	// the lock is free while the read waits
+	require.Eventually(t, func() bool {
+		if !fh.mu.TryLock() {
+			return false
+		}
+		fh.mu.Unlock()
+		return true
+	}, 5*time.Second, time.Millisecond)
+
+	// and closing the handle stops the wait
+	start := time.Now()
+	require.NoError(t, h.Close())
+	assert.Less(t, time.Since(start), 5*time.Second)
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ECLOSED)
+	case <-time.After(5 * time.Second):
+		t.Fatal("read still waiting after the handle was closed")
+	}
+	o.mu.Lock()
+	assert.Zero(t, o.open)
+	o.mu.Unlock()
+}
+
+func TestRWFileHandleCacheMaxFileSize(t *testing.T) {
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	opt.WriteBack = writeBackDelay
+	opt.CacheMaxFileSize = 12
+	r, vfs := newTestVFSOpt(t, &opt)
+	ctx := context.Background()
+	file1 := r.WriteObject(ctx, "big", "0123456789abcdef", t1)
+	file2 := r.WriteObject(ctx, "small", "0123456789", t1)
+	r.CheckRemoteItems(t, file1, file2)
+
+	read := func(name string, contents string) {
+		h, err := vfs.OpenFile(name, os.O_RDONLY, 0777)
+		require.NoError(t, err)
+		buf := make([]byte, 4)
+		for off := 0; off < len(contents); off += len(buf) {
+			n, err := h.ReadAt(buf, int64(off))
+			if err != io.EOF {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, contents[off:off+n], string(buf[:n]))
+		}
+		require.NoError(t, h.Close())
+	}
+	for range 3 {
+		read("big", "0123456789abcdef")
+		read("small", "0123456789")
+	}
+
+	assert.False(t, vfs.cache.Exists("big"))
+	assert.True(t, vfs.cache.Exists("small"))
+	stats := vfs.Stats()["hybrid"].(rc.Params)
+	assert.Equal(t, int64(3*16), stats["directBytes"])
+	assert.Equal(t, int64(3*10), stats["cacheBytes"])
+}
+
+func TestRWFileHandleSourceOpenFailed(t *testing.T) {
+	for _, mixed := range []bool{false, true} {
+		t.Run(fmt.Sprintf("mixed=%v", mixed), func(t *testing.T) {
+			opt := vfscommon.Opt
+			opt.CacheMode = vfscommon.CacheModeFull
+			opt.WriteBack = writeBackDelay
+			r, vfs := newTestVFSOpt(t, &opt)
+			ctx := context.Background()
+			file1 := r.WriteObject(ctx, "file1", "0123456789abcdef", t1)
+			r.CheckRemoteItems(t, file1)
+
+			h, err := vfs.OpenFile("file1", os.O_RDONLY, 0777)
+			require.NoError(t, err)
+			fh, ok := h.(*RWFileHandle)
+			require.True(t, ok)
+
+			if mixed {
+				// Open the cache item as a cache mode read would
+				fh.mu.Lock()
+				err = fh.openPending()
+				fh.mu.Unlock()
+				require.NoError(t, err)
+				assert.True(t, vfs.cache.InUse("file1"))
+			}
+
+			// Switch to direct reads then make the source fail to open
+			fh.bypassCache = true
+			obj, err := r.Fremote.NewObject(ctx, "file1")
+			require.NoError(t, err)
+			require.NoError(t, obj.Remove(ctx))
+
+			buf := make([]byte, 4)
+			_, err = fh.ReadAt(buf, 0)
+			require.Error(t, err)
+			assert.False(t, fh.openedSource)
+
+			assert.NotPanics(t, func() {
+				assert.NoError(t, fh.Flush())
+				assert.NoError(t, fh.Flush())
+				assert.NoError(t, fh.Release())
+				assert.NoError(t, fh.Release())
+				assert.Equal(t, ECLOSED, fh.Close())
+			})
+			assert.False(t, vfs.cache.InUse("file1"))
+		})
+	}
+}
+
+// Test reads at the end of the file return exactly the bytes up to
+// EOF whether they come from the cache, the source or both.
+func TestRWFileHandleTailReads(t *testing.T) {
+	const size = 10000
+	contents := random.String(size)
+	type tailRead struct {
+		name string
+		off  int64
+		len  int
+	}
+	reads := []tailRead{
+		{"last byte", size - 1, 1},
+		{"last byte big buffer", size - 1, 4096},
+		{"last 4k", size - 4096, 4096},
+		{"last 4k big buffer", size - 4096, 8192},
+		{"at EOF", size, 1},
+		{"past EOF", size + 10, 4096},
+		{"whole file big buffer", 0, 2 * size},
+	}
+	paths := []struct {
+		name    string
+		prepare func(opt *vfscommon.Options)
+		cached  int64 // bytes read into the cache before the tail read
+	}{
+		{"cache", func(opt *vfscommon.Options) {}, size},
+		{"direct", func(opt *vfscommon.Options) { opt.CacheMaxFileSize = 0 }, 0},
+		{"split", func(opt *vfscommon.Options) {}, size - 6000},
+	}
+	for _, path := range paths {
+		for _, read := range reads {
+			t.Run(path.name+"/"+read.name, func(t *testing.T) {
+				opt := vfscommon.Opt
+				opt.CacheMode = vfscommon.CacheModeFull
+				opt.WriteBack = writeBackDelay
+				path.prepare(&opt)
+				r, vfs := newTestVFSOpt(t, &opt)
+				file1 := r.WriteObject(context.Background(), "file1", contents, t1)
+				r.CheckRemoteItems(t, file1)
+
+				h, err := vfs.OpenFile("file1", os.O_RDONLY, 0777)
+				require.NoError(t, err)
+				if path.cached > 0 {
+					buf := make([]byte, path.cached)
+					n, err := h.ReadAt(buf, 0)
+					require.NoError(t, err)
+					require.Equal(t, int(path.cached), n)
+				}
+
+				buf := make([]byte, read.len)
+				for i := range buf {
+					buf[i] = 0xAA
+				}
+				n, err := h.ReadAt(buf, read.off)
+				want := ""
+				if read.off < size {
+					want = contents[read.off:min(read.off+int64(read.len), size)]
+				}
+				assert.Equal(t, len(want), n)
+				assert.Equal(t, want, string(buf[:n]))
+				if n < read.len {
+					assert.Equal(t, io.EOF, err)
+					for i := n; i < read.len; i++ {
+						if buf[i] != 0xAA {
+							t.Fatalf("byte %d after EOF overwritten", i)
+						}
+					}
+				} else {
+					assert.NoError(t, err)
+				}
+				require.NoError(t, h.Close())
+			})
+		}
+	}
+}
+
+// Test a read at the end of a file whose object has shrunk doesn't
+// return the stale bytes of the cache file past the new end.
+func TestRWFileHandleTailReadsStaleSize(t *testing.T) {
+	const size = 10000
+	contents := random.String(size)
+	opt := vfscommon.Opt
+	opt.CacheMode = vfscommon.CacheModeFull
+	opt.WriteBack = writeBackDelay
+	r, vfs := newTestVFSOpt(t, &opt)
+	file1 := r.WriteObject(context.Background(), "file1", contents, t1)
+	r.CheckRemoteItems(t, file1)
+
+	h, err := vfs.OpenFile("file1", os.O_RDONLY, 0777)
+	require.NoError(t, err)
+	fh, ok := h.(*RWFileHandle)
+	require.True(t, ok)
+	buf := make([]byte, size)
+	_, err = fh.ReadAt(buf, 0)
+	require.NoError(t, err)
+
+	// The listing now has the object 10 bytes shorter
+	const newSize = size - 10
+	fh.file.setObjectNoUpdate(mockobject.New("file1").WithContent([]byte(contents[:newSize]), mockobject.SeekModeNone))
+
+	buf = make([]byte, 4096)
+	n, err := fh.ReadAt(buf, newSize-1)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, contents[newSize-1:newSize], string(buf[:n]))
+	require.NoError(t, h.Close())
+}
@@ -189,6 +189,44 @@ type VFS struct {
 	usage       *fs.Usage
 	pollChan    chan time.Duration
 	inUse       atomic.Int32 // count of number of opens
+
+	// live values of the options which can be changed with options/set
+	hybridReadAhead atomic.Int64 // Opt.HybridReadAhead
+	hybridBwLimit   atomic.Int64 // Opt.HybridBwLimit
+
+	hybridStats hybridStats // counters of the hybrid read path
+}
+
+// hybridStats counts the bytes read by RWFileHandles in each mode for
+// vfs/stats. They are updated on every read so are atomics.
+type hybridStats struct {
+	cacheBytes   atomic.Int64 // bytes returned from the cache
+	directBytes  atomic.Int64 // bytes returned by direct reads of the source
+	fetchedBytes atomic.Int64 // bytes fetched from the source by direct reads
+	streamWaits  atomic.Int64 // direct reads which waited for a stream of the source
+}
+
+// addRead counts n bytes returned to a reader from the source if
+// direct is set or from the cache otherwise.
+func (s *hybridStats) addRead(direct bool, n int) {
+	if n <= 0 {
+		return
+	}
+	if direct {
+		s.directBytes.Add(int64(n))
+	} else {
+		s.cacheBytes.Add(int64(n))
+	}
+}
+
+// params returns the counters for vfs/stats
+func (s *hybridStats) params() rc.Params {
+	return rc.Params{
+		"cacheBytes":         s.cacheBytes.Load(),
+		"directBytes":        s.directBytes.Load(),
+		"directFetchedBytes": s.fetchedBytes.Load(),
+		"directStreamWaits":  s.streamWaits.Load(),
+	}
 }
 
 // Keep track of active VFS keyed on fs.ConfigString(f)
@@ -226,13 +264,14 @@ func New(ctx context.Context, f fs.Fs, opt *vfscommon.Options) *VFS {
 
 	// Fill out anything else
 	vfs.Opt.Init(ctx)
+	vfs.setDynamicOptions(&vfs.Opt)
 
 	// Find a VFS with the same name and options and return it if possible
 	activeMu.Lock()
 	defer activeMu.Unlock()
 	configName := fs.ConfigString(f)
 	for _, activeVFS := range active[configName] {
-		if vfs.Opt == activeVFS.Opt {
+		if sameStaticOptions(vfs.Opt, activeVFS.Opt) {
 			fs.Debugf(f, "Reusing VFS from active cache")
 			activeVFS.inUse.Add(1)
 			cancel()
@@ -321,8 +360,9 @@ func (vfs *VFS) signalHandler(ctx context.Context) {
 func (vfs *VFS) Stats() (out rc.Params) {
 	out = make(rc.Params)
 	out["fs"] = fs.ConfigString(vfs.f)
-	out["opt"] = vfs.Opt
+	out["opt"] = vfs.Options()
 	out["inUse"] = vfs.inUse.Load()
+	out["hybrid"] = vfs.hybridStats.params()
 
 	var (
 		dirs  int
@@ -343,6 +383,48 @@ func (vfs *VFS) Stats() (out rc.Params) {
 	return out
 }
 
+// Options returns a copy of the options in use including the current
+// values of the ones which can be changed dynamically.
+func (vfs *VFS) Options() vfscommon.Options {
+	opt := vfs.Opt
+	opt.HybridReadAhead = fs.SizeSuffix(vfs.hybridReadAhead.Load())
+	opt.HybridBwLimit = fs.SizeSuffix(vfs.hybridBwLimit.Load())
+	return opt
+}
+
+// setDynamicOptions sets the live values of the options which can be
+// changed while the VFS is running from opt.
+func (vfs *VFS) setDynamicOptions(opt *vfscommon.Options) {
+	vfs.hybridReadAhead.Store(int64(opt.HybridReadAhead))
+	vfs.hybridBwLimit.Store(int64(opt.HybridBwLimit))
+}
+
+// sameStaticOptions returns true if a and b only differ in the
+// options which can be changed dynamically.
+func sameStaticOptions(a, b vfscommon.Options) bool {
+	a.HybridReadAhead, b.HybridReadAhead = 0, 0
+	a.HybridBwLimit, b.HybridBwLimit = 0, 0
+	return a == b
+}
+
+// reloadOptions is called when the global VFS options are changed
+// with options/set and updates the dynamic options of all the active
+// VFSes. Handles pick up the new values on their next read.
+func reloadOptions(ctx context.Context, opt *vfscommon.Options) {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	for _, vfses := range active {
+		for _, vfs := range vfses {
+			fs.Debugf(vfs.f, "Setting hybrid read ahead %v and bwlimit %v", opt.HybridReadAhead, opt.HybridBwLimit)
+			vfs.setDynamicOptions(opt)
+		}
+	}
+}
+
+func init() {
+	vfscommon.OnReload(reloadOptions)
+}
+
 // Return the number of active cache entries and a VFS if any are in
 // the cache.
 func activeCacheEntries() (vfs *VFS, count int) {
@@ -467,7 +467,7 @@ func TestCachePurgeClean(t *testing.T) {
 
 	// Read something to instantiate the cache file
 	buf := make([]byte, 10)
-	_, err = potato1.ReadAt(buf, 10)
+	_, err = potato1.ReadAt(buf, 10, false)
 	require.NoError(t, err)
 
 	// Test cache file present
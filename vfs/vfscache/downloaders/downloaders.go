@@ -151,6 +151,11 @@ func (dls *Downloaders) _countErrors(n int64, err error) {
 		}
 		return
 	}
+	if errors.Is(err, fs.ErrorContentNotReady) || errors.Is(err, fs.ErrorStreamLimit) {
+		// not a failure of the download so don't count it
+		fs.Infof(dls.src, "vfs cache: downloader: %v", err)
+		return
+	}
 	if err != nil {
 		//if err != syscall.ENOSPC {
 		dls.errorCount++
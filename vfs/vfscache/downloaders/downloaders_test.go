@@ -2,12 +2,15 @@ package downloaders
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"sync"
 	"testing"
 	"time"
 
 	_ "github.com/rclone/rclone/backend/local"
+	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fs/operations"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/lib/ranges"
@@ -127,3 +130,18 @@ func TestDownloaders(t *testing.T) {
 		}, 10*time.Second, 10*time.Millisecond)
 	})
 }
+
+func TestCountErrorsContentNotReady(t *testing.T) {
+	dls := &Downloaders{}
+	notReady := fmt.Errorf("open: %w", &fs.ContentNotReadyError{Progress: 10})
+	for range maxErrorCount + 1 {
+		dls.countErrors(0, notReady)
+	}
+	assert.Equal(t, 0, dls.errorCount)
+	assert.NoError(t, dls.lastErr)
+
+	dls.countErrors(0, errors.New("potato"))
+	assert.Equal(t, 1, dls.errorCount)
+	dls.countErrors(100, nil)
+	assert.Equal(t, 0, dls.errorCount)
+}
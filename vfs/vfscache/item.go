@@ -86,6 +86,7 @@ type Info struct {
 	Rs          ranges.Ranges // which parts of the file are present
 	Fingerprint string        // fingerprint of remote object
 	Dirty       bool          // set if the backing file has been modified
+	Pinned      bool          // set if the item was warmed and shouldn't be removed for age
 }
 
 // Items are a slice of *Item ordered by ATime
@@ -871,13 +872,21 @@ func (item *Item) reload(ctx context.Context) error {
 //
 // call with lock held
 func (item *Item) _checkObject(o fs.Object) error {
+	// If the remote never changes the contents of its objects then
+	// a changed or missing fingerprint is just metadata jitter (or
+	// a transient outage) and the cached data is known to be good.
+	immutable := item.c.fremote.Features().Immutable
 	if o == nil {
 		if item.info.Fingerprint != "" {
 			// no remote object && local object
 			// remove local object unless dirty
 			if !item.info.Dirty {
 				// item._remove("stale (remote deleted)")
-				fs.Debugf(item.name, "vfs cache: remote object has gone but local object modified : JGCUSTOM : -NOT- removing it")
+				if immutable {
+					fs.Debugf(item.name, "vfs cache: remote object has gone but remote is immutable - keeping it")
+				} else {
+					fs.Debugf(item.name, "vfs cache: remote object has gone but local object modified : JGCUSTOM : -NOT- removing it")
+				}
 			} else {
 				fs.Debugf(item.name, "vfs cache: remote object has gone but local object modified - keeping it")
 			}
@@ -892,7 +901,11 @@ func (item *Item) _checkObject(o fs.Object) error {
 			// remote object && local object
 			if remoteFingerprint != item.info.Fingerprint {
 				if !item.info.Dirty {
-					fs.Debugf(item.name, "vfs cache: JGCUSTOM : -NOT- removing cached entry as stale (remote fingerprint %q != cached fingerprint %q)", remoteFingerprint, item.info.Fingerprint)
+					if immutable {
+						fs.Debugf(item.name, "vfs cache: remote is immutable - keeping cached entry (remote fingerprint %q != cached fingerprint %q)", remoteFingerprint, item.info.Fingerprint)
+					} else {
+						fs.Debugf(item.name, "vfs cache: JGCUSTOM : -NOT- removing cached entry as stale (remote fingerprint %q != cached fingerprint %q)", remoteFingerprint, item.info.Fingerprint)
+					}
 					// item._remove("stale (remote is different)") jellygrail custom : sometimes this is triggered by error, maybe the fingerprint returned by the remote is wrong and thus the cache is deleted.
 					// We dont need to delete the cache in this fork as files are RO and don't change
 					item.info.Fingerprint = remoteFingerprint
@@ -909,6 +922,9 @@ func (item *Item) _checkObject(o fs.Object) error {
 	}
 	item.o = o
 
+	// before the truncate extends the file to the size of the object
+	item._repairRanges()
+
 	err := item._truncateToCurrentSize()
 	if err != nil {
 		return fmt.Errorf("vfs cache item: open truncate failed: %w", err)
@@ -917,6 +933,43 @@ func (item *Item) _checkObject(o fs.Object) error {
 	return nil
 }
 
+// _repairRanges drops the ranges the metadata has present beyond the
+// end of the cache file, as when the file was cut short or replaced
+// after the metadata was saved, so they are read from the source again
+// rather than served as the zeros the file is extended with.
+//
+// As the size of the cache file is taken as the size of the item, the
+// file is extended back to the size of the object unless it is dirty.
+//
+// call with lock held
+func (item *Item) _repairRanges() {
+	if len(item.info.Rs) == 0 {
+		return
+	}
+	osPath := item.c.toOSPath(item.name)
+	fi, err := os.Stat(osPath)
+	if err != nil {
+		// a missing file is dealt with by _truncate
+		return
+	}
+	size := fi.Size()
+	if item.info.Rs[len(item.info.Rs)-1].End() <= size {
+		return
+	}
+	fs.Errorf(item.name, "vfs cache: dropping ranges %v present beyond the end of the %d byte cache file", item.info.Rs, size)
+	item.info.Rs = item.info.Rs.Intersection(ranges.Range{Pos: 0, Size: size})
+	if !item.info.Dirty && item.o != nil && item.o.Size() > size {
+		err = os.Truncate(osPath, item.o.Size())
+		if err != nil {
+			fs.Errorf(item.name, "vfs cache: failed to extend repaired cache file: %v", err)
+		}
+	}
+	err = item._save()
+	if err != nil {
+		fs.Errorf(item.name, "vfs cache: failed to save repaired ranges: %v", err)
+	}
+}
+
 // WrittenBack checks to see if the item has been written back or not
 func (item *Item) WrittenBack() bool {
 	item.mu.Lock()
@@ -998,7 +1051,7 @@ func (item *Item) RemoveNotInUse(maxAge time.Duration, emptyOnly bool) (removed
 	if maxAge == 0 {
 		removeIt = true // quota-driven removal
 	}
-	if maxAge != 0 {
+	if maxAge != 0 && !item.info.Pinned {
 		cutoff := time.Now().Add(-maxAge)
 		// If not locked and access time too long ago - delete the file
 		accessTime := item.info.ATime
@@ -1259,6 +1312,44 @@ func (item *Item) _ensure(offset, size int64) (err error) {
 	return item.downloaders.Download(r)
 }
 
+// EnsureRange makes sure the range offset, size of o is present in
+// the cache file, downloading it if necessary, and pins the item so
+// the cache cleaner doesn't remove it for age. Pinned items are still
+// removed if the cache is over quota.
+//
+// The item is opened for the duration of the call so it is safe to
+// call while the file is open and being read elsewhere.
+func (item *Item) EnsureRange(o fs.Object, offset, size int64) (err error) {
+	err = item.Open(o)
+	if err != nil {
+		return fmt.Errorf("vfs cache: EnsureRange failed to open item: %w", err)
+	}
+	defer func() {
+		closeErr := item.Close(nil)
+		if err == nil {
+			err = closeErr
+		}
+	}()
+	item.preAccess()
+	defer item.postAccess()
+	item.mu.Lock()
+	defer item.mu.Unlock()
+	offset = max(offset, 0)
+	if offset >= item.info.Size {
+		return nil
+	}
+	r := ranges.Range{Pos: offset, Size: size}
+	r.Clip(item.info.Size)
+	if !item.info.Rs.Present(r) {
+		err = item._ensure(r.Pos, r.Size)
+		if err != nil {
+			return fmt.Errorf("vfs cache: EnsureRange failed to download %+v: %w", r, err)
+		}
+	}
+	item.info.Pinned = true
+	return nil
+}
+
 // _written marks the (offset, size) as present in the backing file
 //
 // This is called by the downloader downloading file segments and the
@@ -1268,9 +1359,15 @@ func (item *Item) _ensure(offset, size int64) (err error) {
 // of the caller as we don't know here whether we are adding reads or
 // writes to the cache file.
 //
+// Nothing is marked for an empty or failed transfer which wrote no
+// bytes, so its range is read from the source again.
+//
 // call with lock held
 func (item *Item) _written(offset, size int64) {
 	// defer log.Trace(item.name, "offset=%d, size=%d", offset, size)("")
+	if size <= 0 {
+		return
+	}
 	item.info.Rs.Insert(ranges.Range{Pos: offset, Size: size})
 }
 
@@ -1396,8 +1493,9 @@ func (item *Item) readAt(b []byte, off int64, DirectReadModeROCache bool) (n int
 	}
 
 	item.info.ATime = time.Now()
-	// Do the reading with Item.mu unlocked and cache protected by preAccess
-
+	// Read with item.mu held so a range found present by the caller
+	// can't be read while the downloaders are writing it, see
+	// WriteAtNoOverwrite
 	n, err = item.fd.ReadAt(b, off)
 	return n, err
 }
@@ -1419,21 +1517,21 @@ func (item *Item) WriteAt(b []byte, off int64) (n int, err error) {
 		err = fmt.Errorf("short write: tried to write %d but only %d written", len(b), n)
 	}
 	item.mu.Lock()
-	item._written(off, int64(n))
-
 	if n > 0 {
+		item._written(off, int64(n))
 		item._dirty()
 	}
 	end := off + int64(n)
 	// Writing off the end of the file so need to make some
 	// zeroes.  we do this by showing that we have written to the
-	// new parts of the file.
-	if off > item.info.Size {
+	// new parts of the file - only if the write extended the file
+	// as otherwise the gap isn't in it.
+	if off > item.info.Size && n > 0 {
 		item._written(item.info.Size, off-item.info.Size)
 		item._dirty()
 	}
 	// Update size
-	if end > item.info.Size {
+	if end > item.info.Size && n > 0 {
 		item.info.Size = end
 	}
 	item.mu.Unlock()
@@ -1445,7 +1543,10 @@ func (item *Item) WriteAt(b []byte, off int64) (n int, err error) {
 // TODO : if allowDirectRead is true, check deeper if we need to be in source mode or in cache mode (in the calling function ? by checking if offset requested is within downloaded ranges, if so, switch in cache mode)
 
 func (item *Item) AllowDirectReadUpdate() bool {
-	cacheDonePath := filepath.Join("/Cache_Check_Video_Library/cache_check", item.c.fremote.Name())
+	if !item.c.opt.Hybrid {
+		return false
+	}
+	cacheDonePath := filepath.Join(item.c.opt.HybridCheckDir, item.c.fremote.Name())
 
 	// Extensions that directly triggers direct-read, TODO-jellygrail : take these allowed extensions from general config
 	// so in the end, every file are RW cached at some point but :
@@ -1488,6 +1589,10 @@ func (item *Item) AllowDirectReadUpdate() bool {
 //
 // This is used by the downloader to write bytes to the file.
 //
+// The ranges are only marked present once written, with item.mu held
+// throughout, so the cache only reads of RO mode never see a range
+// half written.
+//
 // It returns n the total bytes processed and skipped the number of
 // bytes which were processed but not actually written to the file.
 func (item *Item) WriteAtNoOverwrite(b []byte, off int64) (n int, skipped int, err error) {
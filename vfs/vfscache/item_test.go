@@ -15,6 +15,7 @@ import (
 	"github.com/rclone/rclone/fs"
 	"github.com/rclone/rclone/fstest"
 	"github.com/rclone/rclone/lib/random"
+	"github.com/rclone/rclone/lib/ranges"
 	"github.com/rclone/rclone/lib/readers"
 	"github.com/rclone/rclone/vfs/vfscommon"
 	"github.com/stretchr/testify/assert"
@@ -180,33 +181,62 @@ func TestItemTruncateExisting(t *testing.T) {
 	checkObject(t, r, "existing", contents[:40]+zeroes[:20])
 }
 
+func TestItemEnsureRange(t *testing.T) {
+	r, c := newItemTestCache(t)
+
+	contents, obj, item := newFile(t, r, c, "existing")
+
+	// Head and tail including a tail which runs off the end
+	require.NoError(t, item.EnsureRange(obj, 0, 10))
+	require.NoError(t, item.EnsureRange(obj, 90, 20))
+	require.NoError(t, item.EnsureRange(obj, 1000, 10))
+	assert.False(t, item.inUse())
+	assert.True(t, item.info.Pinned)
+	assert.True(t, item.HasRange(ranges.Range{Pos: 0, Size: 10}))
+	assert.True(t, item.HasRange(ranges.Range{Pos: 90, Size: 10}))
+
+	// Read the pinned ranges from the cache only
+	require.NoError(t, item.Open(obj))
+	buf := make([]byte, 10)
+	n, err := item.ReadAt(buf, 90, true)
+	require.NoError(t, err)
+	assert.Equal(t, contents[90:], string(buf[:n]))
+	require.NoError(t, item.Close(nil))
+
+	// Pinned items are not removed for age but are for quota
+	removed, _ := item.RemoveNotInUse(time.Nanosecond, false)
+	assert.False(t, removed)
+	removed, _ = item.RemoveNotInUse(0, false)
+	assert.True(t, removed)
+}
+
 func TestItemReadAt(t *testing.T) {
 	r, c := newItemTestCache(t)
 
 	contents, obj, item := newFile(t, r, c, "existing")
 	buf := make([]byte, 10)
 
-	_, err := item.ReadAt(buf, 10)
+	_, err := item.ReadAt(buf, 10, false)
 	require.Error(t, err)
 
 	require.NoError(t, item.Open(obj))
 
-	n, err := item.ReadAt(buf, 10)
+	n, err := item.ReadAt(buf, 10, false)
 	assert.Equal(t, 10, n)
 	require.NoError(t, err)
 	assert.Equal(t, contents[10:20], string(buf[:n]))
 
-	n, err = item.ReadAt(buf, 95)
+	n, err = item.ReadAt(buf, 95, false)
 	assert.Equal(t, 5, n)
 	assert.Equal(t, io.EOF, err)
 	assert.Equal(t, contents[95:], string(buf[:n]))
 
-	n, err = item.ReadAt(buf, 1000)
+	n, err = item.ReadAt(buf, 1000, false)
 	assert.Equal(t, 0, n)
 	assert.Equal(t, io.EOF, err)
 	assert.Equal(t, contents[:0], string(buf[:n]))
 
-	n, err = item.ReadAt(buf, -1)
+	n, err = item.ReadAt(buf, -1, false)
 	assert.Equal(t, 0, n)
 	assert.Equal(t, io.EOF, err)
 	assert.Equal(t, contents[:0], string(buf[:n]))
@@ -214,6 +244,60 @@ func TestItemReadAt(t *testing.T) {
 	require.NoError(t, item.Close(nil))
 }
 
+// Reads of the ranges found present while the downloaders write the
+// same ranges must never see them half written
+func TestItemReadAtWhileDownloading(t *testing.T) {
+	r, c := newItemTestCache(t)
+	const (
+		size  = 1 << 20
+		chunk = 64 << 10
+	)
+	contents, obj, item := newFileLength(t, r, c, "existing", size)
+	require.NoError(t, item.Open(obj))
+	defer func() {
+		require.NoError(t, item.Close(nil))
+	}()
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for _, i := range rand.Perm(size / chunk) {
+			off := i * chunk
+			// overlap the previous chunk to write some present ranges again
+			start := max(off-chunk/2, 0)
+			_, _, err := item.WriteAtNoOverwrite([]byte(contents[start:off+chunk]), int64(start))
+			assert.NoError(t, err)
+		}
+	}()
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, chunk/4)
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				off := rand.Int63n(size - int64(len(buf)))
+				if !item.GetInfoRsPresent(off, int64(len(buf))) {
+					continue
+				}
+				n, err := item.ReadAt(buf, off, true)
+				if !assert.NoError(t, err) || !assert.Equal(t, contents[off:off+int64(n)], string(buf[:n])) {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.True(t, item.GetInfoRsPresent(0, size))
+}
+
 func TestItemWriteAtNew(t *testing.T) {
 	r, c := newItemTestCache(t)
 	item, _ := c.get("potato")
@@ -297,6 +381,61 @@ func TestItemLoadMeta(t *testing.T) {
 	assert.Equal(t, info, info2)
 }
 
+// Ranges the metadata has present beyond the end of the cache file
+// are read from the source again and dropped from the metadata
+func TestItemRepairRanges(t *testing.T) {
+	r, c := newItemTestCache(t)
+
+	contents, obj, item := newFile(t, r, c, "existing")
+	buf := make([]byte, 10)
+
+	// Download the whole file then cut the cache file short
+	require.NoError(t, item.Open(obj))
+	_, err := item.ReadAt(make([]byte, 100), 0, false)
+	require.NoError(t, err)
+	require.NoError(t, item.Close(nil))
+	assert.Equal(t, ranges.Ranges{{Pos: 0, Size: 100}}, item.info.Rs)
+	require.NoError(t, os.Truncate(c.toOSPath("existing"), 10))
+
+	require.NoError(t, item.Open(obj))
+	assert.Equal(t, ranges.Ranges{{Pos: 0, Size: 10}}, item.info.Rs)
+	assert.False(t, item.HasRange(ranges.Range{Pos: 50, Size: 10}))
+	n, err := item.ReadAt(buf, 50, false)
+	require.NoError(t, err)
+	assert.Equal(t, contents[50:60], string(buf[:n]))
+	n, err = item.ReadAt(buf, 0, true)
+	require.NoError(t, err)
+	assert.Equal(t, contents[:10], string(buf[:n]))
+	require.NoError(t, item.Close(nil))
+
+	// The repaired metadata was saved
+	c.mu.Lock()
+	delete(c.item, item.name)
+	c.mu.Unlock()
+	item2, _ := c._get("existing")
+	exists, err := item2.load()
+	require.NoError(t, err)
+	require.True(t, exists)
+	assert.True(t, item2.info.Rs.Present(ranges.Range{Pos: 50, Size: 10}))
+	assert.False(t, item2.info.Rs.Present(ranges.Range{Pos: 10, Size: 40}))
+}
+
+// A write which failed doesn't mark any range present
+func TestItemWriteAtFailed(t *testing.T) {
+	r, c := newItemTestCache(t)
+
+	_, obj, item := newFile(t, r, c, "existing")
+	require.NoError(t, item.Open(obj))
+	rs := item.info.Rs
+	require.NoError(t, item.fd.Close())
+	_, err := item.WriteAt([]byte("hello"), 200)
+	require.Error(t, err)
+	assert.Equal(t, rs, item.info.Rs)
+	assert.Equal(t, int64(100), item.info.Size)
+	assert.False(t, item.info.Dirty)
+	item.fd = nil
+}
+
 func TestItemReload(t *testing.T) {
 	r, c := newItemTestCache(t)
 
@@ -356,7 +495,7 @@ func TestItemReloadRemoteGone(t *testing.T) {
 
 	// Read something to instantiate the cache file
 	buf := make([]byte, 10)
-	_, err = item.ReadAt(buf, 10)
+	_, err = item.ReadAt(buf, 10, false)
 	require.NoError(t, err)
 
 	// Test cache file present
@@ -371,19 +510,56 @@ func TestItemReloadRemoteGone(t *testing.T) {
 	// Re-open with no object
 	require.NoError(t, item.Open(nil))
 
-	// Check size is now 0
+	// The cached data is never removed by this fork so the size is
+	// unchanged
 	size, err = item.GetSize()
 	require.NoError(t, err)
-	assert.Equal(t, int64(0), size)
+	assert.Equal(t, int64(100), size)
 
-	// Test cache file is now empty
+	// and the cache file is kept
 	fi, err := os.Stat(item.c.toOSPath(item.name))
 	require.NoError(t, err)
-	assert.Equal(t, int64(0), fi.Size())
+	assert.Equal(t, int64(100), fi.Size())
+	assert.True(t, item.HasRange(ranges.Range{Pos: 10, Size: 10}))
 
 	require.NoError(t, item.Close(nil))
 }
 
+func TestItemReloadImmutable(t *testing.T) {
+	for _, immutable := range []bool{false, true} {
+		t.Run(fmt.Sprintf("immutable=%v", immutable), func(t *testing.T) {
+			r, c := newItemTestCache(t)
+			features := c.fremote.Features()
+			oldImmutable := features.Immutable
+			features.Immutable = immutable
+			t.Cleanup(func() { features.Immutable = oldImmutable })
+
+			_, obj, item := newFile(t, r, c, "existing")
+
+			// Read something to instantiate the cache file
+			require.NoError(t, item.Open(obj))
+			buf := make([]byte, 10)
+			_, err := item.ReadAt(buf, 10, false)
+			require.NoError(t, err)
+			require.NoError(t, item.Close(nil))
+			require.True(t, item.HasRange(ranges.Range{Pos: 10, Size: 10}))
+
+			// Change only the modtime the remote reports
+			oldFingerprint := item.info.Fingerprint
+			require.NoError(t, obj.SetModTime(context.Background(), fstest.Time("2006-01-02T15:04:05Z")))
+			obj, err = r.Fremote.NewObject(context.Background(), "existing")
+			require.NoError(t, err)
+
+			// the cached data is kept whatever the remote, only the
+			// fingerprint is updated
+			require.NoError(t, item.Open(obj))
+			assert.NotEqual(t, oldFingerprint, item.info.Fingerprint)
+			assert.True(t, item.HasRange(ranges.Range{Pos: 10, Size: 10}))
+			require.NoError(t, item.Close(nil))
+		})
+	}
+}
+
 func TestItemReloadCacheStale(t *testing.T) {
 	r, c := newItemTestCache(t)
 
@@ -398,7 +574,7 @@ func TestItemReloadCacheStale(t *testing.T) {
 
 	// Read something to instantiate the cache file
 	buf := make([]byte, 10)
-	_, err = item.ReadAt(buf, 10)
+	_, err = item.ReadAt(buf, 10, false)
 	require.NoError(t, err)
 
 	// Test cache file present
@@ -420,29 +596,23 @@ func TestItemReloadCacheStale(t *testing.T) {
 	assert.NotEqual(t, oldFingerprint, item.info.Fingerprint)
 	assert.NotEqual(t, "", item.info.Fingerprint)
 
-	// Check size is now 110
+	// The cached data is never removed by this fork as the remotes it
+	// is used with don't change the contents of their objects, so
+	// the cache file keeps its size
 	size, err = item.GetSize()
 	require.NoError(t, err)
-	assert.Equal(t, int64(110), size)
-
-	// Test cache file is now correct size
+	assert.Equal(t, int64(100), size)
 	fi, err := os.Stat(item.c.toOSPath(item.name))
 	require.NoError(t, err)
-	assert.Equal(t, int64(110), fi.Size())
+	assert.Equal(t, int64(100), fi.Size())
 
-	// Write to the file to make it dirty
-	// This checks we aren't reusing stale data
-	n, err := item.WriteAt([]byte("HELLO"), 0)
+	// and its data is read from the cache
+	n, err := item.ReadAt(buf, 10, true)
 	require.NoError(t, err)
-	assert.Equal(t, 5, n)
-	assert.Equal(t, true, item.IsDirty())
+	assert.Equal(t, contents[10:10+n], string(buf[:n]))
+	assert.False(t, item.IsDirty())
 
 	require.NoError(t, item.Close(nil))
-
-	// Now check with all that swizzling stuff around that the
-	// object is correct
-
-	checkObject(t, r, "existing", "HELLO"+contents2[5:])
 }
 
 func TestItemReadWrite(t *testing.T) {
@@ -489,7 +659,7 @@ func TestItemReadWrite(t *testing.T) {
 	// It returns eof true if the end of file has been reached
 	readCheckBuf := func(t *testing.T, in io.ReadSeeker, buf, buf2 []byte, item *Item, offset int64, N int) (n int, eof bool) {
 		what := fmt.Sprintf("buf=%p, buf2=%p, item=%p, offset=%d, N=%d", buf, buf2, item, offset, N)
-		n, err := item.ReadAt(buf, offset)
+		n, err := item.ReadAt(buf, offset, false)
 
 		_, err2 := in.Seek(offset, io.SeekStart)
 		require.NoError(t, err2, what)
@@ -608,7 +778,7 @@ func TestItemHandleCaching(t *testing.T) {
 	require.NoError(t, item.Open(obj))
 
 	buf := make([]byte, 10)
-	n, err := item.ReadAt(buf, 0)
+	n, err := item.ReadAt(buf, 0, false)
 	assert.Equal(t, 10, n)
 	require.NoError(t, err)
 	assert.Equal(t, contents[:10], string(buf[:n]))
@@ -626,7 +796,7 @@ func TestItemHandleCaching(t *testing.T) {
 	require.NoError(t, item.Open(obj))
 
 	// Read data to verify it works
-	n, err = item.ReadAt(buf, 10)
+	n, err = item.ReadAt(buf, 10, false)
 	assert.Equal(t, 10, n)
 	require.NoError(t, err)
 	assert.Equal(t, contents[10:20], string(buf[:n]))
@@ -672,7 +842,7 @@ func TestItemHandleCachingReset(t *testing.T) {
 	require.NoError(t, item.Open(obj))
 
 	buf := make([]byte, 10)
-	_, err := item.ReadAt(buf, 0)
+	_, err := item.ReadAt(buf, 0, false)
 	require.NoError(t, err)
 
 	require.NoError(t, item.Close(nil))
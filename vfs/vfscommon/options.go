@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"runtime"
+	"sync"
 	"time"
 
 	"github.com/rclone/rclone/fs"
@@ -75,6 +76,11 @@ var OptionsInfo = fs.Options{{
 	Default: fs.SizeSuffix(-1),
 	Help:    "Target minimum free space on the disk containing the cache",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_cache_max_file_size",
+	Default: fs.SizeSuffix(-1),
+	Help:    "Read files larger than this directly from the remote without caching them",
+	Groups:  "VFS",
 }, {
 	Name:    "vfs_read_chunk_size",
 	Default: 128 * fs.Mebi,
@@ -175,10 +181,64 @@ var OptionsInfo = fs.Options{{
 	Default: "",
 	Help:    "Set the extension to read metadata from.",
 	Groups:  "VFS",
+}, {
+	Name:    "vfs_hybrid",
+	Default: true,
+	Help:    "Read files flagged in the hybrid check dir directly from the remote in cache mode full",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_hybrid_check_dir",
+	Default: "/Cache_Check_Video_Library/cache_check",
+	Help:    "Directory with a file per remote path to read directly in hybrid mode",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_hybrid_read_ahead",
+	Default: fs.SizeSuffix(0),
+	Help:    "Size of the ranges requested from the remote for direct reads, 0 to use the chunk size (can be changed with options/set)",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_hybrid_pin_size",
+	Default: 16 * fs.Mebi,
+	Help:    "Size of the head and tail of each file pinned in the cache by vfs/warm",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_hybrid_bwlimit",
+	Default: fs.SizeSuffix(0),
+	Help:    "Bandwidth limit in bytes/s for direct reads of each handle, 0 for off (can be changed with options/set)",
+	Groups:  "VFS",
+}, {
+	Name:    "vfs_hybrid_stream_wait",
+	Default: fs.Duration(10 * time.Second),
+	Help:    "Time direct reads wait for a stream when the remote has as many open as it allows",
+	Groups:  "VFS",
 }}
 
 func init() {
-	fs.RegisterGlobalOptions(fs.OptionsInfo{Name: "vfs", Opt: &Opt, Options: OptionsInfo})
+	fs.RegisterGlobalOptions(fs.OptionsInfo{Name: "vfs", Opt: &Opt, Options: OptionsInfo, Reload: reload})
+}
+
+var (
+	reloadMu    sync.Mutex
+	reloadHooks []func(ctx context.Context, opt *Options)
+)
+
+// OnReload registers fn to be called with the new options when they
+// are changed with options/set so running VFSes can update the
+// options which can be changed dynamically.
+func OnReload(fn func(ctx context.Context, opt *Options)) {
+	reloadMu.Lock()
+	reloadHooks = append(reloadHooks, fn)
+	reloadMu.Unlock()
+}
+
+// reload calls the reload hooks with the new options
+func reload(ctx context.Context) error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	for _, fn := range reloadHooks {
+		fn(ctx, &Opt)
+	}
+	return nil
 }
 
 // Options is options for creating the vfs
@@ -204,6 +264,7 @@ type Options struct {
 	CacheMaxAge        fs.Duration   `config:"vfs_cache_max_age"`
 	CacheMaxSize       fs.SizeSuffix `config:"vfs_cache_max_size"`
 	CacheMinFreeSpace  fs.SizeSuffix `config:"vfs_cache_min_free_space"`
+	CacheMaxFileSize   fs.SizeSuffix `config:"vfs_cache_max_file_size"`
 	CachePollInterval  fs.Duration   `config:"vfs_cache_poll_interval"`
 	CaseInsensitive    bool          `config:"vfs_case_insensitive"`
 	BlockNormDupes     bool          `config:"vfs_block_norm_dupes"`
@@ -216,6 +277,12 @@ type Options struct {
 	DiskSpaceTotalSize fs.SizeSuffix `config:"vfs_disk_space_total_size"`
 	HandleCaching      fs.Duration   `config:"vfs_handle_caching"`     // time to keep handle alive after last close
 	MetadataExtension  string        `config:"vfs_metadata_extension"` // if set respond to files with this extension with metadata
+	Hybrid             bool          `config:"vfs_hybrid"`             // if set read files flagged in HybridCheckDir directly
+	HybridCheckDir     string        `config:"vfs_hybrid_check_dir"`   // directory of flag files for direct reads
+	HybridReadAhead    fs.SizeSuffix `config:"vfs_hybrid_read_ahead"`  // size of ranges requested for direct reads - dynamic
+	HybridPinSize      fs.SizeSuffix `config:"vfs_hybrid_pin_size"`    // size of head and tail pinned by vfs/warm
+	HybridBwLimit      fs.SizeSuffix `config:"vfs_hybrid_bwlimit"`     // bandwidth limit for direct reads per handle - dynamic
+	HybridStreamWait   fs.Duration   `config:"vfs_hybrid_stream_wait"` // time to wait for a stream of a remote at its limit
 }
 
 // Opt is the default options modified by the environment variables and command line flags